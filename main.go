@@ -2,8 +2,12 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"os"
 
 	"notes-api/pkg/api"
+	"notes-api/pkg/config"
 
 	"github.com/sirupsen/logrus"
 )
@@ -11,7 +15,14 @@ import (
 func main() {
 	ctx := context.Background()
 
-	if err := api.ListenAndServe(ctx); err != nil {
+	cfg, err := config.Load(os.Args[1:], os.LookupEnv)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	} else if err != nil {
+		logrus.WithContext(ctx).WithError(err).Fatal("Error loading configuration")
+	}
+
+	if err := api.ListenAndServe(ctx, cfg); err != nil {
 		logrus.WithContext(ctx).WithError(err).Error("Error starting API server")
 	}
 }
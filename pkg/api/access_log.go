@@ -0,0 +1,102 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"notes-api/pkg/config"
+
+	"github.com/sirupsen/logrus"
+)
+
+// accessRecord collects what the handlers of a request learn about it that the access log needs, such as the route
+// that the router matches it to and the user that authenticate finds it to be from.
+type accessRecord struct {
+	route  string
+	userID string
+}
+
+// recordRoute fills in the template of the route that the router matched a request to in its access record. The
+// router only runs it for requests that match a route.
+func recordRoute(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if record, ok := r.Context().Value(accessRecordKey).(*accessRecord); ok {
+			record.route = routeTemplate(r)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// logRequests writes a record of every request to out once it has been served, with its method, route template,
+// status, body size, latency, user, request ID and remote address. The records are logrus JSON or text entries, or
+// Apache combined log lines followed by the route template, request ID and latency in microseconds. Only the sampled
+// share of the requests to sampled paths is logged, except for those that fail with a server error.
+func logRequests(cfg config.AccessLogConfig, out io.Writer) func(http.Handler) http.Handler {
+	sampledPaths := make(map[string]bool)
+	for _, path := range cfg.SampledPaths {
+		sampledPaths[path] = true
+	}
+
+	// Combined lines are written under a lock, as logrus entries are, so that records of concurrent requests cannot
+	// interleave.
+	var outMu sync.Mutex
+	logger := logrus.New()
+	logger.SetOutput(out)
+	if cfg.Format == config.AccessLogJSON {
+		logger.SetFormatter(&logrus.JSONFormatter{})
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			record := &accessRecord{route: "unknown"}
+			recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			start := time.Now()
+			next.ServeHTTP(recorder, r.WithContext(context.WithValue(r.Context(), accessRecordKey, record)))
+			latency := time.Since(start)
+
+			if sampledPaths[r.URL.Path] && recorder.status < http.StatusInternalServerError && rand.Float64() >= cfg.SampleRate {
+				return
+			}
+
+			host, _, err := net.SplitHostPort(r.RemoteAddr)
+			if err != nil {
+				host = r.RemoteAddr
+			}
+
+			if cfg.Format == config.AccessLogCombined {
+				outMu.Lock()
+				defer outMu.Unlock()
+				fmt.Fprintf(out, "%v - %v [%v] \"%v %v %v\" %v %v \"%v\" \"%v\" \"%v\" %v %v\n",
+					host, orDash(record.userID), start.Format("02/Jan/2006:15:04:05 -0700"), r.Method, r.URL.RequestURI(), r.Proto,
+					recorder.status, orDash(recorder.bytes), orDash(r.Referer()), orDash(r.UserAgent()),
+					record.route, orDash(getRequestID(r.Context())), latency.Microseconds())
+				return
+			}
+
+			logger.WithFields(logrus.Fields{
+				"method":     r.Method,
+				"route":      record.route,
+				"status":     recorder.status,
+				"bytes":      recorder.bytes,
+				"latencyMs":  float64(latency) / float64(time.Millisecond),
+				"userId":     record.userID,
+				"requestId":  getRequestID(r.Context()),
+				"remoteAddr": host,
+			}).Info("Request served")
+		})
+	}
+}
+
+// orDash returns the value, or "-", which combined log lines have in place of empty values.
+func orDash(value interface{}) interface{} {
+	if value == "" || value == 0 {
+		return "-"
+	}
+	return value
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"notes-api/pkg/config"
+	"notes-api/pkg/models"
+	"notes-api/pkg/testhelper/mocks"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// accessLogServer serves the API with an access log in the given format, and returns the log along with it.
+func accessLogServer(cfg config.AccessLogConfig, svc *mocks.NoteServiceHandler) (http.Handler, *bytes.Buffer) {
+	out := &bytes.Buffer{}
+	return identifyRequests(logRequests(cfg, out)(route(svc))), out
+}
+
+func TestAPI_LogRequests_ShouldWriteJSONRecord(t *testing.T) {
+	mockSvc := &mocks.NoteServiceHandler{}
+	mockSvc.On("ValidateToken", mock.Anything, "token").Return(&models.Principal{UserID: "user"}, nil)
+	mockSvc.On("GetRevisions", mock.Anything, "user", "1").Return([]models.Revision{}, nil)
+
+	handler, out := accessLogServer(config.AccessLogConfig{Format: config.AccessLogJSON}, mockSvc)
+
+	req, err := http.NewRequest(http.MethodGet, "/note/1/revisions", nil)
+	require.Nil(t, err)
+	req.RemoteAddr = "192.0.2.7:51234"
+	req.Header.Set("Authorization", "Bearer token")
+	req.Header.Set("X-Request-ID", "abc")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var record map[string]interface{}
+	require.Nil(t, json.Unmarshal(out.Bytes(), &record))
+	require.Greater(t, record["latencyMs"], 0.0)
+	delete(record, "latencyMs")
+	delete(record, "time")
+	require.Equal(t, map[string]interface{}{
+		"level":      "info",
+		"msg":        "Request served",
+		"method":     "GET",
+		"route":      "/note/{id}/revisions",
+		"status":     200.0,
+		"bytes":      float64(recorder.Body.Len()),
+		"userId":     "user",
+		"requestId":  "abc",
+		"remoteAddr": "192.0.2.7",
+	}, record)
+}
+
+func TestAPI_LogRequests_ShouldWriteCombinedLine(t *testing.T) {
+	handler, out := accessLogServer(config.AccessLogConfig{Format: config.AccessLogCombined}, &mocks.NoteServiceHandler{})
+
+	req, err := http.NewRequest(http.MethodGet, "/note/1?fields=name", nil)
+	require.Nil(t, err)
+	req.RemoteAddr = "192.0.2.7:51234"
+	req.Header.Set("User-Agent", "curl/7.68.0")
+	req.Header.Set("X-Request-ID", "abc")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	require.Regexp(t, `^192\.0\.2\.7 - - \[\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}\] "GET /note/1\?fields=name HTTP/1\.1" 400 \d+ "-" "curl/7\.68\.0" "/note/{id}" abc \d+\n$`, out.String())
+}
+
+func TestAPI_LogRequests_ShouldLogUnknownRouteOfUnmatchedRequest(t *testing.T) {
+	handler, out := accessLogServer(config.AccessLogConfig{Format: config.AccessLogCombined}, &mocks.NoteServiceHandler{})
+
+	req, err := http.NewRequest(http.MethodGet, "/nowhere", nil)
+	require.Nil(t, err)
+	req.Header.Set("X-Request-ID", "abc")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	require.Regexp(t, `"GET /nowhere HTTP/1\.1" 404 \d+ "-" "-" "unknown" abc \d+\n$`, out.String())
+}
+
+func TestAPI_LogRequests_ShouldOnlyLogSampledPathsWhenTheyFail(t *testing.T) {
+	mockSvc := &mocks.NoteServiceHandler{}
+	mockSvc.On("Ping", mock.Anything).Return(nil).Once()
+	mockSvc.On("Ping", mock.Anything).Return(errors.New("server selection timeout")).Once()
+
+	handler, out := accessLogServer(config.AccessLogConfig{Format: config.AccessLogText, SampledPaths: []string{"/health"}}, mockSvc)
+
+	for _, status := range []int{http.StatusOK, http.StatusInternalServerError} {
+		req, err := http.NewRequest(http.MethodGet, "/health", nil)
+		require.Nil(t, err)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		require.Equal(t, status, recorder.Code)
+	}
+
+	require.Equal(t, 1, bytes.Count(out.Bytes(), []byte("\n")))
+	require.Contains(t, out.String(), "status=500")
+}
@@ -2,90 +2,245 @@ package api
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"notes-api/pkg/auth"
+	"notes-api/pkg/config"
 	"notes-api/pkg/dao"
+	"notes-api/pkg/export"
 	"notes-api/pkg/external"
+	"notes-api/pkg/metrics"
 	"notes-api/pkg/models"
 	"notes-api/pkg/service"
+	"notes-api/pkg/tracing"
 
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-func ListenAndServe(ctx context.Context) error {
-	headers := handlers.AllowedHeaders([]string{"X-Requested-With", "Access-Control-Allow-Origin", "Content-Type"})
-	origins := handlers.AllowedOrigins([]string{"*"})
+func ListenAndServe(ctx context.Context, cfg *config.Config) error {
+	headers := handlers.AllowedHeaders([]string{"X-Requested-With", "Access-Control-Allow-Origin", "Content-Type", "If-Match", requestIDHeader})
+	exposedHeaders := handlers.ExposedHeaders([]string{"ETag", requestIDHeader})
+	origins := handlers.AllowedOrigins(cfg.Server.AllowedOrigins)
 	methods := handlers.AllowedMethods([]string{"GET", "POST", "PUT", "DELETE"})
 
-	router, err := route(ctx)
+	appMetrics := metrics.New()
+
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing)
+	if err != nil {
+		logrus.WithError(err).Error("Error configuring tracing")
+		return err
+	}
+	// Deferred, so that the spans of a server that fails to start are exported as well. An unreachable collector is
+	// given no longer than requests in flight are.
+	defer func() {
+		c, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+		defer cancel()
+
+		if err := shutdownTracing(c); err != nil {
+			logrus.WithError(err).Error("Error exporting remaining spans")
+		}
+	}()
+
+	storage, err := newStorage(cfg.Storage)
 	if err != nil {
 		return err
 	}
 
+	notesService, err := newNotesService(cfg, storage, appMetrics)
+	if err != nil {
+		return err
+	}
+
+	logrus.AddHook(requestIDHook{})
+
+	router := route(&tracing.NoteService{Next: notesService})
+	if cfg.Tracing.Exporter != config.TraceExporterNone {
+		router.Use(traceRequests(cfg.Tracing.ServiceName))
+	}
+	if cfg.Server.MetricsPath != "" {
+		router.Handle(cfg.Server.MetricsPath, appMetrics.Handler()).Methods(http.MethodGet)
+		router.Use(observeRequests(appMetrics))
+	}
+
+	handler := handlers.CORS(headers, exposedHeaders, origins, methods)(router)
+	if cfg.AccessLog.Format != config.AccessLogNone {
+		handler = logRequests(cfg.AccessLog, logrus.StandardLogger().Out)(handler)
+	}
+
 	server := &http.Server{
-		Handler:      handlers.CORS(headers, origins, methods)(router),
-		Addr:         ":8006",
-		WriteTimeout: 20 * time.Second,
-		ReadTimeout:  20 * time.Second,
+		Handler:      identifyRequests(handler),
+		Addr:         cfg.Server.Addr,
+		WriteTimeout: cfg.Server.WriteTimeout,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		BaseContext: func(net.Listener) context.Context {
+			return ctx
+		},
 	}
-	shutdownGracefully(server)
+
+	purger := service.TrashPurger{
+		Service:   notesService,
+		Retention: cfg.Trash.Retention,
+		Interval:  cfg.Trash.PurgeInterval,
+	}
+
+	saveWorker := service.SaveWorker{
+		Service:     notesService,
+		Interval:    cfg.Save.PollInterval,
+		Lease:       cfg.Save.JobLease,
+		MaxAttempts: cfg.Save.MaxAttempts,
+		BaseDelay:   cfg.Save.RetryBaseDelay,
+		MaxDelay:    cfg.Save.RetryMaxDelay,
+	}
+
+	workerCtx, stopWorkers := context.WithCancel(ctx)
+	var workers sync.WaitGroup
+	workers.Add(3)
+	go func() {
+		defer workers.Done()
+		purger.Run(workerCtx)
+	}()
+	go func() {
+		defer workers.Done()
+		saveWorker.Run(workerCtx)
+	}()
+	go func() {
+		defer workers.Done()
+		storage.backfill(workerCtx)
+	}()
+
+	shutdown := shutdownGracefully(server, cfg.Server.ShutdownTimeout)
 
 	logrus.WithContext(ctx).Info("Starting API server...")
-	return server.ListenAndServe()
+	err = server.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		<-shutdown
+		err = nil
+	}
+
+	stopWorkers()
+	workers.Wait()
+	return err
 }
 
-func route(ctx context.Context) (*mux.Router, error) {
-	client, err := mongo.Connect(context.Background(), options.Client().ApplyURI(os.Getenv("MONGO_URI")))
+func newNotesService(cfg *config.Config, storage *storage, appMetrics *metrics.Metrics) (*service.NotesService, error) {
+	extHandler := &metrics.ExtAPI{
+		Next: &tracing.ExtAPI{
+			Next: &external.ExtAPI{
+				Client: &tracing.Requester{
+					Next: &http.Client{
+						Timeout: cfg.Services.Timeout,
+					},
+				},
+				ContentServiceURL: cfg.Services.ContentServiceURL,
+				LoginServiceURL:   cfg.Services.LoginServiceURL,
+			},
+		},
+		Metrics: appMetrics,
+	}
+
+	validator, err := newValidator(cfg, extHandler)
 	if err != nil {
-		logrus.WithError(err).Error("Error creating mongo client")
+		logrus.WithError(err).Error("Error configuring token validation")
 		return nil, err
 	}
 
-	notesDao := dao.NotesDao{
-		Client:     client,
-		Database:   os.Getenv("DATABASE"),
-		Collection: os.Getenv("COLLECTION"),
+	tokenCache := auth.NewCache(validator, auth.CacheConfig{
+		TTL:         cfg.Auth.TokenCacheTTL,
+		NegativeTTL: cfg.Auth.TokenCacheNegativeTTL,
+		MaxEntries:  cfg.Auth.TokenCacheSize,
+	})
+	appMetrics.ObserveTokenCache(tokenCache)
+
+	sealer, err := newSealer(cfg.Save)
+	if err != nil {
+		logrus.WithError(err).Error("Error configuring save token key")
+		return nil, err
 	}
 
-	extHandler := external.ExtAPI{
-		Client: &http.Client{
-			Timeout: 5 * time.Second,
-		},
-		ContentServiceURL: os.Getenv("CONTENT_SERVICE_URL"),
-		LoginServiceURL:   os.Getenv("LOGIN_SERVICE_URL"),
-		Token:             "",
+	return &service.NotesService{
+		Dao:       &metrics.NoteDao{Next: &tracing.NoteDao{Next: storage.notes}, Metrics: appMetrics},
+		Notebooks: &metrics.NotebookDao{Next: &tracing.NotebookDao{Next: storage.notebooks}, Metrics: appMetrics},
+		SaveJobs:  &metrics.SaveJobDao{Next: &tracing.SaveJobDao{Next: storage.saveJobs}, Metrics: appMetrics},
+		Ext:       extHandler,
+		Auth:      tokenCache,
+		Tokens:    sealer,
+		Exporters: export.NewRegistry(),
+		SyncDelay: cfg.Save.AutoSyncDelay,
+	}, nil
+}
+
+// newSealer returns the sealer of the tokens of queued saves, under SAVE_TOKEN_KEY or, if it is not set, a random key.
+func newSealer(cfg config.SaveConfig) (*auth.Sealer, error) {
+	if cfg.TokenKey == "" {
+		logrus.Warn("SAVE_TOKEN_KEY is not set, so saves queued before a restart will not be delivered")
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+		return auth.NewSealer(key)
 	}
 
-	notesService := service.NotesService{
-		Dao: &notesDao,
-		Ext: &extHandler,
+	key, err := base64.StdEncoding.DecodeString(cfg.TokenKey)
+	if err != nil {
+		return nil, err
 	}
+	return auth.NewSealer(key)
+}
 
+func route(svc service.NoteServiceHandler) *mux.Router {
 	router := mux.NewRouter()
-	router.Handle("/health", checkHealth(ctx, &notesService)).Methods(http.MethodGet)
-	router.Handle("/notes", getNotes(ctx, &notesService)).Methods(http.MethodGet)
-	router.Handle("/note/{id}", getNote(ctx, &notesService)).Methods(http.MethodGet)
-	router.Handle("/note/{id}", editNote(ctx, &notesService)).Methods(http.MethodPut)
-	router.Handle("/note/{id}", deleteNote(ctx, &notesService)).Methods(http.MethodDelete)
-	router.Handle("/note", createNote(ctx, &notesService)).Methods(http.MethodPost)
-	router.Handle("/save/{id}", sendToContentService(ctx, &notesService)).Methods(http.MethodPost)
-
-	return router, nil
+	router.Use(recordRoute)
+	router.Handle("/health", checkHealth(svc)).Methods(http.MethodGet)
+
+	secured := router.NewRoute().Subrouter()
+	secured.Use(authenticate(svc))
+	secured.Handle("/notes", getNotes(svc)).Methods(http.MethodGet)
+	secured.Handle("/notes/search", searchNotes(svc)).Methods(http.MethodGet)
+	secured.Handle("/note/{id}", getNote(svc)).Methods(http.MethodGet)
+	secured.Handle("/note/{id}", editNote(svc)).Methods(http.MethodPut)
+	secured.Handle("/note/{id}", deleteNote(svc)).Methods(http.MethodDelete)
+	secured.Handle("/note", createNote(svc)).Methods(http.MethodPost)
+	secured.Handle("/save/{id}", sendToContentService(svc)).Methods(http.MethodPost)
+	secured.Handle("/save/jobs/{id}", getSaveJob(svc)).Methods(http.MethodGet)
+	secured.Handle("/import/files", getContentFiles(svc)).Methods(http.MethodGet)
+	secured.Handle("/import", importNotes(svc)).Methods(http.MethodPost)
+	secured.Handle("/note/{id}/revisions", getRevisions(svc)).Methods(http.MethodGet)
+	secured.Handle("/note/{id}/revisions/{rev}", getRevision(svc)).Methods(http.MethodGet)
+	secured.Handle("/note/{id}/revisions/{rev}/restore", restoreRevision(svc)).Methods(http.MethodPost)
+	secured.Handle("/trash", getTrash(svc)).Methods(http.MethodGet)
+	secured.Handle("/trash/{id}/restore", restoreFromTrash(svc)).Methods(http.MethodPost)
+	secured.Handle("/trash/{id}", purgeNote(svc)).Methods(http.MethodDelete)
+	secured.Handle("/tags", getTags(svc)).Methods(http.MethodGet)
+	secured.Handle("/tags/{tag}", renameTag(svc)).Methods(http.MethodPut)
+	secured.Handle("/note/{id}/move", moveNote(svc)).Methods(http.MethodPost)
+	secured.Handle("/notebooks", getNotebooks(svc)).Methods(http.MethodGet)
+	secured.Handle("/notebook/{id}", getNotebook(svc)).Methods(http.MethodGet)
+	secured.Handle("/notebook/{id}", editNotebook(svc)).Methods(http.MethodPut)
+	secured.Handle("/notebook/{id}", deleteNotebook(svc)).Methods(http.MethodDelete)
+	secured.Handle("/notebook", createNotebook(svc)).Methods(http.MethodPost)
+	secured.Handle("/notebook/{id}/move", moveNotebook(svc)).Methods(http.MethodPost)
+
+	return router
 }
 
-func checkHealth(ctx context.Context, svc service.NoteServiceHandler) http.HandlerFunc {
+func checkHealth(svc service.NoteServiceHandler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
 		logger := logrus.WithContext(ctx)
 		defer closeRequestBody(ctx, r)
 
@@ -99,56 +254,88 @@ func checkHealth(ctx context.Context, svc service.NoteServiceHandler) http.Handl
 	}
 }
 
-func getNotes(ctx context.Context, svc service.NoteServiceHandler) http.HandlerFunc {
+func getNotes(svc service.NoteServiceHandler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
 		logger := logrus.WithContext(ctx)
 		defer closeRequestBody(ctx, r)
 
-		token, err := getAuthToken(r)
+		principal, err := getPrincipal(r)
 		if err != nil {
-			logrus.WithError(err).Error("Error retrieving authorization token from request")
-			respondWithError(ctx, w, http.StatusBadRequest, err.Error())
+			logger.WithError(err).Error("Error retrieving principal from request")
+			respondWithError(ctx, w, http.StatusUnauthorized, err.Error())
 			return
 		}
 
-		if err := svc.ValidateToken(ctx, token); err != nil {
-			logrus.WithError(err).Error("Error validating token")
-			respondWithError(ctx, w, http.StatusUnauthorized, err.Error())
+		query, err := getNoteQuery(r)
+		if err != nil {
+			logger.WithError(err).Error("Error parsing note query")
+			respondWithError(ctx, w, http.StatusBadRequest, err.Error())
 			return
 		}
 
-		notes, err := svc.GetNotes(ctx, "")
+		page, err := svc.ListNotes(ctx, principal.UserID, query)
 		if err != nil {
 			logger.WithError(err).Error("Error retrieving notes")
-			respondWithError(ctx, w, http.StatusInternalServerError, err.Error())
+			respondWithError(ctx, w, errorStatus(err), err.Error())
 			return
 		}
 
-		respondWithSuccess(ctx, w, http.StatusOK, notes)
+		respondWithSuccess(ctx, w, http.StatusOK, page)
 	}
 }
 
-func getNote(ctx context.Context, svc service.NoteServiceHandler) http.HandlerFunc {
+func searchNotes(svc service.NoteServiceHandler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
 		logger := logrus.WithContext(ctx)
 		defer closeRequestBody(ctx, r)
 
-		token, err := getAuthToken(r)
+		principal, err := getPrincipal(r)
 		if err != nil {
-			logrus.WithError(err).Error("Error retrieving authorization token from request")
+			logger.WithError(err).Error("Error retrieving principal from request")
+			respondWithError(ctx, w, http.StatusUnauthorized, err.Error())
+			return
+		}
+
+		params := r.URL.Query()
+		query := models.SearchQuery{
+			Text: params.Get("q"),
+			Next: params.Get("next"),
+		}
+		if query.Limit, err = getLimit(params); err != nil {
+			logger.WithError(err).Error("Error parsing search query")
 			respondWithError(ctx, w, http.StatusBadRequest, err.Error())
 			return
 		}
 
-		if err := svc.ValidateToken(ctx, token); err != nil {
-			logrus.WithError(err).Error("Error validating token")
+		page, err := svc.SearchNotes(ctx, principal.UserID, query)
+		if err != nil {
+			logger.WithError(err).Error("Error searching notes")
+			respondWithError(ctx, w, errorStatus(err), err.Error())
+			return
+		}
+
+		respondWithSuccess(ctx, w, http.StatusOK, page)
+	}
+}
+
+func getNote(svc service.NoteServiceHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := logrus.WithContext(ctx)
+		defer closeRequestBody(ctx, r)
+
+		principal, err := getPrincipal(r)
+		if err != nil {
+			logger.WithError(err).Error("Error retrieving principal from request")
 			respondWithError(ctx, w, http.StatusUnauthorized, err.Error())
 			return
 		}
 
 		id := mux.Vars(r)["id"]
 
-		notes, err := svc.GetNotes(ctx, id)
+		notes, err := svc.GetNotes(ctx, principal.UserID, id)
 		if err != nil {
 			logger.WithError(err).Error("Error retrieving notes")
 			respondWithError(ctx, w, http.StatusInternalServerError, err.Error())
@@ -163,30 +350,33 @@ func getNote(ctx context.Context, svc service.NoteServiceHandler) http.HandlerFu
 			return
 		}
 
+		w.Header().Set("ETag", etag(notes[0].Version))
 		respondWithSuccess(ctx, w, http.StatusOK, notes[0])
 	}
 }
 
-func editNote(ctx context.Context, svc service.NoteServiceHandler) http.HandlerFunc {
+func editNote(svc service.NoteServiceHandler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
 		logger := logrus.WithContext(ctx)
 		defer closeRequestBody(ctx, r)
 
-		token, err := getAuthToken(r)
+		principal, err := getPrincipal(r)
 		if err != nil {
-			logrus.WithError(err).Error("Error retrieving authorization token from request")
-			respondWithError(ctx, w, http.StatusBadRequest, err.Error())
-			return
-		}
-
-		if err := svc.ValidateToken(ctx, token); err != nil {
-			logrus.WithError(err).Error("Error validating token")
+			logger.WithError(err).Error("Error retrieving principal from request")
 			respondWithError(ctx, w, http.StatusUnauthorized, err.Error())
 			return
 		}
 
 		id := mux.Vars(r)["id"]
 
+		version, err := getIfMatchVersion(r)
+		if err != nil {
+			logger.WithError(err).Error("Error retrieving note version from request")
+			respondWithError(ctx, w, errorStatus(err), err.Error())
+			return
+		}
+
 		var note models.NoteRequest
 		if err := json.NewDecoder(r.Body).Decode(&note); err != nil {
 			logger.WithError(err).Error("Error decoding request body")
@@ -194,30 +384,27 @@ func editNote(ctx context.Context, svc service.NoteServiceHandler) http.HandlerF
 			return
 		}
 
-		if err := svc.UpdateNote(ctx, id, note); err != nil {
+		newVersion, err := svc.UpdateNote(ctx, principal.UserID, getToken(r), id, version, note)
+		if err != nil {
 			logger.WithError(err).Error("Error updating note")
-			respondWithError(ctx, w, http.StatusInternalServerError, err.Error())
+			respondWithError(ctx, w, errorStatus(err), err.Error())
 			return
 		}
 
+		w.Header().Set("ETag", etag(newVersion))
 		respondWithSuccess(ctx, w, http.StatusOK, fmt.Sprintf("Note with ID '%v' updated successfully", id))
 	}
 }
 
-func createNote(ctx context.Context, svc service.NoteServiceHandler) http.HandlerFunc {
+func createNote(svc service.NoteServiceHandler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
 		logger := logrus.WithContext(ctx)
 		defer closeRequestBody(ctx, r)
 
-		token, err := getAuthToken(r)
+		principal, err := getPrincipal(r)
 		if err != nil {
-			logrus.WithError(err).Error("Error retrieving authorization token from request")
-			respondWithError(ctx, w, http.StatusBadRequest, err.Error())
-			return
-		}
-
-		if err := svc.ValidateToken(ctx, token); err != nil {
-			logrus.WithError(err).Error("Error validating token")
+			logger.WithError(err).Error("Error retrieving principal from request")
 			respondWithError(ctx, w, http.StatusUnauthorized, err.Error())
 			return
 		}
@@ -229,10 +416,10 @@ func createNote(ctx context.Context, svc service.NoteServiceHandler) http.Handle
 			return
 		}
 
-		id, err := svc.CreateNote(ctx, note)
+		id, err := svc.CreateNote(ctx, principal.UserID, note)
 		if err != nil {
 			logger.WithError(err).Error("Error creating note")
-			respondWithError(ctx, w, http.StatusInternalServerError, err.Error())
+			respondWithError(ctx, w, errorStatus(err), err.Error())
 			return
 		}
 
@@ -240,65 +427,301 @@ func createNote(ctx context.Context, svc service.NoteServiceHandler) http.Handle
 	}
 }
 
-func deleteNote(ctx context.Context, svc service.NoteServiceHandler) http.HandlerFunc {
+func deleteNote(svc service.NoteServiceHandler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
 		logger := logrus.WithContext(ctx)
 		defer closeRequestBody(ctx, r)
 
-		token, err := getAuthToken(r)
+		principal, err := getPrincipal(r)
 		if err != nil {
-			logrus.WithError(err).Error("Error retrieving authorization token from request")
-			respondWithError(ctx, w, http.StatusBadRequest, err.Error())
+			logger.WithError(err).Error("Error retrieving principal from request")
+			respondWithError(ctx, w, http.StatusUnauthorized, err.Error())
+			return
+		}
+
+		id := mux.Vars(r)["id"]
+
+		if err := svc.DeleteNote(ctx, principal.UserID, id); err != nil {
+			logger.WithError(err).Error("Error deleting note")
+			respondWithError(ctx, w, errorStatus(err), err.Error())
 			return
 		}
 
-		if err := svc.ValidateToken(ctx, token); err != nil {
-			logrus.WithError(err).Error("Error validating token")
+		respondWithSuccess(ctx, w, http.StatusOK, fmt.Sprintf("Note with ID '%v' moved to trash", id))
+	}
+}
+
+func sendToContentService(svc service.NoteServiceHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := logrus.WithContext(ctx)
+		defer closeRequestBody(ctx, r)
+
+		principal, err := getPrincipal(r)
+		if err != nil {
+			logger.WithError(err).Error("Error retrieving principal from request")
 			respondWithError(ctx, w, http.StatusUnauthorized, err.Error())
 			return
 		}
 
 		id := mux.Vars(r)["id"]
+		format := r.URL.Query().Get("format")
 
-		if err := svc.DeleteNote(ctx, id); err != nil {
-			logger.WithError(err).Error("Error deleting note")
-			respondWithError(ctx, w, http.StatusInternalServerError, err.Error())
+		job, err := svc.SendToContentService(ctx, principal.UserID, getToken(r), id, format)
+		if err != nil {
+			logger.WithError(err).Error("Error sending note to content service")
+			respondWithError(ctx, w, errorStatus(err), err.Error())
 			return
 		}
 
-		respondWithSuccess(ctx, w, http.StatusOK, fmt.Sprintf("Note with ID '%v' deleted successfully", id))
+		w.Header().Set("Location", fmt.Sprintf("/save/jobs/%v", job.ID.Hex()))
+		respondWithSuccess(ctx, w, http.StatusAccepted, job)
 	}
 }
 
-func sendToContentService(ctx context.Context, svc service.NoteServiceHandler) http.HandlerFunc {
+func getSaveJob(svc service.NoteServiceHandler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
 		logger := logrus.WithContext(ctx)
 		defer closeRequestBody(ctx, r)
 
-		token, err := getAuthToken(r)
+		principal, err := getPrincipal(r)
 		if err != nil {
-			logrus.WithError(err).Error("Error retrieving authorization token from request")
-			respondWithError(ctx, w, http.StatusBadRequest, err.Error())
+			logger.WithError(err).Error("Error retrieving principal from request")
+			respondWithError(ctx, w, http.StatusUnauthorized, err.Error())
 			return
 		}
 
-		if err := svc.ValidateToken(ctx, token); err != nil {
-			logrus.WithError(err).Error("Error validating token")
+		id := mux.Vars(r)["id"]
+
+		job, err := svc.GetSaveJob(ctx, principal.UserID, id)
+		if err != nil {
+			logger.WithError(err).Error("Error retrieving save job")
+			respondWithError(ctx, w, errorStatus(err), err.Error())
+			return
+		}
+
+		respondWithSuccess(ctx, w, http.StatusOK, job)
+	}
+}
+
+func getRevisions(svc service.NoteServiceHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := logrus.WithContext(ctx)
+		defer closeRequestBody(ctx, r)
+
+		principal, err := getPrincipal(r)
+		if err != nil {
+			logger.WithError(err).Error("Error retrieving principal from request")
 			respondWithError(ctx, w, http.StatusUnauthorized, err.Error())
 			return
 		}
 
-		svc.SetToken(token)
+		id := mux.Vars(r)["id"]
+
+		revisions, err := svc.GetRevisions(ctx, principal.UserID, id)
+		if err != nil {
+			logger.WithError(err).Error("Error retrieving revisions")
+			respondWithError(ctx, w, errorStatus(err), err.Error())
+			return
+		}
+
+		respondWithSuccess(ctx, w, http.StatusOK, revisions)
+	}
+}
+
+func getRevision(svc service.NoteServiceHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := logrus.WithContext(ctx)
+		defer closeRequestBody(ctx, r)
+
+		principal, err := getPrincipal(r)
+		if err != nil {
+			logger.WithError(err).Error("Error retrieving principal from request")
+			respondWithError(ctx, w, http.StatusUnauthorized, err.Error())
+			return
+		}
 
 		id := mux.Vars(r)["id"]
+		rev := mux.Vars(r)["rev"]
 
-		if err := svc.SendToContentService(ctx, id); err != nil {
-			logger.WithError(err).Error("Error sending note to content service")
+		revision, err := svc.GetRevision(ctx, principal.UserID, id, rev)
+		if err != nil {
+			logger.WithError(err).Error("Error retrieving revision")
+			respondWithError(ctx, w, errorStatus(err), err.Error())
+			return
+		}
+
+		respondWithSuccess(ctx, w, http.StatusOK, revision)
+	}
+}
+
+func restoreRevision(svc service.NoteServiceHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := logrus.WithContext(ctx)
+		defer closeRequestBody(ctx, r)
+
+		principal, err := getPrincipal(r)
+		if err != nil {
+			logger.WithError(err).Error("Error retrieving principal from request")
+			respondWithError(ctx, w, http.StatusUnauthorized, err.Error())
+			return
+		}
+
+		id := mux.Vars(r)["id"]
+		rev := mux.Vars(r)["rev"]
+
+		version, err := getIfMatchVersion(r)
+		if err != nil {
+			logger.WithError(err).Error("Error retrieving note version from request")
+			respondWithError(ctx, w, errorStatus(err), err.Error())
+			return
+		}
+
+		newVersion, err := svc.RestoreRevision(ctx, principal.UserID, getToken(r), id, rev, version)
+		if err != nil {
+			logger.WithError(err).Error("Error restoring revision")
+			respondWithError(ctx, w, errorStatus(err), err.Error())
+			return
+		}
+
+		w.Header().Set("ETag", etag(newVersion))
+		respondWithSuccess(ctx, w, http.StatusOK, fmt.Sprintf("Note with ID '%v' restored to revision '%v'", id, rev))
+	}
+}
+
+func getTrash(svc service.NoteServiceHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := logrus.WithContext(ctx)
+		defer closeRequestBody(ctx, r)
+
+		principal, err := getPrincipal(r)
+		if err != nil {
+			logger.WithError(err).Error("Error retrieving principal from request")
+			respondWithError(ctx, w, http.StatusUnauthorized, err.Error())
+			return
+		}
+
+		notes, err := svc.GetTrash(ctx, principal.UserID)
+		if err != nil {
+			logger.WithError(err).Error("Error retrieving trash")
+			respondWithError(ctx, w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		respondWithSuccess(ctx, w, http.StatusOK, notes)
+	}
+}
+
+func restoreFromTrash(svc service.NoteServiceHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := logrus.WithContext(ctx)
+		defer closeRequestBody(ctx, r)
+
+		principal, err := getPrincipal(r)
+		if err != nil {
+			logger.WithError(err).Error("Error retrieving principal from request")
+			respondWithError(ctx, w, http.StatusUnauthorized, err.Error())
+			return
+		}
+
+		id := mux.Vars(r)["id"]
+
+		if err := svc.RestoreFromTrash(ctx, principal.UserID, id); err != nil {
+			logger.WithError(err).Error("Error restoring note from trash")
+			respondWithError(ctx, w, errorStatus(err), err.Error())
+			return
+		}
+
+		respondWithSuccess(ctx, w, http.StatusOK, fmt.Sprintf("Note with ID '%v' restored from trash", id))
+	}
+}
+
+func purgeNote(svc service.NoteServiceHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := logrus.WithContext(ctx)
+		defer closeRequestBody(ctx, r)
+
+		principal, err := getPrincipal(r)
+		if err != nil {
+			logger.WithError(err).Error("Error retrieving principal from request")
+			respondWithError(ctx, w, http.StatusUnauthorized, err.Error())
+			return
+		}
+
+		id := mux.Vars(r)["id"]
+
+		if err := svc.PurgeNote(ctx, principal.UserID, id); err != nil {
+			logger.WithError(err).Error("Error permanently deleting note")
+			respondWithError(ctx, w, errorStatus(err), err.Error())
+			return
+		}
+
+		respondWithSuccess(ctx, w, http.StatusOK, fmt.Sprintf("Note with ID '%v' deleted permanently", id))
+	}
+}
+
+func getTags(svc service.NoteServiceHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := logrus.WithContext(ctx)
+		defer closeRequestBody(ctx, r)
+
+		principal, err := getPrincipal(r)
+		if err != nil {
+			logger.WithError(err).Error("Error retrieving principal from request")
+			respondWithError(ctx, w, http.StatusUnauthorized, err.Error())
+			return
+		}
+
+		tags, err := svc.GetTags(ctx, principal.UserID)
+		if err != nil {
+			logger.WithError(err).Error("Error retrieving tags")
 			respondWithError(ctx, w, http.StatusInternalServerError, err.Error())
 			return
 		}
 
-		respondWithSuccess(ctx, w, http.StatusOK, "Note successfully sent to content service")
+		respondWithSuccess(ctx, w, http.StatusOK, tags)
+	}
+}
+
+func renameTag(svc service.NoteServiceHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := logrus.WithContext(ctx)
+		defer closeRequestBody(ctx, r)
+
+		principal, err := getPrincipal(r)
+		if err != nil {
+			logger.WithError(err).Error("Error retrieving principal from request")
+			respondWithError(ctx, w, http.StatusUnauthorized, err.Error())
+			return
+		}
+
+		tag := mux.Vars(r)["tag"]
+
+		var rename models.TagRenameRequest
+		if err := json.NewDecoder(r.Body).Decode(&rename); err != nil {
+			logger.WithError(err).Error("Error decoding request body")
+			respondWithError(ctx, w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		count, err := svc.RenameTag(ctx, principal.UserID, tag, rename.Name)
+		if err != nil {
+			logger.WithError(err).Error("Error renaming tag")
+			respondWithError(ctx, w, errorStatus(err), err.Error())
+			return
+		}
+
+		respondWithSuccess(ctx, w, http.StatusOK, fmt.Sprintf("Tag '%v' renamed to '%v' on %v notes", tag, rename.Name, count))
 	}
 }
 
@@ -330,6 +753,96 @@ func respondWithSuccess(ctx context.Context, w http.ResponseWriter, code int, bo
 	}
 }
 
+func errorStatus(err error) int {
+	var statusErr *external.StatusError
+	switch {
+	case errors.Is(err, dao.ErrNotFound), errors.Is(err, dao.ErrRevisionNotFound), errors.Is(err, dao.ErrNotebookNotFound),
+		errors.Is(err, dao.ErrSaveJobNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrNotebookCycle), errors.Is(err, service.ErrNotebookNotEmpty):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrInvalidQuery):
+		return http.StatusBadRequest
+	case errors.Is(err, errMissingIfMatch):
+		return http.StatusPreconditionRequired
+	case errors.Is(err, errInvalidIfMatch), errors.Is(err, service.ErrVersionConflict):
+		return http.StatusPreconditionFailed
+	case errors.As(err, &statusErr):
+		return http.StatusBadGateway
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+var (
+	errMissingIfMatch = errors.New("If-Match header with the note's current ETag is required")
+	errInvalidIfMatch = errors.New("If-Match header is not a note ETag")
+)
+
+// getNoteQuery reads the limit, next, sort, order, fields, tag, tagMatch and notebookId query parameters of a note
+// listing.
+// Fields may be given as a comma separated list, repeated, or both. Tags are repeated, since a tag may contain a comma.
+func getNoteQuery(r *http.Request) (models.NoteQuery, error) {
+	params := r.URL.Query()
+	query := models.NoteQuery{
+		Next:       params.Get("next"),
+		Sort:       params.Get("sort"),
+		Order:      params.Get("order"),
+		Tags:       params["tag"],
+		TagMatch:   params.Get("tagMatch"),
+		NotebookID: params.Get("notebookId"),
+	}
+
+	limit, err := getLimit(params)
+	if err != nil {
+		return query, err
+	}
+	query.Limit = limit
+
+	for _, value := range params["fields"] {
+		for _, field := range strings.Split(value, ",") {
+			if field = strings.TrimSpace(field); field != "" {
+				query.Fields = append(query.Fields, field)
+			}
+		}
+	}
+
+	return query, nil
+}
+
+// getLimit returns the limit query parameter, or zero when it is absent.
+func getLimit(params url.Values) (int, error) {
+	limit := params.Get("limit")
+	if limit == "" {
+		return 0, nil
+	}
+
+	parsed, err := strconv.Atoi(limit)
+	if err != nil {
+		return 0, fmt.Errorf("limit '%v' is not a number", limit)
+	}
+	return parsed, nil
+}
+
+func etag(version int64) string {
+	return fmt.Sprintf("\"%v\"", version)
+}
+
+// getIfMatchVersion returns the note version named by the request's If-Match header.
+func getIfMatchVersion(r *http.Request) (int64, error) {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		return 0, errMissingIfMatch
+	}
+
+	version, err := strconv.ParseInt(strings.Trim(strings.TrimPrefix(header, "W/"), "\""), 10, 64)
+	if err != nil {
+		return 0, errInvalidIfMatch
+	}
+
+	return version, nil
+}
+
 func closeRequestBody(ctx context.Context, r *http.Request) {
 	logger := logrus.WithContext(ctx)
 
@@ -342,30 +855,24 @@ func closeRequestBody(ctx context.Context, r *http.Request) {
 	}
 }
 
-func shutdownGracefully(server *http.Server) {
+// shutdownGracefully stops the server on interrupt, giving requests in flight up to timeout to finish. The returned
+// channel is closed once the server has stopped.
+func shutdownGracefully(server *http.Server, timeout time.Duration) <-chan struct{} {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+
+	done := make(chan struct{})
 	go func() {
-		signals := make(chan os.Signal, 1)
-		signal.Notify(signals, os.Interrupt)
+		defer close(done)
 		<-signals
+		signal.Stop(signals)
 
-		c, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		c, cancel := context.WithTimeout(context.Background(), timeout)
 		defer cancel()
 
 		if err := server.Shutdown(c); err != nil {
 			logrus.WithError(err).Error("Error shutting down server")
 		}
-
-		<-c.Done()
-		os.Exit(0)
 	}()
-}
-
-func getAuthToken(r *http.Request) (string, error) {
-	tokenHeader := r.Header.Get("Authorization")
-	if tokenHeader == "" {
-		return "", errors.New("no authorization header found")
-	} else if (len(tokenHeader) >= 7 && tokenHeader[:7] != "Bearer ") || len(strings.Split(tokenHeader, " ")) != 2 {
-		return "", errors.New("authorization header must be in format 'Bearer' <token>")
-	}
-	return strings.Split(tokenHeader, " ")[1], nil
+	return done
 }
@@ -3,17 +3,24 @@ package api
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"strings"
 	"testing"
+	"time"
 
+	"notes-api/pkg/dao"
 	"notes-api/pkg/models"
+	"notes-api/pkg/service"
 	"notes-api/pkg/testhelper/mocks"
 
+	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 func TestAPI_CheckHealth_ShouldRespondWith500IfErrorOccursPingingDatabase(t *testing.T) {
@@ -24,7 +31,7 @@ func TestAPI_CheckHealth_ShouldRespondWith500IfErrorOccursPingingDatabase(t *tes
 	require.Nil(t, err)
 
 	recorder := httptest.NewRecorder()
-	httpHandler := http.HandlerFunc(checkHealth(context.TODO(), mockSvc))
+	httpHandler := http.HandlerFunc(checkHealth(mockSvc))
 	httpHandler.ServeHTTP(recorder, req)
 	require.Equal(t, http.StatusInternalServerError, recorder.Code)
 	require.Contains(t, recorder.Body.String(), "test")
@@ -38,140 +45,184 @@ func TestAPI_CheckHealth_ShouldRespondWith200IfNoErrorOccurs(t *testing.T) {
 	require.Nil(t, err)
 
 	recorder := httptest.NewRecorder()
-	httpHandler := http.HandlerFunc(checkHealth(context.TODO(), mockSvc))
+	httpHandler := http.HandlerFunc(checkHealth(mockSvc))
 	httpHandler.ServeHTTP(recorder, req)
 	require.Equal(t, http.StatusOK, recorder.Code)
 	require.Contains(t, recorder.Body.String(), "API is running and connected to database")
 }
 
-func TestAPI_GetNotes_ShouldRespondWith400IfErrorOccursRetrievingAuthToken(t *testing.T) {
+func TestAPI_GetNotes_ShouldRespondWith401IfRequestIsNotAuthenticated(t *testing.T) {
 	mockSvc := &mocks.NoteServiceHandler{}
 
 	req, err := http.NewRequest(http.MethodGet, "/notes", nil)
 	require.Nil(t, err)
 
 	recorder := httptest.NewRecorder()
-	httpHandler := http.HandlerFunc(getNotes(context.TODO(), mockSvc))
+	httpHandler := http.HandlerFunc(getNotes(mockSvc))
 	httpHandler.ServeHTTP(recorder, req)
-	require.Equal(t, http.StatusBadRequest, recorder.Code)
-	require.Contains(t, recorder.Body.String(), "no authorization header found")
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+	require.Contains(t, recorder.Body.String(), "request is not authenticated")
 }
 
-func TestAPI_GetNotes_ShouldRespondWith400IfTokenFormatIsInvalid(t *testing.T) {
+func TestAPI_GetNotes_ShouldRespondWith400IfLimitIsNotANumber(t *testing.T) {
 	mockSvc := &mocks.NoteServiceHandler{}
 
-	req, err := http.NewRequest(http.MethodGet, "/notes", nil)
+	req, err := http.NewRequest(http.MethodGet, "/notes?limit=ten", nil)
 	require.Nil(t, err)
-
-	req.Header.Set("Authorization", "test")
+	req = withPrincipal(req)
 
 	recorder := httptest.NewRecorder()
-	httpHandler := http.HandlerFunc(getNotes(context.TODO(), mockSvc))
+	httpHandler := http.HandlerFunc(getNotes(mockSvc))
 	httpHandler.ServeHTTP(recorder, req)
 	require.Equal(t, http.StatusBadRequest, recorder.Code)
-	require.Contains(t, recorder.Body.String(), "authorization header must be in format 'Bearer'")
+	mockSvc.AssertNotCalled(t, "ListNotes", mock.Anything, mock.Anything, mock.Anything)
 }
 
-func TestAPI_GetNotes_ShouldRespondWith401IfErrorOccursValidatingToken(t *testing.T) {
+func TestAPI_GetNotes_ShouldRespondWith400IfQueryIsInvalid(t *testing.T) {
 	mockSvc := &mocks.NoteServiceHandler{}
-	mockSvc.On("ValidateToken", mock.Anything, mock.Anything).Return(errors.New("test"))
+	mockSvc.On("ListNotes", mock.Anything, mock.Anything, mock.Anything).Return(nil, fmt.Errorf("%w: unknown sort 'size'", service.ErrInvalidQuery))
 
-	req, err := http.NewRequest(http.MethodGet, "/notes", nil)
+	req, err := http.NewRequest(http.MethodGet, "/notes?sort=size", nil)
 	require.Nil(t, err)
-	req.Header.Set("Authorization", "Bearer test")
+	req = withPrincipal(req)
 
 	recorder := httptest.NewRecorder()
-	httpHandler := http.HandlerFunc(getNotes(context.TODO(), mockSvc))
+	httpHandler := http.HandlerFunc(getNotes(mockSvc))
 	httpHandler.ServeHTTP(recorder, req)
-	require.Equal(t, http.StatusUnauthorized, recorder.Code)
-	require.Contains(t, recorder.Body.String(), "test")
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
 }
 
 func TestAPI_GetNotes_ShouldRespondWith500IfServiceErrorOccurs(t *testing.T) {
 	mockSvc := &mocks.NoteServiceHandler{}
-	mockSvc.On("ValidateToken", mock.Anything, mock.Anything).Return(nil)
-	mockSvc.On("GetNotes", mock.Anything, mock.Anything).Return(nil, errors.New("test"))
+	mockSvc.On("ListNotes", mock.Anything, mock.Anything, mock.Anything).Return(nil, errors.New("test"))
 
 	req, err := http.NewRequest(http.MethodGet, "/notes", nil)
 	require.Nil(t, err)
-	req.Header.Set("Authorization", "Bearer test")
+	req = withPrincipal(req)
 
 	recorder := httptest.NewRecorder()
-	httpHandler := http.HandlerFunc(getNotes(context.TODO(), mockSvc))
+	httpHandler := http.HandlerFunc(getNotes(mockSvc))
 	httpHandler.ServeHTTP(recorder, req)
 	require.Equal(t, http.StatusInternalServerError, recorder.Code)
 	require.Contains(t, recorder.Body.String(), "test")
 }
 
+func TestAPI_GetNotes_ShouldPassQueryParametersToService(t *testing.T) {
+	mockSvc := &mocks.NoteServiceHandler{}
+	mockSvc.On("ListNotes", mock.Anything, "test", models.NoteQuery{
+		Limit:  10,
+		Next:   "abc",
+		Sort:   "name",
+		Order:  "desc",
+		Fields: []string{"name", "lastEditedTs", "version"},
+	}).Return(&models.NotePage{Notes: []models.Note{}}, nil)
+
+	req, err := http.NewRequest(http.MethodGet, "/notes?limit=10&next=abc&sort=name&order=desc&fields=name,lastEditedTs&fields=version", nil)
+	require.Nil(t, err)
+	req = withPrincipal(req)
+
+	recorder := httptest.NewRecorder()
+	httpHandler := http.HandlerFunc(getNotes(mockSvc))
+	httpHandler.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	mockSvc.AssertExpectations(t)
+}
+
 func TestAPI_GetNotes_ShouldRespondWith200OnSuccess(t *testing.T) {
 	mockSvc := &mocks.NoteServiceHandler{}
-	mockSvc.On("ValidateToken", mock.Anything, mock.Anything).Return(nil)
-	mockSvc.On("GetNotes", mock.Anything, mock.Anything).Return([]models.Note{}, nil)
+	mockSvc.On("ListNotes", mock.Anything, mock.Anything, mock.Anything).Return(&models.NotePage{Notes: []models.Note{}, Next: "abc"}, nil)
 
 	req, err := http.NewRequest(http.MethodGet, "/notes", nil)
 	require.Nil(t, err)
-	req.Header.Set("Authorization", "Bearer test")
+	req = withPrincipal(req)
 
 	recorder := httptest.NewRecorder()
-	httpHandler := http.HandlerFunc(getNotes(context.TODO(), mockSvc))
+	httpHandler := http.HandlerFunc(getNotes(mockSvc))
 	httpHandler.ServeHTTP(recorder, req)
 	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Contains(t, recorder.Body.String(), `"next":"abc"`)
 }
 
-func TestAPI_GetNote_ShouldRespondWith400IfErrorOccursRetrievingAuthToken(t *testing.T) {
+func TestAPI_SearchNotes_ShouldRespondWith401IfRequestIsNotAuthenticated(t *testing.T) {
 	mockSvc := &mocks.NoteServiceHandler{}
 
-	req, err := http.NewRequest(http.MethodGet, "/note", nil)
+	req, err := http.NewRequest(http.MethodGet, "/notes/search?q=test", nil)
 	require.Nil(t, err)
 
 	recorder := httptest.NewRecorder()
-	httpHandler := http.HandlerFunc(getNote(context.TODO(), mockSvc))
+	httpHandler := http.HandlerFunc(searchNotes(mockSvc))
 	httpHandler.ServeHTTP(recorder, req)
-	require.Equal(t, http.StatusBadRequest, recorder.Code)
-	require.Contains(t, recorder.Body.String(), "no authorization header found")
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
 }
 
-func TestAPI_GetNote_ShouldRespondWith400IfTokenFormatIsInvalid(t *testing.T) {
+func TestAPI_SearchNotes_ShouldRespondWith400IfLimitIsNotANumber(t *testing.T) {
 	mockSvc := &mocks.NoteServiceHandler{}
 
-	req, err := http.NewRequest(http.MethodGet, "/note", nil)
+	req, err := http.NewRequest(http.MethodGet, "/notes/search?q=test&limit=ten", nil)
 	require.Nil(t, err)
+	req = withPrincipal(req)
 
-	req.Header.Set("Authorization", "test")
+	recorder := httptest.NewRecorder()
+	httpHandler := http.HandlerFunc(searchNotes(mockSvc))
+	httpHandler.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestAPI_SearchNotes_ShouldRespondWith400IfQueryIsInvalid(t *testing.T) {
+	mockSvc := &mocks.NoteServiceHandler{}
+	mockSvc.On("SearchNotes", mock.Anything, mock.Anything, mock.Anything).Return(nil, fmt.Errorf("%w: search text is required", service.ErrInvalidQuery))
+
+	req, err := http.NewRequest(http.MethodGet, "/notes/search", nil)
+	require.Nil(t, err)
+	req = withPrincipal(req)
 
 	recorder := httptest.NewRecorder()
-	httpHandler := http.HandlerFunc(getNote(context.TODO(), mockSvc))
+	httpHandler := http.HandlerFunc(searchNotes(mockSvc))
 	httpHandler.ServeHTTP(recorder, req)
 	require.Equal(t, http.StatusBadRequest, recorder.Code)
-	require.Contains(t, recorder.Body.String(), "authorization header must be in format 'Bearer'")
 }
 
-func TestAPI_GetNote_ShouldRespondWith401IfErrorOccursValidatingToken(t *testing.T) {
+func TestAPI_SearchNotes_ShouldRespondWith500IfServiceErrorOccurs(t *testing.T) {
 	mockSvc := &mocks.NoteServiceHandler{}
-	mockSvc.On("ValidateToken", mock.Anything, mock.Anything).Return(errors.New("test"))
+	mockSvc.On("SearchNotes", mock.Anything, mock.Anything, mock.Anything).Return(nil, errors.New("test"))
 
-	req, err := http.NewRequest(http.MethodGet, "/note", nil)
+	req, err := http.NewRequest(http.MethodGet, "/notes/search?q=test", nil)
 	require.Nil(t, err)
-	req.Header.Set("Authorization", "Bearer test")
+	req = withPrincipal(req)
 
 	recorder := httptest.NewRecorder()
-	httpHandler := http.HandlerFunc(getNote(context.TODO(), mockSvc))
+	httpHandler := http.HandlerFunc(searchNotes(mockSvc))
 	httpHandler.ServeHTTP(recorder, req)
-	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+	require.Equal(t, http.StatusInternalServerError, recorder.Code)
 	require.Contains(t, recorder.Body.String(), "test")
 }
 
+func TestAPI_SearchNotes_ShouldRespondWith200OnSuccess(t *testing.T) {
+	mockSvc := &mocks.NoteServiceHandler{}
+	mockSvc.On("SearchNotes", mock.Anything, "test", models.SearchQuery{Text: "meeting notes", Limit: 5, Next: "abc"}).
+		Return(&models.SearchPage{Results: []models.SearchResult{}}, nil)
+
+	req, err := http.NewRequest(http.MethodGet, "/notes/search?q=meeting+notes&limit=5&next=abc", nil)
+	require.Nil(t, err)
+	req = withPrincipal(req)
+
+	recorder := httptest.NewRecorder()
+	httpHandler := http.HandlerFunc(searchNotes(mockSvc))
+	httpHandler.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	mockSvc.AssertExpectations(t)
+}
+
 func TestAPI_GetNote_ShouldRespondWith500IfServiceErrorOccurs(t *testing.T) {
 	mockSvc := &mocks.NoteServiceHandler{}
-	mockSvc.On("ValidateToken", mock.Anything, mock.Anything).Return(nil)
-	mockSvc.On("GetNotes", mock.Anything, mock.Anything).Return(nil, errors.New("test"))
+	mockSvc.On("GetNotes", mock.Anything, mock.Anything, mock.Anything).Return(nil, errors.New("test"))
 
 	req, err := http.NewRequest(http.MethodGet, "/note", nil)
 	require.Nil(t, err)
-	req.Header.Set("Authorization", "Bearer test")
+	req = withPrincipal(req)
 
 	recorder := httptest.NewRecorder()
-	httpHandler := http.HandlerFunc(getNote(context.TODO(), mockSvc))
+	httpHandler := http.HandlerFunc(getNote(mockSvc))
 	httpHandler.ServeHTTP(recorder, req)
 	require.Equal(t, http.StatusInternalServerError, recorder.Code)
 	require.Contains(t, recorder.Body.String(), "test")
@@ -179,15 +230,14 @@ func TestAPI_GetNote_ShouldRespondWith500IfServiceErrorOccurs(t *testing.T) {
 
 func TestAPI_GetNote_ShouldRespondWith500IfMoreThanOneNoteIsReturned(t *testing.T) {
 	mockSvc := &mocks.NoteServiceHandler{}
-	mockSvc.On("ValidateToken", mock.Anything, mock.Anything).Return(nil)
-	mockSvc.On("GetNotes", mock.Anything, mock.Anything).Return([]models.Note{{}, {}}, nil)
+	mockSvc.On("GetNotes", mock.Anything, mock.Anything, mock.Anything).Return([]models.Note{{}, {}}, nil)
 
 	req, err := http.NewRequest(http.MethodGet, "/note", nil)
 	require.Nil(t, err)
-	req.Header.Set("Authorization", "Bearer test")
+	req = withPrincipal(req)
 
 	recorder := httptest.NewRecorder()
-	httpHandler := http.HandlerFunc(getNote(context.TODO(), mockSvc))
+	httpHandler := http.HandlerFunc(getNote(mockSvc))
 	httpHandler.ServeHTTP(recorder, req)
 	require.Equal(t, http.StatusInternalServerError, recorder.Code)
 	require.Contains(t, recorder.Body.String(), "more than one note returned for given ID")
@@ -195,358 +245,607 @@ func TestAPI_GetNote_ShouldRespondWith500IfMoreThanOneNoteIsReturned(t *testing.
 
 func TestAPI_GetNote_ShouldRespondWith204IfNoNotesAreReturned(t *testing.T) {
 	mockSvc := &mocks.NoteServiceHandler{}
-	mockSvc.On("ValidateToken", mock.Anything, mock.Anything).Return(nil)
-	mockSvc.On("GetNotes", mock.Anything, mock.Anything).Return([]models.Note{}, nil)
+	mockSvc.On("GetNotes", mock.Anything, mock.Anything, mock.Anything).Return([]models.Note{}, nil)
 
 	req, err := http.NewRequest(http.MethodGet, "/note", nil)
 	require.Nil(t, err)
-	req.Header.Set("Authorization", "Bearer test")
+	req = withPrincipal(req)
 
 	recorder := httptest.NewRecorder()
-	httpHandler := http.HandlerFunc(getNote(context.TODO(), mockSvc))
+	httpHandler := http.HandlerFunc(getNote(mockSvc))
 	httpHandler.ServeHTTP(recorder, req)
 	require.Equal(t, http.StatusNoContent, recorder.Code)
 }
 
 func TestAPI_GetNote_ShouldRespondWith200OnSuccess(t *testing.T) {
 	mockSvc := &mocks.NoteServiceHandler{}
-	mockSvc.On("ValidateToken", mock.Anything, mock.Anything).Return(nil)
-	mockSvc.On("GetNotes", mock.Anything, mock.Anything).Return([]models.Note{{}}, nil)
+	mockSvc.On("GetNotes", mock.Anything, mock.Anything, mock.Anything).Return([]models.Note{{Version: 3}}, nil)
 
 	req, err := http.NewRequest(http.MethodGet, "/note", nil)
 	require.Nil(t, err)
-	req.Header.Set("Authorization", "Bearer test")
+	req = withPrincipal(req)
 
 	recorder := httptest.NewRecorder()
-	httpHandler := http.HandlerFunc(getNote(context.TODO(), mockSvc))
+	httpHandler := http.HandlerFunc(getNote(mockSvc))
 	httpHandler.ServeHTTP(recorder, req)
 	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, `"3"`, recorder.Header().Get("ETag"))
 }
 
-func TestAPI_EditNote_ShouldRespondWith400IfErrorOccursRetrievingAuthToken(t *testing.T) {
+func TestAPI_EditNote_ShouldRespondWith400IfErrorOccursDecodingRequestBody(t *testing.T) {
 	mockSvc := &mocks.NoteServiceHandler{}
 
-	req, err := http.NewRequest(http.MethodPut, "/note", nil)
+	req, err := http.NewRequest(http.MethodPut, "/note", ioutil.NopCloser(strings.NewReader("")))
 	require.Nil(t, err)
+	req = withPrincipal(req)
+	req.Header.Set("If-Match", `"1"`)
 
 	recorder := httptest.NewRecorder()
-	httpHandler := http.HandlerFunc(editNote(context.TODO(), mockSvc))
+	httpHandler := http.HandlerFunc(editNote(mockSvc))
 	httpHandler.ServeHTTP(recorder, req)
 	require.Equal(t, http.StatusBadRequest, recorder.Code)
-	require.Contains(t, recorder.Body.String(), "no authorization header found")
+	require.Contains(t, recorder.Body.String(), "EOF")
 }
 
-func TestAPI_EditNote_ShouldRespondWith400IfTokenFormatIsInvalid(t *testing.T) {
+func TestAPI_EditNote_ShouldRespondWith500IfServiceErrorOccurs(t *testing.T) {
 	mockSvc := &mocks.NoteServiceHandler{}
+	mockSvc.On("UpdateNote", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(int64(0), errors.New("test"))
 
-	req, err := http.NewRequest(http.MethodPut, "/note", nil)
+	req, err := http.NewRequest(http.MethodPut, "/note", ioutil.NopCloser(strings.NewReader("{}")))
 	require.Nil(t, err)
+	req = withPrincipal(req)
+	req.Header.Set("If-Match", `"1"`)
+
+	recorder := httptest.NewRecorder()
+	httpHandler := http.HandlerFunc(editNote(mockSvc))
+	httpHandler.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusInternalServerError, recorder.Code)
+	require.Contains(t, recorder.Body.String(), "test")
+}
 
-	req.Header.Set("Authorization", "test")
+func TestAPI_EditNote_ShouldRespondWith428IfIfMatchHeaderIsMissing(t *testing.T) {
+	mockSvc := &mocks.NoteServiceHandler{}
+
+	req, err := http.NewRequest(http.MethodPut, "/note", ioutil.NopCloser(strings.NewReader("{}")))
+	require.Nil(t, err)
+	req = withPrincipal(req)
 
 	recorder := httptest.NewRecorder()
-	httpHandler := http.HandlerFunc(editNote(context.TODO(), mockSvc))
+	httpHandler := http.HandlerFunc(editNote(mockSvc))
 	httpHandler.ServeHTTP(recorder, req)
-	require.Equal(t, http.StatusBadRequest, recorder.Code)
-	require.Contains(t, recorder.Body.String(), "authorization header must be in format 'Bearer'")
+	require.Equal(t, http.StatusPreconditionRequired, recorder.Code)
+	require.Contains(t, recorder.Body.String(), "If-Match header")
 }
 
-func TestAPI_EditNote_ShouldRespondWith401IfErrorOccursValidatingToken(t *testing.T) {
+func TestAPI_EditNote_ShouldRespondWith412IfIfMatchHeaderIsNotAVersion(t *testing.T) {
 	mockSvc := &mocks.NoteServiceHandler{}
-	mockSvc.On("ValidateToken", mock.Anything, mock.Anything).Return(errors.New("test"))
 
-	req, err := http.NewRequest(http.MethodPut, "/note", nil)
+	req, err := http.NewRequest(http.MethodPut, "/note", ioutil.NopCloser(strings.NewReader("{}")))
 	require.Nil(t, err)
-	req.Header.Set("Authorization", "Bearer test")
+	req = withPrincipal(req)
+	req.Header.Set("If-Match", `"abc"`)
 
 	recorder := httptest.NewRecorder()
-	httpHandler := http.HandlerFunc(editNote(context.TODO(), mockSvc))
+	httpHandler := http.HandlerFunc(editNote(mockSvc))
 	httpHandler.ServeHTTP(recorder, req)
-	require.Equal(t, http.StatusUnauthorized, recorder.Code)
-	require.Contains(t, recorder.Body.String(), "test")
+	require.Equal(t, http.StatusPreconditionFailed, recorder.Code)
 }
 
-func TestAPI_EditNote_ShouldRespondWith400IfErrorOccursDecodingRequestBody(t *testing.T) {
+func TestAPI_EditNote_ShouldRespondWith412IfVersionIsStale(t *testing.T) {
 	mockSvc := &mocks.NoteServiceHandler{}
-	mockSvc.On("ValidateToken", mock.Anything, mock.Anything).Return(nil)
+	mockSvc.On("UpdateNote", mock.Anything, mock.Anything, mock.Anything, mock.Anything, int64(1), mock.Anything).Return(int64(0), service.ErrVersionConflict)
 
-	req, err := http.NewRequest(http.MethodPut, "/note", ioutil.NopCloser(strings.NewReader("")))
+	req, err := http.NewRequest(http.MethodPut, "/note", ioutil.NopCloser(strings.NewReader("{}")))
+	require.Nil(t, err)
+	req = withPrincipal(req)
+	req.Header.Set("If-Match", `"1"`)
+
+	recorder := httptest.NewRecorder()
+	httpHandler := http.HandlerFunc(editNote(mockSvc))
+	httpHandler.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusPreconditionFailed, recorder.Code)
+	require.Contains(t, recorder.Body.String(), service.ErrVersionConflict.Error())
+}
+
+func TestAPI_EditNote_ShouldRespondWith200OnSuccess(t *testing.T) {
+	mockSvc := &mocks.NoteServiceHandler{}
+	mockSvc.On("UpdateNote", mock.Anything, mock.Anything, mock.Anything, mock.Anything, int64(1), mock.Anything).Return(int64(2), nil)
+
+	req, err := http.NewRequest(http.MethodPut, "/note", ioutil.NopCloser(strings.NewReader("{}")))
+	require.Nil(t, err)
+	req = withPrincipal(req)
+	req.Header.Set("If-Match", `"1"`)
+
+	recorder := httptest.NewRecorder()
+	httpHandler := http.HandlerFunc(editNote(mockSvc))
+	httpHandler.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, `"2"`, recorder.Header().Get("ETag"))
+}
+
+func TestAPI_CreateNote_ShouldRespondWith400IfErrorOccursDecodingRequestBody(t *testing.T) {
+	mockSvc := &mocks.NoteServiceHandler{}
+
+	req, err := http.NewRequest(http.MethodPost, "/note", ioutil.NopCloser(strings.NewReader("")))
 	require.Nil(t, err)
-	req.Header.Set("Authorization", "Bearer test")
+	req = withPrincipal(req)
 
 	recorder := httptest.NewRecorder()
-	httpHandler := http.HandlerFunc(editNote(context.TODO(), mockSvc))
+	httpHandler := http.HandlerFunc(createNote(mockSvc))
 	httpHandler.ServeHTTP(recorder, req)
 	require.Equal(t, http.StatusBadRequest, recorder.Code)
 	require.Contains(t, recorder.Body.String(), "EOF")
 }
 
-func TestAPI_EditNote_ShouldRespondWith500IfServiceErrorOccurs(t *testing.T) {
+func TestAPI_CreateNote_ShouldRespondWith500IfServiceErrorOccurs(t *testing.T) {
 	mockSvc := &mocks.NoteServiceHandler{}
-	mockSvc.On("ValidateToken", mock.Anything, mock.Anything).Return(nil)
-	mockSvc.On("UpdateNote", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("test"))
+	mockSvc.On("CreateNote", mock.Anything, mock.Anything, mock.Anything).Return("", errors.New("test"))
 
-	req, err := http.NewRequest(http.MethodPut, "/note", ioutil.NopCloser(strings.NewReader("{}")))
+	req, err := http.NewRequest(http.MethodPost, "/note", ioutil.NopCloser(strings.NewReader("{}")))
 	require.Nil(t, err)
-	req.Header.Set("Authorization", "Bearer test")
+	req = withPrincipal(req)
 
 	recorder := httptest.NewRecorder()
-	httpHandler := http.HandlerFunc(editNote(context.TODO(), mockSvc))
+	httpHandler := http.HandlerFunc(createNote(mockSvc))
 	httpHandler.ServeHTTP(recorder, req)
 	require.Equal(t, http.StatusInternalServerError, recorder.Code)
 	require.Contains(t, recorder.Body.String(), "test")
 }
 
-func TestAPI_EditNote_ShouldRespondWith200OnSuccess(t *testing.T) {
+func TestAPI_CreateNote_ShouldRespondWith200OnSuccess(t *testing.T) {
 	mockSvc := &mocks.NoteServiceHandler{}
-	mockSvc.On("ValidateToken", mock.Anything, mock.Anything).Return(nil)
-	mockSvc.On("UpdateNote", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockSvc.On("CreateNote", mock.Anything, mock.Anything, mock.Anything).Return("", nil)
 
-	req, err := http.NewRequest(http.MethodPut, "/note", ioutil.NopCloser(strings.NewReader("{}")))
+	req, err := http.NewRequest(http.MethodPost, "/note", ioutil.NopCloser(strings.NewReader("{}")))
+	require.Nil(t, err)
+	req = withPrincipal(req)
+
+	recorder := httptest.NewRecorder()
+	httpHandler := http.HandlerFunc(createNote(mockSvc))
+	httpHandler.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusOK, recorder.Code)
+}
+
+func TestAPI_DeleteNote_ShouldRespondWith500IfServiceErrorOccurs(t *testing.T) {
+	mockSvc := &mocks.NoteServiceHandler{}
+	mockSvc.On("DeleteNote", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("test"))
+
+	req, err := http.NewRequest(http.MethodDelete, "/note", nil)
+	require.Nil(t, err)
+	req = withPrincipal(req)
+
+	recorder := httptest.NewRecorder()
+	httpHandler := http.HandlerFunc(deleteNote(mockSvc))
+	httpHandler.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusInternalServerError, recorder.Code)
+	require.Contains(t, recorder.Body.String(), "test")
+}
+
+func TestAPI_DeleteNote_ShouldRespondWith404IfNoteIsNotFound(t *testing.T) {
+	mockSvc := &mocks.NoteServiceHandler{}
+	mockSvc.On("DeleteNote", mock.Anything, mock.Anything, mock.Anything).Return(dao.ErrNotFound)
+
+	req, err := http.NewRequest(http.MethodDelete, "/note", nil)
+	require.Nil(t, err)
+	req = withPrincipal(req)
+
+	recorder := httptest.NewRecorder()
+	httpHandler := http.HandlerFunc(deleteNote(mockSvc))
+	httpHandler.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusNotFound, recorder.Code)
+	require.Contains(t, recorder.Body.String(), "note not found")
+}
+
+func TestAPI_DeleteNote_ShouldRespondWith200OnSuccess(t *testing.T) {
+	mockSvc := &mocks.NoteServiceHandler{}
+	mockSvc.On("DeleteNote", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	req, err := http.NewRequest(http.MethodDelete, "/note", nil)
 	require.Nil(t, err)
-	req.Header.Set("Authorization", "Bearer test")
+	req = withPrincipal(req)
 
 	recorder := httptest.NewRecorder()
-	httpHandler := http.HandlerFunc(editNote(context.TODO(), mockSvc))
+	httpHandler := http.HandlerFunc(deleteNote(mockSvc))
 	httpHandler.ServeHTTP(recorder, req)
 	require.Equal(t, http.StatusOK, recorder.Code)
 }
 
-func TestAPI_CreateNote_ShouldRespondWith400IfErrorOccursRetrievingAuthToken(t *testing.T) {
+func TestAPI_SendToContentService_ShouldRespondWith500IfServiceErrorOccurs(t *testing.T) {
 	mockSvc := &mocks.NoteServiceHandler{}
+	mockSvc.On("SendToContentService", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, errors.New("test"))
 
 	req, err := http.NewRequest(http.MethodPost, "/note", nil)
 	require.Nil(t, err)
+	req = withPrincipal(req)
 
 	recorder := httptest.NewRecorder()
-	httpHandler := http.HandlerFunc(createNote(context.TODO(), mockSvc))
+	httpHandler := http.HandlerFunc(sendToContentService(mockSvc))
 	httpHandler.ServeHTTP(recorder, req)
-	require.Equal(t, http.StatusBadRequest, recorder.Code)
-	require.Contains(t, recorder.Body.String(), "no authorization header found")
+	require.Equal(t, http.StatusInternalServerError, recorder.Code)
+	require.Contains(t, recorder.Body.String(), "test")
 }
 
-func TestAPI_CreateNote_ShouldRespondWith400IfTokenFormatIsInvalid(t *testing.T) {
+func TestAPI_SendToContentService_ShouldRespondWith404IfNoteIsNotFound(t *testing.T) {
 	mockSvc := &mocks.NoteServiceHandler{}
+	mockSvc.On("SendToContentService", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, dao.ErrNotFound)
 
 	req, err := http.NewRequest(http.MethodPost, "/note", nil)
 	require.Nil(t, err)
+	req = withPrincipal(req)
+
+	recorder := httptest.NewRecorder()
+	httpHandler := http.HandlerFunc(sendToContentService(mockSvc))
+	httpHandler.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusNotFound, recorder.Code)
+}
+
+func TestAPI_SendToContentService_ShouldRespondWith400IfFormatIsUnknown(t *testing.T) {
+	mockSvc := &mocks.NoteServiceHandler{}
+	mockSvc.On("SendToContentService", mock.Anything, mock.Anything, mock.Anything, mock.Anything, "docx").
+		Return(nil, fmt.Errorf("%w: unknown format 'docx'", service.ErrInvalidQuery))
 
-	req.Header.Set("Authorization", "test")
+	req, err := http.NewRequest(http.MethodPost, "/note?format=docx", nil)
+	require.Nil(t, err)
+	req = withPrincipal(req)
 
 	recorder := httptest.NewRecorder()
-	httpHandler := http.HandlerFunc(createNote(context.TODO(), mockSvc))
+	httpHandler := http.HandlerFunc(sendToContentService(mockSvc))
 	httpHandler.ServeHTTP(recorder, req)
 	require.Equal(t, http.StatusBadRequest, recorder.Code)
-	require.Contains(t, recorder.Body.String(), "authorization header must be in format 'Bearer'")
+	require.Contains(t, recorder.Body.String(), "unknown format")
 }
 
-func TestAPI_CreateNote_ShouldRespondWith401IfErrorOccursValidatingToken(t *testing.T) {
+func TestAPI_SendToContentService_ShouldRespondWith202AndJobLocationOnSuccess(t *testing.T) {
+	job := &models.SaveJob{ID: primitive.NewObjectID(), Status: models.SaveJobPending}
+
 	mockSvc := &mocks.NoteServiceHandler{}
-	mockSvc.On("ValidateToken", mock.Anything, mock.Anything).Return(errors.New("test"))
+	mockSvc.On("SendToContentService", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(job, nil)
 
 	req, err := http.NewRequest(http.MethodPost, "/note", nil)
 	require.Nil(t, err)
-	req.Header.Set("Authorization", "Bearer test")
+	req = withPrincipal(req)
 
 	recorder := httptest.NewRecorder()
-	httpHandler := http.HandlerFunc(createNote(context.TODO(), mockSvc))
+	httpHandler := http.HandlerFunc(sendToContentService(mockSvc))
 	httpHandler.ServeHTTP(recorder, req)
-	require.Equal(t, http.StatusUnauthorized, recorder.Code)
-	require.Contains(t, recorder.Body.String(), "test")
+	require.Equal(t, http.StatusAccepted, recorder.Code)
+	require.Equal(t, "/save/jobs/"+job.ID.Hex(), recorder.Header().Get("Location"))
+	require.Contains(t, recorder.Body.String(), `"status":"pending"`)
 }
 
-func TestAPI_CreateNote_ShouldRespondWith400IfErrorOccursDecodingRequestBody(t *testing.T) {
+func TestAPI_GetSaveJob_ShouldRespondWith404IfJobIsNotFound(t *testing.T) {
 	mockSvc := &mocks.NoteServiceHandler{}
-	mockSvc.On("ValidateToken", mock.Anything, mock.Anything).Return(nil)
+	mockSvc.On("GetSaveJob", mock.Anything, mock.Anything, mock.Anything).Return(nil, dao.ErrSaveJobNotFound)
 
-	req, err := http.NewRequest(http.MethodPost, "/note", ioutil.NopCloser(strings.NewReader("")))
+	req, err := http.NewRequest(http.MethodGet, "/save/jobs", nil)
 	require.Nil(t, err)
-	req.Header.Set("Authorization", "Bearer test")
+	req = withPrincipal(req)
 
 	recorder := httptest.NewRecorder()
-	httpHandler := http.HandlerFunc(createNote(context.TODO(), mockSvc))
+	httpHandler := http.HandlerFunc(getSaveJob(mockSvc))
 	httpHandler.ServeHTTP(recorder, req)
-	require.Equal(t, http.StatusBadRequest, recorder.Code)
-	require.Contains(t, recorder.Body.String(), "EOF")
+	require.Equal(t, http.StatusNotFound, recorder.Code)
+	require.Contains(t, recorder.Body.String(), "save job not found")
 }
 
-func TestAPI_CreateNote_ShouldRespondWith500IfServiceErrorOccurs(t *testing.T) {
+func TestAPI_GetSaveJob_ShouldRespondWith200OnSuccess(t *testing.T) {
 	mockSvc := &mocks.NoteServiceHandler{}
-	mockSvc.On("ValidateToken", mock.Anything, mock.Anything).Return(nil)
-	mockSvc.On("CreateNote", mock.Anything, mock.Anything, mock.Anything).Return("", errors.New("test"))
+	mockSvc.On("GetSaveJob", mock.Anything, mock.Anything, mock.Anything).Return(&models.SaveJob{
+		Status:    models.SaveJobFailed,
+		Attempts:  3,
+		LastError: "non-200 status code received: 401",
+	}, nil)
 
-	req, err := http.NewRequest(http.MethodPost, "/note", ioutil.NopCloser(strings.NewReader("{}")))
+	req, err := http.NewRequest(http.MethodGet, "/save/jobs", nil)
 	require.Nil(t, err)
-	req.Header.Set("Authorization", "Bearer test")
+	req = withPrincipal(req)
 
 	recorder := httptest.NewRecorder()
-	httpHandler := http.HandlerFunc(createNote(context.TODO(), mockSvc))
+	httpHandler := http.HandlerFunc(getSaveJob(mockSvc))
+	httpHandler.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Contains(t, recorder.Body.String(), `"lastError":"non-200 status code received: 401"`)
+}
+
+func TestAPI_GetRevisions_ShouldRespondWith500IfServiceErrorOccurs(t *testing.T) {
+	mockSvc := &mocks.NoteServiceHandler{}
+	mockSvc.On("GetRevisions", mock.Anything, mock.Anything, mock.Anything).Return(nil, errors.New("test"))
+
+	req, err := http.NewRequest(http.MethodGet, "/note/id/revisions", nil)
+	require.Nil(t, err)
+	req = withPrincipal(req)
+
+	recorder := httptest.NewRecorder()
+	httpHandler := http.HandlerFunc(getRevisions(mockSvc))
 	httpHandler.ServeHTTP(recorder, req)
 	require.Equal(t, http.StatusInternalServerError, recorder.Code)
 	require.Contains(t, recorder.Body.String(), "test")
 }
 
-func TestAPI_CreateNote_ShouldRespondWith200OnSuccess(t *testing.T) {
+func TestAPI_GetRevisions_ShouldRespondWith200OnSuccess(t *testing.T) {
 	mockSvc := &mocks.NoteServiceHandler{}
-	mockSvc.On("ValidateToken", mock.Anything, mock.Anything).Return(nil)
-	mockSvc.On("CreateNote", mock.Anything, mock.Anything, mock.Anything).Return("", nil)
+	mockSvc.On("GetRevisions", mock.Anything, mock.Anything, mock.Anything).Return([]models.Revision{{}}, nil)
 
-	req, err := http.NewRequest(http.MethodPost, "/note", ioutil.NopCloser(strings.NewReader("{}")))
+	req, err := http.NewRequest(http.MethodGet, "/note/id/revisions", nil)
 	require.Nil(t, err)
-	req.Header.Set("Authorization", "Bearer test")
+	req = withPrincipal(req)
 
 	recorder := httptest.NewRecorder()
-	httpHandler := http.HandlerFunc(createNote(context.TODO(), mockSvc))
+	httpHandler := http.HandlerFunc(getRevisions(mockSvc))
 	httpHandler.ServeHTTP(recorder, req)
 	require.Equal(t, http.StatusOK, recorder.Code)
 }
 
-func TestAPI_DeleteNote_ShouldRespondWith400IfErrorOccursRetrievingAuthToken(t *testing.T) {
+func TestAPI_GetRevision_ShouldRespondWith404IfRevisionIsNotFound(t *testing.T) {
 	mockSvc := &mocks.NoteServiceHandler{}
+	mockSvc.On("GetRevision", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, dao.ErrRevisionNotFound)
 
-	req, err := http.NewRequest(http.MethodDelete, "/note", nil)
+	req, err := http.NewRequest(http.MethodGet, "/note/id/revisions/rev", nil)
 	require.Nil(t, err)
+	req = withPrincipal(req)
 
 	recorder := httptest.NewRecorder()
-	httpHandler := http.HandlerFunc(deleteNote(context.TODO(), mockSvc))
+	httpHandler := http.HandlerFunc(getRevision(mockSvc))
 	httpHandler.ServeHTTP(recorder, req)
-	require.Equal(t, http.StatusBadRequest, recorder.Code)
-	require.Contains(t, recorder.Body.String(), "no authorization header found")
+	require.Equal(t, http.StatusNotFound, recorder.Code)
+	require.Contains(t, recorder.Body.String(), "revision not found")
 }
 
-func TestAPI_DeleteNote_ShouldRespondWith400IfTokenFormatIsInvalid(t *testing.T) {
+func TestAPI_GetRevision_ShouldRespondWith200OnSuccess(t *testing.T) {
 	mockSvc := &mocks.NoteServiceHandler{}
+	mockSvc.On("GetRevision", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(&models.Revision{}, nil)
 
-	req, err := http.NewRequest(http.MethodDelete, "/note", nil)
+	req, err := http.NewRequest(http.MethodGet, "/note/id/revisions/rev", nil)
 	require.Nil(t, err)
+	req = withPrincipal(req)
 
-	req.Header.Set("Authorization", "test")
+	recorder := httptest.NewRecorder()
+	httpHandler := http.HandlerFunc(getRevision(mockSvc))
+	httpHandler.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusOK, recorder.Code)
+}
+
+func TestAPI_RestoreRevision_ShouldRespondWith500IfServiceErrorOccurs(t *testing.T) {
+	mockSvc := &mocks.NoteServiceHandler{}
+	mockSvc.On("RestoreRevision", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(int64(0), errors.New("test"))
+
+	req, err := http.NewRequest(http.MethodPost, "/note/id/revisions/rev/restore", nil)
+	require.Nil(t, err)
+	req = withPrincipal(req)
+	req.Header.Set("If-Match", `"1"`)
 
 	recorder := httptest.NewRecorder()
-	httpHandler := http.HandlerFunc(deleteNote(context.TODO(), mockSvc))
+	httpHandler := http.HandlerFunc(restoreRevision(mockSvc))
 	httpHandler.ServeHTTP(recorder, req)
-	require.Equal(t, http.StatusBadRequest, recorder.Code)
-	require.Contains(t, recorder.Body.String(), "authorization header must be in format 'Bearer'")
+	require.Equal(t, http.StatusInternalServerError, recorder.Code)
+	require.Contains(t, recorder.Body.String(), "test")
 }
 
-func TestAPI_DeleteNote_ShouldRespondWith401IfErrorOccursValidatingToken(t *testing.T) {
+func TestAPI_RestoreRevision_ShouldRespondWith200OnSuccess(t *testing.T) {
 	mockSvc := &mocks.NoteServiceHandler{}
-	mockSvc.On("ValidateToken", mock.Anything, mock.Anything).Return(errors.New("test"))
+	mockSvc.On("RestoreRevision", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, int64(1)).Return(int64(2), nil)
 
-	req, err := http.NewRequest(http.MethodDelete, "/note", nil)
+	req, err := http.NewRequest(http.MethodPost, "/note/id/revisions/rev/restore", nil)
 	require.Nil(t, err)
-	req.Header.Set("Authorization", "Bearer test")
+	req = withPrincipal(req)
+	req.Header.Set("If-Match", `"1"`)
 
 	recorder := httptest.NewRecorder()
-	httpHandler := http.HandlerFunc(deleteNote(context.TODO(), mockSvc))
+	httpHandler := http.HandlerFunc(restoreRevision(mockSvc))
 	httpHandler.ServeHTTP(recorder, req)
-	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+	require.Equal(t, http.StatusOK, recorder.Code)
+}
+
+func TestAPI_GetTrash_ShouldRespondWith500IfServiceErrorOccurs(t *testing.T) {
+	mockSvc := &mocks.NoteServiceHandler{}
+	mockSvc.On("GetTrash", mock.Anything, mock.Anything).Return(nil, errors.New("test"))
+
+	req, err := http.NewRequest(http.MethodGet, "/trash", nil)
+	require.Nil(t, err)
+	req = withPrincipal(req)
+
+	recorder := httptest.NewRecorder()
+	httpHandler := http.HandlerFunc(getTrash(mockSvc))
+	httpHandler.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusInternalServerError, recorder.Code)
 	require.Contains(t, recorder.Body.String(), "test")
 }
 
-func TestAPI_DeleteNote_ShouldRespondWith500IfServiceErrorOccurs(t *testing.T) {
+func TestAPI_GetTrash_ShouldRespondWith200OnSuccess(t *testing.T) {
 	mockSvc := &mocks.NoteServiceHandler{}
-	mockSvc.On("ValidateToken", mock.Anything, mock.Anything).Return(nil)
-	mockSvc.On("DeleteNote", mock.Anything, mock.Anything).Return(errors.New("test"))
+	mockSvc.On("GetTrash", mock.Anything, mock.Anything).Return([]models.Note{{}}, nil)
 
-	req, err := http.NewRequest(http.MethodDelete, "/note", nil)
+	req, err := http.NewRequest(http.MethodGet, "/trash", nil)
+	require.Nil(t, err)
+	req = withPrincipal(req)
+
+	recorder := httptest.NewRecorder()
+	httpHandler := http.HandlerFunc(getTrash(mockSvc))
+	httpHandler.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusOK, recorder.Code)
+}
+
+func TestAPI_RestoreFromTrash_ShouldRespondWith404IfNoteIsNotInTrash(t *testing.T) {
+	mockSvc := &mocks.NoteServiceHandler{}
+	mockSvc.On("RestoreFromTrash", mock.Anything, mock.Anything, mock.Anything).Return(dao.ErrNotFound)
+
+	req, err := http.NewRequest(http.MethodPost, "/trash/id/restore", nil)
 	require.Nil(t, err)
-	req.Header.Set("Authorization", "Bearer test")
+	req = withPrincipal(req)
 
 	recorder := httptest.NewRecorder()
-	httpHandler := http.HandlerFunc(deleteNote(context.TODO(), mockSvc))
+	httpHandler := http.HandlerFunc(restoreFromTrash(mockSvc))
+	httpHandler.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusNotFound, recorder.Code)
+}
+
+func TestAPI_RestoreFromTrash_ShouldRespondWith200OnSuccess(t *testing.T) {
+	mockSvc := &mocks.NoteServiceHandler{}
+	mockSvc.On("RestoreFromTrash", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	req, err := http.NewRequest(http.MethodPost, "/trash/id/restore", nil)
+	require.Nil(t, err)
+	req = withPrincipal(req)
+
+	recorder := httptest.NewRecorder()
+	httpHandler := http.HandlerFunc(restoreFromTrash(mockSvc))
+	httpHandler.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusOK, recorder.Code)
+}
+
+func TestAPI_PurgeNote_ShouldRespondWith500IfServiceErrorOccurs(t *testing.T) {
+	mockSvc := &mocks.NoteServiceHandler{}
+	mockSvc.On("PurgeNote", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("test"))
+
+	req, err := http.NewRequest(http.MethodDelete, "/trash/id", nil)
+	require.Nil(t, err)
+	req = withPrincipal(req)
+
+	recorder := httptest.NewRecorder()
+	httpHandler := http.HandlerFunc(purgeNote(mockSvc))
 	httpHandler.ServeHTTP(recorder, req)
 	require.Equal(t, http.StatusInternalServerError, recorder.Code)
 	require.Contains(t, recorder.Body.String(), "test")
 }
 
-func TestAPI_DeleteNote_ShouldRespondWith200OnSuccess(t *testing.T) {
+func TestAPI_PurgeNote_ShouldRespondWith200OnSuccess(t *testing.T) {
 	mockSvc := &mocks.NoteServiceHandler{}
-	mockSvc.On("ValidateToken", mock.Anything, mock.Anything).Return(nil)
-	mockSvc.On("DeleteNote", mock.Anything, mock.Anything).Return(nil)
+	mockSvc.On("PurgeNote", mock.Anything, mock.Anything, mock.Anything).Return(nil)
 
-	req, err := http.NewRequest(http.MethodDelete, "/note", nil)
+	req, err := http.NewRequest(http.MethodDelete, "/trash/id", nil)
 	require.Nil(t, err)
-	req.Header.Set("Authorization", "Bearer test")
+	req = withPrincipal(req)
 
 	recorder := httptest.NewRecorder()
-	httpHandler := http.HandlerFunc(deleteNote(context.TODO(), mockSvc))
+	httpHandler := http.HandlerFunc(purgeNote(mockSvc))
 	httpHandler.ServeHTTP(recorder, req)
 	require.Equal(t, http.StatusOK, recorder.Code)
 }
 
-func TestAPI_SendToContentService_ShouldRespondWith400IfErrorOccursRetrievingAuthToken(t *testing.T) {
+func withPrincipal(req *http.Request) *http.Request {
+	ctx := context.WithValue(req.Context(), principalKey, &models.Principal{UserID: "test"})
+	ctx = context.WithValue(ctx, tokenKey, "test")
+	return req.WithContext(ctx)
+}
+
+func TestAPI_GetTags_ShouldRespondWith500IfServiceErrorOccurs(t *testing.T) {
 	mockSvc := &mocks.NoteServiceHandler{}
+	mockSvc.On("GetTags", mock.Anything, mock.Anything).Return(nil, errors.New("test"))
 
-	req, err := http.NewRequest(http.MethodPost, "/save", nil)
+	req, err := http.NewRequest(http.MethodGet, "/tags", nil)
 	require.Nil(t, err)
+	req = withPrincipal(req)
 
 	recorder := httptest.NewRecorder()
-	httpHandler := http.HandlerFunc(sendToContentService(context.TODO(), mockSvc))
+	httpHandler := http.HandlerFunc(getTags(mockSvc))
 	httpHandler.ServeHTTP(recorder, req)
-	require.Equal(t, http.StatusBadRequest, recorder.Code)
-	require.Contains(t, recorder.Body.String(), "no authorization header found")
+	require.Equal(t, http.StatusInternalServerError, recorder.Code)
+	require.Contains(t, recorder.Body.String(), "test")
 }
 
-func TestAPI_SendToContentService_ShouldRespondWith400IfTokenFormatIsInvalid(t *testing.T) {
+func TestAPI_GetTags_ShouldRespondWith200OnSuccess(t *testing.T) {
 	mockSvc := &mocks.NoteServiceHandler{}
+	mockSvc.On("GetTags", mock.Anything, "test").Return([]models.TagCount{{Tag: "runbook", Count: 2}}, nil)
 
-	req, err := http.NewRequest(http.MethodPost, "/note", nil)
+	req, err := http.NewRequest(http.MethodGet, "/tags", nil)
 	require.Nil(t, err)
+	req = withPrincipal(req)
 
-	req.Header.Set("Authorization", "test")
+	recorder := httptest.NewRecorder()
+	httpHandler := http.HandlerFunc(getTags(mockSvc))
+	httpHandler.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Contains(t, recorder.Body.String(), `{"tag":"runbook","count":2}`)
+}
+
+func TestAPI_RenameTag_ShouldRespondWith400IfErrorOccursDecodingRequestBody(t *testing.T) {
+	mockSvc := &mocks.NoteServiceHandler{}
+
+	req, err := http.NewRequest(http.MethodPut, "/tags/old", strings.NewReader("{"))
+	require.Nil(t, err)
+	req = withPrincipal(req)
 
 	recorder := httptest.NewRecorder()
-	httpHandler := http.HandlerFunc(sendToContentService(context.TODO(), mockSvc))
+	httpHandler := http.HandlerFunc(renameTag(mockSvc))
 	httpHandler.ServeHTTP(recorder, req)
 	require.Equal(t, http.StatusBadRequest, recorder.Code)
-	require.Contains(t, recorder.Body.String(), "authorization header must be in format 'Bearer'")
 }
 
-func TestAPI_SendToContentService_ShouldRespondWith401IfErrorOccursValidatingToken(t *testing.T) {
+func TestAPI_RenameTag_ShouldRespondWith400IfNameIsInvalid(t *testing.T) {
 	mockSvc := &mocks.NoteServiceHandler{}
-	mockSvc.On("ValidateToken", mock.Anything, mock.Anything).Return(errors.New("test"))
+	mockSvc.On("RenameTag", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(int64(0), fmt.Errorf("%w: tag names must not be empty", service.ErrInvalidQuery))
 
-	req, err := http.NewRequest(http.MethodPost, "/note", nil)
+	req, err := http.NewRequest(http.MethodPut, "/tags/old", strings.NewReader(`{"name":""}`))
 	require.Nil(t, err)
-	req.Header.Set("Authorization", "Bearer test")
+	req = withPrincipal(req)
 
 	recorder := httptest.NewRecorder()
-	httpHandler := http.HandlerFunc(sendToContentService(context.TODO(), mockSvc))
+	httpHandler := http.HandlerFunc(renameTag(mockSvc))
 	httpHandler.ServeHTTP(recorder, req)
-	require.Equal(t, http.StatusUnauthorized, recorder.Code)
-	require.Contains(t, recorder.Body.String(), "test")
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
 }
 
-func TestAPI_SendToContentService_ShouldRespondWith500IfServiceErrorOccurs(t *testing.T) {
+func TestAPI_RenameTag_ShouldRespondWith500IfServiceErrorOccurs(t *testing.T) {
 	mockSvc := &mocks.NoteServiceHandler{}
-	mockSvc.On("ValidateToken", mock.Anything, mock.Anything).Return(nil)
-	mockSvc.On("SetToken", mock.Anything).Return()
-	mockSvc.On("SendToContentService", mock.Anything, mock.Anything).Return(errors.New("test"))
+	mockSvc.On("RenameTag", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(int64(0), errors.New("test"))
 
-	req, err := http.NewRequest(http.MethodPost, "/note", nil)
+	req, err := http.NewRequest(http.MethodPut, "/tags/old", strings.NewReader(`{"name":"new"}`))
 	require.Nil(t, err)
-	req.Header.Set("Authorization", "Bearer test")
+	req = withPrincipal(req)
 
 	recorder := httptest.NewRecorder()
-	httpHandler := http.HandlerFunc(sendToContentService(context.TODO(), mockSvc))
+	httpHandler := http.HandlerFunc(renameTag(mockSvc))
 	httpHandler.ServeHTTP(recorder, req)
 	require.Equal(t, http.StatusInternalServerError, recorder.Code)
 	require.Contains(t, recorder.Body.String(), "test")
 }
 
-func TestAPI_SendToContentService_ShouldRespondWith200OnSuccess(t *testing.T) {
+func TestAPI_RenameTag_ShouldRespondWith200OnSuccess(t *testing.T) {
 	mockSvc := &mocks.NoteServiceHandler{}
-	mockSvc.On("ValidateToken", mock.Anything, mock.Anything).Return(nil)
-	mockSvc.On("SetToken", mock.Anything).Return()
-	mockSvc.On("SendToContentService", mock.Anything, mock.Anything).Return(nil)
+	mockSvc.On("RenameTag", mock.Anything, "test", "old", "new").Return(int64(2), nil)
 
-	req, err := http.NewRequest(http.MethodPost, "/note", nil)
+	req, err := http.NewRequest(http.MethodPut, "/tags/old", strings.NewReader(`{"name":"new"}`))
 	require.Nil(t, err)
-	req.Header.Set("Authorization", "Bearer test")
+	req = withPrincipal(req)
+	req = mux.SetURLVars(req, map[string]string{"tag": "old"})
 
 	recorder := httptest.NewRecorder()
-	httpHandler := http.HandlerFunc(sendToContentService(context.TODO(), mockSvc))
+	httpHandler := http.HandlerFunc(renameTag(mockSvc))
 	httpHandler.ServeHTTP(recorder, req)
 	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Contains(t, recorder.Body.String(), "on 2 notes")
+}
+
+func TestAPI_ShutdownGracefully_ShouldFinishRequestsInFlightOnInterrupt(t *testing.T) {
+	started := make(chan struct{})
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.Start()
+	defer server.Close()
+
+	shutdown := shutdownGracefully(server.Config, time.Minute)
+
+	response := make(chan *http.Response, 1)
+	go func() {
+		resp, err := http.Get(server.URL)
+		require.Nil(t, err)
+		response <- resp
+	}()
+	<-started
+
+	process, err := os.FindProcess(os.Getpid())
+	require.Nil(t, err)
+	require.Nil(t, process.Signal(os.Interrupt))
+
+	select {
+	case <-shutdown:
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not shut down")
+	}
+	resp := <-response
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Nil(t, resp.Body.Close())
 }
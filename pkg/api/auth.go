@@ -0,0 +1,147 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"notes-api/pkg/auth"
+	"notes-api/pkg/config"
+	"notes-api/pkg/models"
+	"notes-api/pkg/service"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+type contextKey int
+
+const (
+	principalKey contextKey = iota
+	tokenKey
+	requestIDKey
+	accessRecordKey
+)
+
+// authenticate validates the bearer token on every request it wraps and attaches the resulting principal and the raw
+// token to the request context, where handlers retrieve them with getPrincipal and getToken.
+func authenticate(svc service.NoteServiceHandler) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			logger := logrus.WithContext(ctx)
+
+			token, err := getAuthToken(r)
+			if err != nil {
+				logger.WithError(err).Error("Error retrieving authorization token from request")
+				respondWithError(ctx, w, http.StatusBadRequest, err.Error())
+				return
+			}
+
+			principal, err := svc.ValidateToken(ctx, token)
+			if err != nil {
+				logger.WithError(err).Error("Error validating token")
+				status := http.StatusUnauthorized
+				if auth.IsUnavailable(err) {
+					status = http.StatusServiceUnavailable
+				}
+				respondWithError(ctx, w, status, err.Error())
+				return
+			}
+
+			if record, ok := ctx.Value(accessRecordKey).(*accessRecord); ok {
+				record.userID = principal.UserID
+			}
+
+			requestCtx := context.WithValue(r.Context(), principalKey, principal)
+			requestCtx = context.WithValue(requestCtx, tokenKey, token)
+			next.ServeHTTP(w, r.WithContext(requestCtx))
+		})
+	}
+}
+
+func getPrincipal(r *http.Request) (*models.Principal, error) {
+	principal, ok := r.Context().Value(principalKey).(*models.Principal)
+	if !ok || principal == nil {
+		return nil, errors.New("request is not authenticated")
+	}
+	return principal, nil
+}
+
+func getToken(r *http.Request) string {
+	token, _ := r.Context().Value(tokenKey).(string)
+	return token
+}
+
+func getAuthToken(r *http.Request) (string, error) {
+	tokenHeader := r.Header.Get("Authorization")
+	if tokenHeader == "" {
+		return "", errors.New("no authorization header found")
+	} else if (len(tokenHeader) >= 7 && tokenHeader[:7] != "Bearer ") || len(strings.Split(tokenHeader, " ")) != 2 {
+		return "", errors.New("authorization header must be in format 'Bearer' <token>")
+	}
+	return strings.Split(tokenHeader, " ")[1], nil
+}
+
+// newValidator builds the token validator selected by AUTH_MODE: the login service (remote, the default), local JWT
+// verification (local), or local verification falling back to the login service (both).
+func newValidator(cfg *config.Config, remote auth.Validator) (auth.Validator, error) {
+	mode := cfg.Auth.Mode
+	if mode == "" || mode == auth.ModeRemote {
+		return remote, nil
+	}
+
+	keys, err := jwtKeys(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	verifier := auth.NewJWTVerifier(auth.JWTConfig{
+		Keys:         keys,
+		Issuer:       cfg.Auth.JWTIssuer,
+		Audience:     cfg.Auth.JWTAudience,
+		VerifyExpiry: cfg.Auth.JWTVerifyExpiry,
+		Leeway:       cfg.Auth.JWTLeeway,
+	})
+
+	switch mode {
+	case auth.ModeLocal:
+		return verifier, nil
+	case auth.ModeBoth:
+		return auth.Chain{verifier, remote}, nil
+	default:
+		return nil, fmt.Errorf("unknown AUTH_MODE '%v'", mode)
+	}
+}
+
+func jwtKeys(cfg *config.Config) (auth.KeySource, error) {
+	if secret := cfg.Auth.JWTHMACSecret; secret != "" {
+		return auth.StaticKeys{"": []byte(secret)}, nil
+	}
+
+	if path := cfg.Auth.JWTPublicKeyFile; path != "" {
+		key, err := auth.LoadPublicKeyFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return auth.StaticKeys{"": key}, nil
+	}
+
+	if path := cfg.Auth.JWKSFile; path != "" {
+		return auth.LoadJWKSFile(path)
+	}
+
+	if url := cfg.Auth.JWKSURL; url != "" {
+		return &auth.RemoteJWKS{
+			URL: url,
+			Client: &http.Client{
+				Timeout: cfg.Services.Timeout,
+			},
+			RefreshInterval: cfg.Auth.JWKSRefreshInterval,
+		}, nil
+	}
+
+	return nil, errors.New("local token verification requires JWT_HMAC_SECRET, JWT_PUBLIC_KEY_FILE, JWKS_FILE or JWKS_URL")
+}
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"notes-api/pkg/external"
+	"notes-api/pkg/models"
+	"notes-api/pkg/testhelper/mocks"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuth_Authenticate_ShouldRespondWith400IfErrorOccursRetrievingAuthToken(t *testing.T) {
+	mockSvc := &mocks.NoteServiceHandler{}
+
+	req, err := http.NewRequest(http.MethodGet, "/notes", nil)
+	require.Nil(t, err)
+
+	recorder := httptest.NewRecorder()
+	authenticate(mockSvc)(failIfCalled(t)).ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+	require.Contains(t, recorder.Body.String(), "no authorization header found")
+}
+
+func TestAuth_Authenticate_ShouldRespondWith400IfTokenFormatIsInvalid(t *testing.T) {
+	mockSvc := &mocks.NoteServiceHandler{}
+
+	req, err := http.NewRequest(http.MethodGet, "/notes", nil)
+	require.Nil(t, err)
+	req.Header.Set("Authorization", "test")
+
+	recorder := httptest.NewRecorder()
+	authenticate(mockSvc)(failIfCalled(t)).ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+	require.Contains(t, recorder.Body.String(), "authorization header must be in format 'Bearer'")
+}
+
+func TestAuth_Authenticate_ShouldRespondWith401IfErrorOccursValidatingToken(t *testing.T) {
+	mockSvc := &mocks.NoteServiceHandler{}
+	mockSvc.On("ValidateToken", mock.Anything, "test").Return(nil, errors.New("test"))
+
+	req, err := http.NewRequest(http.MethodGet, "/notes", nil)
+	require.Nil(t, err)
+	req.Header.Set("Authorization", "Bearer test")
+
+	recorder := httptest.NewRecorder()
+	authenticate(mockSvc)(failIfCalled(t)).ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+	require.Contains(t, recorder.Body.String(), "test")
+}
+
+func TestAuth_Authenticate_ShouldRespondWith503IfLoginServiceFails(t *testing.T) {
+	mockSvc := &mocks.NoteServiceHandler{}
+	mockSvc.On("ValidateToken", mock.Anything, "test").Return(nil, &external.StatusError{StatusCode: http.StatusBadGateway})
+
+	req, err := http.NewRequest(http.MethodGet, "/notes", nil)
+	require.Nil(t, err)
+	req.Header.Set("Authorization", "Bearer test")
+
+	recorder := httptest.NewRecorder()
+	authenticate(mockSvc)(failIfCalled(t)).ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+}
+
+func TestAuth_Authenticate_ShouldAttachPrincipalAndTokenToRequestContext(t *testing.T) {
+	principal := &models.Principal{UserID: "user", Username: "username", Roles: []string{"admin"}}
+
+	mockSvc := &mocks.NoteServiceHandler{}
+	mockSvc.On("ValidateToken", mock.Anything, "test").Return(principal, nil)
+
+	req, err := http.NewRequest(http.MethodGet, "/notes", nil)
+	require.Nil(t, err)
+	req.Header.Set("Authorization", "Bearer test")
+
+	called := false
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+
+		p, err := getPrincipal(r)
+		require.Nil(t, err)
+		require.Equal(t, principal, p)
+		require.Equal(t, "test", getToken(r))
+	})
+
+	recorder := httptest.NewRecorder()
+	authenticate(mockSvc)(next).ServeHTTP(recorder, req)
+	require.True(t, called)
+}
+
+func TestAuth_GetPrincipal_ShouldReturnErrorIfRequestIsNotAuthenticated(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/notes", nil)
+	require.Nil(t, err)
+
+	principal, err := getPrincipal(req)
+	require.Nil(t, principal)
+	require.NotNil(t, err)
+	require.Equal(t, "request is not authenticated", err.Error())
+}
+
+func failIfCalled(t *testing.T) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("next handler should not be called")
+	})
+}
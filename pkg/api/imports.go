@@ -0,0 +1,65 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"notes-api/pkg/models"
+	"notes-api/pkg/service"
+
+	"github.com/sirupsen/logrus"
+)
+
+func getContentFiles(svc service.NoteServiceHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := logrus.WithContext(ctx)
+		defer closeRequestBody(ctx, r)
+
+		if _, err := getPrincipal(r); err != nil {
+			logger.WithError(err).Error("Error retrieving principal from request")
+			respondWithError(ctx, w, http.StatusUnauthorized, err.Error())
+			return
+		}
+
+		files, err := svc.ListContentFiles(ctx, getToken(r))
+		if err != nil {
+			logger.WithError(err).Error("Error listing content service files")
+			respondWithError(ctx, w, errorStatus(err), err.Error())
+			return
+		}
+
+		respondWithSuccess(ctx, w, http.StatusOK, files)
+	}
+}
+
+func importNotes(svc service.NoteServiceHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := logrus.WithContext(ctx)
+		defer closeRequestBody(ctx, r)
+
+		principal, err := getPrincipal(r)
+		if err != nil {
+			logger.WithError(err).Error("Error retrieving principal from request")
+			respondWithError(ctx, w, http.StatusUnauthorized, err.Error())
+			return
+		}
+
+		var request models.ImportRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			logger.WithError(err).Error("Error decoding request body")
+			respondWithError(ctx, w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		result, err := svc.ImportNotes(ctx, principal.UserID, getToken(r), request)
+		if err != nil {
+			logger.WithError(err).Error("Error importing notes")
+			respondWithError(ctx, w, errorStatus(err), err.Error())
+			return
+		}
+
+		respondWithSuccess(ctx, w, http.StatusOK, result)
+	}
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"notes-api/pkg/external"
+	"notes-api/pkg/models"
+	"notes-api/pkg/testhelper/mocks"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestAPI_GetContentFiles_ShouldRespondWith502IfContentServiceFails(t *testing.T) {
+	mockSvc := &mocks.NoteServiceHandler{}
+	mockSvc.On("ListContentFiles", mock.Anything, mock.Anything).Return(nil, &external.StatusError{StatusCode: http.StatusServiceUnavailable})
+
+	req, err := http.NewRequest(http.MethodGet, "/import/files", nil)
+	require.Nil(t, err)
+	req = withPrincipal(req)
+
+	recorder := httptest.NewRecorder()
+	httpHandler := http.HandlerFunc(getContentFiles(mockSvc))
+	httpHandler.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusBadGateway, recorder.Code)
+}
+
+func TestAPI_GetContentFiles_ShouldRespondWith200OnSuccess(t *testing.T) {
+	mockSvc := &mocks.NoteServiceHandler{}
+	mockSvc.On("ListContentFiles", mock.Anything, mock.Anything).Return([]models.ContentFile{{ID: "1", Name: "a.md"}}, nil)
+
+	req, err := http.NewRequest(http.MethodGet, "/import/files", nil)
+	require.Nil(t, err)
+	req = withPrincipal(req)
+
+	recorder := httptest.NewRecorder()
+	httpHandler := http.HandlerFunc(getContentFiles(mockSvc))
+	httpHandler.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Contains(t, recorder.Body.String(), `"name":"a.md"`)
+}
+
+func TestAPI_ImportNotes_ShouldRespondWith400IfBodyIsInvalid(t *testing.T) {
+	mockSvc := &mocks.NoteServiceHandler{}
+
+	req, err := http.NewRequest(http.MethodPost, "/import", strings.NewReader("{"))
+	require.Nil(t, err)
+	req = withPrincipal(req)
+
+	recorder := httptest.NewRecorder()
+	httpHandler := http.HandlerFunc(importNotes(mockSvc))
+	httpHandler.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestAPI_ImportNotes_ShouldRespondWith200WithResultOnSuccess(t *testing.T) {
+	noteID := primitive.NewObjectID()
+
+	mockSvc := &mocks.NoteServiceHandler{}
+	mockSvc.On("ImportNotes", mock.Anything, mock.Anything, mock.Anything, models.ImportRequest{FileIDs: []string{"1", "2"}}).
+		Return(&models.ImportResult{
+			Imported: []models.ImportedFile{{FileID: "1", NoteID: noteID, Name: "a"}},
+			Skipped:  []models.SkippedFile{{FileID: "2", Reason: "file not found in content service"}},
+		}, nil)
+
+	req, err := http.NewRequest(http.MethodPost, "/import", strings.NewReader(`{"fileIds": ["1", "2"]}`))
+	require.Nil(t, err)
+	req = withPrincipal(req)
+
+	recorder := httptest.NewRecorder()
+	httpHandler := http.HandlerFunc(importNotes(mockSvc))
+	httpHandler.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Contains(t, recorder.Body.String(), noteID.Hex())
+	require.Contains(t, recorder.Body.String(), `"reason":"file not found in content service"`)
+}
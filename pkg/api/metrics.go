@@ -0,0 +1,53 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"notes-api/pkg/metrics"
+
+	"github.com/gorilla/mux"
+)
+
+// statusRecorder is a ResponseWriter that remembers the status code written through it, and counts the bytes of the
+// body.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	s.status = status
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func (s *statusRecorder) Write(body []byte) (int, error) {
+	n, err := s.ResponseWriter.Write(body)
+	s.bytes += n
+	return n, err
+}
+
+// routeTemplate returns the template of the route that the router matched a request to, or "unknown".
+func routeTemplate(r *http.Request) string {
+	if current := mux.CurrentRoute(r); current != nil {
+		if template, err := current.GetPathTemplate(); err == nil {
+			return template
+		}
+	}
+	return "unknown"
+}
+
+// observeRequests records every request that the router matches under the template of its route, such as
+// /note/{id}, so that requests for different notes are counted together.
+func observeRequests(m *metrics.Metrics) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route := routeTemplate(r)
+			recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			start := time.Now()
+			next.ServeHTTP(recorder, r)
+			m.ObserveRequest(route, r.Method, recorder.status, time.Since(start))
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"notes-api/pkg/metrics"
+	"notes-api/pkg/testhelper/mocks"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPI_ObserveRequests_ShouldLabelRequestsWithRouteTemplate(t *testing.T) {
+	m := metrics.New()
+	router := route(&mocks.NoteServiceHandler{})
+	router.Handle("/metrics", m.Handler()).Methods(http.MethodGet)
+	router.Use(observeRequests(m))
+
+	for _, id := range []string{"1", "2"} {
+		req, err := http.NewRequest(http.MethodGet, "/note/"+id, nil)
+		require.Nil(t, err)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, "/metrics", nil)
+	require.Nil(t, err)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Contains(t, recorder.Body.String(), `notes_api_http_requests_total{method="GET",route="/note/{id}",status="400"} 2`)
+}
@@ -0,0 +1,228 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"notes-api/pkg/models"
+	"notes-api/pkg/service"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+func getNotebooks(svc service.NoteServiceHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := logrus.WithContext(ctx)
+		defer closeRequestBody(ctx, r)
+
+		principal, err := getPrincipal(r)
+		if err != nil {
+			logger.WithError(err).Error("Error retrieving principal from request")
+			respondWithError(ctx, w, http.StatusUnauthorized, err.Error())
+			return
+		}
+
+		notebooks, err := svc.GetNotebooks(ctx, principal.UserID)
+		if err != nil {
+			logger.WithError(err).Error("Error retrieving notebooks")
+			respondWithError(ctx, w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		respondWithSuccess(ctx, w, http.StatusOK, notebooks)
+	}
+}
+
+func getNotebook(svc service.NoteServiceHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := logrus.WithContext(ctx)
+		defer closeRequestBody(ctx, r)
+
+		principal, err := getPrincipal(r)
+		if err != nil {
+			logger.WithError(err).Error("Error retrieving principal from request")
+			respondWithError(ctx, w, http.StatusUnauthorized, err.Error())
+			return
+		}
+
+		notebook, err := svc.GetNotebook(ctx, principal.UserID, mux.Vars(r)["id"])
+		if err != nil {
+			logger.WithError(err).Error("Error retrieving notebook")
+			respondWithError(ctx, w, errorStatus(err), err.Error())
+			return
+		}
+
+		respondWithSuccess(ctx, w, http.StatusOK, notebook)
+	}
+}
+
+func createNotebook(svc service.NoteServiceHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := logrus.WithContext(ctx)
+		defer closeRequestBody(ctx, r)
+
+		principal, err := getPrincipal(r)
+		if err != nil {
+			logger.WithError(err).Error("Error retrieving principal from request")
+			respondWithError(ctx, w, http.StatusUnauthorized, err.Error())
+			return
+		}
+
+		var notebook models.NotebookRequest
+		if err := json.NewDecoder(r.Body).Decode(&notebook); err != nil {
+			logger.WithError(err).Error("Error decoding request body")
+			respondWithError(ctx, w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		id, err := svc.CreateNotebook(ctx, principal.UserID, notebook)
+		if err != nil {
+			logger.WithError(err).Error("Error creating notebook")
+			respondWithError(ctx, w, errorStatus(err), err.Error())
+			return
+		}
+
+		respondWithSuccess(ctx, w, http.StatusOK, fmt.Sprintf("Notebook with ID '%v' created successfully", id))
+	}
+}
+
+func editNotebook(svc service.NoteServiceHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := logrus.WithContext(ctx)
+		defer closeRequestBody(ctx, r)
+
+		principal, err := getPrincipal(r)
+		if err != nil {
+			logger.WithError(err).Error("Error retrieving principal from request")
+			respondWithError(ctx, w, http.StatusUnauthorized, err.Error())
+			return
+		}
+
+		id := mux.Vars(r)["id"]
+
+		var notebook models.NotebookRequest
+		if err := json.NewDecoder(r.Body).Decode(&notebook); err != nil {
+			logger.WithError(err).Error("Error decoding request body")
+			respondWithError(ctx, w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		if err := svc.UpdateNotebook(ctx, principal.UserID, id, notebook); err != nil {
+			logger.WithError(err).Error("Error updating notebook")
+			respondWithError(ctx, w, errorStatus(err), err.Error())
+			return
+		}
+
+		respondWithSuccess(ctx, w, http.StatusOK, fmt.Sprintf("Notebook with ID '%v' updated successfully", id))
+	}
+}
+
+// deleteNotebook deletes a notebook. The mode query parameter chooses what happens to a notebook that is not empty:
+// "block", the default, refuses to delete it, and "cascade" deletes the notebooks inside it and moves its notes to
+// the trash.
+func deleteNotebook(svc service.NoteServiceHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := logrus.WithContext(ctx)
+		defer closeRequestBody(ctx, r)
+
+		principal, err := getPrincipal(r)
+		if err != nil {
+			logger.WithError(err).Error("Error retrieving principal from request")
+			respondWithError(ctx, w, http.StatusUnauthorized, err.Error())
+			return
+		}
+
+		id := mux.Vars(r)["id"]
+
+		var cascade bool
+		switch mode := r.URL.Query().Get("mode"); mode {
+		case "", "block":
+		case "cascade":
+			cascade = true
+		default:
+			err := fmt.Errorf("delete mode '%v' is not one of 'block' or 'cascade'", mode)
+			logger.WithError(err).Error("Error parsing delete mode")
+			respondWithError(ctx, w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		if err := svc.DeleteNotebook(ctx, principal.UserID, id, cascade); err != nil {
+			logger.WithError(err).Error("Error deleting notebook")
+			respondWithError(ctx, w, errorStatus(err), err.Error())
+			return
+		}
+
+		respondWithSuccess(ctx, w, http.StatusOK, fmt.Sprintf("Notebook with ID '%v' deleted successfully", id))
+	}
+}
+
+func moveNotebook(svc service.NoteServiceHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := logrus.WithContext(ctx)
+		defer closeRequestBody(ctx, r)
+
+		principal, err := getPrincipal(r)
+		if err != nil {
+			logger.WithError(err).Error("Error retrieving principal from request")
+			respondWithError(ctx, w, http.StatusUnauthorized, err.Error())
+			return
+		}
+
+		id := mux.Vars(r)["id"]
+
+		var move models.MoveRequest
+		if err := json.NewDecoder(r.Body).Decode(&move); err != nil {
+			logger.WithError(err).Error("Error decoding request body")
+			respondWithError(ctx, w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		if err := svc.MoveNotebook(ctx, principal.UserID, id, move.NotebookID); err != nil {
+			logger.WithError(err).Error("Error moving notebook")
+			respondWithError(ctx, w, errorStatus(err), err.Error())
+			return
+		}
+
+		respondWithSuccess(ctx, w, http.StatusOK, fmt.Sprintf("Notebook with ID '%v' moved successfully", id))
+	}
+}
+
+func moveNote(svc service.NoteServiceHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := logrus.WithContext(ctx)
+		defer closeRequestBody(ctx, r)
+
+		principal, err := getPrincipal(r)
+		if err != nil {
+			logger.WithError(err).Error("Error retrieving principal from request")
+			respondWithError(ctx, w, http.StatusUnauthorized, err.Error())
+			return
+		}
+
+		id := mux.Vars(r)["id"]
+
+		var move models.MoveRequest
+		if err := json.NewDecoder(r.Body).Decode(&move); err != nil {
+			logger.WithError(err).Error("Error decoding request body")
+			respondWithError(ctx, w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		if err := svc.MoveNote(ctx, principal.UserID, id, move.NotebookID); err != nil {
+			logger.WithError(err).Error("Error moving note")
+			respondWithError(ctx, w, errorStatus(err), err.Error())
+			return
+		}
+
+		respondWithSuccess(ctx, w, http.StatusOK, fmt.Sprintf("Note with ID '%v' moved successfully", id))
+	}
+}
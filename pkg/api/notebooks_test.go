@@ -0,0 +1,275 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"notes-api/pkg/dao"
+	"notes-api/pkg/models"
+	"notes-api/pkg/service"
+	"notes-api/pkg/testhelper/mocks"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPI_GetNotebooks_ShouldRespondWith401IfRequestIsNotAuthenticated(t *testing.T) {
+	mockSvc := &mocks.NoteServiceHandler{}
+
+	req, err := http.NewRequest(http.MethodGet, "/notebooks", nil)
+	require.Nil(t, err)
+
+	recorder := httptest.NewRecorder()
+	httpHandler := http.HandlerFunc(getNotebooks(mockSvc))
+	httpHandler.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+}
+
+func TestAPI_GetNotebooks_ShouldRespondWith500IfServiceErrorOccurs(t *testing.T) {
+	mockSvc := &mocks.NoteServiceHandler{}
+	mockSvc.On("GetNotebooks", mock.Anything, mock.Anything).Return(nil, errors.New("test"))
+
+	req, err := http.NewRequest(http.MethodGet, "/notebooks", nil)
+	require.Nil(t, err)
+	req = withPrincipal(req)
+
+	recorder := httptest.NewRecorder()
+	httpHandler := http.HandlerFunc(getNotebooks(mockSvc))
+	httpHandler.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusInternalServerError, recorder.Code)
+	require.Contains(t, recorder.Body.String(), "test")
+}
+
+func TestAPI_GetNotebooks_ShouldRespondWith200OnSuccess(t *testing.T) {
+	mockSvc := &mocks.NoteServiceHandler{}
+	mockSvc.On("GetNotebooks", mock.Anything, "test").Return([]models.Notebook{}, nil)
+
+	req, err := http.NewRequest(http.MethodGet, "/notebooks", nil)
+	require.Nil(t, err)
+	req = withPrincipal(req)
+
+	recorder := httptest.NewRecorder()
+	httpHandler := http.HandlerFunc(getNotebooks(mockSvc))
+	httpHandler.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusOK, recorder.Code)
+}
+
+func TestAPI_GetNotebook_ShouldRespondWith404IfNotebookIsNotFound(t *testing.T) {
+	mockSvc := &mocks.NoteServiceHandler{}
+	mockSvc.On("GetNotebook", mock.Anything, mock.Anything, mock.Anything).Return(nil, dao.ErrNotebookNotFound)
+
+	req, err := http.NewRequest(http.MethodGet, "/notebook", nil)
+	require.Nil(t, err)
+	req = withPrincipal(req)
+
+	recorder := httptest.NewRecorder()
+	httpHandler := http.HandlerFunc(getNotebook(mockSvc))
+	httpHandler.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusNotFound, recorder.Code)
+}
+
+func TestAPI_GetNotebook_ShouldRespondWith200OnSuccess(t *testing.T) {
+	mockSvc := &mocks.NoteServiceHandler{}
+	mockSvc.On("GetNotebook", mock.Anything, mock.Anything, mock.Anything).Return(&models.Notebook{Name: "Runbooks"}, nil)
+
+	req, err := http.NewRequest(http.MethodGet, "/notebook", nil)
+	require.Nil(t, err)
+	req = withPrincipal(req)
+
+	recorder := httptest.NewRecorder()
+	httpHandler := http.HandlerFunc(getNotebook(mockSvc))
+	httpHandler.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Contains(t, recorder.Body.String(), "Runbooks")
+}
+
+func TestAPI_CreateNotebook_ShouldRespondWith400IfErrorOccursDecodingRequestBody(t *testing.T) {
+	mockSvc := &mocks.NoteServiceHandler{}
+
+	req, err := http.NewRequest(http.MethodPost, "/notebook", strings.NewReader("{"))
+	require.Nil(t, err)
+	req = withPrincipal(req)
+
+	recorder := httptest.NewRecorder()
+	httpHandler := http.HandlerFunc(createNotebook(mockSvc))
+	httpHandler.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestAPI_CreateNotebook_ShouldRespondWith404IfParentIsNotFound(t *testing.T) {
+	mockSvc := &mocks.NoteServiceHandler{}
+	mockSvc.On("CreateNotebook", mock.Anything, mock.Anything, mock.Anything).Return("", dao.ErrNotebookNotFound)
+
+	req, err := http.NewRequest(http.MethodPost, "/notebook", strings.NewReader(`{"name":"test","parentId":"000000000000000000000000"}`))
+	require.Nil(t, err)
+	req = withPrincipal(req)
+
+	recorder := httptest.NewRecorder()
+	httpHandler := http.HandlerFunc(createNotebook(mockSvc))
+	httpHandler.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusNotFound, recorder.Code)
+}
+
+func TestAPI_CreateNotebook_ShouldRespondWith200OnSuccess(t *testing.T) {
+	mockSvc := &mocks.NoteServiceHandler{}
+	mockSvc.On("CreateNotebook", mock.Anything, "test", models.NotebookRequest{Name: "test"}).Return("id", nil)
+
+	req, err := http.NewRequest(http.MethodPost, "/notebook", strings.NewReader(`{"name":"test"}`))
+	require.Nil(t, err)
+	req = withPrincipal(req)
+
+	recorder := httptest.NewRecorder()
+	httpHandler := http.HandlerFunc(createNotebook(mockSvc))
+	httpHandler.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Contains(t, recorder.Body.String(), "id")
+}
+
+func TestAPI_EditNotebook_ShouldRespondWith404IfNotebookIsNotFound(t *testing.T) {
+	mockSvc := &mocks.NoteServiceHandler{}
+	mockSvc.On("UpdateNotebook", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(dao.ErrNotebookNotFound)
+
+	req, err := http.NewRequest(http.MethodPut, "/notebook", strings.NewReader(`{"name":"test"}`))
+	require.Nil(t, err)
+	req = withPrincipal(req)
+
+	recorder := httptest.NewRecorder()
+	httpHandler := http.HandlerFunc(editNotebook(mockSvc))
+	httpHandler.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusNotFound, recorder.Code)
+}
+
+func TestAPI_EditNotebook_ShouldRespondWith200OnSuccess(t *testing.T) {
+	mockSvc := &mocks.NoteServiceHandler{}
+	mockSvc.On("UpdateNotebook", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	req, err := http.NewRequest(http.MethodPut, "/notebook", strings.NewReader(`{"name":"test"}`))
+	require.Nil(t, err)
+	req = withPrincipal(req)
+
+	recorder := httptest.NewRecorder()
+	httpHandler := http.HandlerFunc(editNotebook(mockSvc))
+	httpHandler.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusOK, recorder.Code)
+}
+
+func TestAPI_DeleteNotebook_ShouldRespondWith400IfModeIsUnknown(t *testing.T) {
+	mockSvc := &mocks.NoteServiceHandler{}
+
+	req, err := http.NewRequest(http.MethodDelete, "/notebook?mode=shred", nil)
+	require.Nil(t, err)
+	req = withPrincipal(req)
+
+	recorder := httptest.NewRecorder()
+	httpHandler := http.HandlerFunc(deleteNotebook(mockSvc))
+	httpHandler.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+	mockSvc.AssertNotCalled(t, "DeleteNotebook", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAPI_DeleteNotebook_ShouldRespondWith409IfNotebookIsNotEmpty(t *testing.T) {
+	mockSvc := &mocks.NoteServiceHandler{}
+	mockSvc.On("DeleteNotebook", mock.Anything, mock.Anything, mock.Anything, false).Return(service.ErrNotebookNotEmpty)
+
+	req, err := http.NewRequest(http.MethodDelete, "/notebook", nil)
+	require.Nil(t, err)
+	req = withPrincipal(req)
+
+	recorder := httptest.NewRecorder()
+	httpHandler := http.HandlerFunc(deleteNotebook(mockSvc))
+	httpHandler.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusConflict, recorder.Code)
+}
+
+func TestAPI_DeleteNotebook_ShouldCascadeIfAsked(t *testing.T) {
+	mockSvc := &mocks.NoteServiceHandler{}
+	mockSvc.On("DeleteNotebook", mock.Anything, "test", "id", true).Return(nil)
+
+	req, err := http.NewRequest(http.MethodDelete, "/notebook/id?mode=cascade", nil)
+	require.Nil(t, err)
+	req = withPrincipal(req)
+	req = mux.SetURLVars(req, map[string]string{"id": "id"})
+
+	recorder := httptest.NewRecorder()
+	httpHandler := http.HandlerFunc(deleteNotebook(mockSvc))
+	httpHandler.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	mockSvc.AssertExpectations(t)
+}
+
+func TestAPI_MoveNotebook_ShouldRespondWith400IfErrorOccursDecodingRequestBody(t *testing.T) {
+	mockSvc := &mocks.NoteServiceHandler{}
+
+	req, err := http.NewRequest(http.MethodPost, "/notebook/id/move", strings.NewReader("{"))
+	require.Nil(t, err)
+	req = withPrincipal(req)
+
+	recorder := httptest.NewRecorder()
+	httpHandler := http.HandlerFunc(moveNotebook(mockSvc))
+	httpHandler.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestAPI_MoveNotebook_ShouldRespondWith409IfMoveWouldCreateCycle(t *testing.T) {
+	mockSvc := &mocks.NoteServiceHandler{}
+	mockSvc.On("MoveNotebook", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(service.ErrNotebookCycle)
+
+	req, err := http.NewRequest(http.MethodPost, "/notebook/id/move", strings.NewReader(`{"notebookId":"child"}`))
+	require.Nil(t, err)
+	req = withPrincipal(req)
+
+	recorder := httptest.NewRecorder()
+	httpHandler := http.HandlerFunc(moveNotebook(mockSvc))
+	httpHandler.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusConflict, recorder.Code)
+}
+
+func TestAPI_MoveNotebook_ShouldRespondWith200OnSuccess(t *testing.T) {
+	mockSvc := &mocks.NoteServiceHandler{}
+	mockSvc.On("MoveNotebook", mock.Anything, "test", "id", "parent").Return(nil)
+
+	req, err := http.NewRequest(http.MethodPost, "/notebook/id/move", strings.NewReader(`{"notebookId":"parent"}`))
+	require.Nil(t, err)
+	req = withPrincipal(req)
+	req = mux.SetURLVars(req, map[string]string{"id": "id"})
+
+	recorder := httptest.NewRecorder()
+	httpHandler := http.HandlerFunc(moveNotebook(mockSvc))
+	httpHandler.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	mockSvc.AssertExpectations(t)
+}
+
+func TestAPI_MoveNote_ShouldRespondWith404IfNoteIsNotFound(t *testing.T) {
+	mockSvc := &mocks.NoteServiceHandler{}
+	mockSvc.On("MoveNote", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(dao.ErrNotFound)
+
+	req, err := http.NewRequest(http.MethodPost, "/note/id/move", strings.NewReader(`{"notebookId":""}`))
+	require.Nil(t, err)
+	req = withPrincipal(req)
+
+	recorder := httptest.NewRecorder()
+	httpHandler := http.HandlerFunc(moveNote(mockSvc))
+	httpHandler.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusNotFound, recorder.Code)
+}
+
+func TestAPI_MoveNote_ShouldRespondWith200OnSuccess(t *testing.T) {
+	mockSvc := &mocks.NoteServiceHandler{}
+	mockSvc.On("MoveNote", mock.Anything, "test", "id", "notebook").Return(nil)
+
+	req, err := http.NewRequest(http.MethodPost, "/note/id/move", strings.NewReader(`{"notebookId":"notebook"}`))
+	require.Nil(t, err)
+	req = withPrincipal(req)
+	req = mux.SetURLVars(req, map[string]string{"id": "id"})
+
+	recorder := httptest.NewRecorder()
+	httpHandler := http.HandlerFunc(moveNote(mockSvc))
+	httpHandler.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	mockSvc.AssertExpectations(t)
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"regexp"
+
+	"github.com/sirupsen/logrus"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+// validRequestID matches the request IDs that are accepted from clients. Anything else, such as an ID long enough to
+// bloat every log line or one with characters that could forge log records, is replaced with a generated one.
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// identifyRequests gives every request an ID, taken from its X-Request-ID header when it has a usable one and
+// generated otherwise. The ID is returned in the X-Request-ID header of the response, and attached to the request
+// context, where requestIDHook adds it to every log entry made with that context.
+func identifyRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id = newRequestID()
+		}
+
+		w.Header().Set(requestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey, id)))
+	})
+}
+
+func newRequestID() string {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		logrus.WithError(err).Error("Error generating request ID")
+	}
+	return hex.EncodeToString(id)
+}
+
+// getRequestID returns the ID that identifyRequests gave the request of a context, or an empty string.
+func getRequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// requestIDHook adds the ID of the request being served to the log entries made with logrus.WithContext.
+type requestIDHook struct{}
+
+func (requestIDHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (requestIDHook) Fire(entry *logrus.Entry) error {
+	if entry.Context == nil {
+		return nil
+	}
+	if id := getRequestID(entry.Context); id != "" {
+		entry.Data["requestId"] = id
+	}
+	return nil
+}
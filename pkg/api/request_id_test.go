@@ -0,0 +1,69 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"notes-api/pkg/models"
+	"notes-api/pkg/testhelper/mocks"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPI_IdentifyRequests_ShouldPassRequestIDToService(t *testing.T) {
+	var serviceRequestID string
+	mockSvc := &mocks.NoteServiceHandler{}
+	mockSvc.On("ValidateToken", mock.Anything, "token").Return(&models.Principal{UserID: "user"}, nil)
+	mockSvc.On("GetTags", mock.Anything, "user").Return(nil, nil).Run(func(args mock.Arguments) {
+		serviceRequestID = getRequestID(args.Get(0).(context.Context))
+	})
+
+	req, err := http.NewRequest(http.MethodGet, "/tags", nil)
+	require.Nil(t, err)
+	req.Header.Set("Authorization", "Bearer token")
+	req.Header.Set("X-Request-ID", "edge-7f3a:1")
+
+	recorder := httptest.NewRecorder()
+	identifyRequests(route(mockSvc)).ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, "edge-7f3a:1", recorder.Header().Get("X-Request-ID"))
+	require.Equal(t, "edge-7f3a:1", serviceRequestID)
+}
+
+func TestAPI_IdentifyRequests_ShouldGenerateIDWhenMissingOrInvalid(t *testing.T) {
+	for _, header := range []string{"", "forged\nrecord", strings.Repeat("a", 129)} {
+		req, err := http.NewRequest(http.MethodGet, "/", nil)
+		require.Nil(t, err)
+		req.Header.Set("X-Request-ID", header)
+
+		var requestID string
+		recorder := httptest.NewRecorder()
+		identifyRequests(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requestID = getRequestID(r.Context())
+		})).ServeHTTP(recorder, req)
+
+		require.Regexp(t, "^[0-9a-f]{32}$", requestID)
+		require.Equal(t, requestID, recorder.Header().Get("X-Request-ID"))
+	}
+}
+
+func TestAPI_RequestIDHook_ShouldAddRequestIDToLogEntries(t *testing.T) {
+	logger, entries := test.NewNullLogger()
+	logger.AddHook(requestIDHook{})
+
+	logger.WithContext(context.WithValue(context.Background(), requestIDKey, "abc")).Error("Error retrieving notes")
+	logger.WithContext(context.Background()).Error("Error purging trash")
+	logger.Error("Error shutting down server")
+
+	logged := entries.AllEntries()
+	require.Len(t, logged, 3)
+	require.Equal(t, logrus.Fields{"requestId": "abc"}, logged[0].Data)
+	require.Empty(t, logged[1].Data)
+	require.Empty(t, logged[2].Data)
+}
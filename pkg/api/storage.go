@@ -0,0 +1,113 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"notes-api/pkg/config"
+	"notes-api/pkg/dao"
+
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// storage is the set of DAOs of one backend.
+type storage struct {
+	notes     dao.NoteDaoHandler
+	notebooks dao.NotebookDaoHandler
+	saveJobs  dao.SaveJobDaoHandler
+	// hashTexts hashes the text of the notes written before notes had a text hash, or is nil if the backend has no
+	// such notes left.
+	hashTexts func(ctx context.Context) (int64, error)
+}
+
+// backfill brings the stored notes up to date in the background, until it is done or ctx is cancelled. Until it is
+// done, imports do not find duplicates of the notes that have not been hashed yet.
+func (s *storage) backfill(ctx context.Context) {
+	if s.hashTexts == nil {
+		return
+	}
+
+	logger := logrus.WithContext(ctx)
+	logger.Info("Hashing text of notes; imports may not find duplicates of older notes until it is done")
+
+	hashed, err := s.hashTexts(ctx)
+	if ctx.Err() != nil {
+		logger.WithField("notes", hashed).Info("Stopped hashing text of notes")
+	} else if err != nil {
+		logger.WithError(err).Error("Error hashing text of notes")
+	} else {
+		logger.WithField("notes", hashed).Info("Hashed text of notes")
+	}
+}
+
+// newStorage creates the DAOs for the configured backend: "mongo", the default; "bolt", an embedded database in the
+// file at BOLT_PATH; or "memory", which needs no database and forgets everything on exit.
+func newStorage(cfg config.StorageConfig) (*storage, error) {
+	switch cfg.Backend {
+	case config.StorageMongo:
+		return newMongoStorage(cfg)
+	case config.StorageMemory:
+		logrus.Warn("Using in-memory storage; notes will be lost when the API stops")
+		memoryDao := dao.NewMemoryDao()
+		return &storage{notes: memoryDao, notebooks: memoryDao, saveJobs: memoryDao}, nil
+	case config.StorageBolt:
+		// The file stays open, and locked, for the life of the process; every write is committed before it returns,
+		// so nothing is lost when the process exits without closing it.
+		boltDao, err := dao.OpenBoltDao(cfg.BoltPath)
+		if err != nil {
+			logrus.WithError(err).Error("Error opening bolt database")
+			return nil, err
+		}
+		return &storage{notes: boltDao, notebooks: boltDao, saveJobs: boltDao}, nil
+	default:
+		return nil, fmt.Errorf("unknown STORAGE_BACKEND '%v'", cfg.Backend)
+	}
+}
+
+func newMongoStorage(cfg config.StorageConfig) (*storage, error) {
+	client, err := mongo.Connect(context.Background(), options.Client().ApplyURI(cfg.MongoURI))
+	if err != nil {
+		logrus.WithError(err).Error("Error creating mongo client")
+		return nil, err
+	}
+
+	notesDao := dao.NotesDao{
+		Client:             client,
+		Database:           cfg.Database,
+		Collection:         cfg.Collection,
+		RevisionCollection: cfg.RevisionCollection,
+	}
+
+	// The database may not be reachable yet; the API still starts so that /health can report it, and only search
+	// fails until the indexes exist.
+	indexCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := notesDao.EnsureIndexes(indexCtx); err != nil {
+		logrus.WithError(err).Warn("Error creating database indexes")
+	}
+
+	notebooksDao := dao.NotebooksDao{
+		Client:     client,
+		Database:   cfg.Database,
+		Collection: cfg.NotebookCollection,
+	}
+
+	saveJobsDao := dao.SaveJobsDao{
+		Client:     client,
+		Database:   cfg.Database,
+		Collection: cfg.SaveJobCollection,
+	}
+	if err := saveJobsDao.EnsureIndexes(indexCtx); err != nil {
+		logrus.WithError(err).Warn("Error creating database indexes")
+	}
+
+	return &storage{
+		notes:     &notesDao,
+		notebooks: &notebooksDao,
+		saveJobs:  &saveJobsDao,
+		hashTexts: notesDao.HashTexts,
+	}, nil
+}
@@ -0,0 +1,198 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"notes-api/pkg/config"
+	"notes-api/pkg/dao"
+	"notes-api/pkg/models"
+	"notes-api/pkg/service"
+	"notes-api/pkg/testhelper/mocks"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type documentStorage interface {
+	dao.NoteDaoHandler
+	dao.NotebookDaoHandler
+}
+
+// embeddedStorage returns a new, empty instance of each backend that runs without a database server.
+func embeddedStorage(t *testing.T) map[string]documentStorage {
+	boltDao, err := dao.OpenBoltDao(filepath.Join(t.TempDir(), "notes.db"))
+	require.Nil(t, err)
+	t.Cleanup(func() { boltDao.Close() })
+
+	return map[string]documentStorage{
+		config.StorageMemory: dao.NewMemoryDao(),
+		config.StorageBolt:   boltDao,
+	}
+}
+
+// storageServer runs the API on the given storage, with every request authenticated as the same user.
+func storageServer(t *testing.T, storage documentStorage) func(method string, path string, body io.Reader, header http.Header) *httptest.ResponseRecorder {
+	validator := &mocks.Validator{}
+	validator.On("ValidateToken", mock.Anything, "token").Return(&models.Principal{UserID: "user"}, nil)
+
+	router := route(&service.NotesService{
+		Dao:       storage,
+		Notebooks: storage,
+		Auth:      validator,
+	})
+
+	return func(method string, path string, body io.Reader, header http.Header) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(method, path, body)
+		require.Nil(t, err)
+		for key, values := range header {
+			req.Header[key] = values
+		}
+		req.Header.Set("Authorization", "Bearer token")
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+}
+
+func TestStorage_Backfill_ShouldStopWhenContextIsCancelled(t *testing.T) {
+	started := make(chan struct{})
+	s := &storage{hashTexts: func(ctx context.Context) (int64, error) {
+		close(started)
+		<-ctx.Done()
+		return 0, ctx.Err()
+	}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.backfill(ctx)
+		close(done)
+	}()
+
+	<-started
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("backfill did not stop after its context was cancelled")
+	}
+}
+
+func TestAPI_EmbeddedStorage_ShouldServeNoteLifecycle(t *testing.T) {
+	for name, storage := range embeddedStorage(t) {
+		t.Run(name, func(t *testing.T) {
+			testNoteLifecycle(t, storageServer(t, storage))
+		})
+	}
+}
+
+func TestAPI_EmbeddedStorage_ShouldServeNotebooks(t *testing.T) {
+	for name, storage := range embeddedStorage(t) {
+		t.Run(name, func(t *testing.T) {
+			testNotebooks(t, storageServer(t, storage))
+		})
+	}
+}
+
+func testNoteLifecycle(t *testing.T, do func(method string, path string, body io.Reader, header http.Header) *httptest.ResponseRecorder) {
+
+	recorder := do(http.MethodPost, "/note", strings.NewReader(`{"name":"Deploy runbook","text":"How to deploy the API.","tags":["ops"]}`), nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	id := regexp.MustCompile(`'([0-9a-f]{24})'`).FindStringSubmatch(recorder.Body.String())[1]
+
+	recorder = do(http.MethodGet, "/note/"+id, nil, nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, `"1"`, recorder.Header().Get("ETag"))
+
+	recorder = do(http.MethodPut, "/note/"+id, strings.NewReader(`{"name":"Deploy runbook","text":"How to deploy the API safely."}`), http.Header{"If-Match": {`"1"`}})
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, `"2"`, recorder.Header().Get("ETag"))
+
+	recorder = do(http.MethodPut, "/note/"+id, strings.NewReader(`{"name":"Stale"}`), http.Header{"If-Match": {`"1"`}})
+	require.Equal(t, http.StatusPreconditionFailed, recorder.Code)
+
+	var page models.NotePage
+	recorder = do(http.MethodGet, "/notes?fields=name&tag=ops", nil, nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &page))
+	require.Len(t, page.Notes, 1)
+	require.Equal(t, "Deploy runbook", page.Notes[0].Name)
+	require.Empty(t, page.Notes[0].Text)
+
+	recorder = do(http.MethodGet, "/tags", nil, nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.JSONEq(t, `[{"tag":"ops","count":1}]`, recorder.Body.String())
+
+	var results models.SearchPage
+	recorder = do(http.MethodGet, "/notes/search?q=deploy", nil, nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &results))
+	require.Len(t, results.Results, 1)
+	require.Equal(t, "<mark>Deploy</mark> runbook", results.Results[0].Highlights["name"])
+
+	recorder = do(http.MethodGet, "/note/"+id+"/revisions", nil, nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var revisions []models.Revision
+	require.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &revisions))
+	require.Len(t, revisions, 2)
+
+	recorder = do(http.MethodDelete, "/note/"+id, nil, nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	recorder = do(http.MethodGet, "/notes", nil, nil)
+	require.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &page))
+	require.Empty(t, page.Notes)
+
+	recorder = do(http.MethodPost, "/trash/"+id+"/restore", nil, nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	recorder = do(http.MethodGet, "/notes", nil, nil)
+	require.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &page))
+	require.Len(t, page.Notes, 1)
+}
+
+func testNotebooks(t *testing.T, do func(method string, path string, body io.Reader, header http.Header) *httptest.ResponseRecorder) {
+	idPattern := regexp.MustCompile(`'([0-9a-f]{24})'`)
+
+	recorder := do(http.MethodPost, "/notebook", strings.NewReader(`{"name":"Work"}`), nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	parent := idPattern.FindStringSubmatch(recorder.Body.String())[1]
+
+	recorder = do(http.MethodPost, "/notebook", strings.NewReader(`{"name":"Runbooks","parentId":"`+parent+`"}`), nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	child := idPattern.FindStringSubmatch(recorder.Body.String())[1]
+
+	recorder = do(http.MethodPost, "/notebook/"+parent+"/move", strings.NewReader(`{"notebookId":"`+child+`"}`), nil)
+	require.Equal(t, http.StatusConflict, recorder.Code)
+
+	recorder = do(http.MethodPost, "/note", strings.NewReader(`{"name":"Deploy","notebookId":"`+child+`"}`), nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var page models.NotePage
+	recorder = do(http.MethodGet, "/notes?notebookId="+child, nil, nil)
+	require.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &page))
+	require.Len(t, page.Notes, 1)
+
+	recorder = do(http.MethodDelete, "/notebook/"+parent, nil, nil)
+	require.Equal(t, http.StatusConflict, recorder.Code)
+
+	recorder = do(http.MethodDelete, "/notebook/"+parent+"?mode=cascade", nil, nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	recorder = do(http.MethodGet, "/trash", nil, nil)
+	var trash []models.Note
+	require.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &trash))
+	require.Len(t, trash, 1)
+	require.Nil(t, trash[0].NotebookID)
+}
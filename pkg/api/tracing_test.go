@@ -0,0 +1,60 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"notes-api/pkg/models"
+	"notes-api/pkg/testhelper/mocks"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestAPI_TraceRequests_ShouldContinueIncomingTraceIntoService(t *testing.T) {
+	spans := tracetest.NewSpanRecorder()
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+
+	var serviceSpan trace.SpanContext
+	mockSvc := &mocks.NoteServiceHandler{}
+	mockSvc.On("ValidateToken", mock.Anything, "token").Return(&models.Principal{UserID: "user"}, nil)
+	mockSvc.On("GetRevisions", mock.Anything, "user", "1").Return(nil, nil).Run(func(args mock.Arguments) {
+		serviceSpan = trace.SpanContextFromContext(args.Get(0).(context.Context))
+	})
+
+	router := route(mockSvc)
+	router.Use(traceRequests("notes-api"))
+
+	req, err := http.NewRequest(http.MethodGet, "/note/1/revisions", nil)
+	require.Nil(t, err)
+	req.Header.Set("Authorization", "Bearer token")
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	ended := spans.Ended()
+	require.Len(t, ended, 1)
+	server := ended[0]
+	require.Equal(t, "GET /note/{id}/revisions", server.Name())
+	require.Equal(t, trace.SpanKindServer, server.SpanKind())
+	require.Equal(t, codes.Unset, server.Status().Code)
+	require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.SpanContext().TraceID().String())
+	require.Equal(t, "00f067aa0ba902b7", server.Parent().SpanID().String())
+	require.True(t, server.Parent().IsRemote())
+	require.Equal(t, server.SpanContext().SpanID(), serviceSpan.SpanID())
+}
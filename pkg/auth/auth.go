@@ -0,0 +1,12 @@
+package auth
+
+import (
+	"context"
+
+	"notes-api/pkg/models"
+)
+
+// Validator resolves a bearer token to the principal it was issued to.
+type Validator interface {
+	ValidateToken(ctx context.Context, token string) (*models.Principal, error)
+}
@@ -0,0 +1,206 @@
+package auth
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"notes-api/pkg/external"
+	"notes-api/pkg/models"
+
+	"golang.org/x/sync/singleflight"
+)
+
+type CacheConfig struct {
+	// TTL is the longest a successful validation is reused. Entries expire earlier if the token itself does.
+	TTL time.Duration
+	// NegativeTTL is how long a rejected token is remembered before the validator is asked again.
+	NegativeTTL time.Duration
+	// MaxEntries bounds the cache size. The least recently used entry is evicted once it is reached.
+	MaxEntries int
+}
+
+type CacheStats struct {
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"`
+	Size      int    `json:"size"`
+}
+
+// Cache is a Validator that remembers the results of another Validator, keyed by a hash of the token so raw
+// credentials are never held in memory longer than a single call.
+type Cache struct {
+	validator Validator
+	config    CacheConfig
+	now       func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+	group   singleflight.Group
+
+	hits      uint64
+	misses    uint64
+	evictions uint64
+}
+
+type cacheEntry struct {
+	key       string
+	principal *models.Principal
+	err       error
+	expiresAt time.Time
+}
+
+func NewCache(validator Validator, config CacheConfig) *Cache {
+	return &Cache{
+		validator: validator,
+		config:    config,
+		now:       time.Now,
+		entries:   make(map[string]*list.Element),
+		order:     list.New(),
+	}
+}
+
+func (c *Cache) ValidateToken(ctx context.Context, token string) (*models.Principal, error) {
+	key := hashToken(token)
+
+	if entry, ok := c.get(key); ok {
+		atomic.AddUint64(&c.hits, 1)
+		return entry.principal, entry.err
+	}
+	atomic.AddUint64(&c.misses, 1)
+
+	// Concurrent requests carrying the same token share a single call to the underlying validator.
+	called := false
+	result, err, _ := c.group.Do(key, func() (interface{}, error) {
+		called = true
+		principal, err := c.validator.ValidateToken(ctx, token)
+		c.store(key, principal, err)
+		return principal, err
+	})
+	if err != nil && !called && isCanceled(err) && ctx.Err() == nil {
+		// The shared call was made with the context of another request, which has ended before it returned.
+		principal, err := c.validator.ValidateToken(ctx, token)
+		c.store(key, principal, err)
+		return principal, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return result.(*models.Principal), nil
+}
+
+func (c *Cache) Stats() CacheStats {
+	c.mu.Lock()
+	size := c.order.Len()
+	c.mu.Unlock()
+
+	return CacheStats{
+		Hits:      atomic.LoadUint64(&c.hits),
+		Misses:    atomic.LoadUint64(&c.misses),
+		Evictions: atomic.LoadUint64(&c.evictions),
+		Size:      size,
+	}
+}
+
+func (c *Cache) get(key string) (*cacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := element.Value.(*cacheEntry)
+	if !c.now().Before(entry.expiresAt) {
+		c.remove(element)
+		return nil, false
+	}
+
+	c.order.MoveToFront(element)
+	return entry, true
+}
+
+func (c *Cache) store(key string, principal *models.Principal, err error) {
+	now := c.now()
+
+	var expiresAt time.Time
+	if err != nil {
+		if !isCacheable(err) {
+			return
+		}
+		expiresAt = now.Add(c.config.NegativeTTL)
+	} else {
+		expiresAt = now.Add(c.config.TTL)
+		if principal != nil && !principal.ExpiresAt.IsZero() && principal.ExpiresAt.Before(expiresAt) {
+			expiresAt = principal.ExpiresAt
+		}
+	}
+
+	if !now.Before(expiresAt) || c.config.MaxEntries <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		c.remove(element)
+	}
+
+	for c.order.Len() >= c.config.MaxEntries {
+		c.remove(c.order.Back())
+		atomic.AddUint64(&c.evictions, 1)
+	}
+
+	c.entries[key] = c.order.PushFront(&cacheEntry{
+		key:       key,
+		principal: principal,
+		err:       err,
+		expiresAt: expiresAt,
+	})
+}
+
+func (c *Cache) remove(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.entries, element.Value.(*cacheEntry).key)
+}
+
+// isCacheable reports whether a validation error says something about the token itself. Errors that leave the token
+// unvalidated say nothing about it, so they are never remembered.
+func isCacheable(err error) bool {
+	return !IsUnavailable(err)
+}
+
+// IsUnavailable tells whether a token could not be validated because validation itself failed, rather than because
+// the token was rejected: a timeout, a cancellation, a network failure, or a server error from the login service.
+func IsUnavailable(err error) bool {
+	if isCanceled(err) {
+		return true
+	}
+
+	var statusErr *external.StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Temporary()
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+func isCanceled(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
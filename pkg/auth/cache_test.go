@@ -0,0 +1,176 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"notes-api/pkg/external"
+	"notes-api/pkg/models"
+	"notes-api/pkg/testhelper/mocks"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var testCacheConfig = CacheConfig{
+	TTL:         time.Minute,
+	NegativeTTL: time.Second,
+	MaxEntries:  2,
+}
+
+func TestCache_ValidateToken_ShouldReuseSuccessfulValidations(t *testing.T) {
+	mockValidator := &mocks.Validator{}
+	mockValidator.On("ValidateToken", mock.Anything, "test").Return(&models.Principal{UserID: "user"}, nil).Once()
+
+	cache := NewCache(mockValidator, testCacheConfig)
+
+	for i := 0; i < 3; i++ {
+		principal, err := cache.ValidateToken(context.TODO(), "test")
+		require.Nil(t, err)
+		require.Equal(t, "user", principal.UserID)
+	}
+
+	mockValidator.AssertNumberOfCalls(t, "ValidateToken", 1)
+	require.Equal(t, CacheStats{Hits: 2, Misses: 1, Size: 1}, cache.Stats())
+}
+
+func TestCache_ValidateToken_ShouldExpireEntriesAfterTTL(t *testing.T) {
+	mockValidator := &mocks.Validator{}
+	mockValidator.On("ValidateToken", mock.Anything, "test").Return(&models.Principal{UserID: "user"}, nil)
+
+	now := time.Now()
+	cache := NewCache(mockValidator, testCacheConfig)
+	cache.now = func() time.Time { return now }
+
+	_, err := cache.ValidateToken(context.TODO(), "test")
+	require.Nil(t, err)
+
+	now = now.Add(testCacheConfig.TTL)
+	_, err = cache.ValidateToken(context.TODO(), "test")
+	require.Nil(t, err)
+
+	mockValidator.AssertNumberOfCalls(t, "ValidateToken", 2)
+}
+
+func TestCache_ValidateToken_ShouldExpireEntriesWhenTokenExpires(t *testing.T) {
+	now := time.Now()
+
+	mockValidator := &mocks.Validator{}
+	mockValidator.On("ValidateToken", mock.Anything, "test").Return(&models.Principal{
+		UserID:    "user",
+		ExpiresAt: now.Add(time.Second),
+	}, nil)
+
+	cache := NewCache(mockValidator, testCacheConfig)
+	cache.now = func() time.Time { return now }
+
+	_, err := cache.ValidateToken(context.TODO(), "test")
+	require.Nil(t, err)
+
+	now = now.Add(time.Second)
+	_, err = cache.ValidateToken(context.TODO(), "test")
+	require.Nil(t, err)
+
+	mockValidator.AssertNumberOfCalls(t, "ValidateToken", 2)
+}
+
+func TestCache_ValidateToken_ShouldRememberRejectedTokensForNegativeTTL(t *testing.T) {
+	mockValidator := &mocks.Validator{}
+	mockValidator.On("ValidateToken", mock.Anything, "test").Return(nil, errors.New("test"))
+
+	now := time.Now()
+	cache := NewCache(mockValidator, testCacheConfig)
+	cache.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		principal, err := cache.ValidateToken(context.TODO(), "test")
+		require.Nil(t, principal)
+		require.NotNil(t, err)
+		require.Equal(t, "test", err.Error())
+	}
+	mockValidator.AssertNumberOfCalls(t, "ValidateToken", 1)
+
+	now = now.Add(testCacheConfig.NegativeTTL)
+	_, err := cache.ValidateToken(context.TODO(), "test")
+	require.NotNil(t, err)
+	mockValidator.AssertNumberOfCalls(t, "ValidateToken", 2)
+}
+
+func TestCache_ValidateToken_ShouldNotRememberTransientErrors(t *testing.T) {
+	mockValidator := &mocks.Validator{}
+	mockValidator.On("ValidateToken", mock.Anything, "timeout").Return(nil, context.DeadlineExceeded)
+	mockValidator.On("ValidateToken", mock.Anything, "network").Return(nil, &net.OpError{Op: "dial", Err: errors.New("test")})
+	mockValidator.On("ValidateToken", mock.Anything, "outage").Return(nil, &external.StatusError{StatusCode: http.StatusServiceUnavailable})
+
+	cache := NewCache(mockValidator, testCacheConfig)
+
+	for i := 0; i < 2; i++ {
+		for _, token := range []string{"timeout", "network", "outage"} {
+			_, err := cache.ValidateToken(context.TODO(), token)
+			require.NotNil(t, err)
+		}
+	}
+
+	mockValidator.AssertNumberOfCalls(t, "ValidateToken", 6)
+	require.Equal(t, 0, cache.Stats().Size)
+}
+
+func TestCache_ValidateToken_ShouldValidateAgainWhenSharedCallIsCancelled(t *testing.T) {
+	entered := make(chan struct{})
+	mockValidator := &mocks.Validator{}
+	mockValidator.On("ValidateToken", mock.Anything, "test").Return(nil, context.Canceled).Run(func(args mock.Arguments) {
+		close(entered)
+		<-args.Get(0).(context.Context).Done()
+	}).Once()
+	mockValidator.On("ValidateToken", mock.Anything, "test").Return(&models.Principal{UserID: "user"}, nil).Once()
+
+	cache := NewCache(mockValidator, testCacheConfig)
+
+	leaderCtx, cancel := context.WithCancel(context.Background())
+	leaderErr := make(chan error)
+	go func() {
+		_, err := cache.ValidateToken(leaderCtx, "test")
+		leaderErr <- err
+	}()
+	<-entered
+
+	var principal *models.Principal
+	followerErr := make(chan error)
+	go func() {
+		var err error
+		principal, err = cache.ValidateToken(context.TODO(), "test")
+		followerErr <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	require.Equal(t, context.Canceled, <-leaderErr)
+	require.Nil(t, <-followerErr)
+	require.Equal(t, "user", principal.UserID)
+	mockValidator.AssertNumberOfCalls(t, "ValidateToken", 2)
+}
+
+func TestCache_ValidateToken_ShouldEvictLeastRecentlyUsedEntryWhenFull(t *testing.T) {
+	mockValidator := &mocks.Validator{}
+	mockValidator.On("ValidateToken", mock.Anything, mock.Anything).Return(&models.Principal{UserID: "user"}, nil)
+
+	cache := NewCache(mockValidator, testCacheConfig)
+
+	for _, token := range []string{"a", "b", "a", "c", "a"} {
+		_, err := cache.ValidateToken(context.TODO(), token)
+		require.Nil(t, err)
+	}
+
+	stats := cache.Stats()
+	require.Equal(t, 2, stats.Size)
+	require.Equal(t, uint64(1), stats.Evictions)
+	require.Equal(t, uint64(2), stats.Hits)
+
+	_, err := cache.ValidateToken(context.TODO(), "b")
+	require.Nil(t, err)
+	mockValidator.AssertNumberOfCalls(t, "ValidateToken", 4)
+}
@@ -0,0 +1,30 @@
+package auth
+
+import (
+	"context"
+	"errors"
+
+	"notes-api/pkg/models"
+)
+
+const (
+	ModeRemote = "remote"
+	ModeLocal  = "local"
+	ModeBoth   = "both"
+)
+
+// Chain is a Validator that tries each of its validators in order and accepts the token as soon as one of them does.
+// It is used for ModeBoth, where local verification is tried first and the login service is only asked about tokens
+// that cannot be verified locally.
+type Chain []Validator
+
+func (c Chain) ValidateToken(ctx context.Context, token string) (*models.Principal, error) {
+	err := errors.New("no validators configured")
+	for _, validator := range c {
+		var principal *models.Principal
+		if principal, err = validator.ValidateToken(ctx, token); err == nil {
+			return principal, nil
+		}
+	}
+	return nil, err
+}
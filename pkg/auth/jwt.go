@@ -0,0 +1,166 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"notes-api/pkg/models"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+type JWTConfig struct {
+	// Keys resolves the key a token was signed with.
+	Keys KeySource
+	// Issuer and Audience are only checked when set.
+	Issuer   string
+	Audience string
+	// VerifyExpiry rejects tokens without an exp claim or whose exp has passed.
+	VerifyExpiry bool
+	// Leeway is the clock skew tolerated on exp and nbf.
+	Leeway time.Duration
+}
+
+// JWTVerifier is a Validator that checks token signatures and claims locally instead of asking the login service.
+type JWTVerifier struct {
+	config JWTConfig
+	now    func() time.Time
+}
+
+func NewJWTVerifier(config JWTConfig) *JWTVerifier {
+	return &JWTVerifier{
+		config: config,
+		now:    time.Now,
+	}
+}
+
+func (v *JWTVerifier) ValidateToken(ctx context.Context, token string) (*models.Principal, error) {
+	parser := jwt.Parser{
+		ValidMethods: []string{
+			"HS256", "HS384", "HS512",
+			"RS256", "RS384", "RS512",
+			"PS256", "PS384", "PS512",
+			"ES256", "ES384", "ES512",
+		},
+		SkipClaimsValidation: true,
+	}
+
+	claims := jwt.MapClaims{}
+	if _, err := parser.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return v.config.Keys.Key(ctx, kid)
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := v.verifyClaims(claims); err != nil {
+		return nil, err
+	}
+
+	return principalFromClaims(claims)
+}
+
+func (v *JWTVerifier) verifyClaims(claims jwt.MapClaims) error {
+	now := v.now()
+
+	if v.config.VerifyExpiry {
+		if _, ok := claims["exp"]; !ok {
+			return errors.New("token has no expiry")
+		} else if !claims.VerifyExpiresAt(now.Add(-v.config.Leeway).Unix(), true) {
+			return errors.New("token is expired")
+		}
+	}
+
+	if !claims.VerifyNotBefore(now.Add(v.config.Leeway).Unix(), false) {
+		return errors.New("token is not valid yet")
+	}
+
+	if v.config.Issuer != "" && !claims.VerifyIssuer(v.config.Issuer, true) {
+		return errors.New("token issuer is not accepted")
+	}
+
+	if v.config.Audience != "" && !claims.VerifyAudience(v.config.Audience, true) {
+		return errors.New("token audience is not accepted")
+	}
+
+	return nil
+}
+
+func principalFromClaims(claims jwt.MapClaims) (*models.Principal, error) {
+	var principal models.Principal
+
+	principal.UserID, _ = claims["sub"].(string)
+	if principal.UserID == "" {
+		principal.UserID, _ = claims["id"].(string)
+	}
+	if principal.UserID == "" {
+		return nil, errors.New("token does not include a subject")
+	}
+
+	principal.Username, _ = claims["username"].(string)
+	if principal.Username == "" {
+		principal.Username, _ = claims["preferred_username"].(string)
+	}
+
+	if roles, ok := claims["roles"].([]interface{}); ok {
+		for _, role := range roles {
+			if r, ok := role.(string); ok {
+				principal.Roles = append(principal.Roles, r)
+			}
+		}
+	}
+
+	if exp, ok := claims["exp"].(float64); ok {
+		principal.ExpiresAt = time.Unix(int64(exp), 0)
+	}
+
+	return &principal, nil
+}
+
+// ParsePublicKey parses a PEM encoded RSA or ECDSA public key or certificate.
+func ParsePublicKey(data []byte) (interface{}, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found in public key")
+	}
+
+	var key interface{}
+	switch block.Type {
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		key = cert.PublicKey
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		key = parsed
+	}
+
+	switch key.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey:
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", key)
+	}
+}
+
+func LoadPublicKeyFile(path string) (interface{}, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePublicKey(data)
+}
@@ -0,0 +1,239 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"notes-api/pkg/models"
+	"notes-api/pkg/testhelper/mocks"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var testSecret = []byte("secret")
+
+func signToken(t *testing.T, method jwt.SigningMethod, key interface{}, claims jwt.MapClaims, kid string) string {
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+
+	signed, err := token.SignedString(key)
+	require.Nil(t, err)
+	return signed
+}
+
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub":      "user",
+		"username": "username",
+		"roles":    []string{"admin"},
+		"iss":      "login-service",
+		"aud":      "notes-api",
+		"exp":      time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+func TestJWTVerifier_ValidateToken_ShouldReturnPrincipalForValidHMACToken(t *testing.T) {
+	verifier := NewJWTVerifier(JWTConfig{
+		Keys:         StaticKeys{"": testSecret},
+		Issuer:       "login-service",
+		Audience:     "notes-api",
+		VerifyExpiry: true,
+	})
+
+	principal, err := verifier.ValidateToken(context.TODO(), signToken(t, jwt.SigningMethodHS256, testSecret, validClaims(), ""))
+	require.Nil(t, err)
+	require.Equal(t, "user", principal.UserID)
+	require.Equal(t, "username", principal.Username)
+	require.Equal(t, []string{"admin"}, principal.Roles)
+	require.False(t, principal.ExpiresAt.IsZero())
+}
+
+func TestJWTVerifier_ValidateToken_ShouldReturnErrorIfSignatureIsInvalid(t *testing.T) {
+	verifier := NewJWTVerifier(JWTConfig{Keys: StaticKeys{"": testSecret}})
+
+	_, err := verifier.ValidateToken(context.TODO(), signToken(t, jwt.SigningMethodHS256, []byte("other"), validClaims(), ""))
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "signature is invalid")
+}
+
+func TestJWTVerifier_ValidateToken_ShouldReturnErrorIfTokenIsExpired(t *testing.T) {
+	verifier := NewJWTVerifier(JWTConfig{Keys: StaticKeys{"": testSecret}, VerifyExpiry: true})
+
+	claims := validClaims()
+	claims["exp"] = time.Now().Add(-time.Hour).Unix()
+
+	_, err := verifier.ValidateToken(context.TODO(), signToken(t, jwt.SigningMethodHS256, testSecret, claims, ""))
+	require.NotNil(t, err)
+	require.Equal(t, "token is expired", err.Error())
+}
+
+func TestJWTVerifier_ValidateToken_ShouldAcceptExpiredTokenIfExpiryIsNotVerified(t *testing.T) {
+	verifier := NewJWTVerifier(JWTConfig{Keys: StaticKeys{"": testSecret}, VerifyExpiry: false})
+
+	claims := validClaims()
+	claims["exp"] = time.Now().Add(-time.Hour).Unix()
+
+	_, err := verifier.ValidateToken(context.TODO(), signToken(t, jwt.SigningMethodHS256, testSecret, claims, ""))
+	require.Nil(t, err)
+}
+
+func TestJWTVerifier_ValidateToken_ShouldReturnErrorIfTokenHasNoExpiry(t *testing.T) {
+	verifier := NewJWTVerifier(JWTConfig{Keys: StaticKeys{"": testSecret}, VerifyExpiry: true})
+
+	claims := validClaims()
+	delete(claims, "exp")
+
+	_, err := verifier.ValidateToken(context.TODO(), signToken(t, jwt.SigningMethodHS256, testSecret, claims, ""))
+	require.NotNil(t, err)
+	require.Equal(t, "token has no expiry", err.Error())
+}
+
+func TestJWTVerifier_ValidateToken_ShouldReturnErrorIfIssuerDoesNotMatch(t *testing.T) {
+	verifier := NewJWTVerifier(JWTConfig{Keys: StaticKeys{"": testSecret}, Issuer: "other"})
+
+	_, err := verifier.ValidateToken(context.TODO(), signToken(t, jwt.SigningMethodHS256, testSecret, validClaims(), ""))
+	require.NotNil(t, err)
+	require.Equal(t, "token issuer is not accepted", err.Error())
+}
+
+func TestJWTVerifier_ValidateToken_ShouldReturnErrorIfAudienceDoesNotMatch(t *testing.T) {
+	verifier := NewJWTVerifier(JWTConfig{Keys: StaticKeys{"": testSecret}, Audience: "other"})
+
+	_, err := verifier.ValidateToken(context.TODO(), signToken(t, jwt.SigningMethodHS256, testSecret, validClaims(), ""))
+	require.NotNil(t, err)
+	require.Equal(t, "token audience is not accepted", err.Error())
+}
+
+func TestJWTVerifier_ValidateToken_ShouldReturnErrorIfTokenHasNoSubject(t *testing.T) {
+	verifier := NewJWTVerifier(JWTConfig{Keys: StaticKeys{"": testSecret}})
+
+	claims := validClaims()
+	delete(claims, "sub")
+
+	_, err := verifier.ValidateToken(context.TODO(), signToken(t, jwt.SigningMethodHS256, testSecret, claims, ""))
+	require.NotNil(t, err)
+	require.Equal(t, "token does not include a subject", err.Error())
+}
+
+func TestJWTVerifier_ValidateToken_ShouldRejectHMACTokenSignedWithPublicKey(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.Nil(t, err)
+
+	der, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	require.Nil(t, err)
+
+	verifier := NewJWTVerifier(JWTConfig{Keys: StaticKeys{"": &rsaKey.PublicKey}})
+
+	_, err = verifier.ValidateToken(context.TODO(), signToken(t, jwt.SigningMethodHS256, der, validClaims(), ""))
+	require.NotNil(t, err)
+}
+
+func TestJWTVerifier_ValidateToken_ShouldVerifyRSAAndECDSATokensFromPEMKeys(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.Nil(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.Nil(t, err)
+
+	for _, c := range []struct {
+		method  jwt.SigningMethod
+		private interface{}
+		public  interface{}
+	}{
+		{jwt.SigningMethodRS256, rsaKey, &rsaKey.PublicKey},
+		{jwt.SigningMethodES256, ecKey, &ecKey.PublicKey},
+	} {
+		der, err := x509.MarshalPKIXPublicKey(c.public)
+		require.Nil(t, err)
+
+		key, err := ParsePublicKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+		require.Nil(t, err)
+
+		verifier := NewJWTVerifier(JWTConfig{Keys: StaticKeys{"": key}})
+
+		principal, err := verifier.ValidateToken(context.TODO(), signToken(t, c.method, c.private, validClaims(), ""))
+		require.Nil(t, err)
+		require.Equal(t, "user", principal.UserID)
+	}
+}
+
+func TestJWTVerifier_ValidateToken_ShouldSelectKeyFromJWKSByKid(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.Nil(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.Nil(t, err)
+
+	encode := func(i *big.Int) string {
+		return base64.RawURLEncoding.EncodeToString(i.Bytes())
+	}
+	document := fmt.Sprintf(`{"keys": [
+		{"kty": "RSA", "kid": "rsa", "use": "sig", "n": "%v", "e": "%v"},
+		{"kty": "EC", "kid": "ec", "crv": "P-256", "x": "%v", "y": "%v"},
+		{"kty": "RSA", "kid": "enc", "use": "enc", "n": "AQAB", "e": "AQAB"}
+	]}`, encode(rsaKey.N), encode(big.NewInt(int64(rsaKey.E))), encode(ecKey.X), encode(ecKey.Y))
+
+	keys, err := ParseJWKS([]byte(document))
+	require.Nil(t, err)
+	require.Len(t, keys, 2)
+
+	verifier := NewJWTVerifier(JWTConfig{Keys: keys})
+
+	_, err = verifier.ValidateToken(context.TODO(), signToken(t, jwt.SigningMethodRS256, rsaKey, validClaims(), "rsa"))
+	require.Nil(t, err)
+
+	_, err = verifier.ValidateToken(context.TODO(), signToken(t, jwt.SigningMethodES256, ecKey, validClaims(), "ec"))
+	require.Nil(t, err)
+
+	_, err = verifier.ValidateToken(context.TODO(), signToken(t, jwt.SigningMethodES256, ecKey, validClaims(), "unknown"))
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "no key found for kid 'unknown'")
+}
+
+func TestChain_ValidateToken_ShouldFallBackToNextValidatorOnError(t *testing.T) {
+	local := &mocks.Validator{}
+	local.On("ValidateToken", mock.Anything, "test").Return(nil, errors.New("local"))
+
+	remote := &mocks.Validator{}
+	remote.On("ValidateToken", mock.Anything, "test").Return(&models.Principal{UserID: "user"}, nil)
+
+	principal, err := Chain{local, remote}.ValidateToken(context.TODO(), "test")
+	require.Nil(t, err)
+	require.Equal(t, "user", principal.UserID)
+}
+
+func TestChain_ValidateToken_ShouldNotCallNextValidatorOnSuccess(t *testing.T) {
+	local := &mocks.Validator{}
+	local.On("ValidateToken", mock.Anything, "test").Return(&models.Principal{UserID: "user"}, nil)
+
+	remote := &mocks.Validator{}
+
+	_, err := Chain{local, remote}.ValidateToken(context.TODO(), "test")
+	require.Nil(t, err)
+	remote.AssertNotCalled(t, "ValidateToken", mock.Anything, mock.Anything)
+}
+
+func TestChain_ValidateToken_ShouldReturnLastErrorIfAllValidatorsFail(t *testing.T) {
+	local := &mocks.Validator{}
+	local.On("ValidateToken", mock.Anything, "test").Return(nil, errors.New("local"))
+
+	remote := &mocks.Validator{}
+	remote.On("ValidateToken", mock.Anything, "test").Return(nil, errors.New("remote"))
+
+	_, err := Chain{local, remote}.ValidateToken(context.TODO(), "test")
+	require.NotNil(t, err)
+	require.Equal(t, "remote", err.Error())
+}
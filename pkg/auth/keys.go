@@ -0,0 +1,236 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// KeySource resolves the verification key for a token's kid header. An empty kid asks for the default key.
+type KeySource interface {
+	Key(ctx context.Context, kid string) (interface{}, error)
+}
+
+// StaticKeys is a fixed set of keys indexed by kid. A key stored under the empty kid is used for tokens that do not
+// name a key, and a set holding exactly one key uses it for every token.
+type StaticKeys map[string]interface{}
+
+func (keys StaticKeys) Key(ctx context.Context, kid string) (interface{}, error) {
+	if key, ok := keys[kid]; ok {
+		return key, nil
+	}
+
+	if len(keys) == 1 {
+		for _, key := range keys {
+			return key, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no key found for kid '%v'", kid)
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	K   string `json:"k"`
+}
+
+// ParseJWKS parses the RSA, EC and symmetric signing keys from a JSON Web Key Set document.
+func ParseJWKS(data []byte) (StaticKeys, error) {
+	var document struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+
+	keys := make(StaticKeys)
+	for _, k := range document.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		key, err := k.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("invalid key '%v': %w", k.Kid, err)
+		}
+		keys[k.Kid] = key
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("no signing keys found in key set")
+	}
+
+	return keys, nil
+}
+
+func LoadJWKSFile(path string) (StaticKeys, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseJWKS(data)
+}
+
+func (k jwk) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve '%v'", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "oct":
+		return base64.RawURLEncoding.DecodeString(k.K)
+	default:
+		return nil, fmt.Errorf("unsupported key type '%v'", k.Kty)
+	}
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(data), nil
+}
+
+// RemoteJWKS fetches a key set from a URL and refetches it when a token names a kid it has not seen, at most once per
+// RefreshInterval, so key rotation at the issuer is picked up without a restart. Failed fetches count towards the
+// interval too, so an unreachable URL is not fetched again for every token; until the next attempt, tokens whose key
+// is unknown fail with the error of the last one.
+type RemoteJWKS struct {
+	URL             string
+	Client          *http.Client
+	RefreshInterval time.Duration
+
+	mu   sync.Mutex
+	keys StaticKeys
+	// lastFetched is when the key set was last fetched, whether or not the fetch succeeded, and lastErr is why it
+	// failed.
+	lastFetched time.Time
+	lastErr     error
+	// fetching is closed when the fetch in flight ends, or is nil if there is none. The fetch is made without holding
+	// mu, and concurrent callers that need it wait for it instead of fetching again.
+	fetching chan struct{}
+}
+
+func (j *RemoteJWKS) Key(ctx context.Context, kid string) (interface{}, error) {
+	for {
+		j.mu.Lock()
+		if j.keys != nil {
+			if key, err := j.keys.Key(ctx, kid); err == nil {
+				j.mu.Unlock()
+				return key, nil
+			}
+		}
+
+		if fetching := j.fetching; fetching != nil {
+			j.mu.Unlock()
+			select {
+			case <-fetching:
+				continue
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+
+		if !j.lastFetched.IsZero() && time.Since(j.lastFetched) < j.RefreshInterval {
+			keys, lastErr := j.keys, j.lastErr
+			j.mu.Unlock()
+			if keys == nil {
+				return nil, lastErr
+			}
+			return keys.Key(ctx, kid)
+		}
+
+		done := make(chan struct{})
+		j.fetching = done
+		j.mu.Unlock()
+
+		keys, err := j.fetch(ctx)
+
+		j.mu.Lock()
+		// A fetch cut short by the caller's context says nothing about the URL, so it is not counted as an attempt.
+		if ctx.Err() == nil {
+			j.lastFetched = time.Now()
+			j.lastErr = err
+		}
+		if err == nil {
+			j.keys = keys
+		}
+		j.fetching = nil
+		close(done)
+		j.mu.Unlock()
+
+		if err != nil {
+			return nil, err
+		}
+		return keys.Key(ctx, kid)
+	}
+}
+
+func (j *RemoteJWKS) fetch(ctx context.Context) (StaticKeys, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := j.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("non-200 status code received fetching key set: %v", resp.StatusCode)
+	}
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseJWKS(data)
+}
@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testJWKS = `{"keys": [{"kty": "oct", "kid": "key", "k": "c2VjcmV0"}]}`
+
+func TestRemoteJWKS_Key_ShouldNotFetchAgainWithinIntervalAfterFailedFetch(t *testing.T) {
+	var fetches int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	jwks := &RemoteJWKS{URL: server.URL, Client: server.Client(), RefreshInterval: time.Hour}
+
+	for i := 0; i < 3; i++ {
+		_, err := jwks.Key(context.TODO(), "key")
+		require.NotNil(t, err)
+		require.Contains(t, err.Error(), "502")
+	}
+	require.Equal(t, int32(1), atomic.LoadInt32(&fetches))
+}
+
+func TestRemoteJWKS_Key_ShouldFetchOnceForConcurrentCallers(t *testing.T) {
+	var fetches int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		<-release
+		_, _ = w.Write([]byte(testJWKS))
+	}))
+	defer server.Close()
+
+	jwks := &RemoteJWKS{URL: server.URL, Client: server.Client(), RefreshInterval: time.Hour}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := jwks.Key(context.TODO(), "key")
+			errs <- err
+		}()
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		require.Nil(t, err)
+	}
+	require.Equal(t, int32(1), atomic.LoadInt32(&fetches))
+}
+
+func TestRemoteJWKS_Key_ShouldNotHoldLockWhileFetching(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		_, _ = w.Write([]byte(testJWKS))
+	}))
+	defer server.Close()
+	defer close(release)
+
+	jwks := &RemoteJWKS{URL: server.URL, Client: server.Client(), RefreshInterval: time.Hour}
+	jwks.keys = StaticKeys{"old": []byte("secret"), "older": []byte("secret")}
+
+	go func() { _, _ = jwks.Key(context.Background(), "new") }()
+	time.Sleep(50 * time.Millisecond)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := jwks.Key(context.TODO(), "old")
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		require.Nil(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("known key waited for a fetch of the key set")
+	}
+}
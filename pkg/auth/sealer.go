@@ -0,0 +1,57 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
+)
+
+// ErrUnsealable is returned when a sealed token was sealed with another key, or was not sealed at all.
+var ErrUnsealable = errors.New("sealed token cannot be opened")
+
+// Sealer encrypts the tokens that are kept at rest, such as those that queued saves are delivered with, so that a copy
+// of the database does not give them away.
+type Sealer struct {
+	aead cipher.AEAD
+}
+
+// NewSealer returns a Sealer that encrypts with AES-GCM under a 16, 24 or 32 byte key.
+func NewSealer(key []byte) (*Sealer, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Sealer{aead: aead}, nil
+}
+
+// Seal returns the token encrypted under a random nonce, which is kept at the start of the base64 result.
+func (s *Sealer) Seal(token string) (string, error) {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(s.aead.Seal(nonce, nonce, []byte(token), nil)), nil
+}
+
+// Open returns the token that Seal encrypted.
+func (s *Sealer) Open(sealed string) (string, error) {
+	ciphertext, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil || len(ciphertext) < s.aead.NonceSize() {
+		return "", ErrUnsealable
+	}
+
+	nonce, ciphertext := ciphertext[:s.aead.NonceSize()], ciphertext[s.aead.NonceSize():]
+	token, err := s.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", ErrUnsealable
+	}
+	return string(token), nil
+}
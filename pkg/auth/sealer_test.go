@@ -0,0 +1,39 @@
+package auth
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSealer_ShouldOpenWhatItSealed(t *testing.T) {
+	sealer, err := NewSealer(bytes.Repeat([]byte{1}, 32))
+	require.Nil(t, err)
+
+	first, err := sealer.Seal("token")
+	require.Nil(t, err)
+	second, err := sealer.Seal("token")
+	require.Nil(t, err)
+	require.NotContains(t, first, "token")
+	require.NotEqual(t, first, second)
+
+	token, err := sealer.Open(first)
+	require.Nil(t, err)
+	require.Equal(t, "token", token)
+}
+
+func TestSealer_ShouldNotOpenTokensSealedWithAnotherKeyOrNotSealed(t *testing.T) {
+	sealer, err := NewSealer(bytes.Repeat([]byte{1}, 32))
+	require.Nil(t, err)
+	other, err := NewSealer(bytes.Repeat([]byte{2}, 32))
+	require.Nil(t, err)
+
+	sealed, err := other.Seal("token")
+	require.Nil(t, err)
+
+	for _, value := range []string{sealed, "token", "", "dG9rZW4="} {
+		_, err := sealer.Open(value)
+		require.Equal(t, ErrUnsealable, err, value)
+	}
+}
@@ -0,0 +1,294 @@
+package config
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"notes-api/pkg/auth"
+)
+
+// The storage backends: MongoDB, the default; an embedded bolt database; or memory, which forgets everything on exit.
+const (
+	StorageMongo  = "mongo"
+	StorageMemory = "memory"
+	StorageBolt   = "bolt"
+)
+
+// Config is the configuration of the API. It is read from a file, the environment and the command line by Load.
+type Config struct {
+	Server    ServerConfig    `yaml:"server"`
+	Storage   StorageConfig   `yaml:"storage"`
+	Services  ServicesConfig  `yaml:"services"`
+	Auth      AuthConfig      `yaml:"auth"`
+	Trash     TrashConfig     `yaml:"trash"`
+	Save      SaveConfig      `yaml:"save"`
+	Tracing   TracingConfig   `yaml:"tracing"`
+	AccessLog AccessLogConfig `yaml:"accessLog"`
+}
+
+type ServerConfig struct {
+	Addr         string        `yaml:"addr"`
+	ReadTimeout  time.Duration `yaml:"readTimeout"`
+	WriteTimeout time.Duration `yaml:"writeTimeout"`
+	// ShutdownTimeout is how long requests in flight are given to finish once the server is stopped.
+	ShutdownTimeout time.Duration `yaml:"shutdownTimeout"`
+	// AllowedOrigins are the origins that browsers may call the API from; "*" allows any.
+	AllowedOrigins []string `yaml:"allowedOrigins"`
+	// MetricsPath is where Prometheus metrics are served; empty turns metrics off.
+	MetricsPath string `yaml:"metricsPath"`
+}
+
+type StorageConfig struct {
+	Backend            string `yaml:"backend"`
+	MongoURI           string `yaml:"mongoUri"`
+	Database           string `yaml:"database"`
+	Collection         string `yaml:"collection"`
+	RevisionCollection string `yaml:"revisionCollection"`
+	NotebookCollection string `yaml:"notebookCollection"`
+	SaveJobCollection  string `yaml:"saveJobCollection"`
+	BoltPath           string `yaml:"boltPath"`
+}
+
+// ServicesConfig locates the login and content services. Timeout applies to every call made to them, and to fetches
+// of a JWKS_URL.
+type ServicesConfig struct {
+	ContentServiceURL string        `yaml:"contentServiceUrl"`
+	LoginServiceURL   string        `yaml:"loginServiceUrl"`
+	Timeout           time.Duration `yaml:"timeout"`
+}
+
+type AuthConfig struct {
+	// Mode is one of the auth modes: the login service (remote), local JWT verification (local), or local
+	// verification falling back to the login service (both).
+	Mode                  string        `yaml:"mode"`
+	JWTIssuer             string        `yaml:"jwtIssuer"`
+	JWTAudience           string        `yaml:"jwtAudience"`
+	JWTVerifyExpiry       bool          `yaml:"jwtVerifyExpiry"`
+	JWTLeeway             time.Duration `yaml:"jwtLeeway"`
+	JWTHMACSecret         string        `yaml:"jwtHmacSecret"`
+	JWTPublicKeyFile      string        `yaml:"jwtPublicKeyFile"`
+	JWKSFile              string        `yaml:"jwksFile"`
+	JWKSURL               string        `yaml:"jwksUrl"`
+	JWKSRefreshInterval   time.Duration `yaml:"jwksRefreshInterval"`
+	TokenCacheTTL         time.Duration `yaml:"tokenCacheTtl"`
+	TokenCacheNegativeTTL time.Duration `yaml:"tokenCacheNegativeTtl"`
+	TokenCacheSize        int           `yaml:"tokenCacheSize"`
+}
+
+type TrashConfig struct {
+	Retention     time.Duration `yaml:"retention"`
+	PurgeInterval time.Duration `yaml:"purgeInterval"`
+}
+
+type SaveConfig struct {
+	PollInterval   time.Duration `yaml:"pollInterval"`
+	JobLease       time.Duration `yaml:"jobLease"`
+	MaxAttempts    int           `yaml:"maxAttempts"`
+	RetryBaseDelay time.Duration `yaml:"retryBaseDelay"`
+	RetryMaxDelay  time.Duration `yaml:"retryMaxDelay"`
+	AutoSyncDelay  time.Duration `yaml:"autoSyncDelay"`
+	// TokenKey is the base64 AES key, of 16, 24 or 32 bytes, that the tokens of queued saves are sealed with. Without
+	// one, a random key is used, and saves queued before a restart cannot be delivered.
+	TokenKey string `yaml:"tokenKey"`
+}
+
+// The trace exporters: none, which turns tracing off; an OTLP collector over HTTP; or a file, or standard output,
+// that spans are written to as JSON.
+const (
+	TraceExporterNone   = "none"
+	TraceExporterOTLP   = "otlp"
+	TraceExporterStdout = "stdout"
+)
+
+type TracingConfig struct {
+	Exporter    string `yaml:"exporter"`
+	ServiceName string `yaml:"serviceName"`
+	// OTLPEndpoint is the host and port of the collector, and OTLPInsecure sends spans to it over plain HTTP.
+	OTLPEndpoint string `yaml:"otlpEndpoint"`
+	OTLPInsecure bool   `yaml:"otlpInsecure"`
+	// File is where the stdout exporter writes; empty is standard output.
+	File string `yaml:"file"`
+}
+
+// The formats of the access log: none, which turns it off; logrus JSON or text records; or Apache combined log lines.
+const (
+	AccessLogNone     = "none"
+	AccessLogJSON     = "json"
+	AccessLogText     = "text"
+	AccessLogCombined = "combined"
+)
+
+type AccessLogConfig struct {
+	Format string `yaml:"format"`
+	// SampledPaths are paths, such as health checks, that only SampleRate of the requests to are logged, between 0 for
+	// none and 1 for all. Requests to them that fail with a server error are always logged.
+	SampledPaths []string `yaml:"sampledPaths"`
+	SampleRate   float64  `yaml:"sampleRate"`
+}
+
+// Default returns the configuration used for every setting that is not given.
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			Addr:            ":8006",
+			ReadTimeout:     20 * time.Second,
+			WriteTimeout:    20 * time.Second,
+			ShutdownTimeout: 5 * time.Second,
+			AllowedOrigins:  []string{"*"},
+			MetricsPath:     "/metrics",
+		},
+		Storage: StorageConfig{
+			Backend:            StorageMongo,
+			RevisionCollection: "revisions",
+			NotebookCollection: "notebooks",
+			SaveJobCollection:  "saveJobs",
+			BoltPath:           "notes.db",
+		},
+		Services: ServicesConfig{
+			Timeout: 5 * time.Second,
+		},
+		Auth: AuthConfig{
+			Mode:                  auth.ModeRemote,
+			JWTVerifyExpiry:       true,
+			JWTLeeway:             30 * time.Second,
+			JWKSRefreshInterval:   time.Minute,
+			TokenCacheTTL:         5 * time.Minute,
+			TokenCacheNegativeTTL: 10 * time.Second,
+			TokenCacheSize:        1000,
+		},
+		Trash: TrashConfig{
+			Retention:     30 * 24 * time.Hour,
+			PurgeInterval: time.Hour,
+		},
+		Save: SaveConfig{
+			PollInterval:   5 * time.Second,
+			JobLease:       time.Minute,
+			MaxAttempts:    10,
+			RetryBaseDelay: 5 * time.Second,
+			RetryMaxDelay:  time.Hour,
+			AutoSyncDelay:  10 * time.Second,
+		},
+		Tracing: TracingConfig{
+			Exporter:     TraceExporterNone,
+			ServiceName:  "notes-api",
+			OTLPEndpoint: "localhost:4318",
+		},
+		AccessLog: AccessLogConfig{
+			Format:       AccessLogText,
+			SampledPaths: []string{"/health"},
+		},
+	}
+}
+
+// Validate reports every setting that the API cannot start with, naming each by its environment variable.
+func (c *Config) Validate() error {
+	var problems []string
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+
+	check(c.Server.Addr != "", "LISTEN_ADDR is required")
+	check(len(c.Server.AllowedOrigins) > 0, "CORS_ALLOWED_ORIGINS needs at least one origin")
+	check(c.Server.MetricsPath == "" || strings.HasPrefix(c.Server.MetricsPath, "/"), "METRICS_PATH must start with '/'")
+
+	switch c.Storage.Backend {
+	case StorageMongo:
+		check(c.Storage.MongoURI != "", "MONGO_URI is required when STORAGE_BACKEND is '%v'", StorageMongo)
+		check(c.Storage.Database != "", "DATABASE is required when STORAGE_BACKEND is '%v'", StorageMongo)
+		check(c.Storage.Collection != "", "COLLECTION is required when STORAGE_BACKEND is '%v'", StorageMongo)
+	case StorageBolt:
+		check(c.Storage.BoltPath != "", "BOLT_PATH is required when STORAGE_BACKEND is '%v'", StorageBolt)
+	case StorageMemory:
+	default:
+		check(false, "unknown STORAGE_BACKEND '%v', expected one of %v, %v, %v", c.Storage.Backend,
+			StorageMongo, StorageBolt, StorageMemory)
+	}
+
+	// Saves and imports go to the content service, which every mode of the API serves.
+	check(c.Services.ContentServiceURL != "", "CONTENT_SERVICE_URL is required")
+	check(validURL(c.Services.ContentServiceURL), "CONTENT_SERVICE_URL '%v' is not an absolute URL", c.Services.ContentServiceURL)
+	check(validURL(c.Services.LoginServiceURL), "LOGIN_SERVICE_URL '%v' is not an absolute URL", c.Services.LoginServiceURL)
+	check(validURL(c.Auth.JWKSURL), "JWKS_URL '%v' is not an absolute URL", c.Auth.JWKSURL)
+
+	switch c.Auth.Mode {
+	case auth.ModeRemote, auth.ModeLocal, auth.ModeBoth:
+		check(c.Auth.Mode == auth.ModeLocal || c.Services.LoginServiceURL != "",
+			"LOGIN_SERVICE_URL is required when AUTH_MODE is '%v'", c.Auth.Mode)
+		check(c.Auth.Mode == auth.ModeRemote || c.Auth.JWTHMACSecret != "" || c.Auth.JWTPublicKeyFile != "" ||
+			c.Auth.JWKSFile != "" || c.Auth.JWKSURL != "",
+			"AUTH_MODE '%v' requires JWT_HMAC_SECRET, JWT_PUBLIC_KEY_FILE, JWKS_FILE or JWKS_URL", c.Auth.Mode)
+	default:
+		check(false, "unknown AUTH_MODE '%v', expected one of %v, %v, %v", c.Auth.Mode,
+			auth.ModeRemote, auth.ModeLocal, auth.ModeBoth)
+	}
+	switch c.Tracing.Exporter {
+	case TraceExporterNone, TraceExporterStdout:
+	case TraceExporterOTLP:
+		check(c.Tracing.OTLPEndpoint != "", "TRACING_OTLP_ENDPOINT is required when TRACING_EXPORTER is '%v'", TraceExporterOTLP)
+	default:
+		check(false, "unknown TRACING_EXPORTER '%v', expected one of %v, %v, %v", c.Tracing.Exporter,
+			TraceExporterNone, TraceExporterOTLP, TraceExporterStdout)
+	}
+	check(c.Tracing.ServiceName != "", "TRACING_SERVICE_NAME is required")
+
+	switch c.AccessLog.Format {
+	case AccessLogNone, AccessLogJSON, AccessLogText, AccessLogCombined:
+	default:
+		check(false, "unknown ACCESS_LOG_FORMAT '%v', expected one of %v, %v, %v, %v", c.AccessLog.Format,
+			AccessLogNone, AccessLogJSON, AccessLogText, AccessLogCombined)
+	}
+	for _, path := range c.AccessLog.SampledPaths {
+		check(strings.HasPrefix(path, "/"), "ACCESS_LOG_SAMPLED_PATHS '%v' must start with '/'", path)
+	}
+	check(c.AccessLog.SampleRate >= 0 && c.AccessLog.SampleRate <= 1, "ACCESS_LOG_SAMPLE_RATE must be between 0 and 1")
+
+	check(c.Auth.TokenCacheSize >= 0, "TOKEN_CACHE_SIZE cannot be negative")
+	check(c.Auth.JWTLeeway >= 0, "JWT_LEEWAY cannot be negative")
+
+	for name, duration := range map[string]time.Duration{
+		"READ_TIMEOUT":          c.Server.ReadTimeout,
+		"WRITE_TIMEOUT":         c.Server.WriteTimeout,
+		"SHUTDOWN_TIMEOUT":      c.Server.ShutdownTimeout,
+		"SERVICE_TIMEOUT":       c.Services.Timeout,
+		"JWKS_REFRESH_INTERVAL": c.Auth.JWKSRefreshInterval,
+		"TRASH_RETENTION":       c.Trash.Retention,
+		"TRASH_PURGE_INTERVAL":  c.Trash.PurgeInterval,
+		"SAVE_POLL_INTERVAL":    c.Save.PollInterval,
+		"SAVE_JOB_LEASE":        c.Save.JobLease,
+		"SAVE_RETRY_BASE_DELAY": c.Save.RetryBaseDelay,
+		"SAVE_RETRY_MAX_DELAY":  c.Save.RetryMaxDelay,
+	} {
+		check(duration > 0, "%v must be positive", name)
+	}
+	check(c.Save.RetryBaseDelay <= c.Save.RetryMaxDelay, "SAVE_RETRY_BASE_DELAY cannot be longer than SAVE_RETRY_MAX_DELAY")
+	check(c.Save.MaxAttempts > 0, "SAVE_MAX_ATTEMPTS must be positive")
+	check(c.Save.AutoSyncDelay >= 0, "AUTO_SYNC_DELAY cannot be negative")
+	if c.Save.TokenKey != "" {
+		key, err := base64.StdEncoding.DecodeString(c.Save.TokenKey)
+		check(err == nil && (len(key) == 16 || len(key) == 24 || len(key) == 32), "SAVE_TOKEN_KEY must be 16, 24 or 32 bytes in base64")
+	}
+	check(c.Auth.TokenCacheTTL >= 0 && c.Auth.TokenCacheNegativeTTL >= 0, "TOKEN_CACHE_TTL and TOKEN_CACHE_NEGATIVE_TTL cannot be negative")
+
+	if len(problems) == 0 {
+		return nil
+	}
+	// The map above is walked in no particular order.
+	sort.Strings(problems)
+	return errors.New("invalid configuration: " + strings.Join(problems, "; "))
+}
+
+// validURL tells whether an optional URL setting is either unset or an absolute URL.
+func validURL(value string) bool {
+	if value == "" {
+		return true
+	}
+	parsed, err := url.Parse(value)
+	return err == nil && parsed.Scheme != "" && parsed.Host != ""
+}
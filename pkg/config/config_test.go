@@ -0,0 +1,159 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func env(values map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, ok := values[key]
+		return value, ok
+	}
+}
+
+// withServices adds the URLs of the login and content services, which every configuration needs, to values.
+func withServices(values map[string]string) map[string]string {
+	values["LOGIN_SERVICE_URL"] = "http://login-service"
+	values["CONTENT_SERVICE_URL"] = "http://content-service"
+	return values
+}
+
+func writeFile(t *testing.T, name string, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.Nil(t, ioutil.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func TestConfig_Load_ShouldFailWithoutMongoURI(t *testing.T) {
+	_, err := Load(nil, env(withServices(map[string]string{"DATABASE": "db", "COLLECTION": "notes"})))
+	require.NotNil(t, err)
+	require.Equal(t, "invalid configuration: MONGO_URI is required when STORAGE_BACKEND is 'mongo'", err.Error())
+}
+
+func TestConfig_Load_ShouldUseDefaultsForSettingsNotGiven(t *testing.T) {
+	config, err := Load(nil, env(withServices(map[string]string{"STORAGE_BACKEND": "memory"})))
+	require.Nil(t, err)
+
+	expected := Default()
+	expected.Storage.Backend = StorageMemory
+	expected.Services.LoginServiceURL = "http://login-service"
+	expected.Services.ContentServiceURL = "http://content-service"
+	require.Equal(t, &expected, config)
+	require.Equal(t, ":8006", config.Server.Addr)
+	require.Equal(t, []string{"*"}, config.Server.AllowedOrigins)
+}
+
+func TestConfig_Load_ShouldClearOnlyClearableSettingsWithEmptyEnvironment(t *testing.T) {
+	config, err := Load(nil, env(withServices(map[string]string{
+		"STORAGE_BACKEND":          "memory",
+		"METRICS_PATH":             "",
+		"ACCESS_LOG_SAMPLED_PATHS": "",
+		"LISTEN_ADDR":              "",
+	})))
+	require.Nil(t, err)
+	require.Equal(t, "", config.Server.MetricsPath)
+	require.Nil(t, config.AccessLog.SampledPaths)
+	require.Equal(t, ":8006", config.Server.Addr)
+}
+
+func TestConfig_Load_ShouldPreferFlagsToEnvironmentToFile(t *testing.T) {
+	path := writeFile(t, "config.yaml", `
+server:
+  addr: ":9000"
+  readTimeout: 30s
+  allowedOrigins: [https://notes.example.com]
+storage:
+  mongoUri: mongodb://file
+  database: file
+  collection: notes
+save:
+  maxAttempts: 3
+`)
+
+	config, err := Load([]string{"-config", path, "-database", "flag", "-access-log-sample-rate", "0.25"}, env(withServices(map[string]string{
+		"MONGO_URI":            "mongodb://env",
+		"DATABASE":             "env",
+		"SAVE_RETRY_MAX_DELAY": "2h",
+	})))
+	require.Nil(t, err)
+	require.Equal(t, ":9000", config.Server.Addr)
+	require.Equal(t, 30*time.Second, config.Server.ReadTimeout)
+	require.Equal(t, 20*time.Second, config.Server.WriteTimeout)
+	require.Equal(t, []string{"https://notes.example.com"}, config.Server.AllowedOrigins)
+	require.Equal(t, "mongodb://env", config.Storage.MongoURI)
+	require.Equal(t, "flag", config.Storage.Database)
+	require.Equal(t, "notes", config.Storage.Collection)
+	require.Equal(t, 3, config.Save.MaxAttempts)
+	require.Equal(t, 2*time.Hour, config.Save.RetryMaxDelay)
+	require.Equal(t, 0.25, config.AccessLog.SampleRate)
+}
+
+func TestConfig_Load_ShouldReadJSONFileNamedByEnvironment(t *testing.T) {
+	path := writeFile(t, "config.json", `{"storage": {"backend": "bolt", "boltPath": "/data/notes.db"}, "trash": {"retention": "48h"}}`)
+
+	config, err := Load(nil, env(withServices(map[string]string{"CONFIG_FILE": path})))
+	require.Nil(t, err)
+	require.Equal(t, StorageBolt, config.Storage.Backend)
+	require.Equal(t, "/data/notes.db", config.Storage.BoltPath)
+	require.Equal(t, 48*time.Hour, config.Trash.Retention)
+}
+
+func TestConfig_Load_ShouldRejectUnknownFileSettings(t *testing.T) {
+	path := writeFile(t, "config.yaml", "storage:\n  mongoURL: mongodb://file\n")
+
+	_, err := Load([]string{"-config", path}, env(nil))
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "mongoURL")
+}
+
+func TestConfig_Load_ShouldRejectValuesOfTheWrongType(t *testing.T) {
+	_, err := Load(nil, env(map[string]string{"STORAGE_BACKEND": "memory", "SAVE_POLL_INTERVAL": "5"}))
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "invalid SAVE_POLL_INTERVAL")
+
+	_, err = Load([]string{"-cors-allowed-origins", "a, b", "-jwt-verify-expiry", "maybe"}, env(map[string]string{"STORAGE_BACKEND": "memory"}))
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "invalid -jwt-verify-expiry")
+}
+
+func TestConfig_Validate_ShouldRequireLoginServiceUnlessAuthIsLocal(t *testing.T) {
+	for mode, required := range map[string]bool{"remote": true, "both": true, "local": false} {
+		config := Default()
+		config.Services.ContentServiceURL = "http://content-service"
+		config.Storage.Backend = StorageMemory
+		config.Auth.Mode = mode
+		config.Auth.JWTHMACSecret = "secret"
+
+		err := config.Validate()
+		if required {
+			require.NotNil(t, err, mode)
+			require.Equal(t, fmt.Sprintf("invalid configuration: LOGIN_SERVICE_URL is required when AUTH_MODE is '%v'", mode), err.Error())
+		} else {
+			require.Nil(t, err, mode)
+		}
+	}
+}
+
+func TestConfig_Validate_ShouldReportEveryProblem(t *testing.T) {
+	config := Default()
+	config.Storage.Backend = "postgres"
+	config.Auth.Mode = "local"
+	config.Services.ContentServiceURL = "content-service"
+	config.Save.MaxAttempts = 0
+	config.Server.ShutdownTimeout = 0
+	config.Tracing.Exporter = "jaeger"
+	config.AccessLog.SampleRate = 1.5
+	config.Save.TokenKey = "c2hvcnQ="
+
+	err := config.Validate()
+	require.NotNil(t, err)
+	for _, problem := range []string{"STORAGE_BACKEND", "AUTH_MODE", "CONTENT_SERVICE_URL", "SAVE_MAX_ATTEMPTS", "SHUTDOWN_TIMEOUT", "TRACING_EXPORTER", "ACCESS_LOG_SAMPLE_RATE", "SAVE_TOKEN_KEY"} {
+		require.Contains(t, err.Error(), problem)
+	}
+}
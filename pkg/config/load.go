@@ -0,0 +1,200 @@
+package config
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// setting is a configuration value that can be given by an environment variable and by a command-line flag.
+type setting struct {
+	env   string
+	flag  string
+	usage string
+	// field returns a pointer to the value in a Config: a *string, *int, *float64, *bool, *time.Duration or *[]string.
+	field func(c *Config) interface{}
+}
+
+var settings = []setting{
+	{"LISTEN_ADDR", "addr", "address the API listens on", func(c *Config) interface{} { return &c.Server.Addr }},
+	{"READ_TIMEOUT", "read-timeout", "longest time to read a request", func(c *Config) interface{} { return &c.Server.ReadTimeout }},
+	{"WRITE_TIMEOUT", "write-timeout", "longest time to write a response", func(c *Config) interface{} { return &c.Server.WriteTimeout }},
+	{"SHUTDOWN_TIMEOUT", "shutdown-timeout", "time given to requests in flight on shutdown", func(c *Config) interface{} { return &c.Server.ShutdownTimeout }},
+	{"CORS_ALLOWED_ORIGINS", "cors-allowed-origins", "comma-separated origins browsers may call the API from", func(c *Config) interface{} { return &c.Server.AllowedOrigins }},
+	{"METRICS_PATH", "metrics-path", "path Prometheus metrics are served at; empty turns them off", func(c *Config) interface{} { return &c.Server.MetricsPath }},
+
+	{"STORAGE_BACKEND", "storage-backend", "storage backend: mongo, bolt or memory", func(c *Config) interface{} { return &c.Storage.Backend }},
+	{"MONGO_URI", "mongo-uri", "MongoDB connection string", func(c *Config) interface{} { return &c.Storage.MongoURI }},
+	{"DATABASE", "database", "MongoDB database", func(c *Config) interface{} { return &c.Storage.Database }},
+	{"COLLECTION", "collection", "MongoDB collection of notes", func(c *Config) interface{} { return &c.Storage.Collection }},
+	{"REVISION_COLLECTION", "revision-collection", "MongoDB collection of revisions", func(c *Config) interface{} { return &c.Storage.RevisionCollection }},
+	{"NOTEBOOK_COLLECTION", "notebook-collection", "MongoDB collection of notebooks", func(c *Config) interface{} { return &c.Storage.NotebookCollection }},
+	{"SAVE_JOB_COLLECTION", "save-job-collection", "MongoDB collection of save jobs", func(c *Config) interface{} { return &c.Storage.SaveJobCollection }},
+	{"BOLT_PATH", "bolt-path", "bolt database file", func(c *Config) interface{} { return &c.Storage.BoltPath }},
+
+	{"CONTENT_SERVICE_URL", "content-service-url", "content service base URL", func(c *Config) interface{} { return &c.Services.ContentServiceURL }},
+	{"LOGIN_SERVICE_URL", "login-service-url", "login service base URL", func(c *Config) interface{} { return &c.Services.LoginServiceURL }},
+	{"SERVICE_TIMEOUT", "service-timeout", "timeout of calls to other services", func(c *Config) interface{} { return &c.Services.Timeout }},
+
+	{"AUTH_MODE", "auth-mode", "token validation: remote, local or both", func(c *Config) interface{} { return &c.Auth.Mode }},
+	{"JWT_ISSUER", "jwt-issuer", "required issuer of local tokens", func(c *Config) interface{} { return &c.Auth.JWTIssuer }},
+	{"JWT_AUDIENCE", "jwt-audience", "required audience of local tokens", func(c *Config) interface{} { return &c.Auth.JWTAudience }},
+	{"JWT_VERIFY_EXPIRY", "jwt-verify-expiry", "reject expired local tokens", func(c *Config) interface{} { return &c.Auth.JWTVerifyExpiry }},
+	{"JWT_LEEWAY", "jwt-leeway", "clock skew allowed for local tokens", func(c *Config) interface{} { return &c.Auth.JWTLeeway }},
+	// The secret has no flag, since the command lines of processes are visible to other users.
+	{"JWT_HMAC_SECRET", "", "", func(c *Config) interface{} { return &c.Auth.JWTHMACSecret }},
+	{"JWT_PUBLIC_KEY_FILE", "jwt-public-key-file", "PEM public key of local tokens", func(c *Config) interface{} { return &c.Auth.JWTPublicKeyFile }},
+	{"JWKS_FILE", "jwks-file", "JWKS file of local tokens", func(c *Config) interface{} { return &c.Auth.JWKSFile }},
+	{"JWKS_URL", "jwks-url", "JWKS URL of local tokens", func(c *Config) interface{} { return &c.Auth.JWKSURL }},
+	{"JWKS_REFRESH_INTERVAL", "jwks-refresh-interval", "how often JWKS_URL is fetched", func(c *Config) interface{} { return &c.Auth.JWKSRefreshInterval }},
+	{"TOKEN_CACHE_TTL", "token-cache-ttl", "how long a valid token is cached", func(c *Config) interface{} { return &c.Auth.TokenCacheTTL }},
+	{"TOKEN_CACHE_NEGATIVE_TTL", "token-cache-negative-ttl", "how long a rejected token is cached", func(c *Config) interface{} { return &c.Auth.TokenCacheNegativeTTL }},
+	{"TOKEN_CACHE_SIZE", "token-cache-size", "most tokens cached", func(c *Config) interface{} { return &c.Auth.TokenCacheSize }},
+
+	{"TRASH_RETENTION", "trash-retention", "how long deleted notes are kept", func(c *Config) interface{} { return &c.Trash.Retention }},
+	{"TRASH_PURGE_INTERVAL", "trash-purge-interval", "how often the trash is purged", func(c *Config) interface{} { return &c.Trash.PurgeInterval }},
+
+	{"SAVE_POLL_INTERVAL", "save-poll-interval", "how often queued saves are looked for", func(c *Config) interface{} { return &c.Save.PollInterval }},
+	{"SAVE_JOB_LEASE", "save-job-lease", "how long a save is leased to a worker", func(c *Config) interface{} { return &c.Save.JobLease }},
+	{"SAVE_MAX_ATTEMPTS", "save-max-attempts", "most attempts to deliver a save", func(c *Config) interface{} { return &c.Save.MaxAttempts }},
+	{"SAVE_RETRY_BASE_DELAY", "save-retry-base-delay", "delay before the first retry of a save", func(c *Config) interface{} { return &c.Save.RetryBaseDelay }},
+	{"SAVE_RETRY_MAX_DELAY", "save-retry-max-delay", "longest delay between retries of a save", func(c *Config) interface{} { return &c.Save.RetryMaxDelay }},
+	{"AUTO_SYNC_DELAY", "auto-sync-delay", "delay before an update of a synced note is uploaded", func(c *Config) interface{} { return &c.Save.AutoSyncDelay }},
+	// The key has no flag either.
+	{"SAVE_TOKEN_KEY", "", "", func(c *Config) interface{} { return &c.Save.TokenKey }},
+
+	{"TRACING_EXPORTER", "tracing-exporter", "trace exporter: none, otlp or stdout", func(c *Config) interface{} { return &c.Tracing.Exporter }},
+	{"TRACING_SERVICE_NAME", "tracing-service-name", "service name of spans", func(c *Config) interface{} { return &c.Tracing.ServiceName }},
+	{"TRACING_OTLP_ENDPOINT", "tracing-otlp-endpoint", "host:port of the OTLP/HTTP collector", func(c *Config) interface{} { return &c.Tracing.OTLPEndpoint }},
+	{"TRACING_OTLP_INSECURE", "tracing-otlp-insecure", "send spans to the collector without TLS", func(c *Config) interface{} { return &c.Tracing.OTLPInsecure }},
+	{"TRACING_FILE", "tracing-file", "file the stdout exporter writes spans to; empty is standard output", func(c *Config) interface{} { return &c.Tracing.File }},
+
+	{"ACCESS_LOG_FORMAT", "access-log-format", "access log format: none, json, text or combined", func(c *Config) interface{} { return &c.AccessLog.Format }},
+	{"ACCESS_LOG_SAMPLED_PATHS", "access-log-sampled-paths", "comma-separated paths that are only sampled in the access log", func(c *Config) interface{} { return &c.AccessLog.SampledPaths }},
+	{"ACCESS_LOG_SAMPLE_RATE", "access-log-sample-rate", "share of requests to sampled paths that are logged, from 0 to 1", func(c *Config) interface{} { return &c.AccessLog.SampleRate }},
+}
+
+// clearable are the settings that something is turned off by setting to empty, which an empty environment variable
+// does rather than counting as unset.
+var clearable = map[string]bool{
+	"METRICS_PATH":             true,
+	"ACCESS_LOG_SAMPLED_PATHS": true,
+}
+
+// Load reads the configuration from, in increasing order of precedence: the defaults, the YAML or JSON file named by
+// the -config flag or CONFIG_FILE, environment variables, and the other flags. Empty environment variables count as
+// unset, except for those of clearable settings. lookupEnv is os.LookupEnv, or a stand-in for it. The configuration
+// is validated before it is returned.
+func Load(args []string, lookupEnv func(string) (string, bool)) (*Config, error) {
+	configFile, _ := lookupEnv("CONFIG_FILE")
+
+	flags := flag.NewFlagSet("notes-api", flag.ContinueOnError)
+	path := flags.String("config", configFile, "YAML or JSON configuration file (CONFIG_FILE)")
+
+	byFlag := make(map[string]setting)
+	for _, s := range settings {
+		if s.flag != "" {
+			flags.String(s.flag, "", fmt.Sprintf("%v (%v)", s.usage, s.env))
+			byFlag[s.flag] = s
+		}
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+
+	config := Default()
+	if *path != "" {
+		if err := config.readFile(*path); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, s := range settings {
+		if value, ok := lookupEnv(s.env); ok && (value != "" || clearable[s.env]) {
+			if err := s.set(&config, value); err != nil {
+				return nil, fmt.Errorf("invalid %v: %w", s.env, err)
+			}
+		}
+	}
+
+	var flagErr error
+	flags.Visit(func(f *flag.Flag) {
+		if s, ok := byFlag[f.Name]; ok && flagErr == nil {
+			if err := s.set(&config, f.Value.String()); err != nil {
+				flagErr = fmt.Errorf("invalid -%v: %w", f.Name, err)
+			}
+		}
+	})
+	if flagErr != nil {
+		return nil, flagErr
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// readFile reads a configuration file over the configuration. JSON is read as the YAML it is a subset of. Settings
+// that the file leaves out keep their values, and ones that do not exist are an error.
+func (c *Config) readFile(path string) error {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading config file: %w", err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil && err != io.EOF {
+		return fmt.Errorf("error parsing config file '%v': %w", path, err)
+	}
+	return nil
+}
+
+func (s setting) set(c *Config, value string) error {
+	switch field := s.field(c).(type) {
+	case *string:
+		*field = value
+	case *int:
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		*field = parsed
+	case *float64:
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		*field = parsed
+	case *bool:
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		*field = parsed
+	case *time.Duration:
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		*field = parsed
+	case *[]string:
+		*field = nil
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				*field = append(*field, item)
+			}
+		}
+	default:
+		panic(fmt.Sprintf("setting %v has unsupported type %T", s.env, field))
+	}
+	return nil
+}
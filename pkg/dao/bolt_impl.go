@@ -0,0 +1,176 @@
+package dao
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	boltMetaBucket       = []byte("meta")
+	boltSchemaVersionKey = []byte("schemaVersion")
+)
+
+// boltMigrations each bring the database from the schema version equal to their index to the next one, and run in
+// the same transaction as the update of the recorded version. To change the schema, append a migration; released
+// migrations must never be edited or reordered.
+var boltMigrations = []func(tx *bolt.Tx) error{
+	// 1: a bucket for each collection.
+	func(tx *bolt.Tx) error {
+		for _, name := range []string{notesCollection, revisionsCollection, notebooksCollection} {
+			if _, err := tx.CreateBucketIfNotExists([]byte(name)); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	// 2: a bucket for save jobs.
+	func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(saveJobsCollection))
+		return err
+	},
+	// 3: the text hash of every note.
+	func(tx *bolt.Tx) error {
+		notes := boltCollection{bucket: tx.Bucket([]byte(notesCollection))}
+		docs, err := notes.all()
+		if err != nil {
+			return err
+		}
+		for _, doc := range docs {
+			text, _ := doc["text"].(string)
+			doc["textHash"] = TextHash(text)
+			if err := notes.put(doc); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+// BoltDao is a NoteDaoHandler, NotebookDaoHandler and SaveJobDaoHandler that stores notes, revisions, notebooks and
+// save jobs in a bbolt database file, for single node deployments without MongoDB. Each collection is a bucket of BSON
+// documents keyed by ID, and filters and updates are evaluated as they are by MemoryDao. Queries scan the whole bucket,
+// which suits the thousands of notes of a person or a small team.
+type BoltDao struct {
+	documentDao
+	db *bolt.DB
+}
+
+// OpenBoltDao opens the database at path, creating it if needed, and migrates it to the current schema. The file is
+// locked until Close is called, so only one process can use it at a time.
+func OpenBoltDao(path string) (*BoltDao, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := migrateBolt(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &BoltDao{documentDao: documentDao{store: &boltStore{db: db}}, db: db}, nil
+}
+
+func (dao *BoltDao) Close() error {
+	return dao.db.Close()
+}
+
+func migrateBolt(db *bolt.DB) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		meta, err := tx.CreateBucketIfNotExists(boltMetaBucket)
+		if err != nil {
+			return err
+		}
+
+		var version uint64
+		if raw := meta.Get(boltSchemaVersionKey); raw != nil {
+			version = binary.BigEndian.Uint64(raw)
+		}
+		if version > uint64(len(boltMigrations)) {
+			return fmt.Errorf("database schema version %v is newer than the latest known version %v", version, len(boltMigrations))
+		}
+
+		for ; version < uint64(len(boltMigrations)); version++ {
+			if err := boltMigrations[version](tx); err != nil {
+				return fmt.Errorf("migrating database to schema version %v: %w", version+1, err)
+			}
+		}
+
+		raw := make([]byte, 8)
+		binary.BigEndian.PutUint64(raw, version)
+		return meta.Put(boltSchemaVersionKey, raw)
+	})
+}
+
+type boltStore struct {
+	db *bolt.DB
+}
+
+func (store *boltStore) view(collection string, fn func(documentCollection) error) error {
+	return store.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(collection))
+		if bucket == nil {
+			return fmt.Errorf("collection %v does not exist", collection)
+		}
+		return fn(boltCollection{bucket: bucket})
+	})
+}
+
+func (store *boltStore) update(collection string, fn func(documentCollection) error) error {
+	return store.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(collection))
+		if bucket == nil {
+			return fmt.Errorf("collection %v does not exist", collection)
+		}
+		return fn(boltCollection{bucket: bucket})
+	})
+}
+
+// boltCollection is a bucket of BSON documents keyed by the bytes of their IDs, which bbolt keeps in ID order.
+type boltCollection struct {
+	bucket *bolt.Bucket
+}
+
+func (c boltCollection) all() ([]bson.M, error) {
+	var docs []bson.M
+	err := c.bucket.ForEach(func(_, raw []byte) error {
+		var doc bson.M
+		if err := bson.Unmarshal(raw, &doc); err != nil {
+			return err
+		}
+		docs = append(docs, doc)
+		return nil
+	})
+	return docs, err
+}
+
+func (c boltCollection) get(id primitive.ObjectID) (bson.M, error) {
+	raw := c.bucket.Get(id[:])
+	if raw == nil {
+		return nil, nil
+	}
+
+	var doc bson.M
+	if err := bson.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+func (c boltCollection) put(doc bson.M) error {
+	id := doc["_id"].(primitive.ObjectID)
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return c.bucket.Put(id[:], raw)
+}
+
+func (c boltCollection) delete(id primitive.ObjectID) error {
+	return c.bucket.Delete(id[:])
+}
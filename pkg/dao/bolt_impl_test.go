@@ -0,0 +1,154 @@
+package dao
+
+import (
+	"context"
+	"encoding/binary"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"notes-api/pkg/models"
+)
+
+func TestBoltDao_OpenBoltDao_ShouldPersistNotesAcrossRestarts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.db")
+	note := models.Note{ID: primitive.NewObjectID(), OwnerID: "user", Name: "a", Tags: []string{"x"}, LastEditedTs: time.Now().UTC().Truncate(time.Millisecond)}
+
+	dao, err := OpenBoltDao(path)
+	require.Nil(t, err)
+	require.Nil(t, dao.CreateNote(context.TODO(), note))
+	require.Nil(t, dao.Close())
+
+	dao, err = OpenBoltDao(path)
+	require.Nil(t, err)
+	defer dao.Close()
+
+	notes, err := dao.GetNotes(context.TODO(), NoteFilter{OwnerID: "user"})
+	require.Nil(t, err)
+	require.Equal(t, []models.Note{note}, notes)
+}
+
+func TestBoltDao_OpenBoltDao_ShouldRecordSchemaVersion(t *testing.T) {
+	dao, err := OpenBoltDao(filepath.Join(t.TempDir(), "notes.db"))
+	require.Nil(t, err)
+	defer dao.Close()
+
+	require.Nil(t, dao.db.View(func(tx *bolt.Tx) error {
+		raw := tx.Bucket(boltMetaBucket).Get(boltSchemaVersionKey)
+		require.Equal(t, uint64(len(boltMigrations)), binary.BigEndian.Uint64(raw))
+		return nil
+	}))
+}
+
+func TestBoltDao_OpenBoltDao_ShouldReturnErrorIfSchemaIsNewer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.db")
+	db, err := bolt.Open(path, 0600, nil)
+	require.Nil(t, err)
+	require.Nil(t, db.Update(func(tx *bolt.Tx) error {
+		meta, err := tx.CreateBucket(boltMetaBucket)
+		if err != nil {
+			return err
+		}
+		raw := make([]byte, 8)
+		binary.BigEndian.PutUint64(raw, uint64(len(boltMigrations)+1))
+		return meta.Put(boltSchemaVersionKey, raw)
+	}))
+	require.Nil(t, db.Close())
+
+	_, err = OpenBoltDao(path)
+	require.NotNil(t, err)
+}
+
+func TestBoltDao_OpenBoltDao_ShouldHashTextOfNotesWrittenBeforeSchemaVersion3(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.db")
+	id := primitive.NewObjectID()
+	db, err := bolt.Open(path, 0600, nil)
+	require.Nil(t, err)
+	require.Nil(t, db.Update(func(tx *bolt.Tx) error {
+		for _, migration := range boltMigrations[:2] {
+			if err := migration(tx); err != nil {
+				return err
+			}
+		}
+		meta, err := tx.CreateBucket(boltMetaBucket)
+		if err != nil {
+			return err
+		}
+		raw := make([]byte, 8)
+		binary.BigEndian.PutUint64(raw, 2)
+		if err := meta.Put(boltSchemaVersionKey, raw); err != nil {
+			return err
+		}
+		return boltCollection{bucket: tx.Bucket([]byte(notesCollection))}.put(bson.M{"_id": id, "ownerId": "user", "text": "old"})
+	}))
+	require.Nil(t, db.Close())
+
+	dao, err := OpenBoltDao(path)
+	require.Nil(t, err)
+	defer dao.Close()
+
+	notes, err := dao.GetNotes(context.TODO(), NoteFilter{TextHashes: []string{TextHash("old")}})
+	require.Nil(t, err)
+	require.Len(t, notes, 1)
+	require.Equal(t, id, notes[0].ID)
+}
+
+func TestBoltDao_CreateNote_ShouldReturnErrorIfIDExists(t *testing.T) {
+	dao, err := OpenBoltDao(filepath.Join(t.TempDir(), "notes.db"))
+	require.Nil(t, err)
+	defer dao.Close()
+	note := models.Note{ID: primitive.NewObjectID()}
+
+	require.Nil(t, dao.CreateNote(context.TODO(), note))
+	require.Equal(t, errDuplicateID, dao.CreateNote(context.TODO(), note))
+}
+
+func TestBoltDao_UpdateNote_ShouldOnlyUpdateMatchingVersion(t *testing.T) {
+	dao, err := OpenBoltDao(filepath.Join(t.TempDir(), "notes.db"))
+	require.Nil(t, err)
+	defer dao.Close()
+	note := models.Note{ID: primitive.NewObjectID(), OwnerID: "user", Version: 1}
+	require.Nil(t, dao.CreateNote(context.TODO(), note))
+
+	version, name := int64(1), "b"
+	filter := NoteFilter{IDs: []primitive.ObjectID{note.ID}, Version: &version}
+	patch := NotePatch{Name: &name, IncrementVersion: true}
+	require.Nil(t, dao.UpdateNote(context.TODO(), filter, patch))
+	require.Equal(t, ErrNotFound, dao.UpdateNote(context.TODO(), filter, patch))
+
+	notes, err := dao.GetNotes(context.TODO(), NoteFilter{IDs: []primitive.ObjectID{note.ID}})
+	require.Nil(t, err)
+	require.Equal(t, "b", notes[0].Name)
+	require.Equal(t, int64(2), notes[0].Version)
+}
+
+func TestBoltDao_ClaimSaveJob_ShouldPersistLease(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.db")
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	job := models.SaveJob{ID: primitive.NewObjectID(), Status: models.SaveJobPending, NextAttemptTs: now}
+
+	dao, err := OpenBoltDao(path)
+	require.Nil(t, err)
+	require.Nil(t, dao.CreateSaveJob(context.TODO(), job))
+	claimed, err := dao.ClaimSaveJob(context.TODO(), now, now.Add(time.Minute))
+	require.Nil(t, err)
+	require.Equal(t, job.ID, claimed.ID)
+	require.Nil(t, dao.Close())
+
+	dao, err = OpenBoltDao(path)
+	require.Nil(t, err)
+	defer dao.Close()
+
+	claimed, err = dao.ClaimSaveJob(context.TODO(), now, now.Add(time.Minute))
+	require.Nil(t, err)
+	require.Nil(t, claimed)
+
+	claimed, err = dao.ClaimSaveJob(context.TODO(), now.Add(time.Minute), now.Add(2*time.Minute))
+	require.Nil(t, err)
+	require.Equal(t, 2, claimed.Attempts)
+}
@@ -2,15 +2,38 @@ package dao
 
 import (
 	"context"
-	"go.mongodb.org/mongo-driver/bson"
+	"errors"
 
 	"notes-api/pkg/models"
 )
 
+var (
+	// ErrNotFound is returned when a filter matches no notes, including notes that exist but belong to another user.
+	ErrNotFound = errors.New("note not found")
+	// ErrRevisionNotFound is the revision equivalent of ErrNotFound.
+	ErrRevisionNotFound = errors.New("revision not found")
+)
+
+// IsNotFound tells whether an error is one of the errors returned when a filter matches nothing.
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound) ||
+		errors.Is(err, ErrRevisionNotFound) ||
+		errors.Is(err, ErrNotebookNotFound) ||
+		errors.Is(err, ErrSaveJobNotFound)
+}
+
 type NoteDaoHandler interface {
 	Ping(ctx context.Context) error
-	GetNotes(ctx context.Context, filter map[string]interface{}) ([]models.Note, error)
-	UpdateNote(ctx context.Context, filter map[string]interface{}, updates bson.M) error
-	DeleteNote(ctx context.Context, filter map[string]interface{}) error
+	GetNotes(ctx context.Context, filter NoteFilter) ([]models.Note, error)
+	ListNotes(ctx context.Context, filter NoteFilter, opts ListOptions) ([]models.Note, error)
+	SearchNotes(ctx context.Context, filter NoteFilter, search string, opts ListOptions) ([]models.SearchResult, error)
+	UpdateNote(ctx context.Context, filter NoteFilter, patch NotePatch) error
+	UpdateNotes(ctx context.Context, filter NoteFilter, patch NotePatch) (int64, error)
+	DeleteNote(ctx context.Context, filter NoteFilter) error
+	DeleteNotes(ctx context.Context, filter NoteFilter) (int64, error)
 	CreateNote(ctx context.Context, note models.Note) error
+	CountTags(ctx context.Context, filter NoteFilter) ([]models.TagCount, error)
+	GetRevisions(ctx context.Context, filter RevisionFilter) ([]models.Revision, error)
+	CreateRevision(ctx context.Context, revision models.Revision) error
+	DeleteRevisions(ctx context.Context, filter RevisionFilter) error
 }
@@ -7,21 +7,23 @@ import (
 	"notes-api/pkg/models"
 
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/mongo/readpref"
 )
 
 type NotesDao struct {
-	Client     *mongo.Client
-	Database   string
-	Collection string
+	Client             *mongo.Client
+	Database           string
+	Collection         string
+	RevisionCollection string
 }
 
 func (dao *NotesDao) Ping(ctx context.Context) error {
 	return dao.Client.Ping(ctx, readpref.Primary())
 }
 
-func (dao *NotesDao) GetNotes(ctx context.Context, filter map[string]interface{}) ([]models.Note, error) {
-	cursor, err := dao.getCollection().Find(ctx, filter)
+func (dao *NotesDao) GetNotes(ctx context.Context, filter NoteFilter) ([]models.Note, error) {
+	cursor, err := dao.getCollection().Find(ctx, filter.query())
 	if err != nil {
 		return nil, err
 	}
@@ -34,25 +36,117 @@ func (dao *NotesDao) GetNotes(ctx context.Context, filter map[string]interface{}
 	return notes, nil
 }
 
-func (dao *NotesDao) UpdateNote(ctx context.Context, filter map[string]interface{}, updates bson.M) error {
-	result := dao.getCollection().FindOneAndUpdate(ctx, filter, updates)
-	if result.Err() != nil {
+// ListNotes returns the notes matching filter in the order given by opts, reading at most opts.Limit of them.
+func (dao *NotesDao) ListNotes(ctx context.Context, filter NoteFilter, opts ListOptions) ([]models.Note, error) {
+	findOptions := options.Find()
+	if sort := opts.sort(); len(sort) > 0 {
+		findOptions.SetSort(sort)
+	}
+	if opts.Skip > 0 {
+		findOptions.SetSkip(opts.Skip)
+	}
+	if opts.Limit > 0 {
+		findOptions.SetLimit(opts.Limit)
+	}
+	if projection := opts.projection(); len(projection) > 0 {
+		findOptions.SetProjection(projection)
+	}
+
+	cursor, err := dao.getCollection().Find(ctx, opts.query(filter), findOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	var notes []models.Note
+	if err := cursor.All(ctx, &notes); err != nil {
+		return nil, err
+	}
+
+	return notes, nil
+}
+
+// SearchNotes runs a $text search for the notes matching filter, most relevant first. It needs the text index created
+// by EnsureIndexes. opts.Sort and opts.After are ignored; notes with equal scores are ordered by ID so that pages are
+// stable.
+func (dao *NotesDao) SearchNotes(ctx context.Context, filter NoteFilter, search string, opts ListOptions) ([]models.SearchResult, error) {
+	textFilter := filter.query()
+	textFilter["$text"] = bson.M{"$search": search}
+
+	score := bson.M{"$meta": "textScore"}
+	projection := bson.M{"score": score}
+	for key, value := range opts.projection() {
+		projection[key] = value
+	}
+
+	findOptions := options.Find().
+		SetProjection(projection).
+		SetSort(bson.D{{Key: "score", Value: score}, {Key: "_id", Value: 1}})
+	if opts.Skip > 0 {
+		findOptions.SetSkip(opts.Skip)
+	}
+	if opts.Limit > 0 {
+		findOptions.SetLimit(opts.Limit)
+	}
+
+	cursor, err := dao.getCollection().Find(ctx, textFilter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	var scored []struct {
+		models.Note `bson:",inline"`
+		Score       float64 `bson:"score"`
+	}
+	if err := cursor.All(ctx, &scored); err != nil {
+		return nil, err
+	}
+
+	results := make([]models.SearchResult, len(scored))
+	for i := range scored {
+		results[i] = models.SearchResult{Note: scored[i].Note, Score: scored[i].Score}
+	}
+
+	return results, nil
+}
+
+func (dao *NotesDao) UpdateNote(ctx context.Context, filter NoteFilter, patch NotePatch) error {
+	result := dao.getCollection().FindOneAndUpdate(ctx, filter.query(), patch.update())
+	if errors.Is(result.Err(), mongo.ErrNoDocuments) {
+		return ErrNotFound
+	} else if result.Err() != nil {
 		return result.Err()
 	}
 
 	return nil
 }
 
-func (dao *NotesDao) DeleteNote(ctx context.Context, filter map[string]interface{}) error {
-	result, err := dao.getCollection().DeleteOne(ctx, filter)
+// UpdateNotes applies patch to every note matching filter and returns the number of notes modified.
+func (dao *NotesDao) UpdateNotes(ctx context.Context, filter NoteFilter, patch NotePatch) (int64, error) {
+	result, err := dao.getCollection().UpdateMany(ctx, filter.query(), patch.update())
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+func (dao *NotesDao) DeleteNote(ctx context.Context, filter NoteFilter) error {
+	result, err := dao.getCollection().DeleteOne(ctx, filter.query())
 	if err != nil {
 		return err
 	} else if result.DeletedCount == 0 {
-		return errors.New("no notes were deleted")
+		return ErrNotFound
 	}
 	return nil
 }
 
+func (dao *NotesDao) DeleteNotes(ctx context.Context, filter NoteFilter) (int64, error) {
+	result, err := dao.getCollection().DeleteMany(ctx, filter.query())
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
 func (dao *NotesDao) CreateNote(ctx context.Context, note models.Note) error {
 	_, err := dao.getCollection().InsertOne(ctx, note)
 	if err != nil {
@@ -62,6 +156,125 @@ func (dao *NotesDao) CreateNote(ctx context.Context, note models.Note) error {
 	return nil
 }
 
+// CountTags returns the number of notes matching filter with each tag, most used first.
+func (dao *NotesDao) CountTags(ctx context.Context, filter NoteFilter) ([]models.TagCount, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter.query()}},
+		{{Key: "$unwind", Value: "$tags"}},
+		{{Key: "$group", Value: bson.M{"_id": "$tags", "count": bson.M{"$sum": 1}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}}},
+	}
+
+	cursor, err := dao.getCollection().Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	var counts []models.TagCount
+	if err := cursor.All(ctx, &counts); err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+// GetRevisions returns the revisions matching filter, newest first.
+func (dao *NotesDao) GetRevisions(ctx context.Context, filter RevisionFilter) ([]models.Revision, error) {
+	cursor, err := dao.getRevisionCollection().Find(ctx, filter.query(), options.Find().SetSort(bson.D{{Key: "_id", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	var revisions []models.Revision
+	if err := cursor.All(ctx, &revisions); err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+func (dao *NotesDao) CreateRevision(ctx context.Context, revision models.Revision) error {
+	_, err := dao.getRevisionCollection().InsertOne(ctx, revision)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (dao *NotesDao) DeleteRevisions(ctx context.Context, filter RevisionFilter) error {
+	_, err := dao.getRevisionCollection().DeleteMany(ctx, filter.query())
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// EnsureIndexes creates the indexes the DAO's queries rely on. Creating an index that already exists is a no-op.
+// The text index leads with ownerId, so every text search must be scoped to a single owner; names weigh more than
+// text when ranking.
+func (dao *NotesDao) EnsureIndexes(ctx context.Context) error {
+	_, err := dao.getCollection().Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "ownerId", Value: 1}, {Key: "name", Value: "text"}, {Key: "text", Value: "text"}},
+			Options: options.Index().
+				SetName("ownerId_text").
+				SetWeights(bson.M{"name": 3, "text": 1}),
+		},
+		{
+			Keys:    bson.D{{Key: "ownerId", Value: 1}, {Key: "tags", Value: 1}},
+			Options: options.Index().SetName("ownerId_tags"),
+		},
+		{
+			Keys:    bson.D{{Key: "ownerId", Value: 1}, {Key: "notebookId", Value: 1}},
+			Options: options.Index().SetName("ownerId_notebookId"),
+		},
+		{
+			Keys:    bson.D{{Key: "ownerId", Value: 1}, {Key: "textHash", Value: 1}},
+			Options: options.Index().SetName("ownerId_textHash"),
+		},
+		{
+			// Only notes in the trash have deletedAt, so the index of the trash purge stays small.
+			Keys:    bson.D{{Key: "deletedAt", Value: 1}},
+			Options: options.Index().SetName("deletedAt").SetSparse(true),
+		},
+	})
+	return err
+}
+
+// HashTexts sets the TextHash of the notes written before notes had one, and returns how many it set. It can be
+// stopped and run again.
+func (dao *NotesDao) HashTexts(ctx context.Context) (int64, error) {
+	unhashed := bson.M{"textHash": bson.M{"$exists": false}}
+	cursor, err := dao.getCollection().Find(ctx, unhashed, options.Find().SetProjection(bson.M{"text": 1}))
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var hashed int64
+	for cursor.Next(ctx) {
+		var note models.Note
+		if err := cursor.Decode(&note); err != nil {
+			return hashed, err
+		}
+
+		// A note that is edited in the meantime is hashed by the edit, which is left alone.
+		result, err := dao.getCollection().UpdateOne(ctx, bson.M{"_id": note.ID, "textHash": bson.M{"$exists": false}},
+			bson.M{"$set": bson.M{"textHash": TextHash(note.Text)}})
+		if err != nil {
+			return hashed, err
+		}
+		hashed += result.ModifiedCount
+	}
+	return hashed, cursor.Err()
+}
+
 func (dao *NotesDao) getCollection() *mongo.Collection {
 	return dao.Client.Database(dao.Database).Collection(dao.Collection)
 }
+
+func (dao *NotesDao) getRevisionCollection() *mongo.Collection {
+	return dao.Client.Database(dao.Database).Collection(dao.RevisionCollection)
+}
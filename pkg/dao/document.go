@@ -0,0 +1,591 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"notes-api/pkg/models"
+)
+
+const (
+	notesCollection     = "notes"
+	revisionsCollection = "revisions"
+	notebooksCollection = "notebooks"
+	saveJobsCollection  = "saveJobs"
+)
+
+// errDuplicateID is returned when a document is created with the ID of an existing one.
+var errDuplicateID = errors.New("a document with this ID already exists")
+
+// documentStore holds BSON documents in named collections. It is what the in-memory and embedded backends have to
+// provide; documentDao implements the DAO interfaces on top of it, so both backends evaluate filters and updates
+// the same way.
+type documentStore interface {
+	// view runs fn with read access to a collection.
+	view(collection string, fn func(documentCollection) error) error
+	// update runs fn with write access to a collection. Its changes are kept only if fn returns nil.
+	update(collection string, fn func(documentCollection) error) error
+}
+
+type documentCollection interface {
+	// all returns every document in the collection in ID order. The documents must not be modified.
+	all() ([]bson.M, error)
+	// get returns the document with the given ID, or nil.
+	get(id primitive.ObjectID) (bson.M, error)
+	// put stores a document, replacing the one with the same ID.
+	put(doc bson.M) error
+	// delete removes the document with the given ID.
+	delete(id primitive.ObjectID) error
+}
+
+// documentDao is a NoteDaoHandler, NotebookDaoHandler and SaveJobDaoHandler over a documentStore. Documents are
+// stored as they would be in MongoDB, and filters and updates are evaluated by matches and applyUpdate.
+type documentDao struct {
+	store documentStore
+}
+
+func (dao *documentDao) Ping(ctx context.Context) error {
+	return dao.store.view(notesCollection, func(documentCollection) error { return nil })
+}
+
+func (dao *documentDao) GetNotes(ctx context.Context, filter NoteFilter) ([]models.Note, error) {
+	var notes []models.Note
+	err := dao.store.view(notesCollection, func(c documentCollection) error {
+		docs, err := find(c, filter.query())
+		if err != nil {
+			return err
+		}
+		notes, err = decodeNotes(docs)
+		return err
+	})
+	return notes, err
+}
+
+func (dao *documentDao) ListNotes(ctx context.Context, filter NoteFilter, opts ListOptions) ([]models.Note, error) {
+	var notes []models.Note
+	err := dao.store.view(notesCollection, func(c documentCollection) error {
+		docs, err := find(c, opts.query(filter))
+		if err != nil {
+			return err
+		}
+
+		sortDocuments(docs, opts.sort())
+		docs = page(docs, opts)
+		projection := opts.projection()
+		for i := range docs {
+			docs[i] = project(docs[i], projection)
+		}
+
+		notes, err = decodeNotes(docs)
+		return err
+	})
+	return notes, err
+}
+
+// SearchNotes approximates a MongoDB text search: a note matches if its name or text contains one of the words of the
+// search, every quoted phrase and none of the negated words. Words are compared whole and without stemming. The score
+// is the number of times the words occur, with words in the name counting three times.
+func (dao *documentDao) SearchNotes(ctx context.Context, filter NoteFilter, search string, opts ListOptions) ([]models.SearchResult, error) {
+	var results []models.SearchResult
+	err := dao.store.view(notesCollection, func(c documentCollection) error {
+		docs, err := find(c, filter.query())
+		if err != nil {
+			return err
+		}
+
+		query := parseSearch(search)
+		scores := map[primitive.ObjectID]float64{}
+		var found []bson.M
+		for _, doc := range docs {
+			name, _ := doc["name"].(string)
+			text, _ := doc["text"].(string)
+			if score := query.score(name, text); score > 0 {
+				scores[doc["_id"].(primitive.ObjectID)] = score
+				found = append(found, doc)
+			}
+		}
+
+		// Documents come in ID order, so a stable sort keeps notes with equal scores in ID order.
+		sort.SliceStable(found, func(i, j int) bool {
+			return scores[found[i]["_id"].(primitive.ObjectID)] > scores[found[j]["_id"].(primitive.ObjectID)]
+		})
+
+		found = page(found, opts)
+		projection := opts.projection()
+		results = make([]models.SearchResult, len(found))
+		for i, doc := range found {
+			if err := fromDocument(project(doc, projection), &results[i].Note); err != nil {
+				return err
+			}
+			results[i].Score = scores[results[i].Note.ID]
+		}
+		return nil
+	})
+	return results, err
+}
+
+func (dao *documentDao) UpdateNote(ctx context.Context, filter NoteFilter, patch NotePatch) error {
+	var updated int64
+	err := dao.store.update(notesCollection, func(c documentCollection) error {
+		var err error
+		updated, err = updateDocuments(c, filter.query(), patch.update(), false)
+		return err
+	})
+	if err != nil {
+		return err
+	} else if updated == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (dao *documentDao) UpdateNotes(ctx context.Context, filter NoteFilter, patch NotePatch) (int64, error) {
+	var updated int64
+	err := dao.store.update(notesCollection, func(c documentCollection) error {
+		var err error
+		updated, err = updateDocuments(c, filter.query(), patch.update(), true)
+		return err
+	})
+	return updated, err
+}
+
+func (dao *documentDao) DeleteNote(ctx context.Context, filter NoteFilter) error {
+	var deleted int64
+	err := dao.store.update(notesCollection, func(c documentCollection) error {
+		var err error
+		deleted, err = removeDocuments(c, filter.query(), false)
+		return err
+	})
+	if err != nil {
+		return err
+	} else if deleted == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (dao *documentDao) DeleteNotes(ctx context.Context, filter NoteFilter) (int64, error) {
+	var deleted int64
+	err := dao.store.update(notesCollection, func(c documentCollection) error {
+		var err error
+		deleted, err = removeDocuments(c, filter.query(), true)
+		return err
+	})
+	return deleted, err
+}
+
+func (dao *documentDao) CreateNote(ctx context.Context, note models.Note) error {
+	return dao.store.update(notesCollection, func(c documentCollection) error {
+		return insertDocument(c, note)
+	})
+}
+
+// CountTags returns the number of notes matching filter with each tag, most used first.
+func (dao *documentDao) CountTags(ctx context.Context, filter NoteFilter) ([]models.TagCount, error) {
+	counts := map[string]int64{}
+	err := dao.store.view(notesCollection, func(c documentCollection) error {
+		docs, err := find(c, filter.query())
+		if err != nil {
+			return err
+		}
+
+		for _, doc := range docs {
+			tags, _ := doc["tags"].(primitive.A)
+			for _, tag := range tags {
+				if name, ok := tag.(string); ok {
+					counts[name]++
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tagCounts := make([]models.TagCount, 0, len(counts))
+	for tag, count := range counts {
+		tagCounts = append(tagCounts, models.TagCount{Tag: tag, Count: count})
+	}
+	sort.Slice(tagCounts, func(i, j int) bool {
+		if tagCounts[i].Count != tagCounts[j].Count {
+			return tagCounts[i].Count > tagCounts[j].Count
+		}
+		return tagCounts[i].Tag < tagCounts[j].Tag
+	})
+
+	return tagCounts, nil
+}
+
+// GetRevisions returns the revisions matching filter, newest first.
+func (dao *documentDao) GetRevisions(ctx context.Context, filter RevisionFilter) ([]models.Revision, error) {
+	var revisions []models.Revision
+	err := dao.store.view(revisionsCollection, func(c documentCollection) error {
+		docs, err := find(c, filter.query())
+		if err != nil {
+			return err
+		}
+		sortDocuments(docs, bson.D{{Key: "_id", Value: -1}})
+
+		revisions = make([]models.Revision, len(docs))
+		for i, doc := range docs {
+			if err := fromDocument(doc, &revisions[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return revisions, err
+}
+
+func (dao *documentDao) CreateRevision(ctx context.Context, revision models.Revision) error {
+	return dao.store.update(revisionsCollection, func(c documentCollection) error {
+		return insertDocument(c, revision)
+	})
+}
+
+func (dao *documentDao) DeleteRevisions(ctx context.Context, filter RevisionFilter) error {
+	return dao.store.update(revisionsCollection, func(c documentCollection) error {
+		_, err := removeDocuments(c, filter.query(), true)
+		return err
+	})
+}
+
+// GetNotebooks returns the notebooks matching filter, ordered by name.
+func (dao *documentDao) GetNotebooks(ctx context.Context, filter NotebookFilter) ([]models.Notebook, error) {
+	var notebooks []models.Notebook
+	err := dao.store.view(notebooksCollection, func(c documentCollection) error {
+		docs, err := find(c, filter.query())
+		if err != nil {
+			return err
+		}
+		sortDocuments(docs, bson.D{{Key: "name", Value: 1}})
+
+		notebooks = make([]models.Notebook, len(docs))
+		for i, doc := range docs {
+			if err := fromDocument(doc, &notebooks[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return notebooks, err
+}
+
+func (dao *documentDao) CreateNotebook(ctx context.Context, notebook models.Notebook) error {
+	return dao.store.update(notebooksCollection, func(c documentCollection) error {
+		return insertDocument(c, notebook)
+	})
+}
+
+func (dao *documentDao) UpdateNotebook(ctx context.Context, filter NotebookFilter, patch NotebookPatch) error {
+	var updated int64
+	err := dao.store.update(notebooksCollection, func(c documentCollection) error {
+		var err error
+		updated, err = updateDocuments(c, filter.query(), patch.update(), false)
+		return err
+	})
+	if err != nil {
+		return err
+	} else if updated == 0 {
+		return ErrNotebookNotFound
+	}
+	return nil
+}
+
+func (dao *documentDao) DeleteNotebooks(ctx context.Context, filter NotebookFilter) (int64, error) {
+	var deleted int64
+	err := dao.store.update(notebooksCollection, func(c documentCollection) error {
+		var err error
+		deleted, err = removeDocuments(c, filter.query(), true)
+		return err
+	})
+	return deleted, err
+}
+
+func (dao *documentDao) CreateSaveJob(ctx context.Context, job models.SaveJob) error {
+	return dao.store.update(saveJobsCollection, func(c documentCollection) error {
+		return insertDocument(c, job)
+	})
+}
+
+// CreateSaveJobIfNone creates the job unless a job matches filter, as SaveJobsDao does. The check and the creation
+// share one write to the store, which is what makes them one step.
+func (dao *documentDao) CreateSaveJobIfNone(ctx context.Context, filter SaveJobFilter, job models.SaveJob) (bool, error) {
+	created := false
+	err := dao.store.update(saveJobsCollection, func(c documentCollection) error {
+		docs, err := find(c, filter.query())
+		if err != nil || len(docs) > 0 {
+			return err
+		}
+
+		created = true
+		return insertDocument(c, job)
+	})
+	return created && err == nil, err
+}
+
+func (dao *documentDao) GetSaveJobs(ctx context.Context, filter SaveJobFilter) ([]models.SaveJob, error) {
+	var jobs []models.SaveJob
+	err := dao.store.view(saveJobsCollection, func(c documentCollection) error {
+		docs, err := find(c, filter.query())
+		if err != nil {
+			return err
+		}
+
+		jobs = make([]models.SaveJob, len(docs))
+		for i, doc := range docs {
+			if err := fromDocument(doc, &jobs[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return jobs, err
+}
+
+// ClaimSaveJob takes the pending job that has been due the longest, as SaveJobsDao does.
+func (dao *documentDao) ClaimSaveJob(ctx context.Context, now time.Time, leaseUntil time.Time) (*models.SaveJob, error) {
+	var job *models.SaveJob
+	err := dao.store.update(saveJobsCollection, func(c documentCollection) error {
+		query, update, sortBy := claimSaveJob(now, leaseUntil)
+
+		docs, err := find(c, query)
+		if err != nil || len(docs) == 0 {
+			return err
+		}
+		sortDocuments(docs, sortBy)
+
+		changes, err := toDocument(update)
+		if err != nil {
+			return err
+		}
+		claimed := copyDocument(docs[0])
+		if err := applyUpdate(claimed, changes); err != nil {
+			return err
+		}
+		if err := c.put(claimed); err != nil {
+			return err
+		}
+
+		job = &models.SaveJob{}
+		return fromDocument(claimed, job)
+	})
+	return job, err
+}
+
+func (dao *documentDao) UpdateSaveJob(ctx context.Context, filter SaveJobFilter, patch SaveJobPatch) error {
+	var updated int64
+	err := dao.store.update(saveJobsCollection, func(c documentCollection) error {
+		var err error
+		updated, err = updateDocuments(c, filter.query(), patch.update(), false)
+		return err
+	})
+	if err != nil {
+		return err
+	} else if updated == 0 {
+		return ErrSaveJobNotFound
+	}
+	return nil
+}
+
+// find returns the documents of a collection that match filter, in ID order.
+func find(c documentCollection, filter bson.M) ([]bson.M, error) {
+	query, err := toDocument(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	docs, err := c.all()
+	if err != nil {
+		return nil, err
+	}
+
+	var found []bson.M
+	for _, doc := range docs {
+		ok, err := matches(doc, query)
+		if err != nil {
+			return nil, err
+		} else if ok {
+			found = append(found, doc)
+		}
+	}
+
+	return found, nil
+}
+
+// updateDocuments applies updates to the first document matching filter, or to all of them if many is set. It
+// returns the number of documents changed when many is set, as UpdateMany does, and the number matched otherwise,
+// as FindOneAndUpdate does.
+func updateDocuments(c documentCollection, filter bson.M, updates bson.M, many bool) (int64, error) {
+	changes, err := toDocument(updates)
+	if err != nil {
+		return 0, err
+	}
+
+	docs, err := find(c, filter)
+	if err != nil {
+		return 0, err
+	}
+	if !many && len(docs) > 1 {
+		docs = docs[:1]
+	}
+
+	var updated int64
+	for _, doc := range docs {
+		replacement := copyDocument(doc)
+		if err := applyUpdate(replacement, changes); err != nil {
+			return 0, err
+		}
+		if many && reflect.DeepEqual(doc, replacement) {
+			continue
+		}
+		if err := c.put(replacement); err != nil {
+			return 0, err
+		}
+		updated++
+	}
+
+	return updated, nil
+}
+
+// removeDocuments deletes the first document matching filter, or all of them if many is set, and returns the number
+// deleted.
+func removeDocuments(c documentCollection, filter bson.M, many bool) (int64, error) {
+	docs, err := find(c, filter)
+	if err != nil {
+		return 0, err
+	}
+	if !many && len(docs) > 1 {
+		docs = docs[:1]
+	}
+
+	for _, doc := range docs {
+		if err := c.delete(doc["_id"].(primitive.ObjectID)); err != nil {
+			return 0, err
+		}
+	}
+
+	return int64(len(docs)), nil
+}
+
+func insertDocument(c documentCollection, model interface{}) error {
+	doc, err := toDocument(model)
+	if err != nil {
+		return err
+	}
+
+	existing, err := c.get(doc["_id"].(primitive.ObjectID))
+	if err != nil {
+		return err
+	} else if existing != nil {
+		return errDuplicateID
+	}
+
+	return c.put(doc)
+}
+
+func page(docs []bson.M, opts ListOptions) []bson.M {
+	if opts.Skip >= int64(len(docs)) {
+		return nil
+	}
+	docs = docs[opts.Skip:]
+	if opts.Limit > 0 && opts.Limit < int64(len(docs)) {
+		docs = docs[:opts.Limit]
+	}
+	return docs
+}
+
+// copyDocument copies a document deeply enough for applyUpdate: top level fields and arrays are copied, and nested
+// documents, which updates replace rather than modify, are shared.
+func copyDocument(doc bson.M) bson.M {
+	copied := make(bson.M, len(doc))
+	for key, value := range doc {
+		if array, ok := value.(primitive.A); ok {
+			value = append(primitive.A{}, array...)
+		}
+		copied[key] = value
+	}
+	return copied
+}
+
+func decodeNotes(docs []bson.M) ([]models.Note, error) {
+	notes := make([]models.Note, len(docs))
+	for i, doc := range docs {
+		if err := fromDocument(doc, &notes[i]); err != nil {
+			return nil, err
+		}
+	}
+	return notes, nil
+}
+
+type searchQuery struct {
+	words   []string
+	phrases []string
+	negated []string
+}
+
+func parseSearch(search string) searchQuery {
+	var query searchQuery
+	for i, part := range strings.Split(strings.ToLower(search), `"`) {
+		if i%2 == 1 {
+			if phrase := strings.TrimSpace(part); phrase != "" {
+				query.phrases = append(query.phrases, phrase)
+				query.words = append(query.words, searchWords(phrase)...)
+			}
+			continue
+		}
+		for _, field := range strings.Fields(part) {
+			if strings.HasPrefix(field, "-") {
+				query.negated = append(query.negated, searchWords(field)...)
+			} else {
+				query.words = append(query.words, searchWords(field)...)
+			}
+		}
+	}
+	return query
+}
+
+func (query searchQuery) score(name string, text string) float64 {
+	name, text = strings.ToLower(name), strings.ToLower(text)
+
+	for _, phrase := range query.phrases {
+		if !strings.Contains(name, phrase) && !strings.Contains(text, phrase) {
+			return 0
+		}
+	}
+
+	counts := map[string]float64{}
+	for _, word := range searchWords(name) {
+		counts[word] += 3
+	}
+	for _, word := range searchWords(text) {
+		counts[word]++
+	}
+
+	for _, word := range query.negated {
+		if counts[word] > 0 {
+			return 0
+		}
+	}
+
+	var score float64
+	for _, word := range query.words {
+		score += counts[word]
+	}
+	return score
+}
+
+func searchWords(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
@@ -0,0 +1,85 @@
+package dao
+
+import (
+	"sort"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MemoryDao is a NoteDaoHandler, NotebookDaoHandler and SaveJobDaoHandler that keeps notes, revisions, notebooks and
+// save jobs in memory, for local development and tests. It evaluates the same filters and updates as NotesDao; see
+// matches and applyUpdate for the supported operators. It is safe for concurrent use, and everything in it is lost when
+// the process exits.
+type MemoryDao struct {
+	documentDao
+}
+
+func NewMemoryDao() *MemoryDao {
+	return &MemoryDao{documentDao{store: &memoryStore{collections: map[string]memoryCollection{}}}}
+}
+
+// memoryStore keeps each collection in a map. Writers work on a copy of the map that replaces the original when they
+// succeed, so a failed update leaves the collection as it was.
+type memoryStore struct {
+	mu          sync.RWMutex
+	collections map[string]memoryCollection
+}
+
+func (store *memoryStore) view(collection string, fn func(documentCollection) error) error {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	return fn(store.collections[collection])
+}
+
+func (store *memoryStore) update(collection string, fn func(documentCollection) error) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	staged := make(memoryCollection, len(store.collections[collection]))
+	for id, doc := range store.collections[collection] {
+		staged[id] = doc
+	}
+
+	if err := fn(staged); err != nil {
+		return err
+	}
+
+	store.collections[collection] = staged
+	return nil
+}
+
+type memoryCollection map[primitive.ObjectID]bson.M
+
+func (c memoryCollection) all() ([]bson.M, error) {
+	ids := make([]primitive.ObjectID, 0, len(c))
+	for id := range c {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		cmp, _ := compareValues(ids[i], ids[j])
+		return cmp < 0
+	})
+
+	docs := make([]bson.M, len(ids))
+	for i, id := range ids {
+		docs[i] = c[id]
+	}
+	return docs, nil
+}
+
+func (c memoryCollection) get(id primitive.ObjectID) (bson.M, error) {
+	return c[id], nil
+}
+
+func (c memoryCollection) put(doc bson.M) error {
+	c[doc["_id"].(primitive.ObjectID)] = doc
+	return nil
+}
+
+func (c memoryCollection) delete(id primitive.ObjectID) error {
+	delete(c, id)
+	return nil
+}
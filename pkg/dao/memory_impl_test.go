@@ -0,0 +1,299 @@
+package dao
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"notes-api/pkg/models"
+)
+
+func TestMemoryDao_CreateNote_ShouldReturnErrorIfIDExists(t *testing.T) {
+	dao := NewMemoryDao()
+	note := models.Note{ID: primitive.NewObjectID()}
+
+	require.Nil(t, dao.CreateNote(context.TODO(), note))
+	require.Equal(t, errDuplicateID, dao.CreateNote(context.TODO(), note))
+}
+
+func TestMemoryDao_GetNotes_ShouldReturnCopiesOfMatchingNotes(t *testing.T) {
+	dao := NewMemoryDao()
+	note := models.Note{ID: primitive.NewObjectID(), OwnerID: "user", Name: "a", Tags: []string{"x"}, LastEditedTs: time.Now().UTC().Truncate(time.Millisecond)}
+	require.Nil(t, dao.CreateNote(context.TODO(), note))
+	require.Nil(t, dao.CreateNote(context.TODO(), models.Note{ID: primitive.NewObjectID(), OwnerID: "other"}))
+
+	notes, err := dao.GetNotes(context.TODO(), NoteFilter{OwnerID: "user"})
+	require.Nil(t, err)
+	require.Equal(t, []models.Note{note}, notes)
+
+	notes[0].Tags[0] = "changed"
+	notes, err = dao.GetNotes(context.TODO(), NoteFilter{OwnerID: "user"})
+	require.Nil(t, err)
+	require.Equal(t, []string{"x"}, notes[0].Tags)
+}
+
+func TestMemoryDao_UpdateNote_ShouldReturnNotFoundIfNothingMatches(t *testing.T) {
+	dao := NewMemoryDao()
+
+	name := "a"
+	err := dao.UpdateNote(context.TODO(), NoteFilter{IDs: []primitive.ObjectID{primitive.NewObjectID()}}, NotePatch{Name: &name})
+	require.Equal(t, ErrNotFound, err)
+}
+
+func TestMemoryDao_UpdateNote_ShouldApplyVersionedUpdate(t *testing.T) {
+	dao := NewMemoryDao()
+	id := primitive.NewObjectID()
+	require.Nil(t, dao.CreateNote(context.TODO(), models.Note{ID: id, Version: 1}))
+
+	version, name := int64(1), "a"
+	filter := NoteFilter{IDs: []primitive.ObjectID{id}, Version: &version}
+	patch := NotePatch{Name: &name, IncrementVersion: true}
+	require.Nil(t, dao.UpdateNote(context.TODO(), filter, patch))
+	require.Equal(t, ErrNotFound, dao.UpdateNote(context.TODO(), filter, patch))
+
+	notes, err := dao.GetNotes(context.TODO(), NoteFilter{IDs: []primitive.ObjectID{id}})
+	require.Nil(t, err)
+	require.Equal(t, "a", notes[0].Name)
+	require.Equal(t, int64(2), notes[0].Version)
+}
+
+func TestMemoryDao_UpdateNote_ShouldLetOnlyOneConcurrentWriterOfAVersionSucceed(t *testing.T) {
+	dao := NewMemoryDao()
+	id := primitive.NewObjectID()
+	require.Nil(t, dao.CreateNote(context.TODO(), models.Note{ID: id, Version: 1}))
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			version, name := int64(1), fmt.Sprint(i)
+			err := dao.UpdateNote(context.TODO(), NoteFilter{IDs: []primitive.ObjectID{id}, Version: &version}, NotePatch{
+				Name:             &name,
+				IncrementVersion: true,
+			})
+			if err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	require.Equal(t, 1, succeeded)
+}
+
+func TestMemoryDao_UpdateNotes_ShouldReturnNumberOfNotesChanged(t *testing.T) {
+	dao := NewMemoryDao()
+	require.Nil(t, dao.CreateNote(context.TODO(), models.Note{ID: primitive.NewObjectID(), Tags: []string{"a"}}))
+	require.Nil(t, dao.CreateNote(context.TODO(), models.Note{ID: primitive.NewObjectID(), Tags: []string{"a", "b"}}))
+	require.Nil(t, dao.CreateNote(context.TODO(), models.Note{ID: primitive.NewObjectID()}))
+
+	changed, err := dao.UpdateNotes(context.TODO(), NoteFilter{AllTags: []string{"a"}}, NotePatch{AddTag: "b"})
+	require.Nil(t, err)
+	require.Equal(t, int64(1), changed)
+}
+
+func TestMemoryDao_DeleteNote_ShouldDeleteOnlyFirstMatch(t *testing.T) {
+	dao := NewMemoryDao()
+	require.Nil(t, dao.CreateNote(context.TODO(), models.Note{ID: primitive.NewObjectID(), OwnerID: "user"}))
+	require.Nil(t, dao.CreateNote(context.TODO(), models.Note{ID: primitive.NewObjectID(), OwnerID: "user"}))
+
+	require.Nil(t, dao.DeleteNote(context.TODO(), NoteFilter{OwnerID: "user"}))
+	notes, err := dao.GetNotes(context.TODO(), NoteFilter{})
+	require.Nil(t, err)
+	require.Len(t, notes, 1)
+
+	deleted, err := dao.DeleteNotes(context.TODO(), NoteFilter{OwnerID: "user"})
+	require.Nil(t, err)
+	require.Equal(t, int64(1), deleted)
+	require.Equal(t, ErrNotFound, dao.DeleteNote(context.TODO(), NoteFilter{OwnerID: "user"}))
+}
+
+func TestMemoryDao_ListNotes_ShouldSortPageAndProject(t *testing.T) {
+	dao := NewMemoryDao()
+	for _, name := range []string{"c", "a", "d", "b"} {
+		require.Nil(t, dao.CreateNote(context.TODO(), models.Note{ID: primitive.NewObjectID(), Name: name, Text: "text"}))
+	}
+
+	notes, err := dao.ListNotes(context.TODO(), NoteFilter{}, ListOptions{
+		Sort:   SortByName,
+		Skip:   1,
+		Limit:  2,
+		Fields: []string{"name"},
+	})
+	require.Nil(t, err)
+	require.Len(t, notes, 2)
+	require.Equal(t, "b", notes[0].Name)
+	require.Equal(t, "c", notes[1].Name)
+	require.Empty(t, notes[0].Text)
+	require.False(t, notes[0].ID.IsZero())
+}
+
+func TestMemoryDao_SearchNotes_ShouldRankNameMatchesFirstAndHonourNegation(t *testing.T) {
+	dao := NewMemoryDao()
+	inText := models.Note{ID: primitive.NewObjectID(), Name: "Notes", Text: "how to deploy the api"}
+	inName := models.Note{ID: primitive.NewObjectID(), Name: "Deploy runbook", Text: "steps"}
+	negated := models.Note{ID: primitive.NewObjectID(), Name: "Deploy", Text: "legacy"}
+	for _, note := range []models.Note{inText, inName, negated} {
+		require.Nil(t, dao.CreateNote(context.TODO(), note))
+	}
+
+	results, err := dao.SearchNotes(context.TODO(), NoteFilter{}, "deploy -legacy", ListOptions{})
+	require.Nil(t, err)
+	require.Len(t, results, 2)
+	require.Equal(t, inName.ID, results[0].Note.ID)
+	require.Equal(t, inText.ID, results[1].Note.ID)
+	require.Greater(t, results[0].Score, results[1].Score)
+}
+
+func TestMemoryDao_SearchNotes_ShouldRequirePhrases(t *testing.T) {
+	dao := NewMemoryDao()
+	require.Nil(t, dao.CreateNote(context.TODO(), models.Note{ID: primitive.NewObjectID(), Text: "coffee shop"}))
+	require.Nil(t, dao.CreateNote(context.TODO(), models.Note{ID: primitive.NewObjectID(), Text: "shop for coffee"}))
+
+	results, err := dao.SearchNotes(context.TODO(), NoteFilter{}, `"coffee shop"`, ListOptions{})
+	require.Nil(t, err)
+	require.Len(t, results, 1)
+}
+
+func TestMemoryDao_CountTags_ShouldCountMatchingNotesPerTag(t *testing.T) {
+	dao := NewMemoryDao()
+	require.Nil(t, dao.CreateNote(context.TODO(), models.Note{ID: primitive.NewObjectID(), OwnerID: "user", Tags: []string{"a", "b"}}))
+	require.Nil(t, dao.CreateNote(context.TODO(), models.Note{ID: primitive.NewObjectID(), OwnerID: "user", Tags: []string{"b"}}))
+	require.Nil(t, dao.CreateNote(context.TODO(), models.Note{ID: primitive.NewObjectID(), OwnerID: "other", Tags: []string{"a"}}))
+
+	counts, err := dao.CountTags(context.TODO(), NoteFilter{OwnerID: "user"})
+	require.Nil(t, err)
+	require.Equal(t, []models.TagCount{{Tag: "b", Count: 2}, {Tag: "a", Count: 1}}, counts)
+}
+
+func TestMemoryDao_GetRevisions_ShouldReturnNewestFirst(t *testing.T) {
+	dao := NewMemoryDao()
+	noteID := primitive.NewObjectID()
+	first := models.Revision{ID: primitive.NewObjectID(), NoteID: noteID, Version: 1}
+	second := models.Revision{ID: primitive.NewObjectID(), NoteID: noteID, Version: 2}
+	require.Nil(t, dao.CreateRevision(context.TODO(), first))
+	require.Nil(t, dao.CreateRevision(context.TODO(), second))
+
+	revisions, err := dao.GetRevisions(context.TODO(), RevisionFilter{NoteIDs: []primitive.ObjectID{noteID}})
+	require.Nil(t, err)
+	require.Equal(t, []int64{2, 1}, []int64{revisions[0].Version, revisions[1].Version})
+
+	require.Nil(t, dao.DeleteRevisions(context.TODO(), RevisionFilter{NoteIDs: []primitive.ObjectID{noteID}}))
+	revisions, err = dao.GetRevisions(context.TODO(), RevisionFilter{NoteIDs: []primitive.ObjectID{noteID}})
+	require.Nil(t, err)
+	require.Empty(t, revisions)
+}
+
+func TestMemoryDao_UpdateNotebook_ShouldReturnNotFoundIfNothingMatches(t *testing.T) {
+	dao := NewMemoryDao()
+
+	name := "a"
+	err := dao.UpdateNotebook(context.TODO(), NotebookFilter{IDs: []primitive.ObjectID{primitive.NewObjectID()}}, NotebookPatch{Name: &name})
+	require.Equal(t, ErrNotebookNotFound, err)
+}
+
+func TestMemoryDao_GetNotebooks_ShouldReturnNotebooksByName(t *testing.T) {
+	dao := NewMemoryDao()
+	parent := models.Notebook{ID: primitive.NewObjectID(), Name: "b"}
+	child := models.Notebook{ID: primitive.NewObjectID(), Name: "a", ParentID: &parent.ID}
+	require.Nil(t, dao.CreateNotebook(context.TODO(), parent))
+	require.Nil(t, dao.CreateNotebook(context.TODO(), child))
+
+	notebooks, err := dao.GetNotebooks(context.TODO(), NotebookFilter{})
+	require.Nil(t, err)
+	require.Equal(t, "a", notebooks[0].Name)
+	require.Equal(t, parent.ID, *notebooks[0].ParentID)
+
+	deleted, err := dao.DeleteNotebooks(context.TODO(), NotebookFilter{IDs: []primitive.ObjectID{parent.ID, child.ID}})
+	require.Nil(t, err)
+	require.Equal(t, int64(2), deleted)
+}
+
+func TestMemoryDao_ClaimSaveJob_ShouldLeaseLongestDueJob(t *testing.T) {
+	dao := NewMemoryDao()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	older := models.SaveJob{ID: primitive.NewObjectID(), Status: models.SaveJobPending, NextAttemptTs: now.Add(-time.Minute)}
+	newer := models.SaveJob{ID: primitive.NewObjectID(), Status: models.SaveJobPending, NextAttemptTs: now}
+	later := models.SaveJob{ID: primitive.NewObjectID(), Status: models.SaveJobPending, NextAttemptTs: now.Add(time.Minute)}
+	for _, job := range []models.SaveJob{newer, later, older} {
+		require.Nil(t, dao.CreateSaveJob(context.TODO(), job))
+	}
+
+	lease := now.Add(time.Hour)
+	job, err := dao.ClaimSaveJob(context.TODO(), now, lease)
+	require.Nil(t, err)
+	require.Equal(t, older.ID, job.ID)
+	require.Equal(t, 1, job.Attempts)
+	require.Equal(t, lease, job.NextAttemptTs)
+
+	job, err = dao.ClaimSaveJob(context.TODO(), now, lease)
+	require.Nil(t, err)
+	require.Equal(t, newer.ID, job.ID)
+
+	job, err = dao.ClaimSaveJob(context.TODO(), now, lease)
+	require.Nil(t, err)
+	require.Nil(t, job)
+}
+
+func TestMemoryDao_CreateSaveJobIfNone_ShouldLetOnlyOneConcurrentCallCreateAJob(t *testing.T) {
+	dao := NewMemoryDao()
+	noteID := primitive.NewObjectID()
+	filter := SaveJobFilter{NoteID: &noteID, Status: models.SaveJobPending}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	created := 0
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, err := dao.CreateSaveJobIfNone(context.TODO(), filter, models.SaveJob{ID: primitive.NewObjectID(), NoteID: noteID, Status: models.SaveJobPending})
+			require.Nil(t, err)
+			if ok {
+				mu.Lock()
+				created++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.Equal(t, 1, created)
+	jobs, err := dao.GetSaveJobs(context.TODO(), SaveJobFilter{})
+	require.Nil(t, err)
+	require.Len(t, jobs, 1)
+}
+
+func TestMemoryDao_UpdateSaveJob_ShouldOnlyUpdateWhileClaimHolds(t *testing.T) {
+	dao := NewMemoryDao()
+	now := time.Now()
+	require.Nil(t, dao.CreateSaveJob(context.TODO(), models.SaveJob{ID: primitive.NewObjectID(), Status: models.SaveJobPending, Token: "token", NextAttemptTs: now}))
+
+	job, err := dao.ClaimSaveJob(context.TODO(), now, now)
+	require.Nil(t, err)
+	_, err = dao.ClaimSaveJob(context.TODO(), now, now)
+	require.Nil(t, err)
+
+	stale := job.Attempts
+	err = dao.UpdateSaveJob(context.TODO(), SaveJobFilter{IDs: []primitive.ObjectID{job.ID}, Attempts: &stale}, SaveJobPatch{Status: models.SaveJobDelivered})
+	require.Equal(t, ErrSaveJobNotFound, err)
+
+	current := stale + 1
+	err = dao.UpdateSaveJob(context.TODO(), SaveJobFilter{IDs: []primitive.ObjectID{job.ID}, Attempts: &current}, SaveJobPatch{Status: models.SaveJobDelivered, RemoveToken: true})
+	require.Nil(t, err)
+
+	jobs, err := dao.GetSaveJobs(context.TODO(), SaveJobFilter{IDs: []primitive.ObjectID{job.ID}})
+	require.Nil(t, err)
+	require.Equal(t, models.SaveJobDelivered, jobs[0].Status)
+	require.Empty(t, jobs[0].Token)
+}
@@ -0,0 +1,409 @@
+package dao
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// The in-memory DAO stores documents as they would be stored in MongoDB: models are marshalled to BSON and read back
+// into bson.M, so struct tags, omitempty and type conversions (time.Time to primitive.DateTime, slices to
+// primitive.A) apply exactly as they do for NotesDao. Filters and updates go through the same conversion before they
+// are evaluated, so both sides of every comparison have the same types.
+
+// toDocument converts a model, filter or update to its stored form.
+func toDocument(v interface{}) (bson.M, error) {
+	raw, err := bson.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc bson.M
+	if err := bson.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+// fromDocument decodes a stored document into a model.
+func fromDocument(doc bson.M, v interface{}) error {
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	return bson.Unmarshal(raw, v)
+}
+
+// matches reports whether doc matches a filter. It supports fields, including fields of embedded documents named with
+// dots, compared by equality or with $eq, $ne, $gt, $gte, $lt, $lte, $in, $nin, $all and $exists, combined with $and
+// and $or. Equality with an array field matches if any element is equal, as in MongoDB.
+func matches(doc bson.M, filter bson.M) (bool, error) {
+	for key, condition := range filter {
+		var ok bool
+		var err error
+
+		switch key {
+		case "$and", "$or":
+			ok, err = matchesLogical(doc, key, condition)
+		default:
+			if strings.HasPrefix(key, "$") {
+				return false, fmt.Errorf("unsupported filter operator %v", key)
+			}
+			value, exists := lookup(doc, key)
+			ok, err = matchesCondition(value, exists, condition)
+		}
+
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// lookup returns the value of a field of doc, following dots into embedded documents.
+func lookup(doc bson.M, key string) (interface{}, bool) {
+	path := strings.Split(key, ".")
+	for _, field := range path[:len(path)-1] {
+		embedded, ok := doc[field].(bson.M)
+		if !ok {
+			return nil, false
+		}
+		doc = embedded
+	}
+
+	value, exists := doc[path[len(path)-1]]
+	return value, exists
+}
+
+func matchesLogical(doc bson.M, operator string, condition interface{}) (bool, error) {
+	filters, ok := condition.(primitive.A)
+	if !ok {
+		return false, fmt.Errorf("%v needs an array of filters", operator)
+	}
+
+	for _, f := range filters {
+		filter, ok := f.(bson.M)
+		if !ok {
+			return false, fmt.Errorf("%v needs an array of filters", operator)
+		}
+
+		ok, err := matches(doc, filter)
+		if err != nil {
+			return false, err
+		} else if ok && operator == "$or" {
+			return true, nil
+		} else if !ok && operator == "$and" {
+			return false, nil
+		}
+	}
+
+	return operator == "$and", nil
+}
+
+func matchesCondition(value interface{}, exists bool, condition interface{}) (bool, error) {
+	operators, ok := condition.(bson.M)
+	if !ok || !isOperatorDocument(operators) {
+		return exists && matchesValue(value, condition), nil
+	}
+
+	for operator, argument := range operators {
+		var ok bool
+
+		switch operator {
+		case "$exists":
+			want, isBool := argument.(bool)
+			if !isBool {
+				return false, fmt.Errorf("$exists needs a boolean")
+			}
+			ok = exists == want
+		case "$eq":
+			ok = exists && matchesValue(value, argument)
+		case "$ne":
+			ok = !exists || !matchesValue(value, argument)
+		case "$gt", "$gte", "$lt", "$lte":
+			ok = exists && anyElement(value, func(v interface{}) bool {
+				c, comparable := compareValues(v, argument)
+				return comparable && compares(c, operator)
+			})
+		case "$in", "$nin":
+			candidates, isArray := argument.(primitive.A)
+			if !isArray {
+				return false, fmt.Errorf("%v needs an array", operator)
+			}
+			found := false
+			for _, candidate := range candidates {
+				if exists && matchesValue(value, candidate) {
+					found = true
+					break
+				}
+			}
+			ok = found == (operator == "$in")
+		case "$all":
+			required, isArray := argument.(primitive.A)
+			if !isArray {
+				return false, fmt.Errorf("$all needs an array")
+			}
+			ok = exists && len(required) > 0
+			for _, r := range required {
+				if ok && !matchesValue(value, r) {
+					ok = false
+				}
+			}
+		default:
+			return false, fmt.Errorf("unsupported filter operator %v", operator)
+		}
+
+		if !ok {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// matchesValue compares a field with a value, matching arrays that contain the value as well as equal values.
+func matchesValue(value interface{}, want interface{}) bool {
+	if valuesEqual(value, want) {
+		return true
+	}
+	if array, ok := value.(primitive.A); ok {
+		for _, element := range array {
+			if valuesEqual(element, want) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func anyElement(value interface{}, predicate func(interface{}) bool) bool {
+	if array, ok := value.(primitive.A); ok {
+		for _, element := range array {
+			if predicate(element) {
+				return true
+			}
+		}
+		return false
+	}
+	return predicate(value)
+}
+
+func compares(c int, operator string) bool {
+	switch operator {
+	case "$gt":
+		return c > 0
+	case "$gte":
+		return c >= 0
+	case "$lt":
+		return c < 0
+	default:
+		return c <= 0
+	}
+}
+
+func isOperatorDocument(doc bson.M) bool {
+	for key := range doc {
+		if !strings.HasPrefix(key, "$") {
+			return false
+		}
+	}
+	return len(doc) > 0
+}
+
+func valuesEqual(a interface{}, b interface{}) bool {
+	if c, ok := compareValues(a, b); ok {
+		return c == 0
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// compareValues orders two values of the same BSON type. Numbers of different types are compared by value. The
+// second result is false if the values cannot be ordered.
+func compareValues(a interface{}, b interface{}) (int, bool) {
+	switch x := a.(type) {
+	case string:
+		if y, ok := b.(string); ok {
+			return strings.Compare(x, y), true
+		}
+	case primitive.ObjectID:
+		if y, ok := b.(primitive.ObjectID); ok {
+			return bytes.Compare(x[:], y[:]), true
+		}
+	case primitive.DateTime:
+		if y, ok := b.(primitive.DateTime); ok {
+			return compareFloats(float64(x), float64(y)), true
+		}
+	case bool:
+		if y, ok := b.(bool); ok {
+			if x == y {
+				return 0, true
+			} else if !x {
+				return -1, true
+			}
+			return 1, true
+		}
+	default:
+		x64, xOk := toFloat(a)
+		y64, yOk := toFloat(b)
+		if xOk && yOk {
+			return compareFloats(x64, y64), true
+		}
+	}
+	return 0, false
+}
+
+func compareFloats(x float64, y float64) int {
+	if x < y {
+		return -1
+	} else if x > y {
+		return 1
+	}
+	return 0
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+// applyUpdate applies $set, $unset, $inc, $addToSet and $pull updates to doc in place.
+func applyUpdate(doc bson.M, updates bson.M) error {
+	for operator, argument := range updates {
+		fields, ok := argument.(bson.M)
+		if !ok {
+			return fmt.Errorf("%v needs a document", operator)
+		}
+
+		for key, value := range fields {
+			switch operator {
+			case "$set":
+				doc[key] = value
+			case "$unset":
+				delete(doc, key)
+			case "$inc":
+				current, exists := doc[key]
+				if !exists {
+					doc[key] = value
+					continue
+				}
+				sum, err := addNumbers(current, value)
+				if err != nil {
+					return err
+				}
+				doc[key] = sum
+			case "$addToSet":
+				array, _ := doc[key].(primitive.A)
+				if !matchesValue(array, value) {
+					array = append(array, value)
+				}
+				doc[key] = array
+			case "$pull":
+				array, _ := doc[key].(primitive.A)
+				kept := primitive.A{}
+				for _, element := range array {
+					if !valuesEqual(element, value) {
+						kept = append(kept, element)
+					}
+				}
+				if _, exists := doc[key]; exists {
+					doc[key] = kept
+				}
+			default:
+				return fmt.Errorf("unsupported update operator %v", operator)
+			}
+		}
+	}
+
+	return nil
+}
+
+func addNumbers(a interface{}, b interface{}) (interface{}, error) {
+	switch x := a.(type) {
+	case int32:
+		if y, ok := b.(int32); ok {
+			return x + y, nil
+		}
+	case int64:
+		switch y := b.(type) {
+		case int32:
+			return x + int64(y), nil
+		case int64:
+			return x + y, nil
+		}
+	}
+
+	x, xOk := toFloat(a)
+	y, yOk := toFloat(b)
+	if !xOk || !yOk {
+		return nil, fmt.Errorf("cannot increment non-numeric value")
+	}
+	return x + y, nil
+}
+
+// project keeps only the fields of doc included by projection, and _id.
+func project(doc bson.M, projection bson.M) bson.M {
+	if len(projection) == 0 {
+		return doc
+	}
+
+	projected := bson.M{"_id": doc["_id"]}
+	for key := range projection {
+		if value, exists := doc[key]; exists {
+			projected[key] = value
+		}
+	}
+	return projected
+}
+
+// sortDocuments sorts docs by the keys of sortBy, 1 for ascending and -1 for descending. Missing fields sort first.
+func sortDocuments(docs []bson.M, sortBy bson.D) {
+	sort.SliceStable(docs, func(i, j int) bool {
+		for _, key := range sortBy {
+			a, aExists := docs[i][key.Key]
+			b, bExists := docs[j][key.Key]
+
+			var c int
+			switch {
+			case !aExists && !bExists:
+				c = 0
+			case !aExists:
+				c = -1
+			case !bExists:
+				c = 1
+			default:
+				c, _ = compareValues(a, b)
+			}
+
+			if c != 0 {
+				if isDescending(key.Value) {
+					return c > 0
+				}
+				return c < 0
+			}
+		}
+		return false
+	})
+}
+
+func isDescending(direction interface{}) bool {
+	if n, ok := direction.(int); ok {
+		return n < 0
+	}
+	n, _ := toFloat(direction)
+	return n < 0
+}
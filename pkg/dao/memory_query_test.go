@@ -0,0 +1,113 @@
+package dao
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMemoryQuery_Matches_ShouldEvaluateOperators(t *testing.T) {
+	id := primitive.NewObjectID()
+	now := time.Now()
+	doc, err := toDocument(bson.M{
+		"_id":          id,
+		"ownerId":      "user",
+		"version":      int64(3),
+		"tags":         []string{"a", "b"},
+		"lastEditedTs": now,
+		"export":       bson.M{"fileId": "f", "exportedTs": now},
+	})
+	require.Nil(t, err)
+
+	for name, test := range map[string]struct {
+		filter map[string]interface{}
+		want   bool
+	}{
+		"equal":              {map[string]interface{}{"ownerId": "user", "_id": id}, true},
+		"not equal":          {map[string]interface{}{"ownerId": "other"}, false},
+		"array contains":     {map[string]interface{}{"tags": "a"}, true},
+		"exists":             {map[string]interface{}{"version": bson.M{"$exists": true}}, true},
+		"not exists":         {map[string]interface{}{"deletedAt": bson.M{"$exists": false}}, true},
+		"missing field":      {map[string]interface{}{"deletedAt": "x"}, false},
+		"number types":       {map[string]interface{}{"version": 3}, true},
+		"less than time":     {map[string]interface{}{"lastEditedTs": bson.M{"$lt": now.Add(time.Second)}}, true},
+		"greater than id":    {map[string]interface{}{"_id": bson.M{"$gt": id}}, false},
+		"in":                 {map[string]interface{}{"_id": bson.M{"$in": []primitive.ObjectID{primitive.NewObjectID(), id}}}, true},
+		"in array":           {map[string]interface{}{"tags": bson.M{"$in": []string{"c", "b"}}}, true},
+		"all":                {map[string]interface{}{"tags": bson.M{"$all": []string{"a", "b"}}}, true},
+		"not all":            {map[string]interface{}{"tags": bson.M{"$all": []string{"a", "c"}}}, false},
+		"ne":                 {map[string]interface{}{"ownerId": bson.M{"$ne": "user"}}, false},
+		"or":                 {map[string]interface{}{"$or": []bson.M{{"ownerId": "other"}, {"version": int64(3)}}}, true},
+		"or with none":       {map[string]interface{}{"$or": []bson.M{{"ownerId": "other"}, {"version": int64(4)}}}, false},
+		"and":                {map[string]interface{}{"$and": []bson.M{{"ownerId": "user"}, {"version": int64(4)}}}, false},
+		"several conditions": {map[string]interface{}{"version": bson.M{"$gte": 3, "$lt": 4}}, true},
+		"embedded":           {map[string]interface{}{"export.exportedTs": bson.M{"$lt": now.Add(time.Second)}}, true},
+		"embedded missing":   {map[string]interface{}{"export.url": bson.M{"$exists": true}}, false},
+		"not embedded":       {map[string]interface{}{"ownerId.id": bson.M{"$exists": false}}, true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			query, err := toDocument(test.filter)
+			require.Nil(t, err)
+
+			ok, err := matches(doc, query)
+			require.Nil(t, err)
+			require.Equal(t, test.want, ok)
+		})
+	}
+}
+
+func TestMemoryQuery_Matches_ShouldReturnErrorForUnsupportedOperator(t *testing.T) {
+	query, err := toDocument(bson.M{"name": bson.M{"$regex": "a"}})
+	require.Nil(t, err)
+
+	_, err = matches(bson.M{"name": "a"}, query)
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "$regex")
+}
+
+func TestMemoryQuery_ApplyUpdate_ShouldApplyOperators(t *testing.T) {
+	doc, err := toDocument(bson.M{"name": "a", "version": int64(1), "tags": []string{"x", "y"}, "deletedAt": time.Now()})
+	require.Nil(t, err)
+
+	updates, err := toDocument(bson.M{
+		"$set":      bson.M{"name": "b"},
+		"$inc":      bson.M{"version": 1},
+		"$unset":    bson.M{"deletedAt": ""},
+		"$addToSet": bson.M{"tags": "z"},
+	})
+	require.Nil(t, err)
+	require.Nil(t, applyUpdate(doc, updates))
+
+	pull, err := toDocument(bson.M{"$pull": bson.M{"tags": "x"}})
+	require.Nil(t, err)
+	require.Nil(t, applyUpdate(doc, pull))
+
+	require.Equal(t, bson.M{"name": "b", "version": int64(2), "tags": primitive.A{"y", "z"}}, doc)
+}
+
+func TestMemoryQuery_ApplyUpdate_ShouldNotAddExistingValueToSet(t *testing.T) {
+	doc := bson.M{"tags": primitive.A{"x"}}
+
+	require.Nil(t, applyUpdate(doc, bson.M{"$addToSet": bson.M{"tags": "x"}}))
+	require.Equal(t, primitive.A{"x"}, doc["tags"])
+}
+
+func TestMemoryQuery_SortDocuments_ShouldSortByKeysInOrder(t *testing.T) {
+	docs := []bson.M{
+		{"name": "b", "n": int32(1)},
+		{"name": "a", "n": int32(1)},
+		{"name": "c", "n": int32(2)},
+		{"n": int32(1)},
+	}
+
+	sortDocuments(docs, bson.D{{Key: "n", Value: -1}, {Key: "name", Value: 1}})
+	require.Equal(t, []bson.M{
+		{"name": "c", "n": int32(2)},
+		{"n": int32(1)},
+		{"name": "a", "n": int32(1)},
+		{"name": "b", "n": int32(1)},
+	}, docs)
+}
@@ -0,0 +1,18 @@
+package dao
+
+import (
+	"context"
+	"errors"
+
+	"notes-api/pkg/models"
+)
+
+// ErrNotebookNotFound is the notebook equivalent of ErrNotFound.
+var ErrNotebookNotFound = errors.New("notebook not found")
+
+type NotebookDaoHandler interface {
+	GetNotebooks(ctx context.Context, filter NotebookFilter) ([]models.Notebook, error)
+	CreateNotebook(ctx context.Context, notebook models.Notebook) error
+	UpdateNotebook(ctx context.Context, filter NotebookFilter, patch NotebookPatch) error
+	DeleteNotebooks(ctx context.Context, filter NotebookFilter) (int64, error)
+}
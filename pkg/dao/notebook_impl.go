@@ -0,0 +1,65 @@
+package dao
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"notes-api/pkg/models"
+)
+
+type NotebooksDao struct {
+	Client     *mongo.Client
+	Database   string
+	Collection string
+}
+
+// GetNotebooks returns the notebooks matching filter, ordered by name.
+func (dao *NotebooksDao) GetNotebooks(ctx context.Context, filter NotebookFilter) ([]models.Notebook, error) {
+	cursor, err := dao.getCollection().Find(ctx, filter.query(), options.Find().SetSort(bson.D{{Key: "name", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	var notebooks []models.Notebook
+	if err := cursor.All(ctx, &notebooks); err != nil {
+		return nil, err
+	}
+
+	return notebooks, nil
+}
+
+func (dao *NotebooksDao) CreateNotebook(ctx context.Context, notebook models.Notebook) error {
+	_, err := dao.getCollection().InsertOne(ctx, notebook)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (dao *NotebooksDao) UpdateNotebook(ctx context.Context, filter NotebookFilter, patch NotebookPatch) error {
+	result := dao.getCollection().FindOneAndUpdate(ctx, filter.query(), patch.update())
+	if errors.Is(result.Err(), mongo.ErrNoDocuments) {
+		return ErrNotebookNotFound
+	} else if result.Err() != nil {
+		return result.Err()
+	}
+
+	return nil
+}
+
+func (dao *NotebooksDao) DeleteNotebooks(ctx context.Context, filter NotebookFilter) (int64, error) {
+	result, err := dao.getCollection().DeleteMany(ctx, filter.query())
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+func (dao *NotebooksDao) getCollection() *mongo.Collection {
+	return dao.Client.Database(dao.Database).Collection(dao.Collection)
+}
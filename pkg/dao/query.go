@@ -0,0 +1,472 @@
+package dao
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"notes-api/pkg/models"
+)
+
+// Trash selects notes by whether they are in the trash.
+type Trash int
+
+const (
+	// ExcludeTrash selects notes that are not in the trash. It is the zero value, so a filter leaves out the trash
+	// unless it asks for it.
+	ExcludeTrash Trash = iota
+	// OnlyTrash selects notes that are in the trash.
+	OnlyTrash
+	// AnyTrash selects notes whether or not they are in the trash.
+	AnyTrash
+)
+
+// NoteFilter selects notes. Every field that is set narrows the selection.
+//
+// A nil list of IDs does not narrow the selection, but an empty one selects nothing, so that a filter built from an
+// empty list can never select every note.
+type NoteFilter struct {
+	// IDs selects the notes with any of the given IDs.
+	IDs     []primitive.ObjectID
+	OwnerID string
+	// Version selects the notes at the given version. Version 0 selects notes created before versioning.
+	Version *int64
+	Trash   Trash
+	// DeletedBefore selects the notes moved to the trash before the given time, whatever Trash is.
+	DeletedBefore time.Time
+	// AllTags selects the notes that have every one of the given tags, and AnyTags those that have at least one.
+	AllTags []string
+	AnyTags []string
+	// NotebookIDs selects the notes in any of the given notebooks.
+	NotebookIDs []primitive.ObjectID
+	// NotExportedAfter selects the notes that have not been exported, or whose export was taken no later than the given
+	// time. Exports taken in the same millisecond, the precision times are stored at, count as no later.
+	NotExportedAfter *time.Time
+	// TextHashes selects the notes whose text has any of the given TextHash values.
+	TextHashes []string
+}
+
+// TextHash returns the hash of a note's text that is stored as its TextHash.
+func TextHash(text string) string {
+	hash := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(hash[:])
+}
+
+// NotePatch is a change to notes. Fields that are not set are left unchanged.
+type NotePatch struct {
+	Name *string
+	// Text replaces the text of the notes, and their TextHash with it.
+	Text *string
+	// Tags replaces the tags of the notes if it is not nil.
+	Tags         []string
+	LastEditedTs *time.Time
+	// IncrementVersion adds one to the version of the notes.
+	IncrementVersion bool
+	// DeletedAt moves the notes to the trash at the given time, and Restore takes them out of it.
+	DeletedAt *time.Time
+	Restore   bool
+	// NotebookID moves the notes into a notebook, and RemoveFromNotebook moves them out of any notebook.
+	NotebookID         *primitive.ObjectID
+	RemoveFromNotebook bool
+	// AddTag adds a tag to the notes that do not have it, and RemoveTag removes one. A patch that changes tags may
+	// only set one of Tags, AddTag and RemoveTag.
+	AddTag    string
+	RemoveTag string
+	// Export records a copy of the notes delivered to the content service.
+	Export *models.NoteExport
+	// AutoSync turns automatic re-uploads of the notes on or off.
+	AutoSync *bool
+}
+
+// NoteSort is the order of a listing of notes. Notes with the same sort value are ordered by ID, in the same
+// direction.
+type NoteSort string
+
+const (
+	SortByLastEdited NoteSort = "lastEditedTs"
+	SortByName       NoteSort = "name"
+	SortByCreated    NoteSort = "created"
+)
+
+// ListOptions controls the order, size and shape of the result of ListNotes and SearchNotes.
+type ListOptions struct {
+	// Sort is the order of a listing; without it, notes are in no particular order. SearchNotes ignores it.
+	Sort       NoteSort
+	Descending bool
+	// After continues a sorted listing after the given note. Only its ID and the sort field are read.
+	After *models.Note
+	Skip  int64
+	Limit int64
+	// Fields are the JSON names of the note fields to read, or all of them if empty. The ID and the sort field are
+	// always read, so that a listing can be continued after its last note.
+	Fields []string
+}
+
+// RevisionFilter selects revisions the way NoteFilter selects notes.
+type RevisionFilter struct {
+	IDs     []primitive.ObjectID
+	NoteIDs []primitive.ObjectID
+	OwnerID string
+}
+
+// NotebookFilter selects notebooks the way NoteFilter selects notes.
+type NotebookFilter struct {
+	IDs     []primitive.ObjectID
+	OwnerID string
+}
+
+// NotebookPatch is a change to a notebook. Fields that are not set are left unchanged.
+type NotebookPatch struct {
+	Name         *string
+	LastEditedTs *time.Time
+	// ParentID moves the notebook into another notebook, and MoveToTop moves it to the top level.
+	ParentID  *primitive.ObjectID
+	MoveToTop bool
+}
+
+// SaveJobFilter selects save jobs the way NoteFilter selects notes.
+type SaveJobFilter struct {
+	IDs     []primitive.ObjectID
+	OwnerID string
+	// Attempts selects the jobs that have been attempted the given number of times. A worker that sets it to the
+	// attempts of the job it claimed only updates the job while its claim holds.
+	Attempts *int
+	NoteID   *primitive.ObjectID
+	Status   string
+	// AutoSync selects only the jobs queued by updates of notes that are synced automatically.
+	AutoSync bool
+}
+
+// SaveJobPatch is a change to a save job. Fields that are not set are left unchanged.
+type SaveJobPatch struct {
+	Status        string
+	LastError     *string
+	NextAttemptTs *time.Time
+	FinishedTs    *time.Time
+	RemoveToken   bool
+	File          *models.UploadedFile
+}
+
+// The methods below translate filters, patches and options to MongoDB queries, updates, sorts and projections.
+// documentDao evaluates the same documents, so both kinds of backend share one translation.
+
+func (f NoteFilter) query() bson.M {
+	query := bson.M{}
+	if f.IDs != nil {
+		query["_id"] = bson.M{"$in": f.IDs}
+	}
+	if f.OwnerID != "" {
+		query["ownerId"] = f.OwnerID
+	}
+	if f.Version != nil {
+		if *f.Version == 0 {
+			// Notes created before versioning have no version field.
+			query["version"] = bson.M{"$exists": false}
+		} else {
+			query["version"] = *f.Version
+		}
+	}
+
+	switch {
+	case !f.DeletedBefore.IsZero():
+		query["deletedAt"] = bson.M{"$lt": f.DeletedBefore}
+	case f.Trash == ExcludeTrash:
+		query["deletedAt"] = bson.M{"$exists": false}
+	case f.Trash == OnlyTrash:
+		query["deletedAt"] = bson.M{"$exists": true}
+	}
+
+	tags := bson.M{}
+	if len(f.AllTags) > 0 {
+		tags["$all"] = f.AllTags
+	}
+	if len(f.AnyTags) > 0 {
+		tags["$in"] = f.AnyTags
+	}
+	if len(tags) > 0 {
+		query["tags"] = tags
+	}
+
+	if f.NotebookIDs != nil {
+		query["notebookId"] = bson.M{"$in": f.NotebookIDs}
+	}
+
+	if f.NotExportedAfter != nil {
+		query["$or"] = []bson.M{
+			{"export": bson.M{"$exists": false}},
+			{"export.exportedTs": bson.M{"$lte": *f.NotExportedAfter}},
+		}
+	}
+
+	if f.TextHashes != nil {
+		query["textHash"] = bson.M{"$in": f.TextHashes}
+	}
+
+	return query
+}
+
+func (p NotePatch) update() bson.M {
+	set, unset := bson.M{}, bson.M{}
+	if p.Name != nil {
+		set["name"] = *p.Name
+	}
+	if p.Text != nil {
+		set["text"] = *p.Text
+		set["textHash"] = TextHash(*p.Text)
+	}
+	if p.Tags != nil {
+		set["tags"] = p.Tags
+	}
+	if p.LastEditedTs != nil {
+		set["lastEditedTs"] = *p.LastEditedTs
+	}
+	if p.DeletedAt != nil {
+		set["deletedAt"] = *p.DeletedAt
+	}
+	if p.Restore {
+		unset["deletedAt"] = ""
+	}
+	if p.NotebookID != nil {
+		set["notebookId"] = *p.NotebookID
+	}
+	if p.RemoveFromNotebook {
+		unset["notebookId"] = ""
+	}
+	if p.Export != nil {
+		set["export"] = *p.Export
+	}
+	if p.AutoSync != nil {
+		set["autoSync"] = *p.AutoSync
+	}
+
+	update := bson.M{}
+	if len(set) > 0 {
+		update["$set"] = set
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	if p.IncrementVersion {
+		update["$inc"] = bson.M{"version": 1}
+	}
+	if p.AddTag != "" {
+		update["$addToSet"] = bson.M{"tags": p.AddTag}
+	}
+	if p.RemoveTag != "" {
+		update["$pull"] = bson.M{"tags": p.RemoveTag}
+	}
+
+	return update
+}
+
+// query returns the query for the notes of filter that come after opts.After in the order of the listing.
+func (opts ListOptions) query(filter NoteFilter) bson.M {
+	query := filter.query()
+
+	key := opts.sortKey()
+	if opts.After == nil || key == "" {
+		return query
+	}
+
+	operator := "$gt"
+	if opts.Descending {
+		operator = "$lt"
+	}
+
+	var after bson.M
+	if key == "_id" {
+		after = bson.M{"_id": bson.M{operator: opts.After.ID}}
+	} else {
+		var value interface{} = opts.After.Name
+		if key == "lastEditedTs" {
+			value = opts.After.LastEditedTs
+		}
+
+		after = bson.M{"$or": []bson.M{
+			{key: bson.M{operator: value}},
+			{key: value, "_id": bson.M{operator: opts.After.ID}},
+		}}
+	}
+
+	// The condition is added to the filter's query, unless the query already constrains the same key.
+	for k := range after {
+		if _, ok := query[k]; ok {
+			return bson.M{"$and": []bson.M{query, after}}
+		}
+	}
+	for k, v := range after {
+		query[k] = v
+	}
+	return query
+}
+
+func (opts ListOptions) sortKey() string {
+	switch opts.Sort {
+	case SortByLastEdited:
+		return "lastEditedTs"
+	case SortByName:
+		return "name"
+	case SortByCreated:
+		return "_id"
+	default:
+		return ""
+	}
+}
+
+func (opts ListOptions) sort() bson.D {
+	key := opts.sortKey()
+	if key == "" {
+		return nil
+	}
+
+	direction := 1
+	if opts.Descending {
+		direction = -1
+	}
+
+	sort := bson.D{{Key: key, Value: direction}}
+	if key != "_id" {
+		sort = append(sort, bson.E{Key: "_id", Value: direction})
+	}
+	return sort
+}
+
+func (opts ListOptions) projection() bson.M {
+	if len(opts.Fields) == 0 {
+		return nil
+	}
+
+	projection := bson.M{"_id": 1}
+	if key := opts.sortKey(); key != "" {
+		projection[key] = 1
+	}
+	for _, field := range opts.Fields {
+		// Notes are stored with the same field names as their JSON, except for the ID.
+		if field == "id" {
+			field = "_id"
+		}
+		projection[field] = 1
+	}
+	return projection
+}
+
+func (f RevisionFilter) query() bson.M {
+	query := bson.M{}
+	if f.IDs != nil {
+		query["_id"] = bson.M{"$in": f.IDs}
+	}
+	if f.NoteIDs != nil {
+		query["noteId"] = bson.M{"$in": f.NoteIDs}
+	}
+	if f.OwnerID != "" {
+		query["ownerId"] = f.OwnerID
+	}
+	return query
+}
+
+func (f NotebookFilter) query() bson.M {
+	query := bson.M{}
+	if f.IDs != nil {
+		query["_id"] = bson.M{"$in": f.IDs}
+	}
+	if f.OwnerID != "" {
+		query["ownerId"] = f.OwnerID
+	}
+	return query
+}
+
+func (p NotebookPatch) update() bson.M {
+	set, unset := bson.M{}, bson.M{}
+	if p.Name != nil {
+		set["name"] = *p.Name
+	}
+	if p.LastEditedTs != nil {
+		set["lastEditedTs"] = *p.LastEditedTs
+	}
+	if p.ParentID != nil {
+		set["parentId"] = *p.ParentID
+	}
+	if p.MoveToTop {
+		unset["parentId"] = ""
+	}
+
+	update := bson.M{}
+	if len(set) > 0 {
+		update["$set"] = set
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	return update
+}
+
+func (f SaveJobFilter) query() bson.M {
+	query := bson.M{}
+	if f.IDs != nil {
+		query["_id"] = bson.M{"$in": f.IDs}
+	}
+	if f.OwnerID != "" {
+		query["ownerId"] = f.OwnerID
+	}
+	if f.Attempts != nil {
+		query["attempts"] = *f.Attempts
+	}
+	if f.NoteID != nil {
+		query["noteId"] = *f.NoteID
+	}
+	if f.Status != "" {
+		query["status"] = f.Status
+	}
+	if f.AutoSync {
+		query["autoSync"] = true
+	}
+	return query
+}
+
+func (p SaveJobPatch) update() bson.M {
+	set, unset := bson.M{}, bson.M{}
+	if p.Status != "" {
+		set["status"] = p.Status
+	}
+	if p.LastError != nil {
+		set["lastError"] = *p.LastError
+	}
+	if p.NextAttemptTs != nil {
+		set["nextAttemptTs"] = *p.NextAttemptTs
+	}
+	if p.FinishedTs != nil {
+		set["finishedTs"] = *p.FinishedTs
+	}
+	if p.RemoveToken {
+		unset["token"] = ""
+	}
+	if p.File != nil {
+		set["file"] = *p.File
+	}
+
+	update := bson.M{}
+	if len(set) > 0 {
+		update["$set"] = set
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	return update
+}
+
+// claimSaveJob returns the query, update and sort that claim the pending save job that has been due the longest.
+func claimSaveJob(now time.Time, leaseUntil time.Time) (bson.M, bson.M, bson.D) {
+	query := bson.M{
+		"status":        models.SaveJobPending,
+		"nextAttemptTs": bson.M{"$lte": now},
+	}
+	update := bson.M{
+		"$set": bson.M{"nextAttemptTs": leaseUntil},
+		"$inc": bson.M{"attempts": 1},
+	}
+	sort := bson.D{{Key: "nextAttemptTs", Value: 1}, {Key: "_id", Value: 1}}
+	return query, update, sort
+}
@@ -0,0 +1,118 @@
+package dao
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"notes-api/pkg/models"
+)
+
+func TestQuery_NoteFilter_ShouldSelectNotes(t *testing.T) {
+	id, notebookID := primitive.NewObjectID(), primitive.NewObjectID()
+	deletedAt := time.Now()
+	note, err := toDocument(models.Note{ID: id, OwnerID: "user", Version: 2, Tags: []string{"a", "b"}, NotebookID: &notebookID})
+	require.Nil(t, err)
+	trashed, err := toDocument(models.Note{ID: id, OwnerID: "user", DeletedAt: &deletedAt})
+	require.Nil(t, err)
+	unversioned, err := toDocument(bson.M{"_id": id, "ownerId": "user"})
+	require.Nil(t, err)
+	exported, err := toDocument(models.Note{ID: id, OwnerID: "user", Export: &models.NoteExport{ExportedTs: deletedAt}})
+	require.Nil(t, err)
+	hashed, err := toDocument(models.Note{ID: id, OwnerID: "user", Text: "text", TextHash: TextHash("text")})
+	require.Nil(t, err)
+
+	version, zero := int64(2), int64(0)
+	before, after := deletedAt.Add(-time.Second), deletedAt.Add(time.Second)
+	for name, test := range map[string]struct {
+		filter NoteFilter
+		doc    bson.M
+		want   bool
+	}{
+		"empty":                  {NoteFilter{}, note, true},
+		"owner":                  {NoteFilter{OwnerID: "other"}, note, false},
+		"ids":                    {NoteFilter{IDs: []primitive.ObjectID{primitive.NewObjectID(), id}}, note, true},
+		"empty ids":              {NoteFilter{IDs: []primitive.ObjectID{}}, note, false},
+		"version":                {NoteFilter{Version: &version}, note, true},
+		"version zero":           {NoteFilter{Version: &zero}, note, false},
+		"unversioned":            {NoteFilter{Version: &zero}, unversioned, true},
+		"trash excluded":         {NoteFilter{}, trashed, false},
+		"only trash":             {NoteFilter{Trash: OnlyTrash}, note, false},
+		"any trash":              {NoteFilter{Trash: AnyTrash}, trashed, true},
+		"deleted before":         {NoteFilter{DeletedBefore: deletedAt.Add(time.Second)}, trashed, true},
+		"deleted after":          {NoteFilter{DeletedBefore: deletedAt.Add(-time.Second)}, trashed, false},
+		"all tags":               {NoteFilter{AllTags: []string{"a", "c"}}, note, false},
+		"any tags":               {NoteFilter{AnyTags: []string{"c", "b"}}, note, true},
+		"notebooks":              {NoteFilter{NotebookIDs: []primitive.ObjectID{notebookID}}, note, true},
+		"notebooks without note": {NoteFilter{NotebookIDs: []primitive.ObjectID{notebookID}}, unversioned, false},
+		"never exported":         {NoteFilter{NotExportedAfter: &before}, note, true},
+		"exported before":        {NoteFilter{NotExportedAfter: &after}, exported, true},
+		"exported at":            {NoteFilter{NotExportedAfter: &deletedAt}, exported, true},
+		"exported after":         {NoteFilter{NotExportedAfter: &before}, exported, false},
+		"text hashes":            {NoteFilter{TextHashes: []string{TextHash("other"), TextHash("text")}}, hashed, true},
+		"other text hashes":      {NoteFilter{TextHashes: []string{TextHash("other")}}, hashed, false},
+		"unhashed":               {NoteFilter{TextHashes: []string{TextHash("")}}, note, false},
+	} {
+		query, err := toDocument(test.filter.query())
+		require.Nil(t, err, name)
+		got, err := matches(test.doc, query)
+		require.Nil(t, err, name)
+		require.Equal(t, test.want, got, name)
+	}
+}
+
+func TestQuery_NotePatch_ShouldTranslateToUpdate(t *testing.T) {
+	name, now, notebookID := "a", time.Now(), primitive.NewObjectID()
+
+	require.Equal(t, bson.M{
+		"$set": bson.M{"name": name, "tags": []string{}, "lastEditedTs": now, "notebookId": notebookID},
+		"$inc": bson.M{"version": 1},
+	}, NotePatch{Name: &name, Tags: []string{}, LastEditedTs: &now, NotebookID: &notebookID, IncrementVersion: true}.update())
+	require.Equal(t, bson.M{
+		"$unset": bson.M{"deletedAt": "", "notebookId": ""},
+	}, NotePatch{Restore: true, RemoveFromNotebook: true}.update())
+	require.Equal(t, bson.M{"$addToSet": bson.M{"tags": "a"}}, NotePatch{AddTag: "a"}.update())
+	require.Equal(t, bson.M{"$pull": bson.M{"tags": "a"}}, NotePatch{RemoveTag: "a"}.update())
+
+	text := "text"
+	require.Equal(t, bson.M{
+		"$set": bson.M{"text": text, "textHash": "982d9e3eb996f559e633f4d194def3761d909f5a3b647d1a851fead67c32c9d1"},
+	}, NotePatch{Text: &text}.update())
+}
+
+func TestQuery_ListOptions_ShouldContinueAfterNote(t *testing.T) {
+	after := models.Note{ID: primitive.NewObjectID(), Name: "b"}
+
+	query := ListOptions{Sort: SortByName, After: &after}.query(NoteFilter{OwnerID: "user"})
+	require.Equal(t, bson.M{
+		"ownerId":   "user",
+		"deletedAt": bson.M{"$exists": false},
+		"$or": []bson.M{
+			{"name": bson.M{"$gt": "b"}},
+			{"name": "b", "_id": bson.M{"$gt": after.ID}},
+		},
+	}, query)
+
+	query = ListOptions{Sort: SortByCreated, Descending: true, After: &after}.query(NoteFilter{OwnerID: "user"})
+	require.Equal(t, bson.M{
+		"ownerId":   "user",
+		"deletedAt": bson.M{"$exists": false},
+		"_id":       bson.M{"$lt": after.ID},
+	}, query)
+
+	filter := NoteFilter{IDs: []primitive.ObjectID{after.ID}}
+	query = ListOptions{Sort: SortByCreated, After: &after}.query(filter)
+	require.Equal(t, bson.M{"$and": []bson.M{filter.query(), {"_id": bson.M{"$gt": after.ID}}}}, query)
+}
+
+func TestQuery_ListOptions_ShouldSortAndProjectWithIDAndSortField(t *testing.T) {
+	opts := ListOptions{Sort: SortByLastEdited, Descending: true, Fields: []string{"id", "name"}}
+
+	require.Equal(t, bson.D{{Key: "lastEditedTs", Value: -1}, {Key: "_id", Value: -1}}, opts.sort())
+	require.Equal(t, bson.M{"_id": 1, "lastEditedTs": 1, "name": 1}, opts.projection())
+	require.Nil(t, ListOptions{}.sort())
+	require.Nil(t, ListOptions{}.projection())
+}
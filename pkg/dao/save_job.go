@@ -0,0 +1,22 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"notes-api/pkg/models"
+)
+
+// ErrSaveJobNotFound is the save job equivalent of ErrNotFound.
+var ErrSaveJobNotFound = errors.New("save job not found")
+
+type SaveJobDaoHandler interface {
+	CreateSaveJob(ctx context.Context, job models.SaveJob) error
+	// CreateSaveJobIfNone creates the job unless a job matches filter, and tells whether it did. The check and the
+	// creation are one step, so that of concurrent calls with the same filter only one creates its job.
+	CreateSaveJobIfNone(ctx context.Context, filter SaveJobFilter, job models.SaveJob) (bool, error)
+	GetSaveJobs(ctx context.Context, filter SaveJobFilter) ([]models.SaveJob, error)
+	ClaimSaveJob(ctx context.Context, now time.Time, leaseUntil time.Time) (*models.SaveJob, error)
+	UpdateSaveJob(ctx context.Context, filter SaveJobFilter, patch SaveJobPatch) error
+}
@@ -0,0 +1,118 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"notes-api/pkg/models"
+)
+
+type SaveJobsDao struct {
+	Client     *mongo.Client
+	Database   string
+	Collection string
+}
+
+func (dao *SaveJobsDao) CreateSaveJob(ctx context.Context, job models.SaveJob) error {
+	_, err := dao.getCollection().InsertOne(ctx, job)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// CreateSaveJobIfNone upserts the job on filter. Concurrent upserts can both insert unless a unique index covers the
+// filter, as noteId_pendingSync covers the sync jobs of a note that have not been attempted; the loser of such a race
+// gets a duplicate key error, which means that a job exists.
+func (dao *SaveJobsDao) CreateSaveJobIfNone(ctx context.Context, filter SaveJobFilter, job models.SaveJob) (bool, error) {
+	result, err := dao.getCollection().UpdateOne(ctx, filter.query(), bson.M{"$setOnInsert": job}, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return result.UpsertedCount > 0, nil
+}
+
+func (dao *SaveJobsDao) GetSaveJobs(ctx context.Context, filter SaveJobFilter) ([]models.SaveJob, error) {
+	cursor, err := dao.getCollection().Find(ctx, filter.query())
+	if err != nil {
+		return nil, err
+	}
+
+	var jobs []models.SaveJob
+	if err := cursor.All(ctx, &jobs); err != nil {
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+// ClaimSaveJob takes the pending job that has been due the longest, counts an attempt and hides it from other claims
+// until leaseUntil, and returns it as updated. It returns nil if no job is due. A job whose worker stops before
+// finishing it is claimed again once its lease runs out.
+func (dao *SaveJobsDao) ClaimSaveJob(ctx context.Context, now time.Time, leaseUntil time.Time) (*models.SaveJob, error) {
+	query, update, sort := claimSaveJob(now, leaseUntil)
+
+	result := dao.getCollection().FindOneAndUpdate(ctx, query, update, options.FindOneAndUpdate().
+		SetSort(sort).
+		SetReturnDocument(options.After))
+	if errors.Is(result.Err(), mongo.ErrNoDocuments) {
+		return nil, nil
+	} else if result.Err() != nil {
+		return nil, result.Err()
+	}
+
+	var job models.SaveJob
+	if err := result.Decode(&job); err != nil {
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+func (dao *SaveJobsDao) UpdateSaveJob(ctx context.Context, filter SaveJobFilter, patch SaveJobPatch) error {
+	result := dao.getCollection().FindOneAndUpdate(ctx, filter.query(), patch.update())
+	if errors.Is(result.Err(), mongo.ErrNoDocuments) {
+		return ErrSaveJobNotFound
+	} else if result.Err() != nil {
+		return result.Err()
+	}
+
+	return nil
+}
+
+// EnsureIndexes creates the index that claims are served from, the one that finds the pending jobs of a note, and the
+// one that allows a note a single sync job that has not been attempted.
+func (dao *SaveJobsDao) EnsureIndexes(ctx context.Context) error {
+	_, err := dao.getCollection().Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "status", Value: 1}, {Key: "nextAttemptTs", Value: 1}},
+			Options: options.Index().SetName("status_nextAttemptTs"),
+		},
+		{
+			Keys:    bson.D{{Key: "noteId", Value: 1}, {Key: "status", Value: 1}},
+			Options: options.Index().SetName("noteId_status"),
+		},
+		{
+			Keys: bson.D{{Key: "noteId", Value: 1}},
+			Options: options.Index().SetName("noteId_pendingSync").SetUnique(true).SetPartialFilterExpression(bson.M{
+				"autoSync": true,
+				"status":   models.SaveJobPending,
+				"attempts": 0,
+			}),
+		},
+	})
+	return err
+}
+
+func (dao *SaveJobsDao) getCollection() *mongo.Collection {
+	return dao.Client.Database(dao.Database).Collection(dao.Collection)
+}
@@ -0,0 +1,70 @@
+package export
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"notes-api/pkg/models"
+)
+
+// Exporter renders a note as a file of one format.
+type Exporter interface {
+	// Extension is the file extension of the format, without the leading dot.
+	Extension() string
+	ContentType() string
+	Export(note models.Note) ([]byte, error)
+}
+
+// The formats of the built-in exporters.
+const (
+	FormatText     = "txt"
+	FormatMarkdown = "md"
+	FormatHTML     = "html"
+	FormatJSON     = "json"
+)
+
+// Registry holds the exporters notes can be saved with, by format name. It is safe for concurrent use.
+type Registry struct {
+	mu        sync.RWMutex
+	exporters map[string]Exporter
+}
+
+// NewRegistry returns a registry holding the built-in exporters.
+func NewRegistry() *Registry {
+	registry := &Registry{exporters: make(map[string]Exporter)}
+	registry.Register(FormatText, TextExporter{})
+	registry.Register(FormatMarkdown, MarkdownExporter{})
+	registry.Register(FormatHTML, HTMLExporter{})
+	registry.Register(FormatJSON, JSONExporter{})
+	return registry
+}
+
+// Register adds an exporter under a format name, replacing any exporter already registered under it. Format names
+// are case insensitive.
+func (r *Registry) Register(format string, exporter Exporter) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.exporters[strings.ToLower(format)] = exporter
+}
+
+// Get returns the exporter registered under a format name.
+func (r *Registry) Get(format string) (Exporter, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	exporter, ok := r.exporters[strings.ToLower(format)]
+	return exporter, ok
+}
+
+// Formats returns the registered format names in alphabetical order.
+func (r *Registry) Formats() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	formats := make([]string, 0, len(r.exporters))
+	for format := range r.exporters {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+	return formats
+}
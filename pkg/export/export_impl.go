@@ -0,0 +1,130 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"html/template"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"notes-api/pkg/models"
+)
+
+// TextExporter exports the text of a note as it is.
+type TextExporter struct{}
+
+func (TextExporter) Extension() string {
+	return "txt"
+}
+
+func (TextExporter) ContentType() string {
+	return "text/plain; charset=utf-8"
+}
+
+func (TextExporter) Export(note models.Note) ([]byte, error) {
+	return []byte(note.Text), nil
+}
+
+// MarkdownExporter exports the text of a note, which is taken to be Markdown already, after a YAML front matter block
+// holding the note's name, tags and timestamps.
+type MarkdownExporter struct{}
+
+type frontMatter struct {
+	Name       string    `yaml:"name"`
+	Tags       []string  `yaml:"tags,omitempty"`
+	Created    time.Time `yaml:"created"`
+	LastEdited time.Time `yaml:"lastEdited"`
+}
+
+func (MarkdownExporter) Extension() string {
+	return "md"
+}
+
+func (MarkdownExporter) ContentType() string {
+	return "text/markdown; charset=utf-8"
+}
+
+func (MarkdownExporter) Export(note models.Note) ([]byte, error) {
+	var file bytes.Buffer
+	file.WriteString("---\n")
+
+	encoder := yaml.NewEncoder(&file)
+	encoder.SetIndent(2)
+	err := encoder.Encode(frontMatter{
+		Name:       note.Name,
+		Tags:       note.Tags,
+		Created:    note.ID.Timestamp().UTC(),
+		LastEdited: note.LastEditedTs.UTC(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+
+	file.WriteString("---\n\n")
+	file.WriteString(note.Text)
+	return file.Bytes(), nil
+}
+
+// HTMLExporter exports a note as an HTML document, with a paragraph for each block of text separated by blank lines.
+type HTMLExporter struct{}
+
+var htmlDocument = template.Must(template.New("note").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Name}}</title>
+</head>
+<body>
+<h1>{{.Name}}</h1>
+{{range .Paragraphs}}<p>{{range $i, $line := .}}{{if $i}}<br>
+{{end}}{{$line}}{{end}}</p>
+{{end}}</body>
+</html>
+`))
+
+func (HTMLExporter) Extension() string {
+	return "html"
+}
+
+func (HTMLExporter) ContentType() string {
+	return "text/html; charset=utf-8"
+}
+
+func (HTMLExporter) Export(note models.Note) ([]byte, error) {
+	var paragraphs [][]string
+	for _, block := range strings.Split(strings.ReplaceAll(note.Text, "\r\n", "\n"), "\n\n") {
+		if block = strings.Trim(block, "\n"); block != "" {
+			paragraphs = append(paragraphs, strings.Split(block, "\n"))
+		}
+	}
+
+	var file bytes.Buffer
+	err := htmlDocument.Execute(&file, struct {
+		Name       string
+		Paragraphs [][]string
+	}{note.Name, paragraphs})
+	if err != nil {
+		return nil, err
+	}
+	return file.Bytes(), nil
+}
+
+// JSONExporter exports a note as it is returned by the API.
+type JSONExporter struct{}
+
+func (JSONExporter) Extension() string {
+	return "json"
+}
+
+func (JSONExporter) ContentType() string {
+	return "application/json"
+}
+
+func (JSONExporter) Export(note models.Note) ([]byte, error) {
+	return json.MarshalIndent(note, "", "  ")
+}
@@ -0,0 +1,84 @@
+package export
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"gopkg.in/yaml.v3"
+
+	"notes-api/pkg/models"
+)
+
+func testNote() models.Note {
+	return models.Note{
+		ID:           primitive.NewObjectIDFromTimestamp(time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)),
+		Name:         "Plans: <draft>",
+		Text:         "# Plans\n\nfirst & second\nthird\n\n\nfourth",
+		Tags:         []string{"work", "q3"},
+		LastEditedTs: time.Date(2021, 6, 2, 8, 30, 0, 0, time.UTC),
+	}
+}
+
+func TestExport_Registry_ShouldHoldBuiltInFormatsCaseInsensitively(t *testing.T) {
+	registry := NewRegistry()
+	require.Equal(t, []string{"html", "json", "md", "txt"}, registry.Formats())
+
+	exporter, ok := registry.Get("MD")
+	require.True(t, ok)
+	require.Equal(t, "md", exporter.Extension())
+
+	_, ok = registry.Get("docx")
+	require.False(t, ok)
+
+	registry.Register("Plain", TextExporter{})
+	_, ok = registry.Get("plain")
+	require.True(t, ok)
+}
+
+func TestExport_TextExporter_ShouldExportTextAsItIs(t *testing.T) {
+	file, err := TextExporter{}.Export(testNote())
+	require.Nil(t, err)
+	require.Equal(t, testNote().Text, string(file))
+}
+
+func TestExport_MarkdownExporter_ShouldPrependFrontMatter(t *testing.T) {
+	file, err := MarkdownExporter{}.Export(testNote())
+	require.Nil(t, err)
+
+	parts := strings.SplitN(string(file), "---\n", 3)
+	require.Len(t, parts, 3)
+	require.Equal(t, "", parts[0])
+	require.Equal(t, "\n"+testNote().Text, parts[2])
+
+	var header map[string]interface{}
+	require.Nil(t, yaml.Unmarshal([]byte(parts[1]), &header))
+	require.Equal(t, map[string]interface{}{
+		"name":       "Plans: <draft>",
+		"tags":       []interface{}{"work", "q3"},
+		"created":    time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC),
+		"lastEdited": time.Date(2021, 6, 2, 8, 30, 0, 0, time.UTC),
+	}, header)
+}
+
+func TestExport_HTMLExporter_ShouldEscapeTextIntoParagraphs(t *testing.T) {
+	file, err := HTMLExporter{}.Export(testNote())
+	require.Nil(t, err)
+
+	html := string(file)
+	require.Contains(t, html, "<title>Plans: &lt;draft&gt;</title>")
+	require.Contains(t, html, "<p># Plans</p>\n<p>first &amp; second<br>\nthird</p>\n<p>fourth</p>\n")
+}
+
+func TestExport_JSONExporter_ShouldExportNote(t *testing.T) {
+	note := testNote()
+	file, err := JSONExporter{}.Export(note)
+	require.Nil(t, err)
+
+	var exported models.Note
+	require.Nil(t, json.Unmarshal(file, &exported))
+	require.Equal(t, note, exported)
+}
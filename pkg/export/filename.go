@@ -0,0 +1,83 @@
+package export
+
+import (
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// maxFileNameBytes is the longest file name most file systems accept.
+const maxFileNameBytes = 255
+
+// reservedNames are the names Windows reserves for devices, whatever their extension.
+var reservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true,
+	"COM9": true, "LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true,
+	"LPT8": true, "LPT9": true,
+}
+
+// FileName returns a file name for a note with the given name that is safe on any common file system, with the given
+// extension.
+//
+// Letters of every script are kept, in their composed form. Whitespace and the characters file systems reserve are
+// replaced with underscores, and control and invisible formatting characters are dropped. An extension the note name
+// already has is removed, the name is shortened to fit within 255 bytes with the new extension, and a name that is
+// empty or reserved on Windows is replaced or prefixed.
+func FileName(name string, extension string) string {
+	name = norm.NFC.String(strings.ToValidUTF8(name, ""))
+	if ext := filepath.Ext(name); ext != "" && isExtension(ext[1:]) {
+		name = name[:len(name)-len(ext)]
+	}
+
+	var sanitized strings.Builder
+	replaced := false
+	for _, r := range name {
+		switch {
+		case unicode.IsSpace(r), strings.ContainsRune(`<>:"/\|?*`, r):
+			if !replaced {
+				sanitized.WriteRune('_')
+			}
+			replaced = true
+			continue
+		case unicode.IsControl(r), unicode.Is(unicode.Cf, r):
+			continue
+		}
+		sanitized.WriteRune(r)
+		replaced = false
+	}
+
+	// Leading dots hide files, and Windows drops trailing dots and spaces.
+	base := strings.Trim(sanitized.String(), "._")
+	if base == "" {
+		base = "note"
+	}
+	if reservedNames[strings.ToUpper(base)] {
+		base = "_" + base
+	}
+
+	suffix := "." + extension
+	for len(base)+len(suffix) > maxFileNameBytes {
+		_, size := utf8.DecodeLastRuneInString(base)
+		base = strings.TrimRight(base[:len(base)-size], "._")
+	}
+
+	return base + suffix
+}
+
+// isExtension reports whether the text after the last dot of a name looks like a file extension rather than part of
+// the name, as in "v1.2 notes".
+func isExtension(ext string) bool {
+	if ext == "" || len(ext) > 8 {
+		return false
+	}
+	for _, r := range ext {
+		if r > unicode.MaxASCII || !(unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return true
+}
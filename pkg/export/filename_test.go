@@ -0,0 +1,40 @@
+package export
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExport_FileName_ShouldSanitizeName(t *testing.T) {
+	for name, want := range map[string]string{
+		"My Note":                    "My_Note.md",
+		"my note.txt":                "my_note.md",
+		"v1.2 notes":                 "v1.2_notes.md",
+		"a/b\\c:d*e?f\"g<h>i|j":      "a_b_c_d_e_f_g_h_i_j.md",
+		"  tabs\tand\n  newlines  ":  "tabs_and_newlines.md",
+		"日本語 メモ":                     "日本語_メモ.md",
+		"Cafe\u0301":                 "Caf\u00e9.md",
+		"zero\u200bwidth\u202eright": "zerowidthright.md",
+		"bell\a":                     "bell.md",
+		"bad \xff utf8":              "bad_utf8.md",
+		"..hidden.":                  "hidden.md",
+		"":                           "note.md",
+		"???":                        "note.md",
+		"con":                        "_con.md",
+		"LPT1.txt":                   "_LPT1.md",
+		"console":                    "console.md",
+	} {
+		require.Equal(t, want, FileName(name, "md"), name)
+	}
+}
+
+func TestExport_FileName_ShouldShortenLongNamesWithoutSplittingCharacters(t *testing.T) {
+	fileName := FileName(strings.Repeat("é", 200), "html")
+
+	require.LessOrEqual(t, len(fileName), 255)
+	require.True(t, utf8.ValidString(fileName))
+	require.True(t, strings.HasSuffix(fileName, "é.html"))
+}
@@ -0,0 +1,107 @@
+package export
+
+import (
+	"bytes"
+	"errors"
+	"mime"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+
+	"notes-api/pkg/models"
+)
+
+var (
+	// ErrUnsupportedFile is returned by Parse for a file that is neither plain text nor Markdown.
+	ErrUnsupportedFile = errors.New("file is not plain text or Markdown")
+	// ErrInvalidText is returned by Parse for a file that is not UTF-8 text.
+	ErrInvalidText = errors.New("file is not UTF-8 text")
+)
+
+// Parse reads a note from a plain text or Markdown file, recognised by its extension or, failing that, its content
+// type.
+//
+// The note is named after the file, without its extension, and holds the file's text. A Markdown file may start with
+// a YAML front matter block, as MarkdownExporter writes; its name and tags are then those of the front matter, and
+// the front matter is not part of the text.
+func Parse(fileName string, contentType string, content []byte) (models.NoteRequest, error) {
+	markdown, ok := isMarkdown(fileName, contentType)
+	if !ok {
+		return models.NoteRequest{}, ErrUnsupportedFile
+	}
+
+	// Editors on Windows start UTF-8 files with a byte order mark.
+	content = bytes.TrimPrefix(content, []byte("\xef\xbb\xbf"))
+	if !utf8.Valid(content) {
+		return models.NoteRequest{}, ErrInvalidText
+	}
+
+	note := models.NoteRequest{
+		Name: strings.TrimSuffix(fileName, filepath.Ext(fileName)),
+		Text: string(content),
+	}
+	if markdown {
+		parseFrontMatter(&note)
+	}
+	return note, nil
+}
+
+// CanParse reports whether Parse reads files with the given name and content type.
+func CanParse(fileName string, contentType string) bool {
+	_, ok := isMarkdown(fileName, contentType)
+	return ok
+}
+
+// isMarkdown reports whether a file is Markdown rather than plain text, and whether it is either.
+func isMarkdown(fileName string, contentType string) (markdown bool, ok bool) {
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".md", ".markdown":
+		return true, true
+	case ".txt", ".text":
+		return false, true
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false, false
+	}
+	switch mediaType {
+	case "text/markdown", "text/x-markdown":
+		return true, true
+	case "text/plain":
+		return false, true
+	}
+	return false, false
+}
+
+// parseFrontMatter moves the front matter at the start of a note's text, if there is any, into the note. Text that
+// only looks like front matter, such as a horizontal rule, is left alone.
+func parseFrontMatter(note *models.NoteRequest) {
+	text := strings.ReplaceAll(note.Text, "\r\n", "\n")
+	if !strings.HasPrefix(text, "---\n") {
+		return
+	}
+
+	end := strings.Index(text[4:], "\n---\n")
+	if end < 0 {
+		return
+	}
+	header, body := text[4:4+end+1], text[4+end+5:]
+
+	var matter struct {
+		Name string   `yaml:"name"`
+		Tags []string `yaml:"tags"`
+	}
+	if err := yaml.Unmarshal([]byte(header), &matter); err != nil {
+		return
+	}
+
+	if matter.Name != "" {
+		note.Name = matter.Name
+	}
+	note.Tags = matter.Tags
+	// MarkdownExporter separates the front matter from the text with a blank line.
+	note.Text = strings.TrimPrefix(body, "\n")
+}
@@ -0,0 +1,46 @@
+package export
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"notes-api/pkg/models"
+)
+
+func TestExport_Parse_ShouldReadTextFile(t *testing.T) {
+	note, err := Parse("shopping list.txt", "", []byte("\xef\xbb\xbfmilk\r\neggs"))
+	require.Nil(t, err)
+	require.Equal(t, models.NoteRequest{Name: "shopping list", Text: "milk\r\neggs"}, note)
+}
+
+func TestExport_Parse_ShouldReadMarkdownExportedByMarkdownExporter(t *testing.T) {
+	exported := testNote()
+	file, err := MarkdownExporter{}.Export(exported)
+	require.Nil(t, err)
+
+	note, err := Parse("Plans_draft.md", "", file)
+	require.Nil(t, err)
+	require.Equal(t, models.NoteRequest{Name: exported.Name, Text: exported.Text, Tags: exported.Tags}, note)
+}
+
+func TestExport_Parse_ShouldKeepTextThatOnlyLooksLikeFrontMatter(t *testing.T) {
+	for _, text := range []string{
+		"---\nno closing line",
+		"---\n: not yaml\n---\n\ntext",
+	} {
+		note, err := Parse("notes", "text/markdown; charset=utf-8", []byte(text))
+		require.Nil(t, err, text)
+		require.Equal(t, models.NoteRequest{Name: "notes", Text: text}, note, text)
+	}
+}
+
+func TestExport_Parse_ShouldRejectOtherFiles(t *testing.T) {
+	_, err := Parse("report.pdf", "application/pdf", []byte("%PDF"))
+	require.Equal(t, ErrUnsupportedFile, err)
+	require.False(t, CanParse("report", "application/pdf"))
+	require.True(t, CanParse("report", "text/plain"))
+
+	_, err = Parse("binary.txt", "", []byte{0xff, 0xfe, 0x00})
+	require.Equal(t, ErrInvalidText, err)
+}
@@ -3,10 +3,14 @@ package external
 import (
 	"bytes"
 	"context"
+
+	"notes-api/pkg/models"
 )
 
 type ExtAPIHandler interface {
-	SetToken(token string)
-	ValidateToken(ctx context.Context, token string) error
-	SendToContentService(ctx context.Context, body bytes.Buffer, contentType string) error
+	ValidateToken(ctx context.Context, token string) (*models.Principal, error)
+	SendToContentService(ctx context.Context, token string, body bytes.Buffer, contentType string) (*models.UploadedFile, error)
+	ListContentFiles(ctx context.Context, token string) ([]models.ContentFile, error)
+	DownloadContentFile(ctx context.Context, token string, id string) ([]byte, error)
+	DeleteContentFile(ctx context.Context, token string, id string) error
 }
@@ -3,11 +3,41 @@ package external
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
+	"net/url"
+
+	"notes-api/pkg/models"
 )
 
+// MaxDownloadBytes is the largest file DownloadContentFile will read.
+const MaxDownloadBytes = 10 << 20
+
+// ErrFileTooLarge is returned by DownloadContentFile for a file larger than MaxDownloadBytes.
+var ErrFileTooLarge = fmt.Errorf("file is larger than %v bytes", MaxDownloadBytes)
+
+// StatusError is returned when the content service responds with a status other than 200.
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("non-200 status code received: %v", e.StatusCode)
+}
+
+// Temporary reports whether the request may succeed if it is retried unchanged: client errors other than timeouts and
+// rate limiting will fail the same way again.
+func (e *StatusError) Temporary() bool {
+	if e.StatusCode == http.StatusRequestTimeout || e.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	return e.StatusCode < 400 || e.StatusCode >= 500
+}
+
 type Requester interface {
 	Do(r *http.Request) (*http.Response, error)
 }
@@ -16,57 +46,188 @@ type ExtAPI struct {
 	Client            Requester
 	LoginServiceURL   string
 	ContentServiceURL string
-	Token             string
-}
-
-func (ext *ExtAPI) SetToken(token string) {
-	ext.Token = token
 }
 
-func (ext *ExtAPI) ValidateToken(ctx context.Context, token string) error {
+func (ext *ExtAPI) ValidateToken(ctx context.Context, token string) (*models.Principal, error) {
 	if ext.LoginServiceURL == "" {
-		return errors.New("login service url cannot be empty")
+		return nil, errors.New("login service url cannot be empty")
 	}
 
-	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%v/token", ext.LoginServiceURL), nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%v/token", ext.LoginServiceURL), nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	req.Header.Add("Authorization", fmt.Sprintf("Bearer %v", token))
 
 	resp, err := ext.Client.Do(req)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if resp.Body != nil {
+		defer resp.Body.Close()
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return errors.New(fmt.Sprintf("non-200 status code received: %v", resp.StatusCode))
+		return nil, &StatusError{StatusCode: resp.StatusCode}
 	}
 
-	return nil
+	if resp.Body == nil {
+		return nil, errors.New("login service response body is empty")
+	}
+
+	var principal models.Principal
+	if err := json.NewDecoder(resp.Body).Decode(&principal); err != nil {
+		return nil, err
+	}
+
+	if principal.UserID == "" {
+		return nil, errors.New("login service response did not include a user id")
+	}
+
+	return &principal, nil
+}
+
+// SendToContentService uploads a file to the content service on behalf of the caller whose token is given, and
+// returns the ID and URL the content service gave the file. The token is passed per call rather than held on ExtAPI
+// because a single ExtAPI is shared by all concurrent requests.
+func (ext *ExtAPI) SendToContentService(ctx context.Context, token string, body bytes.Buffer, contentType string) (*models.UploadedFile, error) {
+	if ext.ContentServiceURL == "" {
+		return nil, errors.New("content service url cannot be empty")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%v/upload", ext.ContentServiceURL), &body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %v", token))
+	req.Header.Add("Content-Type", contentType)
+
+	res, err := ext.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.Body != nil {
+		defer res.Body.Close()
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, &StatusError{StatusCode: res.StatusCode}
+	}
+
+	// The file is uploaded by now, so a response without a body is taken as an upload the content service says
+	// nothing about, rather than a failure that would have the upload retried.
+	var file models.UploadedFile
+	if res.Body != nil {
+		if err := json.NewDecoder(res.Body).Decode(&file); err != nil && err != io.EOF {
+			return nil, err
+		}
+	}
+
+	return &file, nil
+}
+
+// ListContentFiles returns the files the caller whose token is given has stored in the content service.
+func (ext *ExtAPI) ListContentFiles(ctx context.Context, token string) ([]models.ContentFile, error) {
+	if ext.ContentServiceURL == "" {
+		return nil, errors.New("content service url cannot be empty")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%v/files", ext.ContentServiceURL), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %v", token))
+
+	res, err := ext.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.Body != nil {
+		defer res.Body.Close()
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, &StatusError{StatusCode: res.StatusCode}
+	}
+
+	if res.Body == nil {
+		return nil, errors.New("content service response body is empty")
+	}
+
+	var files []models.ContentFile
+	if err := json.NewDecoder(res.Body).Decode(&files); err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// DownloadContentFile returns the content of a file the caller whose token is given has stored in the content
+// service.
+func (ext *ExtAPI) DownloadContentFile(ctx context.Context, token string, id string) ([]byte, error) {
+	if ext.ContentServiceURL == "" {
+		return nil, errors.New("content service url cannot be empty")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%v/download/%v", ext.ContentServiceURL, url.PathEscape(id)), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %v", token))
+
+	res, err := ext.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.Body != nil {
+		defer res.Body.Close()
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, &StatusError{StatusCode: res.StatusCode}
+	}
+
+	if res.Body == nil {
+		return nil, errors.New("content service response body is empty")
+	}
+
+	content, err := ioutil.ReadAll(io.LimitReader(res.Body, MaxDownloadBytes+1))
+	if err != nil {
+		return nil, err
+	} else if len(content) > MaxDownloadBytes {
+		return nil, ErrFileTooLarge
+	}
+
+	return content, nil
 }
 
-func (ext *ExtAPI) SendToContentService(ctx context.Context, body bytes.Buffer, contentType string) error {
+// DeleteContentFile deletes a file the caller whose token is given has stored in the content service.
+func (ext *ExtAPI) DeleteContentFile(ctx context.Context, token string, id string) error {
 	if ext.ContentServiceURL == "" {
 		return errors.New("content service url cannot be empty")
 	}
 
-	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%v/upload", ext.ContentServiceURL), &body)
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, fmt.Sprintf("%v/files/%v", ext.ContentServiceURL, url.PathEscape(id)), nil)
 	if err != nil {
 		return err
 	}
 
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %v", ext.Token))
-	req.Header.Add("Content-Type", contentType)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %v", token))
 
 	res, err := ext.Client.Do(req)
 	if err != nil {
 		return err
 	}
+	if res.Body != nil {
+		defer res.Body.Close()
+	}
 
-	if res.StatusCode != http.StatusOK {
-		return fmt.Errorf("non-200 status code received: %v", res.StatusCode)
+	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusNoContent {
+		return &StatusError{StatusCode: res.StatusCode}
 	}
 
 	return nil
@@ -6,27 +6,21 @@ import (
 	"errors"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"io"
+	"io/ioutil"
 	"net/http"
+	"notes-api/pkg/models"
 	"notes-api/pkg/testhelper/mocks"
+	"strings"
 	"testing"
 )
 
-func TestExternal_SetToken_ShouldSetToken(t *testing.T) {
-	ext := ExtAPI{
-		Token: "",
-	}
-
-	ext.SetToken("test")
-
-	require.Equal(t, "test", ext.Token)
-}
-
 func TestExternal_ValidateToken_ShouldReturnErrorIfLoginServiceURLIsBlank(t *testing.T) {
 	ext := ExtAPI{
 		LoginServiceURL: "",
 	}
 
-	err := ext.ValidateToken(context.TODO(), "test")
+	_, err := ext.ValidateToken(context.TODO(), "test")
 	require.NotNil(t, err)
 	require.Equal(t, "login service url cannot be empty", err.Error())
 }
@@ -40,7 +34,7 @@ func TestExternal_ValidateToken_ShouldReturnErrorIfErrorOccursPerformingRequest(
 		Client: mockRequester,
 	}
 
-	err := ext.ValidateToken(context.TODO(), "test")
+	_, err := ext.ValidateToken(context.TODO(), "test")
 	require.NotNil(t, err)
 	require.Equal(t, "test", err.Error())
 }
@@ -54,21 +48,72 @@ func TestExternal_ValidateToken_ShouldReturnErrorIfResponseStatusCodeIsNot200(t
 		Client: mockRequester,
 	}
 
-	err := ext.ValidateToken(context.TODO(), "test")
+	_, err := ext.ValidateToken(context.TODO(), "test")
 	require.NotNil(t, err)
 	require.Equal(t, "non-200 status code received: 418", err.Error())
 }
 
-func TestExternal_ValidateToken_ShouldReturnNoErrorIfResponseIs200(t *testing.T) {
+// closeRecorder is a response body that remembers whether it was closed.
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestExternal_ValidateToken_ShouldCloseBodyIfResponseStatusCodeIsNot200(t *testing.T) {
+	body := &closeRecorder{Reader: strings.NewReader(`{"error":"invalid token"}`)}
 	mockRequester := &mocks.Requester{}
-	mockRequester.On("Do", mock.Anything).Return(&http.Response{StatusCode: http.StatusOK}, nil)
+	mockRequester.On("Do", mock.Anything).Return(&http.Response{StatusCode: http.StatusUnauthorized, Body: body}, nil)
+
+	ext := ExtAPI{
+		LoginServiceURL: "test",
+		Client: mockRequester,
+	}
+
+	_, err := ext.ValidateToken(context.TODO(), "test")
+	require.NotNil(t, err)
+	require.True(t, body.closed)
+}
+
+func TestExternal_ValidateToken_ShouldReturnErrorIfResponseDoesNotIncludeUserID(t *testing.T) {
+	mockRequester := &mocks.Requester{}
+	mockRequester.On("Do", mock.Anything).Return(&http.Response{
+		StatusCode: http.StatusOK,
+		Body:       ioutil.NopCloser(strings.NewReader("{}")),
+	}, nil)
+
+	ext := ExtAPI{
+		LoginServiceURL: "test",
+		Client: mockRequester,
+	}
+
+	_, err := ext.ValidateToken(context.TODO(), "test")
+	require.NotNil(t, err)
+	require.Equal(t, "login service response did not include a user id", err.Error())
+}
+
+func TestExternal_ValidateToken_ShouldReturnPrincipalIfResponseIs200(t *testing.T) {
+	mockRequester := &mocks.Requester{}
+	mockRequester.On("Do", mock.Anything).Return(&http.Response{
+		StatusCode: http.StatusOK,
+		Body:       ioutil.NopCloser(strings.NewReader(`{"id": "user", "username": "username", "roles": ["admin"], "expiresAt": "2030-01-01T00:00:00Z"}`)),
+	}, nil)
 
 	ext := ExtAPI{
 		LoginServiceURL: "test",
 		Client: mockRequester,
 	}
 
-	require.Nil(t, ext.ValidateToken(context.TODO(), "test"))
+	principal, err := ext.ValidateToken(context.TODO(), "test")
+	require.Nil(t, err)
+	require.Equal(t, "user", principal.UserID)
+	require.Equal(t, "username", principal.Username)
+	require.Equal(t, []string{"admin"}, principal.Roles)
+	require.Equal(t, 2030, principal.ExpiresAt.Year())
 }
 
 func TestExternal_SendToContentService_ShouldReturnErrorIfContentServiceURLIsBlank(t *testing.T) {
@@ -76,7 +121,7 @@ func TestExternal_SendToContentService_ShouldReturnErrorIfContentServiceURLIsBla
 		ContentServiceURL: "",
 	}
 
-	err := ext.SendToContentService(context.TODO(), *bytes.NewBuffer(nil), "")
+	_, err := ext.SendToContentService(context.TODO(), "test", *bytes.NewBuffer(nil), "")
 	require.NotNil(t, err)
 	require.Equal(t, "content service url cannot be empty", err.Error())
 }
@@ -90,7 +135,7 @@ func TestExternal_SendToContentService_ShouldReturnErrorIfErrorOccursPerformingR
 		Client: mockRequester,
 	}
 
-	err := ext.SendToContentService(context.TODO(), *bytes.NewBuffer(nil), "test")
+	_, err := ext.SendToContentService(context.TODO(), "test", *bytes.NewBuffer(nil), "test")
 	require.NotNil(t, err)
 	require.Equal(t, "test", err.Error())
 }
@@ -104,11 +149,27 @@ func TestExternal_SendToContentService_ShouldReturnErrorIfResponseStatusCodeIsNo
 		Client: mockRequester,
 	}
 
-	err := ext.SendToContentService(context.TODO(), *bytes.NewBuffer(nil), "test")
+	_, err := ext.SendToContentService(context.TODO(), "test", *bytes.NewBuffer(nil), "test")
 	require.NotNil(t, err)
 	require.Equal(t, "non-200 status code received: 418", err.Error())
 }
 
+func TestExternal_SendToContentService_ShouldSendGivenToken(t *testing.T) {
+	mockRequester := &mocks.Requester{}
+	mockRequester.On("Do", mock.MatchedBy(func(r *http.Request) bool {
+		return r.Header.Get("Authorization") == "Bearer token"
+	})).Return(&http.Response{StatusCode: http.StatusOK}, nil)
+
+	ext := ExtAPI{
+		ContentServiceURL: "test",
+		Client:            mockRequester,
+	}
+
+	_, err := ext.SendToContentService(context.TODO(), "token", *bytes.NewBuffer(nil), "test")
+	require.Nil(t, err)
+	mockRequester.AssertExpectations(t)
+}
+
 func TestExternal_SendToContentService_ShouldReturnNoErrorIfResponseIs200(t *testing.T) {
 	mockRequester := &mocks.Requester{}
 	mockRequester.On("Do", mock.Anything).Return(&http.Response{StatusCode: http.StatusOK}, nil)
@@ -118,6 +179,130 @@ func TestExternal_SendToContentService_ShouldReturnNoErrorIfResponseIs200(t *tes
 		Client: mockRequester,
 	}
 
-	err := ext.SendToContentService(context.TODO(), *bytes.NewBuffer(nil), "test")
+	_, err := ext.SendToContentService(context.TODO(), "test", *bytes.NewBuffer(nil), "test")
 	require.Nil(t, err)
 }
+
+func TestExternal_SendToContentService_ShouldReturnUploadedFile(t *testing.T) {
+	mockRequester := &mocks.Requester{}
+	mockRequester.On("Do", mock.Anything).Return(&http.Response{
+		StatusCode: http.StatusOK,
+		Body:       ioutil.NopCloser(strings.NewReader(`{"id": "file", "url": "http://content-service/files/file"}`)),
+	}, nil)
+
+	ext := ExtAPI{
+		ContentServiceURL: "test",
+		Client:            mockRequester,
+	}
+
+	file, err := ext.SendToContentService(context.TODO(), "test", *bytes.NewBuffer(nil), "test")
+	require.Nil(t, err)
+	require.Equal(t, &models.UploadedFile{ID: "file", URL: "http://content-service/files/file"}, file)
+}
+
+func TestExternal_StatusError_Temporary_ShouldOnlyBeFalseForClientErrors(t *testing.T) {
+	for code, want := range map[int]bool{
+		http.StatusBadRequest:          false,
+		http.StatusUnauthorized:        false,
+		http.StatusRequestTimeout:      true,
+		http.StatusTooManyRequests:     true,
+		http.StatusInternalServerError: true,
+		http.StatusServiceUnavailable:  true,
+	} {
+		err := &StatusError{StatusCode: code}
+		require.Equal(t, want, err.Temporary(), code)
+	}
+}
+
+func TestExternal_ListContentFiles_ShouldReturnErrorIfResponseStatusCodeIsNot200(t *testing.T) {
+	mockRequester := &mocks.Requester{}
+	mockRequester.On("Do", mock.Anything).Return(&http.Response{StatusCode: http.StatusUnauthorized}, nil)
+
+	ext := ExtAPI{
+		ContentServiceURL: "test",
+		Client:            mockRequester,
+	}
+
+	_, err := ext.ListContentFiles(context.TODO(), "token")
+	require.Equal(t, &StatusError{StatusCode: http.StatusUnauthorized}, err)
+}
+
+func TestExternal_ListContentFiles_ShouldReturnCallersFiles(t *testing.T) {
+	mockRequester := &mocks.Requester{}
+	mockRequester.On("Do", mock.MatchedBy(func(r *http.Request) bool {
+		return r.Method == http.MethodGet && r.URL.String() == "test/files" && r.Header.Get("Authorization") == "Bearer token"
+	})).Return(&http.Response{
+		StatusCode: http.StatusOK,
+		Body:       ioutil.NopCloser(strings.NewReader(`[{"id": "1", "name": "a.md", "contentType": "text/markdown", "size": 3}]`)),
+	}, nil)
+
+	ext := ExtAPI{
+		ContentServiceURL: "test",
+		Client:            mockRequester,
+	}
+
+	files, err := ext.ListContentFiles(context.TODO(), "token")
+	require.Nil(t, err)
+	require.Equal(t, []models.ContentFile{{ID: "1", Name: "a.md", ContentType: "text/markdown", Size: 3}}, files)
+}
+
+func TestExternal_DownloadContentFile_ShouldReturnFileContent(t *testing.T) {
+	mockRequester := &mocks.Requester{}
+	mockRequester.On("Do", mock.MatchedBy(func(r *http.Request) bool {
+		return r.URL.String() == "test/download/a%2Fb" && r.Header.Get("Authorization") == "Bearer token"
+	})).Return(&http.Response{
+		StatusCode: http.StatusOK,
+		Body:       ioutil.NopCloser(strings.NewReader("text")),
+	}, nil)
+
+	ext := ExtAPI{
+		ContentServiceURL: "test",
+		Client:            mockRequester,
+	}
+
+	content, err := ext.DownloadContentFile(context.TODO(), "token", "a/b")
+	require.Nil(t, err)
+	require.Equal(t, "text", string(content))
+}
+
+func TestExternal_DownloadContentFile_ShouldReturnErrorIfFileIsTooLarge(t *testing.T) {
+	mockRequester := &mocks.Requester{}
+	mockRequester.On("Do", mock.Anything).Return(&http.Response{
+		StatusCode: http.StatusOK,
+		Body:       ioutil.NopCloser(bytes.NewReader(make([]byte, MaxDownloadBytes+1))),
+	}, nil)
+
+	ext := ExtAPI{
+		ContentServiceURL: "test",
+		Client:            mockRequester,
+	}
+
+	_, err := ext.DownloadContentFile(context.TODO(), "token", "1")
+	require.Equal(t, ErrFileTooLarge, err)
+}
+
+func TestExternal_DeleteContentFile_ShouldDeleteFile(t *testing.T) {
+	mockRequester := &mocks.Requester{}
+	mockRequester.On("Do", mock.MatchedBy(func(r *http.Request) bool {
+		return r.Method == http.MethodDelete && r.URL.String() == "test/files/a%2Fb" && r.Header.Get("Authorization") == "Bearer token"
+	})).Return(&http.Response{StatusCode: http.StatusNoContent}, nil)
+
+	ext := ExtAPI{
+		ContentServiceURL: "test",
+		Client:            mockRequester,
+	}
+
+	require.Nil(t, ext.DeleteContentFile(context.TODO(), "token", "a/b"))
+}
+
+func TestExternal_DeleteContentFile_ShouldReturnStatusErrorIfDeleteFails(t *testing.T) {
+	mockRequester := &mocks.Requester{}
+	mockRequester.On("Do", mock.Anything).Return(&http.Response{StatusCode: http.StatusNotFound}, nil)
+
+	ext := ExtAPI{
+		ContentServiceURL: "test",
+		Client:            mockRequester,
+	}
+
+	require.Equal(t, &StatusError{StatusCode: http.StatusNotFound}, ext.DeleteContentFile(context.TODO(), "token", "1"))
+}
@@ -0,0 +1,164 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"notes-api/pkg/dao"
+	"notes-api/pkg/models"
+)
+
+// timeDao starts timing a storage operation, and returns the function that records it once it returns its error.
+func (m *Metrics) timeDao(operation string) func(err error) error {
+	start := time.Now()
+	return func(err error) error {
+		m.ObserveDao(operation, time.Since(start), daoFailed(err))
+		return err
+	}
+}
+
+// daoFailed tells whether a storage operation failed. Finding nothing to read or change is an answer rather than a
+// failure.
+func daoFailed(err error) bool {
+	return err != nil && !dao.IsNotFound(err)
+}
+
+// NoteDao is a dao.NoteDaoHandler that records the duration and failures of the operations of the one it wraps.
+type NoteDao struct {
+	Next    dao.NoteDaoHandler
+	Metrics *Metrics
+}
+
+func (d *NoteDao) Ping(ctx context.Context) error {
+	done := d.Metrics.timeDao("Ping")
+	return done(d.Next.Ping(ctx))
+}
+
+func (d *NoteDao) GetNotes(ctx context.Context, filter dao.NoteFilter) ([]models.Note, error) {
+	done := d.Metrics.timeDao("GetNotes")
+	notes, err := d.Next.GetNotes(ctx, filter)
+	return notes, done(err)
+}
+
+func (d *NoteDao) ListNotes(ctx context.Context, filter dao.NoteFilter, opts dao.ListOptions) ([]models.Note, error) {
+	done := d.Metrics.timeDao("ListNotes")
+	notes, err := d.Next.ListNotes(ctx, filter, opts)
+	return notes, done(err)
+}
+
+func (d *NoteDao) SearchNotes(ctx context.Context, filter dao.NoteFilter, search string, opts dao.ListOptions) ([]models.SearchResult, error) {
+	done := d.Metrics.timeDao("SearchNotes")
+	results, err := d.Next.SearchNotes(ctx, filter, search, opts)
+	return results, done(err)
+}
+
+func (d *NoteDao) UpdateNote(ctx context.Context, filter dao.NoteFilter, patch dao.NotePatch) error {
+	done := d.Metrics.timeDao("UpdateNote")
+	return done(d.Next.UpdateNote(ctx, filter, patch))
+}
+
+func (d *NoteDao) UpdateNotes(ctx context.Context, filter dao.NoteFilter, patch dao.NotePatch) (int64, error) {
+	done := d.Metrics.timeDao("UpdateNotes")
+	changed, err := d.Next.UpdateNotes(ctx, filter, patch)
+	return changed, done(err)
+}
+
+func (d *NoteDao) DeleteNote(ctx context.Context, filter dao.NoteFilter) error {
+	done := d.Metrics.timeDao("DeleteNote")
+	return done(d.Next.DeleteNote(ctx, filter))
+}
+
+func (d *NoteDao) DeleteNotes(ctx context.Context, filter dao.NoteFilter) (int64, error) {
+	done := d.Metrics.timeDao("DeleteNotes")
+	deleted, err := d.Next.DeleteNotes(ctx, filter)
+	return deleted, done(err)
+}
+
+func (d *NoteDao) CreateNote(ctx context.Context, note models.Note) error {
+	done := d.Metrics.timeDao("CreateNote")
+	return done(d.Next.CreateNote(ctx, note))
+}
+
+func (d *NoteDao) CountTags(ctx context.Context, filter dao.NoteFilter) ([]models.TagCount, error) {
+	done := d.Metrics.timeDao("CountTags")
+	tags, err := d.Next.CountTags(ctx, filter)
+	return tags, done(err)
+}
+
+func (d *NoteDao) GetRevisions(ctx context.Context, filter dao.RevisionFilter) ([]models.Revision, error) {
+	done := d.Metrics.timeDao("GetRevisions")
+	revisions, err := d.Next.GetRevisions(ctx, filter)
+	return revisions, done(err)
+}
+
+func (d *NoteDao) CreateRevision(ctx context.Context, revision models.Revision) error {
+	done := d.Metrics.timeDao("CreateRevision")
+	return done(d.Next.CreateRevision(ctx, revision))
+}
+
+func (d *NoteDao) DeleteRevisions(ctx context.Context, filter dao.RevisionFilter) error {
+	done := d.Metrics.timeDao("DeleteRevisions")
+	return done(d.Next.DeleteRevisions(ctx, filter))
+}
+
+// NotebookDao is the dao.NotebookDaoHandler equivalent of NoteDao.
+type NotebookDao struct {
+	Next    dao.NotebookDaoHandler
+	Metrics *Metrics
+}
+
+func (d *NotebookDao) GetNotebooks(ctx context.Context, filter dao.NotebookFilter) ([]models.Notebook, error) {
+	done := d.Metrics.timeDao("GetNotebooks")
+	notebooks, err := d.Next.GetNotebooks(ctx, filter)
+	return notebooks, done(err)
+}
+
+func (d *NotebookDao) CreateNotebook(ctx context.Context, notebook models.Notebook) error {
+	done := d.Metrics.timeDao("CreateNotebook")
+	return done(d.Next.CreateNotebook(ctx, notebook))
+}
+
+func (d *NotebookDao) UpdateNotebook(ctx context.Context, filter dao.NotebookFilter, patch dao.NotebookPatch) error {
+	done := d.Metrics.timeDao("UpdateNotebook")
+	return done(d.Next.UpdateNotebook(ctx, filter, patch))
+}
+
+func (d *NotebookDao) DeleteNotebooks(ctx context.Context, filter dao.NotebookFilter) (int64, error) {
+	done := d.Metrics.timeDao("DeleteNotebooks")
+	deleted, err := d.Next.DeleteNotebooks(ctx, filter)
+	return deleted, done(err)
+}
+
+// SaveJobDao is the dao.SaveJobDaoHandler equivalent of NoteDao.
+type SaveJobDao struct {
+	Next    dao.SaveJobDaoHandler
+	Metrics *Metrics
+}
+
+func (d *SaveJobDao) CreateSaveJob(ctx context.Context, job models.SaveJob) error {
+	done := d.Metrics.timeDao("CreateSaveJob")
+	return done(d.Next.CreateSaveJob(ctx, job))
+}
+
+func (d *SaveJobDao) CreateSaveJobIfNone(ctx context.Context, filter dao.SaveJobFilter, job models.SaveJob) (bool, error) {
+	done := d.Metrics.timeDao("CreateSaveJobIfNone")
+	created, err := d.Next.CreateSaveJobIfNone(ctx, filter, job)
+	return created, done(err)
+}
+
+func (d *SaveJobDao) GetSaveJobs(ctx context.Context, filter dao.SaveJobFilter) ([]models.SaveJob, error) {
+	done := d.Metrics.timeDao("GetSaveJobs")
+	jobs, err := d.Next.GetSaveJobs(ctx, filter)
+	return jobs, done(err)
+}
+
+func (d *SaveJobDao) ClaimSaveJob(ctx context.Context, now time.Time, leaseUntil time.Time) (*models.SaveJob, error) {
+	done := d.Metrics.timeDao("ClaimSaveJob")
+	job, err := d.Next.ClaimSaveJob(ctx, now, leaseUntil)
+	return job, done(err)
+}
+
+func (d *SaveJobDao) UpdateSaveJob(ctx context.Context, filter dao.SaveJobFilter, patch dao.SaveJobPatch) error {
+	done := d.Metrics.timeDao("UpdateSaveJob")
+	return done(d.Next.UpdateSaveJob(ctx, filter, patch))
+}
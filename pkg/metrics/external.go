@@ -0,0 +1,66 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"time"
+
+	"notes-api/pkg/external"
+	"notes-api/pkg/models"
+)
+
+// timeExternal starts timing a call to another service, and returns the function that records it once it returns
+// its error.
+func (m *Metrics) timeExternal(service string, operation string) func(err error) error {
+	start := time.Now()
+	return func(err error) error {
+		m.ObserveExternal(service, operation, time.Since(start), externalFailed(err))
+		return err
+	}
+}
+
+// externalFailed tells whether a call to another service failed. A service that turns a request down as a client
+// error, such as a login service rejecting a token, has not failed.
+func externalFailed(err error) bool {
+	var statusErr *external.StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Temporary()
+	}
+	return err != nil
+}
+
+// ExtAPI is an external.ExtAPIHandler that records the duration and failures of the calls of the one it wraps.
+type ExtAPI struct {
+	Next    external.ExtAPIHandler
+	Metrics *Metrics
+}
+
+func (e *ExtAPI) ValidateToken(ctx context.Context, token string) (*models.Principal, error) {
+	done := e.Metrics.timeExternal(ServiceLogin, "ValidateToken")
+	principal, err := e.Next.ValidateToken(ctx, token)
+	return principal, done(err)
+}
+
+func (e *ExtAPI) SendToContentService(ctx context.Context, token string, body bytes.Buffer, contentType string) (*models.UploadedFile, error) {
+	done := e.Metrics.timeExternal(ServiceContent, "SendToContentService")
+	file, err := e.Next.SendToContentService(ctx, token, body, contentType)
+	return file, done(err)
+}
+
+func (e *ExtAPI) ListContentFiles(ctx context.Context, token string) ([]models.ContentFile, error) {
+	done := e.Metrics.timeExternal(ServiceContent, "ListContentFiles")
+	files, err := e.Next.ListContentFiles(ctx, token)
+	return files, done(err)
+}
+
+func (e *ExtAPI) DownloadContentFile(ctx context.Context, token string, id string) ([]byte, error) {
+	done := e.Metrics.timeExternal(ServiceContent, "DownloadContentFile")
+	content, err := e.Next.DownloadContentFile(ctx, token, id)
+	return content, done(err)
+}
+
+func (e *ExtAPI) DeleteContentFile(ctx context.Context, token string, id string) error {
+	done := e.Metrics.timeExternal(ServiceContent, "DeleteContentFile")
+	return done(e.Next.DeleteContentFile(ctx, token, id))
+}
@@ -0,0 +1,123 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "notes_api"
+
+// The services that the API calls.
+const (
+	ServiceLogin   = "login"
+	ServiceContent = "content"
+)
+
+// Metrics is the set of Prometheus metrics of the API: the rate, errors and duration of the requests it serves, and
+// of the calls it makes to its storage and to other services. Each Metrics has a registry of its own, so that any
+// number of them can be created.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests        *prometheus.CounterVec
+	requestErrors   *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+
+	daoDuration *prometheus.HistogramVec
+	daoErrors   *prometheus.CounterVec
+
+	externalDuration *prometheus.HistogramVec
+	externalErrors   *prometheus.CounterVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "Requests served, by route template, method and status code.",
+		}, []string{"route", "method", "status"}),
+		requestErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_request_errors_total",
+			Help:      "Requests that failed with a server error, by route template, method and status code.",
+		}, []string{"route", "method", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Time taken to serve requests, by route template, method and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		daoDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "dao_operation_duration_seconds",
+			Help:      "Time taken by storage operations, by operation.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"operation"}),
+		daoErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "dao_operation_errors_total",
+			Help:      "Storage operations that failed, by operation. Finding nothing is not a failure.",
+		}, []string{"operation"}),
+		externalDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "external_request_duration_seconds",
+			Help:      "Time taken by calls to other services, by service and operation.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"service", "operation"}),
+		externalErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "external_request_errors_total",
+			Help:      "Calls to other services that failed, by service and operation. Requests a service rejects as a client error are not failures of the service.",
+		}, []string{"service", "operation"}),
+	}
+
+	m.registry.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		m.requests,
+		m.requestErrors,
+		m.requestDuration,
+		m.daoDuration,
+		m.daoErrors,
+		m.externalDuration,
+		m.externalErrors,
+	)
+	return m
+}
+
+// Handler serves the metrics in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// ObserveRequest records a request served on the route with the given template.
+func (m *Metrics) ObserveRequest(route string, method string, status int, duration time.Duration) {
+	labels := prometheus.Labels{"route": route, "method": method, "status": strconv.Itoa(status)}
+	m.requests.With(labels).Inc()
+	m.requestDuration.With(labels).Observe(duration.Seconds())
+	if status >= http.StatusInternalServerError {
+		m.requestErrors.With(labels).Inc()
+	}
+}
+
+// ObserveDao records a storage operation.
+func (m *Metrics) ObserveDao(operation string, duration time.Duration, failed bool) {
+	m.daoDuration.WithLabelValues(operation).Observe(duration.Seconds())
+	if failed {
+		m.daoErrors.WithLabelValues(operation).Inc()
+	}
+}
+
+// ObserveExternal records a call to another service.
+func (m *Metrics) ObserveExternal(service string, operation string, duration time.Duration, failed bool) {
+	m.externalDuration.WithLabelValues(service, operation).Observe(duration.Seconds())
+	if failed {
+		m.externalErrors.WithLabelValues(service, operation).Inc()
+	}
+}
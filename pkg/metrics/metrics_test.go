@@ -0,0 +1,102 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"notes-api/pkg/auth"
+	"notes-api/pkg/dao"
+	"notes-api/pkg/external"
+	"notes-api/pkg/models"
+	"notes-api/pkg/testhelper/mocks"
+)
+
+func TestMetrics_ObserveRequest_ShouldCountServerErrors(t *testing.T) {
+	m := New()
+
+	m.ObserveRequest("/note/{id}", http.MethodGet, http.StatusOK, time.Millisecond)
+	m.ObserveRequest("/note/{id}", http.MethodGet, http.StatusNotFound, time.Millisecond)
+	m.ObserveRequest("/note/{id}", http.MethodGet, http.StatusInternalServerError, time.Millisecond)
+
+	require.Equal(t, 3, testutil.CollectAndCount(m.requests))
+	require.Equal(t, 1.0, testutil.ToFloat64(m.requests.WithLabelValues("/note/{id}", http.MethodGet, "404")))
+	require.Equal(t, 1, testutil.CollectAndCount(m.requestErrors))
+	require.Equal(t, 1.0, testutil.ToFloat64(m.requestErrors.WithLabelValues("/note/{id}", http.MethodGet, "500")))
+}
+
+func TestNoteDao_ShouldNotCountFindingNothingAsFailure(t *testing.T) {
+	mockDao := &mocks.NoteDaoHandler{}
+	mockDao.On("UpdateNote", mock.Anything, mock.Anything, mock.Anything).Return(dao.ErrNotFound).Once()
+	mockDao.On("UpdateNote", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("test")).Once()
+
+	m := New()
+	noteDao := &NoteDao{Next: mockDao, Metrics: m}
+
+	require.Equal(t, dao.ErrNotFound, noteDao.UpdateNote(context.TODO(), dao.NoteFilter{}, dao.NotePatch{}))
+	require.Equal(t, 0, testutil.CollectAndCount(m.daoErrors))
+
+	require.Equal(t, "test", noteDao.UpdateNote(context.TODO(), dao.NoteFilter{}, dao.NotePatch{}).Error())
+	require.Equal(t, 1.0, testutil.ToFloat64(m.daoErrors.WithLabelValues("UpdateNote")))
+	require.Equal(t, 1, testutil.CollectAndCount(m.daoDuration))
+}
+
+func TestExtAPI_ShouldOnlyCountFailuresOfTheService(t *testing.T) {
+	mockExt := &mocks.ExtAPIHandler{}
+	mockExt.On("ValidateToken", mock.Anything, "rejected").Return(nil, &external.StatusError{StatusCode: http.StatusUnauthorized})
+	mockExt.On("ValidateToken", mock.Anything, "valid").Return(&models.Principal{UserID: "user"}, nil)
+	mockExt.On("ListContentFiles", mock.Anything, mock.Anything).Return(nil, &external.StatusError{StatusCode: http.StatusServiceUnavailable})
+
+	m := New()
+	ext := &ExtAPI{Next: mockExt, Metrics: m}
+
+	_, err := ext.ValidateToken(context.TODO(), "rejected")
+	require.NotNil(t, err)
+	principal, err := ext.ValidateToken(context.TODO(), "valid")
+	require.Nil(t, err)
+	require.Equal(t, "user", principal.UserID)
+	_, err = ext.ListContentFiles(context.TODO(), "token")
+	require.NotNil(t, err)
+
+	require.Equal(t, 1, testutil.CollectAndCount(m.externalErrors))
+	require.Equal(t, 1.0, testutil.ToFloat64(m.externalErrors.WithLabelValues(ServiceContent, "ListContentFiles")))
+	require.Equal(t, 2, testutil.CollectAndCount(m.externalDuration))
+}
+
+func TestMetrics_ObserveTokenCache_ShouldExportCacheStats(t *testing.T) {
+	mockValidator := &mocks.Validator{}
+	mockValidator.On("ValidateToken", mock.Anything, mock.Anything).Return(&models.Principal{UserID: "user"}, nil)
+	cache := auth.NewCache(mockValidator, auth.CacheConfig{TTL: time.Minute, MaxEntries: 1})
+
+	m := New()
+	m.ObserveTokenCache(cache)
+
+	for _, token := range []string{"a", "a", "b"} {
+		_, err := cache.ValidateToken(context.TODO(), token)
+		require.Nil(t, err)
+	}
+
+	expected := `
+# HELP notes_api_token_cache_entries Tokens in the cache.
+# TYPE notes_api_token_cache_entries gauge
+notes_api_token_cache_entries 1
+# HELP notes_api_token_cache_evictions_total Tokens evicted from the cache to make room for others.
+# TYPE notes_api_token_cache_evictions_total counter
+notes_api_token_cache_evictions_total 1
+# HELP notes_api_token_cache_hits_total Token validations answered from the cache.
+# TYPE notes_api_token_cache_hits_total counter
+notes_api_token_cache_hits_total 1
+# HELP notes_api_token_cache_misses_total Token validations that the cache passed on to the validator.
+# TYPE notes_api_token_cache_misses_total counter
+notes_api_token_cache_misses_total 2
+`
+	require.Nil(t, testutil.GatherAndCompare(m.registry, strings.NewReader(expected),
+		"notes_api_token_cache_entries", "notes_api_token_cache_evictions_total", "notes_api_token_cache_hits_total", "notes_api_token_cache_misses_total"))
+}
@@ -0,0 +1,43 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"notes-api/pkg/auth"
+)
+
+// tokenCacheCollector exports the statistics of a token cache, which it reads from the cache whenever the metrics are
+// scraped.
+type tokenCacheCollector struct {
+	cache     *auth.Cache
+	hits      *prometheus.Desc
+	misses    *prometheus.Desc
+	evictions *prometheus.Desc
+	entries   *prometheus.Desc
+}
+
+// ObserveTokenCache exports the hits, misses, evictions and size of the cache of validated tokens.
+func (m *Metrics) ObserveTokenCache(cache *auth.Cache) {
+	m.registry.MustRegister(&tokenCacheCollector{
+		cache:     cache,
+		hits:      prometheus.NewDesc(namespace+"_token_cache_hits_total", "Token validations answered from the cache.", nil, nil),
+		misses:    prometheus.NewDesc(namespace+"_token_cache_misses_total", "Token validations that the cache passed on to the validator.", nil, nil),
+		evictions: prometheus.NewDesc(namespace+"_token_cache_evictions_total", "Tokens evicted from the cache to make room for others.", nil, nil),
+		entries:   prometheus.NewDesc(namespace+"_token_cache_entries", "Tokens in the cache.", nil, nil),
+	})
+}
+
+func (c *tokenCacheCollector) Describe(descs chan<- *prometheus.Desc) {
+	descs <- c.hits
+	descs <- c.misses
+	descs <- c.evictions
+	descs <- c.entries
+}
+
+func (c *tokenCacheCollector) Collect(metrics chan<- prometheus.Metric) {
+	stats := c.cache.Stats()
+	metrics <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, float64(stats.Hits))
+	metrics <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, float64(stats.Misses))
+	metrics <- prometheus.MustNewConstMetric(c.evictions, prometheus.CounterValue, float64(stats.Evictions))
+	metrics <- prometheus.MustNewConstMetric(c.entries, prometheus.GaugeValue, float64(stats.Size))
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ContentFile describes a file stored in the content service.
+type ContentFile struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	ContentType  string    `json:"contentType"`
+	Size         int64     `json:"size"`
+	LastModified time.Time `json:"lastModified"`
+}
+
+// UploadedFile is the content service's response to an upload.
+type UploadedFile struct {
+	ID  string `json:"id"`
+	URL string `json:"url"`
+}
+
+// ImportRequest is the body of an import: the content service files to create notes from, and the notebook to create
+// them in, if any.
+type ImportRequest struct {
+	FileIDs    []string `json:"fileIds"`
+	NotebookID string   `json:"notebookId"`
+}
+
+// ImportResult reports what an import did with each of the files it was asked to import.
+type ImportResult struct {
+	Imported []ImportedFile `json:"imported"`
+	Skipped  []SkippedFile  `json:"skipped"`
+}
+
+// ImportedFile is a content service file that an import created a note from.
+type ImportedFile struct {
+	FileID string             `json:"fileId"`
+	NoteID primitive.ObjectID `json:"noteId"`
+	Name   string             `json:"name"`
+}
+
+// SkippedFile is a content service file that an import did not create a note from. NoteID is set when the file was
+// skipped because a note with the same content already exists.
+type SkippedFile struct {
+	FileID string              `json:"fileId"`
+	Reason string              `json:"reason"`
+	NoteID *primitive.ObjectID `json:"noteId,omitempty"`
+}
@@ -9,11 +9,52 @@ import (
 type NoteRequest struct {
 	Name string `json:"name"`
 	Text string `json:"text"`
+	// Tags replaces the note's tags when present; an update without tags leaves them as they are.
+	Tags []string `json:"tags"`
+	// NotebookID is the notebook to create the note in; empty creates it outside any notebook. It is ignored by
+	// updates; notes are moved with a move request.
+	NotebookID string `json:"notebookId"`
+	// AutoSync turns automatic re-uploads of the note to the content service on or off when present; an update
+	// without it leaves it as it is.
+	AutoSync *bool `json:"autoSync"`
 }
 
 type Note struct {
-	ID           primitive.ObjectID `json:"id" bson:"_id"`
-	Name         string             `json:"name" bson:"name"`
-	LastEditedTs time.Time          `json:"lastEditedTs" bson:"lastEditedTs"`
-	Text         string             `json:"text" bson:"text"`
+	ID           primitive.ObjectID  `json:"id" bson:"_id"`
+	OwnerID      string              `json:"ownerId" bson:"ownerId"`
+	Name         string              `json:"name" bson:"name"`
+	LastEditedTs time.Time           `json:"lastEditedTs" bson:"lastEditedTs"`
+	Text         string              `json:"text" bson:"text"`
+	Version      int64               `json:"version" bson:"version"`
+	Tags         []string            `json:"tags,omitempty" bson:"tags,omitempty"`
+	NotebookID   *primitive.ObjectID `json:"notebookId,omitempty" bson:"notebookId,omitempty"`
+	DeletedAt    *time.Time          `json:"deletedAt,omitempty" bson:"deletedAt,omitempty"`
+	// Export is the copy of the note last delivered to the content service, if any.
+	Export *NoteExport `json:"export,omitempty" bson:"export,omitempty"`
+	// ExportStatus is one of the ExportStatus values. It is worked out when the note is read, and is not stored.
+	ExportStatus string `json:"exportStatus,omitempty" bson:"-"`
+	// AutoSync has every update of the note uploaded to the content service, replacing the file last delivered.
+	AutoSync bool `json:"autoSync" bson:"autoSync,omitempty"`
+	// TextHash is the hash of Text, which is written along with it, so that notes can be found by their text without
+	// reading it.
+	TextHash string `json:"-" bson:"textHash,omitempty"`
+}
+
+// The export statuses of a note: never delivered to the content service, unchanged since it was last delivered, or
+// changed since.
+const (
+	ExportStatusNever  = "never"
+	ExportStatusSynced = "synced"
+	ExportStatusStale  = "stale"
+)
+
+// NoteExport is a copy of a note delivered to the content service.
+type NoteExport struct {
+	FileID string `json:"fileId" bson:"fileId"`
+	URL    string `json:"url,omitempty" bson:"url,omitempty"`
+	Format string `json:"format" bson:"format"`
+	// ExportedTs is when the copy was taken, which is when it was saved rather than when it was delivered.
+	ExportedTs time.Time `json:"exportedTs" bson:"exportedTs"`
+	// ContentHash is the hash of the name, text and tags of the note as they were copied.
+	ContentHash string `json:"contentHash" bson:"contentHash"`
 }
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type NotebookRequest struct {
+	Name string `json:"name"`
+	// ParentID is the notebook to create the notebook in; empty creates it at the top level. It is ignored by updates,
+	// which only rename; notebooks are moved with a move request.
+	ParentID string `json:"parentId"`
+}
+
+// MoveRequest names the notebook a note or notebook is moved into. An empty NotebookID moves it to the top level.
+type MoveRequest struct {
+	NotebookID string `json:"notebookId"`
+}
+
+type Notebook struct {
+	ID           primitive.ObjectID  `json:"id" bson:"_id"`
+	OwnerID      string              `json:"ownerId" bson:"ownerId"`
+	Name         string              `json:"name" bson:"name"`
+	ParentID     *primitive.ObjectID `json:"parentId,omitempty" bson:"parentId,omitempty"`
+	LastEditedTs time.Time           `json:"lastEditedTs" bson:"lastEditedTs"`
+}
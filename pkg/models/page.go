@@ -0,0 +1,49 @@
+package models
+
+// NoteQuery describes one page of a note listing.
+type NoteQuery struct {
+	// Limit is the maximum number of notes to return; zero means the service default.
+	Limit int
+	// Next is the token returned with the previous page, or empty for the first page.
+	Next string
+	// Sort is one of "lastEditedTs", "name" or "created"; empty means "lastEditedTs".
+	Sort string
+	// Order is "asc" or "desc"; empty means the default order of the sort field.
+	Order string
+	// Fields restricts the returned note fields by their JSON names; empty returns every field.
+	Fields []string
+	// Tags restricts the listing to notes with these tags.
+	Tags []string
+	// TagMatch is "all" to require every tag or "any" to require at least one; empty means "all".
+	TagMatch string
+	// NotebookID restricts the listing to the notes directly inside a notebook.
+	NotebookID string
+}
+
+// NotePage is one page of a note listing. Next is empty on the last page.
+type NotePage struct {
+	Notes []Note `json:"notes"`
+	Next  string `json:"next,omitempty"`
+}
+
+// SearchQuery describes one page of full-text search results.
+type SearchQuery struct {
+	// Text is the search string, in MongoDB $text syntax: words, "quoted phrases" and -negated words.
+	Text  string
+	Limit int
+	Next  string
+}
+
+// SearchResult is a note matching a search, with its relevance score and the matching parts of its name and text.
+// Highlights are HTML: the note's text is escaped and matched words are wrapped in <mark> elements.
+type SearchResult struct {
+	Note       Note              `json:"note"`
+	Score      float64           `json:"score"`
+	Highlights map[string]string `json:"highlights,omitempty"`
+}
+
+// SearchPage is one page of search results, most relevant first. Next is empty on the last page.
+type SearchPage struct {
+	Results []SearchResult `json:"results"`
+	Next    string         `json:"next,omitempty"`
+}
@@ -0,0 +1,12 @@
+package models
+
+import (
+	"time"
+)
+
+type Principal struct {
+	UserID    string    `json:"id"`
+	Username  string    `json:"username"`
+	Roles     []string  `json:"roles"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
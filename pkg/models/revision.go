@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Revision struct {
+	ID       primitive.ObjectID `json:"id" bson:"_id"`
+	NoteID   primitive.ObjectID `json:"noteId" bson:"noteId"`
+	OwnerID  string             `json:"ownerId" bson:"ownerId"`
+	Name     string             `json:"name" bson:"name"`
+	Text     string             `json:"text" bson:"text"`
+	Version  int64              `json:"version" bson:"version"`
+	EditedBy string             `json:"editedBy" bson:"editedBy"`
+	EditedTs time.Time          `json:"editedTs" bson:"editedTs"`
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// The statuses of a SaveJob. A job is pending until it is delivered or has failed for good.
+const (
+	SaveJobPending   = "pending"
+	SaveJobDelivered = "delivered"
+	SaveJobFailed    = "failed"
+)
+
+// SaveJob is a queued upload of a note to the content service. The file is rendered when the job is created, so edits
+// made to the note afterwards are not part of it, unless the job is an AutoSync job.
+type SaveJob struct {
+	ID      primitive.ObjectID `json:"id" bson:"_id"`
+	OwnerID string             `json:"ownerId" bson:"ownerId"`
+	NoteID  primitive.ObjectID `json:"noteId" bson:"noteId"`
+	// Format is the export format the note was rendered in.
+	Format      string `json:"format" bson:"format"`
+	FileName    string `json:"fileName" bson:"fileName"`
+	ContentType string `json:"contentType" bson:"contentType"`
+	Content     string `json:"-" bson:"content"`
+	// ContentHash is the content hash of the note as it was rendered, which is recorded on the note once the job is
+	// delivered.
+	ContentHash string `json:"-" bson:"contentHash"`
+	// Token is the bearer token of the user who saved the note, which the file is uploaded with, sealed so that it is
+	// not kept in the clear. It is removed once the job is finished.
+	Token         string     `json:"-" bson:"token,omitempty"`
+	Status        string     `json:"status" bson:"status"`
+	Attempts      int        `json:"attempts" bson:"attempts"`
+	LastError     string     `json:"lastError,omitempty" bson:"lastError,omitempty"`
+	CreatedTs     time.Time  `json:"createdTs" bson:"createdTs"`
+	NextAttemptTs time.Time  `json:"nextAttemptTs" bson:"nextAttemptTs"`
+	FinishedTs    *time.Time `json:"finishedTs,omitempty" bson:"finishedTs,omitempty"`
+	// AutoSync marks a job queued by an update of a note that is synced automatically. Its file is rendered when it
+	// is delivered rather than when it is queued, so that one job carries all the updates made while it waited, and
+	// it replaces the file last delivered for the note.
+	AutoSync bool `json:"autoSync,omitempty" bson:"autoSync,omitempty"`
+	// File is the content service's record of the delivered file.
+	File *UploadedFile `json:"file,omitempty" bson:"file,omitempty"`
+}
@@ -0,0 +1,12 @@
+package models
+
+// TagCount is the number of notes with a tag.
+type TagCount struct {
+	Tag   string `json:"tag" bson:"_id"`
+	Count int64  `json:"count" bson:"count"`
+}
+
+// TagRenameRequest is the body of a tag rename.
+type TagRenameRequest struct {
+	Name string `json:"name"`
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+
+	"notes-api/pkg/models"
+)
+
+// contentHash is the hash of the parts of a note that are exported: its name, text and tags. Saving a note and editing
+// it without changing any of them leaves its export in sync.
+func contentHash(note models.Note) string {
+	hash := sha256.New()
+	// Every part is prefixed with its length, so that no two notes' parts run together the same way.
+	write := func(part string) {
+		var length [binary.MaxVarintLen64]byte
+		hash.Write(length[:binary.PutUvarint(length[:], uint64(len(part)))])
+		hash.Write([]byte(part))
+	}
+
+	write(note.Name)
+	write(note.Text)
+	for _, tag := range note.Tags {
+		write(tag)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// exportStatus works out the export status of a note.
+func exportStatus(note models.Note) string {
+	switch {
+	case note.Export == nil:
+		return models.ExportStatusNever
+	case note.Export.ContentHash == contentHash(note):
+		return models.ExportStatusSynced
+	default:
+		return models.ExportStatusStale
+	}
+}
+
+// setExportStatus sets the export status of each note.
+func setExportStatus(notes []models.Note) {
+	for i := range notes {
+		notes[i].ExportStatus = exportStatus(notes[i])
+	}
+}
@@ -0,0 +1,31 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"notes-api/pkg/models"
+)
+
+func TestService_ExportStatus_ShouldCompareExportedContentWithNote(t *testing.T) {
+	note := models.Note{Name: "a", Text: "text", Tags: []string{"x"}, LastEditedTs: time.Now()}
+	require.Equal(t, models.ExportStatusNever, exportStatus(note))
+
+	note.Export = &models.NoteExport{ContentHash: contentHash(note)}
+	require.Equal(t, models.ExportStatusSynced, exportStatus(note))
+
+	note.LastEditedTs = note.LastEditedTs.Add(time.Hour)
+	note.Version++
+	require.Equal(t, models.ExportStatusSynced, exportStatus(note))
+
+	for _, changed := range []models.Note{
+		{Name: "b", Text: "text", Tags: []string{"x"}, Export: note.Export},
+		{Name: "a", Text: "text!", Tags: []string{"x"}, Export: note.Export},
+		{Name: "a", Text: "text", Export: note.Export},
+		{Name: "a", Text: "text\x00x", Export: note.Export},
+	} {
+		require.Equal(t, models.ExportStatusStale, exportStatus(changed), changed)
+	}
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"notes-api/pkg/dao"
+	"notes-api/pkg/export"
+	"notes-api/pkg/external"
+	"notes-api/pkg/models"
+)
+
+// The reasons an import skips a file, besides the error that reading it failed with.
+const (
+	skipNotFound  = "file not found in content service"
+	skipDuplicate = "a note with the same text already exists"
+)
+
+// ListContentFiles returns the files the caller whose token is given has stored in the content service.
+func (svc *NotesService) ListContentFiles(ctx context.Context, token string) ([]models.ContentFile, error) {
+	return svc.Ext.ListContentFiles(ctx, token)
+}
+
+// ImportNotes creates a note from each of the given content service files that is plain text or Markdown, on behalf
+// of the caller whose token is given, and reports what it did with every file.
+//
+// A file is skipped if the user already has a note with the same text, in the trash or not, or if an earlier file of
+// the same import has it, so a failed import can be repeated without creating the notes it did create twice. Notes
+// are found by their TextHash, so on MongoDB, notes written before notes had one are not found until the backfill
+// started with the API has hashed them.
+func (svc *NotesService) ImportNotes(ctx context.Context, userID string, token string, request models.ImportRequest) (*models.ImportResult, error) {
+	if len(request.FileIDs) == 0 {
+		return nil, fmt.Errorf("%w: no files to import", ErrInvalidQuery)
+	}
+
+	if _, err := svc.notebookRef(ctx, userID, request.NotebookID); err != nil {
+		return nil, err
+	}
+
+	files, err := svc.Ext.ListContentFiles(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	filesByID := make(map[string]models.ContentFile, len(files))
+	for _, file := range files {
+		filesByID[file.ID] = file
+	}
+
+	// The notes created by this import, by their TextHash.
+	imported := make(map[string]primitive.ObjectID, len(request.FileIDs))
+
+	result := &models.ImportResult{
+		Imported: []models.ImportedFile{},
+		Skipped:  []models.SkippedFile{},
+	}
+	requested := make(map[string]bool, len(request.FileIDs))
+	for _, fileID := range request.FileIDs {
+		if requested[fileID] {
+			continue
+		}
+		requested[fileID] = true
+
+		file, ok := filesByID[fileID]
+		if !ok {
+			result.Skipped = append(result.Skipped, models.SkippedFile{FileID: fileID, Reason: skipNotFound})
+			continue
+		}
+
+		noteRequest, err := svc.readContentFile(ctx, token, file)
+		if err != nil {
+			reason := err.Error()
+			var statusErr *external.StatusError
+			if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound {
+				reason = skipNotFound
+			}
+			result.Skipped = append(result.Skipped, models.SkippedFile{FileID: fileID, Reason: reason})
+			continue
+		}
+
+		hash := dao.TextHash(noteRequest.Text)
+		duplicate, ok := imported[hash]
+		if !ok {
+			// A note in the trash counts, since restoring it would make a duplicate of the imported one.
+			notes, err := svc.Dao.GetNotes(ctx, dao.NoteFilter{OwnerID: userID, TextHashes: []string{hash}, Trash: dao.AnyTrash})
+			if err != nil {
+				return nil, err
+			}
+			if len(notes) > 0 {
+				duplicate, ok = notes[0].ID, true
+			}
+		}
+		if ok {
+			result.Skipped = append(result.Skipped, models.SkippedFile{FileID: fileID, Reason: skipDuplicate, NoteID: &duplicate})
+			continue
+		}
+
+		noteRequest.NotebookID = request.NotebookID
+		id, err := svc.CreateNote(ctx, userID, noteRequest)
+		if err != nil {
+			return nil, err
+		}
+		noteID, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return nil, err
+		}
+		imported[hash] = noteID
+
+		result.Imported = append(result.Imported, models.ImportedFile{FileID: fileID, NoteID: noteID, Name: noteRequest.Name})
+	}
+
+	return result, nil
+}
+
+// readContentFile downloads a content service file and reads a note from it. Files that are not text or Markdown are
+// not downloaded.
+func (svc *NotesService) readContentFile(ctx context.Context, token string, file models.ContentFile) (models.NoteRequest, error) {
+	if !export.CanParse(file.Name, file.ContentType) {
+		return models.NoteRequest{}, export.ErrUnsupportedFile
+	}
+
+	content, err := svc.Ext.DownloadContentFile(ctx, token, file.ID)
+	if err != nil {
+		return models.NoteRequest{}, err
+	}
+
+	return export.Parse(file.Name, file.ContentType, content)
+}
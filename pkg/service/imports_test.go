@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"notes-api/pkg/dao"
+	"notes-api/pkg/export"
+	"notes-api/pkg/external"
+	"notes-api/pkg/models"
+	"notes-api/pkg/testhelper/mocks"
+)
+
+func TestService_ImportNotes_ShouldReturnInvalidQueryIfNoFilesAreGiven(t *testing.T) {
+	service := NotesService{}
+
+	_, err := service.ImportNotes(context.TODO(), "user", "token", models.ImportRequest{})
+	require.True(t, errors.Is(err, ErrInvalidQuery))
+}
+
+func TestService_ImportNotes_ShouldReturnErrorIfFilesCannotBeListed(t *testing.T) {
+	mockExt := &mocks.ExtAPIHandler{}
+	mockExt.On("ListContentFiles", mock.Anything, "token").Return(nil, &external.StatusError{StatusCode: http.StatusBadGateway})
+
+	service := NotesService{
+		Ext: mockExt,
+	}
+
+	_, err := service.ImportNotes(context.TODO(), "user", "token", models.ImportRequest{FileIDs: []string{"1"}})
+	require.Equal(t, &external.StatusError{StatusCode: http.StatusBadGateway}, err)
+}
+
+func TestService_ImportNotes_ShouldCreateNotesFromTextAndMarkdownFilesOnce(t *testing.T) {
+	memoryDao := dao.NewMemoryDao()
+	mockExt := &mocks.ExtAPIHandler{}
+	mockExt.On("ListContentFiles", mock.Anything, "token").Return([]models.ContentFile{
+		{ID: "text", Name: "todo.txt"},
+		{ID: "markdown", Name: "plans.md"},
+		{ID: "copy", Name: "todo (copy).txt"},
+		{ID: "existing", Name: "old.txt"},
+		{ID: "pdf", Name: "scan.pdf", ContentType: "application/pdf"},
+		{ID: "gone", Name: "gone.txt"},
+	}, nil)
+	mockExt.On("DownloadContentFile", mock.Anything, "token", "text").Return([]byte("milk"), nil)
+	mockExt.On("DownloadContentFile", mock.Anything, "token", "markdown").Return([]byte("---\nname: Plans\ntags: [work]\n---\n\n# Q3"), nil)
+	mockExt.On("DownloadContentFile", mock.Anything, "token", "copy").Return([]byte("milk"), nil)
+	mockExt.On("DownloadContentFile", mock.Anything, "token", "existing").Return([]byte("already here"), nil)
+	mockExt.On("DownloadContentFile", mock.Anything, "token", "gone").Return(nil, &external.StatusError{StatusCode: http.StatusNotFound})
+
+	service := NotesService{
+		Dao: memoryDao,
+		Ext: mockExt,
+	}
+
+	existingID, err := service.CreateNote(context.TODO(), "user", models.NoteRequest{Name: "old", Text: "already here"})
+	require.Nil(t, err)
+
+	result, err := service.ImportNotes(context.TODO(), "user", "token", models.ImportRequest{
+		FileIDs: []string{"text", "markdown", "copy", "existing", "pdf", "gone", "unknown", "text"},
+	})
+	require.Nil(t, err)
+
+	require.Len(t, result.Imported, 2)
+	require.Equal(t, "todo", result.Imported[0].Name)
+	require.Equal(t, "Plans", result.Imported[1].Name)
+
+	reasons := make(map[string]string)
+	for _, skipped := range result.Skipped {
+		reasons[skipped.FileID] = skipped.Reason
+	}
+	require.Equal(t, map[string]string{
+		"copy":     skipDuplicate,
+		"existing": skipDuplicate,
+		"pdf":      export.ErrUnsupportedFile.Error(),
+		"gone":     skipNotFound,
+		"unknown":  skipNotFound,
+	}, reasons)
+	require.Equal(t, existingID, result.Skipped[1].NoteID.Hex())
+	mockExt.AssertNotCalled(t, "DownloadContentFile", mock.Anything, mock.Anything, "pdf")
+
+	notes, err := service.GetNotes(context.TODO(), "user", result.Imported[1].NoteID.Hex())
+	require.Nil(t, err)
+	require.Equal(t, "# Q3", notes[0].Text)
+	require.Equal(t, []string{"work"}, notes[0].Tags)
+}
+
+func TestService_ImportNotes_ShouldSkipTextOfNotesInTrashAndAsEdited(t *testing.T) {
+	memoryDao := dao.NewMemoryDao()
+	mockExt := &mocks.ExtAPIHandler{}
+	mockExt.On("ListContentFiles", mock.Anything, "token").Return([]models.ContentFile{
+		{ID: "trashed", Name: "trashed.txt"},
+		{ID: "edited", Name: "edited.txt"},
+		{ID: "before", Name: "before.txt"},
+	}, nil)
+	mockExt.On("DownloadContentFile", mock.Anything, "token", "trashed").Return([]byte("in the trash"), nil)
+	mockExt.On("DownloadContentFile", mock.Anything, "token", "edited").Return([]byte("after"), nil)
+	mockExt.On("DownloadContentFile", mock.Anything, "token", "before").Return([]byte("before"), nil)
+
+	service := NotesService{
+		Dao: memoryDao,
+		Ext: mockExt,
+	}
+
+	trashedID, err := service.CreateNote(context.TODO(), "user", models.NoteRequest{Name: "trashed", Text: "in the trash"})
+	require.Nil(t, err)
+	require.Nil(t, service.DeleteNote(context.TODO(), "user", trashedID))
+	editedID, err := service.CreateNote(context.TODO(), "user", models.NoteRequest{Name: "edited", Text: "before"})
+	require.Nil(t, err)
+	_, err = service.UpdateNote(context.TODO(), "user", "token", editedID, 1, models.NoteRequest{Name: "edited", Text: "after"})
+	require.Nil(t, err)
+
+	result, err := service.ImportNotes(context.TODO(), "user", "token", models.ImportRequest{FileIDs: []string{"trashed", "edited", "before"}})
+	require.Nil(t, err)
+
+	require.Len(t, result.Imported, 1)
+	require.Equal(t, "before", result.Imported[0].FileID)
+	require.Len(t, result.Skipped, 2)
+	require.Equal(t, trashedID, result.Skipped[0].NoteID.Hex())
+	require.Equal(t, editedID, result.Skipped[1].NoteID.Hex())
+}
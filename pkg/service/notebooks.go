@@ -0,0 +1,267 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"notes-api/pkg/dao"
+	"notes-api/pkg/models"
+)
+
+func (svc *NotesService) GetNotebooks(ctx context.Context, userID string) ([]models.Notebook, error) {
+	notebooks, err := svc.Notebooks.GetNotebooks(ctx, dao.NotebookFilter{OwnerID: userID})
+	if err != nil {
+		return nil, err
+	} else if notebooks == nil {
+		notebooks = []models.Notebook{}
+	}
+
+	return notebooks, nil
+}
+
+func (svc *NotesService) GetNotebook(ctx context.Context, userID string, id string) (*models.Notebook, error) {
+	objectId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	notebooks, err := svc.Notebooks.GetNotebooks(ctx, dao.NotebookFilter{
+		IDs:     []primitive.ObjectID{objectId},
+		OwnerID: userID,
+	})
+	if err != nil {
+		return nil, err
+	} else if len(notebooks) == 0 {
+		return nil, dao.ErrNotebookNotFound
+	}
+
+	return &notebooks[0], nil
+}
+
+func (svc *NotesService) CreateNotebook(ctx context.Context, userID string, notebookRequest models.NotebookRequest) (string, error) {
+	parentID, err := svc.notebookRef(ctx, userID, notebookRequest.ParentID)
+	if err != nil {
+		return "", err
+	}
+
+	notebook := models.Notebook{
+		ID:           primitive.NewObjectID(),
+		OwnerID:      userID,
+		Name:         notebookRequest.Name,
+		ParentID:     parentID,
+		LastEditedTs: time.Now(),
+	}
+
+	if err := svc.Notebooks.CreateNotebook(ctx, notebook); err != nil {
+		return "", err
+	}
+
+	return notebook.ID.Hex(), nil
+}
+
+// UpdateNotebook renames a notebook.
+func (svc *NotesService) UpdateNotebook(ctx context.Context, userID string, id string, notebookRequest models.NotebookRequest) error {
+	objectId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	filter := dao.NotebookFilter{
+		IDs:     []primitive.ObjectID{objectId},
+		OwnerID: userID,
+	}
+
+	now := time.Now()
+
+	return svc.Notebooks.UpdateNotebook(ctx, filter, dao.NotebookPatch{
+		Name:         &notebookRequest.Name,
+		LastEditedTs: &now,
+	})
+}
+
+// MoveNotebook moves a notebook, with everything in it, into another notebook, or to the top level if parentID is
+// empty. Moving a notebook into itself or into a notebook inside it would detach them from the tree, so it fails with
+// ErrNotebookCycle.
+func (svc *NotesService) MoveNotebook(ctx context.Context, userID string, id string, parentID string) error {
+	objectId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	parent, err := svc.notebookRef(ctx, userID, parentID)
+	if err != nil {
+		return err
+	}
+
+	patch := dao.NotebookPatch{MoveToTop: true}
+	if parent != nil {
+		parents, err := svc.notebookParents(ctx, userID)
+		if err != nil {
+			return err
+		}
+
+		// Walk up from the new parent; reaching the notebook being moved means the new parent is inside it. The
+		// visited check stops the walk on cycles left behind by concurrent moves.
+		visited := map[primitive.ObjectID]bool{}
+		for current := parent; current != nil && !visited[*current]; current = parents[*current] {
+			if *current == objectId {
+				return ErrNotebookCycle
+			}
+			visited[*current] = true
+		}
+
+		patch = dao.NotebookPatch{ParentID: parent}
+	}
+
+	filter := dao.NotebookFilter{
+		IDs:     []primitive.ObjectID{objectId},
+		OwnerID: userID,
+	}
+
+	return svc.Notebooks.UpdateNotebook(ctx, filter, patch)
+}
+
+// DeleteNotebook deletes a notebook and every notebook inside it. Without cascade, it fails with ErrNotebookNotEmpty
+// if the notebook holds any notebooks or notes outside the trash. With cascade, the notes in the deleted notebooks
+// are moved to the trash. Either way, notes in the trash that were in the deleted notebooks are restored to the top
+// level. Notes taken out of the deleted notebooks get a new version, as moved notes do.
+func (svc *NotesService) DeleteNotebook(ctx context.Context, userID string, id string, cascade bool) error {
+	objectId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	parents, err := svc.notebookParents(ctx, userID)
+	if err != nil {
+		return err
+	} else if _, ok := parents[objectId]; !ok {
+		return dao.ErrNotebookNotFound
+	}
+
+	ids := descendants(parents, objectId)
+
+	if !cascade {
+		if len(ids) > 1 {
+			return ErrNotebookNotEmpty
+		}
+
+		notes, err := svc.Dao.ListNotes(ctx, dao.NoteFilter{
+			OwnerID:     userID,
+			NotebookIDs: []primitive.ObjectID{objectId},
+		}, dao.ListOptions{Limit: 1, Fields: []string{"id"}})
+		if err != nil {
+			return err
+		} else if len(notes) > 0 {
+			return ErrNotebookNotEmpty
+		}
+	}
+
+	now := time.Now()
+	if cascade {
+		_, err := svc.Dao.UpdateNotes(ctx, dao.NoteFilter{
+			OwnerID:     userID,
+			NotebookIDs: ids,
+		}, dao.NotePatch{DeletedAt: &now, LastEditedTs: &now, IncrementVersion: true})
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = svc.Dao.UpdateNotes(ctx, dao.NoteFilter{
+		OwnerID:     userID,
+		NotebookIDs: ids,
+		Trash:       dao.AnyTrash,
+	}, dao.NotePatch{RemoveFromNotebook: true, LastEditedTs: &now, IncrementVersion: true})
+	if err != nil {
+		return err
+	}
+
+	_, err = svc.Notebooks.DeleteNotebooks(ctx, dao.NotebookFilter{
+		IDs:     ids,
+		OwnerID: userID,
+	})
+	return err
+}
+
+// MoveNote moves a note into a notebook, or out of any notebook if notebookID is empty. A move is an edit of the
+// note, so it takes the next version and writes based on the version before it are rejected.
+func (svc *NotesService) MoveNote(ctx context.Context, userID string, id string, notebookID string) error {
+	objectId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	notebook, err := svc.notebookRef(ctx, userID, notebookID)
+	if err != nil {
+		return err
+	}
+
+	filter := dao.NoteFilter{
+		IDs:     []primitive.ObjectID{objectId},
+		OwnerID: userID,
+	}
+
+	now := time.Now()
+	patch := dao.NotePatch{LastEditedTs: &now, IncrementVersion: true}
+	if notebook != nil {
+		patch.NotebookID = notebook
+	} else {
+		patch.RemoveFromNotebook = true
+	}
+
+	return svc.Dao.UpdateNote(ctx, filter, patch)
+}
+
+// notebookRef checks that the user has the notebook with the given ID and returns the ID, or nil if id is empty.
+func (svc *NotesService) notebookRef(ctx context.Context, userID string, id string) (*primitive.ObjectID, error) {
+	if id == "" {
+		return nil, nil
+	}
+
+	notebook, err := svc.GetNotebook(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &notebook.ID, nil
+}
+
+// notebookParents returns the parent of each of the user's notebooks, nil for top level notebooks.
+func (svc *NotesService) notebookParents(ctx context.Context, userID string) (map[primitive.ObjectID]*primitive.ObjectID, error) {
+	notebooks, err := svc.Notebooks.GetNotebooks(ctx, dao.NotebookFilter{OwnerID: userID})
+	if err != nil {
+		return nil, err
+	}
+
+	parents := make(map[primitive.ObjectID]*primitive.ObjectID, len(notebooks))
+	for _, notebook := range notebooks {
+		parents[notebook.ID] = notebook.ParentID
+	}
+
+	return parents, nil
+}
+
+// descendants returns the given notebook followed by every notebook inside it, at any depth.
+func descendants(parents map[primitive.ObjectID]*primitive.ObjectID, id primitive.ObjectID) []primitive.ObjectID {
+	children := map[primitive.ObjectID][]primitive.ObjectID{}
+	for child, parent := range parents {
+		if parent != nil {
+			children[*parent] = append(children[*parent], child)
+		}
+	}
+
+	ids := []primitive.ObjectID{id}
+	seen := map[primitive.ObjectID]bool{id: true}
+	for i := 0; i < len(ids); i++ {
+		for _, child := range children[ids[i]] {
+			if !seen[child] {
+				seen[child] = true
+				ids = append(ids, child)
+			}
+		}
+	}
+
+	return ids
+}
@@ -0,0 +1,353 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"notes-api/pkg/dao"
+	"notes-api/pkg/models"
+	"notes-api/pkg/testhelper/mocks"
+)
+
+// notebookTree returns notebooks a, b inside a, and c inside b.
+func notebookTree() (a, b, c models.Notebook) {
+	a = models.Notebook{ID: primitive.NewObjectID()}
+	b = models.Notebook{ID: primitive.NewObjectID(), ParentID: &a.ID}
+	c = models.Notebook{ID: primitive.NewObjectID(), ParentID: &b.ID}
+	return a, b, c
+}
+
+func TestService_GetNotebooks_ShouldReturnErrorOnDaoError(t *testing.T) {
+	mockNotebooks := &mocks.NotebookDaoHandler{}
+	mockNotebooks.On("GetNotebooks", mock.Anything, mock.Anything).Return(nil, errors.New("test"))
+
+	service := NotesService{
+		Notebooks: mockNotebooks,
+	}
+
+	notebooks, err := service.GetNotebooks(context.TODO(), "user")
+	require.Nil(t, notebooks)
+	require.NotNil(t, err)
+	require.Equal(t, "test", err.Error())
+}
+
+func TestService_GetNotebooks_ShouldScopeFilterToUser(t *testing.T) {
+	mockNotebooks := &mocks.NotebookDaoHandler{}
+	mockNotebooks.On("GetNotebooks", mock.Anything, dao.NotebookFilter{OwnerID: "user"}).Return(nil, nil)
+
+	service := NotesService{
+		Notebooks: mockNotebooks,
+	}
+
+	notebooks, err := service.GetNotebooks(context.TODO(), "user")
+	require.Nil(t, err)
+	require.Equal(t, []models.Notebook{}, notebooks)
+	mockNotebooks.AssertExpectations(t)
+}
+
+func TestService_GetNotebook_ShouldReturnNotFoundIfNoNotebookMatches(t *testing.T) {
+	mockNotebooks := &mocks.NotebookDaoHandler{}
+	mockNotebooks.On("GetNotebooks", mock.Anything, mock.Anything).Return([]models.Notebook{}, nil)
+
+	service := NotesService{
+		Notebooks: mockNotebooks,
+	}
+
+	notebook, err := service.GetNotebook(context.TODO(), "user", "000000000000000000000000")
+	require.Nil(t, notebook)
+	require.Equal(t, dao.ErrNotebookNotFound, err)
+}
+
+func TestService_CreateNotebook_ShouldReturnNotFoundIfParentDoesNotExist(t *testing.T) {
+	mockNotebooks := &mocks.NotebookDaoHandler{}
+	mockNotebooks.On("GetNotebooks", mock.Anything, mock.Anything).Return([]models.Notebook{}, nil)
+
+	service := NotesService{
+		Notebooks: mockNotebooks,
+	}
+
+	_, err := service.CreateNotebook(context.TODO(), "user", models.NotebookRequest{ParentID: "000000000000000000000000"})
+	require.Equal(t, dao.ErrNotebookNotFound, err)
+	mockNotebooks.AssertNotCalled(t, "CreateNotebook", mock.Anything, mock.Anything)
+}
+
+func TestService_CreateNotebook_ShouldCreateNotebookInParent(t *testing.T) {
+	parent := models.Notebook{ID: primitive.NewObjectID()}
+
+	mockNotebooks := &mocks.NotebookDaoHandler{}
+	mockNotebooks.On("GetNotebooks", mock.Anything, mock.Anything).Return([]models.Notebook{parent}, nil)
+	mockNotebooks.On("CreateNotebook", mock.Anything, mock.MatchedBy(func(notebook models.Notebook) bool {
+		return notebook.OwnerID == "user" && notebook.Name == "Runbooks" && *notebook.ParentID == parent.ID
+	})).Return(nil)
+
+	service := NotesService{
+		Notebooks: mockNotebooks,
+	}
+
+	id, err := service.CreateNotebook(context.TODO(), "user", models.NotebookRequest{Name: "Runbooks", ParentID: parent.ID.Hex()})
+	require.Nil(t, err)
+	require.NotEmpty(t, id)
+	mockNotebooks.AssertExpectations(t)
+}
+
+func TestService_MoveNotebook_ShouldReturnCycleErrorIfMovedIntoItself(t *testing.T) {
+	a, b, c := notebookTree()
+
+	mockNotebooks := &mocks.NotebookDaoHandler{}
+	mockNotebooks.On("GetNotebooks", mock.Anything, mock.Anything).Return([]models.Notebook{a, b, c}, nil)
+
+	service := NotesService{
+		Notebooks: mockNotebooks,
+	}
+
+	err := service.MoveNotebook(context.TODO(), "user", a.ID.Hex(), a.ID.Hex())
+	require.Equal(t, ErrNotebookCycle, err)
+}
+
+func TestService_MoveNotebook_ShouldReturnCycleErrorIfMovedIntoDescendant(t *testing.T) {
+	a, b, c := notebookTree()
+
+	mockNotebooks := &mocks.NotebookDaoHandler{}
+	mockNotebooks.On("GetNotebooks", mock.Anything, mock.Anything).Return([]models.Notebook{a, b, c}, nil)
+
+	service := NotesService{
+		Notebooks: mockNotebooks,
+	}
+
+	err := service.MoveNotebook(context.TODO(), "user", a.ID.Hex(), c.ID.Hex())
+	require.Equal(t, ErrNotebookCycle, err)
+	mockNotebooks.AssertNotCalled(t, "UpdateNotebook", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestService_MoveNotebook_ShouldSetParent(t *testing.T) {
+	a, b, c := notebookTree()
+
+	mockNotebooks := &mocks.NotebookDaoHandler{}
+	mockNotebooks.On("GetNotebooks", mock.Anything, mock.Anything).Return([]models.Notebook{a, b, c}, nil)
+	mockNotebooks.On("UpdateNotebook", mock.Anything, dao.NotebookFilter{
+		IDs:     []primitive.ObjectID{c.ID},
+		OwnerID: "user",
+	}, dao.NotebookPatch{ParentID: &a.ID}).Return(nil)
+
+	service := NotesService{
+		Notebooks: mockNotebooks,
+	}
+
+	err := service.MoveNotebook(context.TODO(), "user", c.ID.Hex(), a.ID.Hex())
+	require.Nil(t, err)
+	mockNotebooks.AssertExpectations(t)
+}
+
+func TestService_MoveNotebook_ShouldUnsetParentIfMovedToTopLevel(t *testing.T) {
+	_, b, _ := notebookTree()
+
+	mockNotebooks := &mocks.NotebookDaoHandler{}
+	mockNotebooks.On("UpdateNotebook", mock.Anything, mock.Anything, dao.NotebookPatch{MoveToTop: true}).Return(nil)
+
+	service := NotesService{
+		Notebooks: mockNotebooks,
+	}
+
+	err := service.MoveNotebook(context.TODO(), "user", b.ID.Hex(), "")
+	require.Nil(t, err)
+	mockNotebooks.AssertExpectations(t)
+}
+
+func TestService_DeleteNotebook_ShouldReturnNotFoundIfNotebookDoesNotExist(t *testing.T) {
+	mockNotebooks := &mocks.NotebookDaoHandler{}
+	mockNotebooks.On("GetNotebooks", mock.Anything, mock.Anything).Return([]models.Notebook{}, nil)
+
+	service := NotesService{
+		Notebooks: mockNotebooks,
+	}
+
+	err := service.DeleteNotebook(context.TODO(), "user", "000000000000000000000000", false)
+	require.Equal(t, dao.ErrNotebookNotFound, err)
+}
+
+func TestService_DeleteNotebook_ShouldBlockIfNotebookHasNotebooks(t *testing.T) {
+	a, b, c := notebookTree()
+
+	mockNotebooks := &mocks.NotebookDaoHandler{}
+	mockNotebooks.On("GetNotebooks", mock.Anything, mock.Anything).Return([]models.Notebook{a, b, c}, nil)
+
+	service := NotesService{
+		Notebooks: mockNotebooks,
+	}
+
+	err := service.DeleteNotebook(context.TODO(), "user", b.ID.Hex(), false)
+	require.Equal(t, ErrNotebookNotEmpty, err)
+	mockNotebooks.AssertNotCalled(t, "DeleteNotebooks", mock.Anything, mock.Anything)
+}
+
+func TestService_DeleteNotebook_ShouldBlockIfNotebookHasNotes(t *testing.T) {
+	_, _, c := notebookTree()
+
+	mockNotebooks := &mocks.NotebookDaoHandler{}
+	mockNotebooks.On("GetNotebooks", mock.Anything, mock.Anything).Return([]models.Notebook{c}, nil)
+	mockDao := &mocks.NoteDaoHandler{}
+	mockDao.On("ListNotes", mock.Anything, mock.Anything, mock.Anything).Return([]models.Note{{}}, nil)
+
+	service := NotesService{
+		Dao:       mockDao,
+		Notebooks: mockNotebooks,
+	}
+
+	err := service.DeleteNotebook(context.TODO(), "user", c.ID.Hex(), false)
+	require.Equal(t, ErrNotebookNotEmpty, err)
+	mockNotebooks.AssertNotCalled(t, "DeleteNotebooks", mock.Anything, mock.Anything)
+}
+
+func TestService_DeleteNotebook_ShouldDeleteEmptyNotebook(t *testing.T) {
+	_, _, c := notebookTree()
+
+	mockNotebooks := &mocks.NotebookDaoHandler{}
+	mockNotebooks.On("GetNotebooks", mock.Anything, mock.Anything).Return([]models.Notebook{c}, nil)
+	mockNotebooks.On("DeleteNotebooks", mock.Anything, dao.NotebookFilter{
+		IDs:     []primitive.ObjectID{c.ID},
+		OwnerID: "user",
+	}).Return(int64(1), nil)
+	mockDao := &mocks.NoteDaoHandler{}
+	mockDao.On("ListNotes", mock.Anything, mock.Anything, mock.Anything).Return([]models.Note{}, nil)
+	mockDao.On("UpdateNotes", mock.Anything, mock.Anything, mock.MatchedBy(func(patch dao.NotePatch) bool {
+		return patch.RemoveFromNotebook && patch.IncrementVersion && patch.LastEditedTs != nil
+	})).Return(int64(0), nil)
+
+	service := NotesService{
+		Dao:       mockDao,
+		Notebooks: mockNotebooks,
+	}
+
+	err := service.DeleteNotebook(context.TODO(), "user", c.ID.Hex(), false)
+	require.Nil(t, err)
+	mockDao.AssertExpectations(t)
+	mockNotebooks.AssertExpectations(t)
+}
+
+func TestService_DeleteNotebook_ShouldCascadeToNotebooksAndNotesInside(t *testing.T) {
+	a, b, c := notebookTree()
+	ids := []primitive.ObjectID{b.ID, c.ID}
+
+	mockNotebooks := &mocks.NotebookDaoHandler{}
+	mockNotebooks.On("GetNotebooks", mock.Anything, mock.Anything).Return([]models.Notebook{a, b, c}, nil)
+	mockNotebooks.On("DeleteNotebooks", mock.Anything, dao.NotebookFilter{
+		IDs:     ids,
+		OwnerID: "user",
+	}).Return(int64(2), nil)
+	mockDao := &mocks.NoteDaoHandler{}
+	mockDao.On("UpdateNotes", mock.Anything, dao.NoteFilter{
+		OwnerID:     "user",
+		NotebookIDs: ids,
+	}, mock.MatchedBy(func(patch dao.NotePatch) bool {
+		return patch.DeletedAt != nil && patch.IncrementVersion && patch.LastEditedTs != nil
+	})).Return(int64(5), nil)
+	mockDao.On("UpdateNotes", mock.Anything, dao.NoteFilter{
+		OwnerID:     "user",
+		NotebookIDs: ids,
+		Trash:       dao.AnyTrash,
+	}, mock.MatchedBy(func(patch dao.NotePatch) bool {
+		return patch.RemoveFromNotebook && patch.IncrementVersion && patch.LastEditedTs != nil
+	})).Return(int64(6), nil)
+
+	service := NotesService{
+		Dao:       mockDao,
+		Notebooks: mockNotebooks,
+	}
+
+	err := service.DeleteNotebook(context.TODO(), "user", b.ID.Hex(), true)
+	require.Nil(t, err)
+	mockDao.AssertExpectations(t)
+	mockNotebooks.AssertExpectations(t)
+}
+
+func TestService_MoveNote_ShouldReturnNotFoundIfNotebookDoesNotExist(t *testing.T) {
+	mockNotebooks := &mocks.NotebookDaoHandler{}
+	mockNotebooks.On("GetNotebooks", mock.Anything, mock.Anything).Return([]models.Notebook{}, nil)
+	mockDao := &mocks.NoteDaoHandler{}
+
+	service := NotesService{
+		Dao:       mockDao,
+		Notebooks: mockNotebooks,
+	}
+
+	err := service.MoveNote(context.TODO(), "user", "000000000000000000000000", "000000000000000000000000")
+	require.Equal(t, dao.ErrNotebookNotFound, err)
+	mockDao.AssertNotCalled(t, "UpdateNote", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestService_MoveNote_ShouldSetNotebook(t *testing.T) {
+	notebook := models.Notebook{ID: primitive.NewObjectID()}
+
+	mockNotebooks := &mocks.NotebookDaoHandler{}
+	mockNotebooks.On("GetNotebooks", mock.Anything, mock.Anything).Return([]models.Notebook{notebook}, nil)
+	mockDao := &mocks.NoteDaoHandler{}
+	mockDao.On("UpdateNote", mock.Anything, mock.Anything, mock.MatchedBy(func(patch dao.NotePatch) bool {
+		return patch.NotebookID != nil && *patch.NotebookID == notebook.ID && !patch.RemoveFromNotebook &&
+			patch.IncrementVersion && patch.LastEditedTs != nil
+	})).Return(nil)
+
+	service := NotesService{
+		Dao:       mockDao,
+		Notebooks: mockNotebooks,
+	}
+
+	err := service.MoveNote(context.TODO(), "user", "000000000000000000000000", notebook.ID.Hex())
+	require.Nil(t, err)
+	mockDao.AssertExpectations(t)
+}
+
+func TestService_MoveNote_ShouldUnsetNotebookIfMovedOut(t *testing.T) {
+	mockDao := &mocks.NoteDaoHandler{}
+	mockDao.On("UpdateNote", mock.Anything, mock.Anything, mock.MatchedBy(func(patch dao.NotePatch) bool {
+		return patch.NotebookID == nil && patch.RemoveFromNotebook && patch.IncrementVersion && patch.LastEditedTs != nil
+	})).Return(nil)
+
+	service := NotesService{
+		Dao: mockDao,
+	}
+
+	err := service.MoveNote(context.TODO(), "user", "000000000000000000000000", "")
+	require.Nil(t, err)
+	mockDao.AssertExpectations(t)
+}
+
+func TestService_CreateNote_ShouldReturnNotFoundIfNotebookDoesNotExist(t *testing.T) {
+	mockNotebooks := &mocks.NotebookDaoHandler{}
+	mockNotebooks.On("GetNotebooks", mock.Anything, mock.Anything).Return([]models.Notebook{}, nil)
+	mockDao := &mocks.NoteDaoHandler{}
+
+	service := NotesService{
+		Dao:       mockDao,
+		Notebooks: mockNotebooks,
+	}
+
+	_, err := service.CreateNote(context.TODO(), "user", models.NoteRequest{NotebookID: "000000000000000000000000"})
+	require.Equal(t, dao.ErrNotebookNotFound, err)
+	mockDao.AssertNotCalled(t, "CreateNote", mock.Anything, mock.Anything)
+}
+
+func TestService_CreateNote_ShouldCreateNoteInNotebook(t *testing.T) {
+	notebook := models.Notebook{ID: primitive.NewObjectID()}
+
+	mockNotebooks := &mocks.NotebookDaoHandler{}
+	mockNotebooks.On("GetNotebooks", mock.Anything, mock.Anything).Return([]models.Notebook{notebook}, nil)
+	mockDao := &mocks.NoteDaoHandler{}
+	mockDao.On("CreateNote", mock.Anything, mock.MatchedBy(func(note models.Note) bool {
+		return note.NotebookID != nil && *note.NotebookID == notebook.ID
+	})).Return(nil)
+	mockDao.On("CreateRevision", mock.Anything, mock.Anything).Return(nil)
+
+	service := NotesService{
+		Dao:       mockDao,
+		Notebooks: mockNotebooks,
+	}
+
+	_, err := service.CreateNote(context.TODO(), "user", models.NoteRequest{NotebookID: notebook.ID.Hex()})
+	require.Nil(t, err)
+	mockDao.AssertExpectations(t)
+}
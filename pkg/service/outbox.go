@@ -0,0 +1,282 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"notes-api/pkg/dao"
+	"notes-api/pkg/export"
+	"notes-api/pkg/external"
+	"notes-api/pkg/models"
+)
+
+// SendToContentService queues a note to be uploaded to the content service on behalf of the caller whose token is
+// given, and returns the job that tracks the upload. The note is rendered now, in the given export format or as plain
+// text if none is given; a SaveWorker delivers it later.
+func (svc *NotesService) SendToContentService(ctx context.Context, userID string, token string, id string, format string) (*models.SaveJob, error) {
+	if format == "" {
+		format = export.FormatText
+	}
+	exporter, ok := svc.Exporters.Get(format)
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown format '%v', expected one of %v", ErrInvalidQuery, format,
+			strings.Join(svc.Exporters.Formats(), ", "))
+	}
+
+	notes, err := svc.GetNotes(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	} else if len(notes) == 0 {
+		return nil, dao.ErrNotFound
+	}
+	note := notes[0]
+
+	content, err := exporter.Export(note)
+	if err != nil {
+		return nil, err
+	}
+
+	sealed, err := svc.Tokens.Seal(token)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	job := models.SaveJob{
+		ID:            primitive.NewObjectID(),
+		OwnerID:       userID,
+		NoteID:        note.ID,
+		Format:        strings.ToLower(format),
+		FileName:      export.FileName(note.Name, exporter.Extension()),
+		ContentType:   exporter.ContentType(),
+		Content:       string(content),
+		ContentHash:   contentHash(note),
+		Token:         sealed,
+		Status:        models.SaveJobPending,
+		CreatedTs:     now,
+		NextAttemptTs: now,
+	}
+
+	if err := svc.SaveJobs.CreateSaveJob(ctx, job); err != nil {
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+func (svc *NotesService) GetSaveJob(ctx context.Context, userID string, id string) (*models.SaveJob, error) {
+	objectId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	jobs, err := svc.SaveJobs.GetSaveJobs(ctx, dao.SaveJobFilter{
+		IDs:     []primitive.ObjectID{objectId},
+		OwnerID: userID,
+	})
+	if err != nil {
+		return nil, err
+	} else if len(jobs) == 0 {
+		return nil, dao.ErrSaveJobNotFound
+	}
+
+	return &jobs[0], nil
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// openToken returns the token that a save job is delivered with. A token sealed with another key, such as the random
+// one used when SAVE_TOKEN_KEY is not set before a restart, is reported as expired.
+func (svc *NotesService) openToken(ctx context.Context, job *models.SaveJob) (string, error) {
+	token, err := svc.Tokens.Open(job.Token)
+	if err != nil {
+		logrus.WithContext(ctx).WithError(err).WithField("jobId", job.ID.Hex()).Warn("Error opening token of save job")
+		return "", ErrSaveTokenExpired
+	}
+	return token, nil
+}
+
+// upload sends the file of a save job to the content service as a multipart form.
+func (svc *NotesService) upload(ctx context.Context, token string, job *models.SaveJob) (*models.UploadedFile, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	// The file name is sent as UTF-8, as browsers send it, rather than in the RFC 2231 encoding that few servers read.
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename="%v"`, quoteEscaper.Replace(job.FileName)))
+	header.Set("Content-Type", job.ContentType)
+
+	formFile, err := writer.CreatePart(header)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := formFile.Write([]byte(job.Content)); err != nil {
+		return nil, err
+	}
+
+	if err := writer.Close(); err != nil {
+		logrus.WithContext(ctx).WithError(err).Error("Error closing multipart writer")
+	}
+
+	return svc.Ext.SendToContentService(ctx, token, body, writer.FormDataContentType())
+}
+
+// SaveWorker delivers queued save jobs to the content service. A failed upload is retried after a delay that doubles
+// with every attempt, from BaseDelay up to MaxDelay, until MaxAttempts have been made or the content service rejects
+// the upload in a way that retrying cannot fix.
+//
+// Any number of workers, in any number of processes, can share the queue: a job is leased to one worker at a time,
+// and is handed to another if its worker does not finish it within Lease.
+type SaveWorker struct {
+	Service     *NotesService
+	Interval    time.Duration
+	Lease       time.Duration
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// Run delivers the jobs that are due immediately and then every Interval until ctx is cancelled.
+func (w *SaveWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	for {
+		w.deliverDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *SaveWorker) deliverDue(ctx context.Context) {
+	logger := logrus.WithContext(ctx)
+
+	for ctx.Err() == nil {
+		now := time.Now()
+		job, err := w.Service.SaveJobs.ClaimSaveJob(ctx, now, now.Add(w.Lease))
+		if err != nil {
+			logger.WithError(err).Error("Error claiming save job")
+			return
+		} else if job == nil {
+			return
+		}
+
+		w.deliver(ctx, job)
+	}
+}
+
+func (w *SaveWorker) deliver(ctx context.Context, job *models.SaveJob) {
+	logger := logrus.WithContext(ctx).WithField("jobId", job.ID.Hex()).WithField("attempt", job.Attempts)
+
+	// A sync job takes its copy of the note now, rather than when it was queued.
+	exportedTs := job.CreatedTs
+	var previous *models.NoteExport
+	var file *models.UploadedFile
+	token, uploadErr := w.Service.openToken(ctx, job)
+	if uploadErr == nil && job.AutoSync {
+		exportedTs = time.Now()
+		previous, uploadErr = w.Service.renderSync(ctx, job)
+	}
+	if uploadErr == nil {
+		file, uploadErr = w.Service.upload(ctx, token, job)
+	}
+
+	// The token was valid when the save was queued, so the content service rejecting it means that it has expired
+	// since, which retrying cannot fix.
+	var statusErr *external.StatusError
+	if errors.As(uploadErr, &statusErr) && statusErr.StatusCode == http.StatusUnauthorized {
+		uploadErr = ErrSaveTokenExpired
+	}
+
+	now := time.Now()
+	patch := dao.SaveJobPatch{Status: models.SaveJobDelivered, FinishedTs: &now, RemoveToken: true, File: file}
+	if uploadErr != nil {
+		message := uploadErr.Error()
+		patch = dao.SaveJobPatch{Status: models.SaveJobFailed, LastError: &message, FinishedTs: &now, RemoveToken: true}
+
+		permanent := errors.Is(uploadErr, dao.ErrNotFound) || errors.Is(uploadErr, ErrSaveTokenExpired) ||
+			errors.As(uploadErr, &statusErr) && !statusErr.Temporary()
+		if !permanent && job.Attempts < w.MaxAttempts {
+			next := now.Add(w.backoff(job.Attempts))
+			patch = dao.SaveJobPatch{LastError: &message, NextAttemptTs: &next}
+		}
+	}
+
+	// The attempt count fences out a worker whose lease ran out while it was uploading, so that a job is only
+	// finished or rescheduled by the worker that holds it.
+	err := w.Service.SaveJobs.UpdateSaveJob(ctx, dao.SaveJobFilter{
+		IDs:      []primitive.ObjectID{job.ID},
+		Attempts: &job.Attempts,
+	}, patch)
+	if err != nil {
+		logger.WithError(err).Error("Error recording save job result")
+		return
+	}
+
+	switch {
+	case uploadErr == nil:
+		logger.Info("Delivered note to content service")
+		recordErr := w.recordExport(ctx, job, file, exportedTs)
+		if job.AutoSync {
+			w.replaceExport(ctx, job, token, previous, file, recordErr)
+		}
+	case patch.Status == models.SaveJobFailed:
+		logger.WithError(uploadErr).Error("Giving up delivering note to content service")
+	default:
+		logger.WithError(uploadErr).WithField("nextAttemptTs", *patch.NextAttemptTs).Warn("Error delivering note to content service")
+	}
+}
+
+// recordExport records a delivered job, and the time its copy of the note was taken, on its note, unless the note has
+// been deleted or a later copy of it has been delivered already, in which case it returns dao.ErrNotFound.
+func (w *SaveWorker) recordExport(ctx context.Context, job *models.SaveJob, file *models.UploadedFile, exportedTs time.Time) error {
+	logger := logrus.WithContext(ctx).WithField("jobId", job.ID.Hex()).WithField("noteId", job.NoteID.Hex())
+
+	err := w.Service.Dao.UpdateNote(ctx, dao.NoteFilter{
+		IDs:              []primitive.ObjectID{job.NoteID},
+		OwnerID:          job.OwnerID,
+		Trash:            dao.AnyTrash,
+		NotExportedAfter: &exportedTs,
+	}, dao.NotePatch{
+		Export: &models.NoteExport{
+			FileID:      file.ID,
+			URL:         file.URL,
+			Format:      job.Format,
+			ExportedTs:  exportedTs,
+			ContentHash: job.ContentHash,
+		},
+	})
+	if errors.Is(err, dao.ErrNotFound) {
+		logger.Info("Not recording export on note that is gone or has a later export")
+	} else if err != nil {
+		logger.WithError(err).Error("Error recording export on note")
+	}
+	return err
+}
+
+// backoff returns the delay before the attempt following the given one.
+func (w *SaveWorker) backoff(attempts int) time.Duration {
+	delay := w.BaseDelay
+	for i := 1; i < attempts && delay < w.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > w.MaxDelay {
+		delay = w.MaxDelay
+	}
+	return delay
+}
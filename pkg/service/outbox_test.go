@@ -0,0 +1,429 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"notes-api/pkg/auth"
+	"notes-api/pkg/dao"
+	"notes-api/pkg/export"
+	"notes-api/pkg/external"
+	"notes-api/pkg/models"
+	"notes-api/pkg/testhelper/mocks"
+)
+
+func TestService_SendToContentService_ShouldReturnErrorOnDaoError(t *testing.T) {
+	mockDao := &mocks.NoteDaoHandler{}
+	mockDao.On("GetNotes", mock.Anything, mock.Anything).Return([]models.Note{}, errors.New("test"))
+
+	service := NotesService{
+		Dao:       mockDao,
+		Exporters: export.NewRegistry(),
+	}
+
+	job, err := service.SendToContentService(context.TODO(), "user", "token", "", "")
+	require.Nil(t, job)
+	require.NotNil(t, err)
+	require.Equal(t, "test", err.Error())
+}
+
+func TestService_SendToContentService_ShouldReturnNotFoundIfNoteDoesNotExist(t *testing.T) {
+	mockDao := &mocks.NoteDaoHandler{}
+	mockDao.On("GetNotes", mock.Anything, mock.Anything).Return([]models.Note{}, nil)
+
+	service := NotesService{
+		Dao:       mockDao,
+		Exporters: export.NewRegistry(),
+	}
+
+	_, err := service.SendToContentService(context.TODO(), "user", "token", "000000000000000000000000", "")
+	require.Equal(t, dao.ErrNotFound, err)
+}
+
+func TestService_SendToContentService_ShouldQueueRenderedNoteWithCallersToken(t *testing.T) {
+	note := models.Note{ID: primitive.NewObjectID(), Name: "my note.md", Text: "text"}
+
+	mockDao := &mocks.NoteDaoHandler{}
+	mockDao.On("GetNotes", mock.Anything, mock.Anything).Return([]models.Note{note}, nil)
+	mockJobs := &mocks.SaveJobDaoHandler{}
+	mockJobs.On("CreateSaveJob", mock.Anything, mock.MatchedBy(func(job models.SaveJob) bool {
+		return job.OwnerID == "user" && job.NoteID == note.ID && job.Format == "txt" && job.FileName == "my_note.txt" &&
+			job.ContentType == "text/plain; charset=utf-8" && job.Content == "text" && job.Token != "token" &&
+			job.Status == models.SaveJobPending && !job.NextAttemptTs.After(time.Now())
+	})).Return(nil)
+
+	service := NotesService{
+		Dao:       mockDao,
+		SaveJobs:  mockJobs,
+		Tokens:    testSealer(t),
+		Exporters: export.NewRegistry(),
+	}
+
+	job, err := service.SendToContentService(context.TODO(), "user", "token", note.ID.Hex(), "")
+	require.Nil(t, err)
+	require.False(t, job.ID.IsZero())
+	mockJobs.AssertExpectations(t)
+
+	token, err := service.Tokens.Open(job.Token)
+	require.Nil(t, err)
+	require.Equal(t, "token", token)
+}
+
+func TestService_SendToContentService_ShouldRenderNoteInRequestedFormat(t *testing.T) {
+	note := models.Note{ID: primitive.NewObjectID(), Name: "Résumé: 2021/22", Text: "# Jobs", Tags: []string{"cv"}}
+
+	mockDao := &mocks.NoteDaoHandler{}
+	mockDao.On("GetNotes", mock.Anything, mock.Anything).Return([]models.Note{note}, nil)
+	mockJobs := &mocks.SaveJobDaoHandler{}
+	mockJobs.On("CreateSaveJob", mock.Anything, mock.MatchedBy(func(job models.SaveJob) bool {
+		return job.Format == "md" && job.FileName == "Résumé_2021_22.md" && job.ContentType == "text/markdown; charset=utf-8" &&
+			strings.HasPrefix(job.Content, "---\nname: 'Résumé: 2021/22'\n") && strings.HasSuffix(job.Content, "---\n\n# Jobs")
+	})).Return(nil)
+
+	service := NotesService{
+		Dao:       mockDao,
+		SaveJobs:  mockJobs,
+		Tokens:    testSealer(t),
+		Exporters: export.NewRegistry(),
+	}
+
+	_, err := service.SendToContentService(context.TODO(), "user", "token", note.ID.Hex(), "MD")
+	require.Nil(t, err)
+	mockJobs.AssertExpectations(t)
+}
+
+func TestService_SendToContentService_ShouldReturnInvalidQueryForUnknownFormat(t *testing.T) {
+	service := NotesService{
+		Exporters: export.NewRegistry(),
+	}
+
+	_, err := service.SendToContentService(context.TODO(), "user", "token", primitive.NewObjectID().Hex(), "docx")
+	require.True(t, errors.Is(err, ErrInvalidQuery))
+	require.Contains(t, err.Error(), "html, json, md, txt")
+}
+
+func TestService_GetSaveJob_ShouldReturnNotFoundIfUserHasNoSuchJob(t *testing.T) {
+	id := primitive.NewObjectID()
+
+	mockJobs := &mocks.SaveJobDaoHandler{}
+	mockJobs.On("GetSaveJobs", mock.Anything, dao.SaveJobFilter{
+		IDs:     []primitive.ObjectID{id},
+		OwnerID: "user",
+	}).Return([]models.SaveJob{}, nil)
+
+	service := NotesService{
+		SaveJobs: mockJobs,
+		Tokens:   testSealer(t),
+	}
+
+	job, err := service.GetSaveJob(context.TODO(), "user", id.Hex())
+	require.Nil(t, job)
+	require.Equal(t, dao.ErrSaveJobNotFound, err)
+}
+
+// uploadRecorder is a Requester that records which token each uploaded note body was sent with.
+type uploadRecorder struct {
+	mu      sync.Mutex
+	uploads map[string]string
+}
+
+func (u *uploadRecorder) Do(r *http.Request) (*http.Response, error) {
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		return nil, err
+	}
+
+	text, err := ioutil.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+
+	u.mu.Lock()
+	u.uploads[string(text)] = strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	u.mu.Unlock()
+
+	return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+}
+
+// testSealer returns a sealer of the tokens of queued saves.
+func testSealer(t *testing.T) *auth.Sealer {
+	sealer, err := auth.NewSealer(make([]byte, 32))
+	require.Nil(t, err)
+	return sealer
+}
+
+func saveWorker(service *NotesService) *SaveWorker {
+	return &SaveWorker{
+		Service:     service,
+		Interval:    time.Hour,
+		Lease:       time.Minute,
+		MaxAttempts: 3,
+		BaseDelay:   time.Minute,
+		MaxDelay:    time.Hour,
+	}
+}
+
+// Run with -race: the ExtAPI is shared between all concurrent saves, as it is in the server.
+func TestSaveWorker_ShouldDeliverEachQueuedSaveWithItsCallersToken(t *testing.T) {
+	memoryDao := dao.NewMemoryDao()
+	recorder := &uploadRecorder{uploads: make(map[string]string)}
+
+	const users = 50
+
+	noteIDs := make([]string, users)
+	for i := range noteIDs {
+		id, err := (&NotesService{Dao: memoryDao}).CreateNote(context.TODO(), fmt.Sprintf("user-%v", i), models.NoteRequest{
+			Name: "note",
+			Text: fmt.Sprintf("note of user-%v", i),
+		})
+		require.Nil(t, err)
+		noteIDs[i] = id
+	}
+
+	service := &NotesService{
+		Dao:      memoryDao,
+		SaveJobs: memoryDao,
+		Tokens:   testSealer(t),
+		Ext: &external.ExtAPI{
+			Client:            recorder,
+			ContentServiceURL: "http://content-service",
+		},
+		Exporters: export.NewRegistry(),
+	}
+
+	jobs := make([]*models.SaveJob, users)
+	var wg sync.WaitGroup
+	for i := 0; i < users; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			userID := fmt.Sprintf("user-%v", i)
+			job, err := service.SendToContentService(context.TODO(), userID, "token-of-"+userID, noteIDs[i], "")
+			require.Nil(t, err)
+			jobs[i] = job
+		}(i)
+	}
+	wg.Wait()
+
+	saveWorker(service).deliverDue(context.TODO())
+
+	require.Len(t, recorder.uploads, users)
+	for i, job := range jobs {
+		userID := fmt.Sprintf("user-%v", i)
+		require.Equal(t, "token-of-"+userID, recorder.uploads["note of "+userID])
+
+		delivered, err := service.GetSaveJob(context.TODO(), userID, job.ID.Hex())
+		require.Nil(t, err)
+		require.Equal(t, models.SaveJobDelivered, delivered.Status)
+		require.Equal(t, 1, delivered.Attempts)
+		require.Empty(t, delivered.Token)
+	}
+}
+
+func queuedSave(t *testing.T, uploadErr error) (*NotesService, *models.SaveJob) {
+	memoryDao := dao.NewMemoryDao()
+	mockExt := &mocks.ExtAPIHandler{}
+	if uploadErr != nil {
+		mockExt.On("SendToContentService", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, uploadErr)
+	} else {
+		mockExt.On("SendToContentService", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(&models.UploadedFile{ID: "file"}, nil)
+	}
+
+	service := &NotesService{
+		Dao:       memoryDao,
+		SaveJobs:  memoryDao,
+		Tokens:    testSealer(t),
+		Ext:       mockExt,
+		Exporters: export.NewRegistry(),
+	}
+
+	id, err := service.CreateNote(context.TODO(), "user", models.NoteRequest{Name: "note"})
+	require.Nil(t, err)
+	job, err := service.SendToContentService(context.TODO(), "user", "token", id, "")
+	require.Nil(t, err)
+
+	return service, job
+}
+
+func TestSaveWorker_ShouldUploadFileWithItsNameAndContentType(t *testing.T) {
+	memoryDao := dao.NewMemoryDao()
+	var upload *multipart.Part
+	mockExt := &mocks.ExtAPIHandler{}
+	mockExt.On("SendToContentService", mock.Anything, "token", mock.Anything, mock.Anything).Return(&models.UploadedFile{ID: "file"}, nil).Run(func(args mock.Arguments) {
+		body := args.Get(2).(bytes.Buffer)
+		_, params, err := mime.ParseMediaType(args.String(3))
+		require.Nil(t, err)
+		upload, err = multipart.NewReader(&body, params["boundary"]).NextPart()
+		require.Nil(t, err)
+	})
+
+	service := &NotesService{
+		Dao:       memoryDao,
+		SaveJobs:  memoryDao,
+		Tokens:    testSealer(t),
+		Ext:       mockExt,
+		Exporters: export.NewRegistry(),
+	}
+
+	id, err := service.CreateNote(context.TODO(), "user", models.NoteRequest{Name: "Ünïcode notes", Text: "text"})
+	require.Nil(t, err)
+	_, err = service.SendToContentService(context.TODO(), "user", "token", id, "html")
+	require.Nil(t, err)
+
+	saveWorker(service).deliverDue(context.TODO())
+
+	require.NotNil(t, upload)
+	require.Equal(t, "file", upload.FormName())
+	require.Equal(t, "Ünïcode_notes.html", upload.FileName())
+	require.Equal(t, "text/html; charset=utf-8", upload.Header.Get("Content-Type"))
+}
+
+func TestSaveWorker_ShouldRescheduleTemporaryFailures(t *testing.T) {
+	service, job := queuedSave(t, &external.StatusError{StatusCode: http.StatusServiceUnavailable})
+
+	saveWorker(service).deliverDue(context.TODO())
+
+	job, err := service.GetSaveJob(context.TODO(), "user", job.ID.Hex())
+	require.Nil(t, err)
+	require.Equal(t, models.SaveJobPending, job.Status)
+	require.Equal(t, 1, job.Attempts)
+	require.Equal(t, "non-200 status code received: 503", job.LastError)
+	require.WithinDuration(t, time.Now().Add(time.Minute), job.NextAttemptTs, 5*time.Second)
+	token, err := service.Tokens.Open(job.Token)
+	require.Nil(t, err)
+	require.Equal(t, "token", token)
+}
+
+func TestSaveWorker_ShouldFailJobIfContentServiceRejectsIt(t *testing.T) {
+	service, job := queuedSave(t, &external.StatusError{StatusCode: http.StatusRequestEntityTooLarge})
+
+	saveWorker(service).deliverDue(context.TODO())
+
+	job, err := service.GetSaveJob(context.TODO(), "user", job.ID.Hex())
+	require.Nil(t, err)
+	require.Equal(t, models.SaveJobFailed, job.Status)
+	require.NotNil(t, job.FinishedTs)
+	require.Empty(t, job.Token)
+}
+
+func TestSaveWorker_ShouldAskToSaveAgainIfTokenHasExpired(t *testing.T) {
+	service, job := queuedSave(t, &external.StatusError{StatusCode: http.StatusUnauthorized})
+
+	saveWorker(service).deliverDue(context.TODO())
+
+	job, err := service.GetSaveJob(context.TODO(), "user", job.ID.Hex())
+	require.Nil(t, err)
+	require.Equal(t, models.SaveJobFailed, job.Status)
+	require.Equal(t, ErrSaveTokenExpired.Error(), job.LastError)
+	require.Equal(t, 1, job.Attempts)
+}
+
+func TestSaveWorker_ShouldAskToSaveAgainIfTokenWasSealedWithAnotherKey(t *testing.T) {
+	service, job := queuedSave(t, nil)
+	sealer, err := auth.NewSealer(bytes.Repeat([]byte{2}, 32))
+	require.Nil(t, err)
+	service.Tokens = sealer
+
+	saveWorker(service).deliverDue(context.TODO())
+
+	job, err = service.GetSaveJob(context.TODO(), "user", job.ID.Hex())
+	require.Nil(t, err)
+	require.Equal(t, models.SaveJobFailed, job.Status)
+	require.Equal(t, ErrSaveTokenExpired.Error(), job.LastError)
+	service.Ext.(*mocks.ExtAPIHandler).AssertNotCalled(t, "SendToContentService", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSaveWorker_ShouldFailJobAfterMaxAttempts(t *testing.T) {
+	service, job := queuedSave(t, errors.New("connection refused"))
+	worker := saveWorker(service)
+	worker.BaseDelay = 0
+
+	worker.deliverDue(context.TODO())
+
+	job, err := service.GetSaveJob(context.TODO(), "user", job.ID.Hex())
+	require.Nil(t, err)
+	require.Equal(t, models.SaveJobFailed, job.Status)
+	require.Equal(t, worker.MaxAttempts, job.Attempts)
+	require.Equal(t, "connection refused", job.LastError)
+}
+
+func TestSaveWorker_ShouldOnlyRecordResultWhileClaimHolds(t *testing.T) {
+	sealer := testSealer(t)
+	token, err := sealer.Seal("token")
+	require.Nil(t, err)
+	job := &models.SaveJob{ID: primitive.NewObjectID(), Token: token, Attempts: 2}
+
+	mockJobs := &mocks.SaveJobDaoHandler{}
+	mockJobs.On("ClaimSaveJob", mock.Anything, mock.Anything, mock.Anything).Return(job, nil).Once()
+	mockJobs.On("ClaimSaveJob", mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+	mockJobs.On("UpdateSaveJob", mock.Anything, mock.MatchedBy(func(filter dao.SaveJobFilter) bool {
+		return filter.Attempts != nil && *filter.Attempts == 2
+	}), mock.Anything).Return(dao.ErrSaveJobNotFound)
+	mockExt := &mocks.ExtAPIHandler{}
+	mockExt.On("SendToContentService", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(&models.UploadedFile{ID: "file"}, nil)
+
+	saveWorker(&NotesService{SaveJobs: mockJobs, Ext: mockExt, Tokens: sealer}).deliverDue(context.TODO())
+	mockJobs.AssertExpectations(t)
+	mockExt.AssertExpectations(t)
+}
+
+func TestSaveWorker_Backoff_ShouldDoubleUpToMaxDelay(t *testing.T) {
+	worker := SaveWorker{BaseDelay: time.Second, MaxDelay: 10 * time.Second}
+
+	require.Equal(t, time.Second, worker.backoff(1))
+	require.Equal(t, 2*time.Second, worker.backoff(2))
+	require.Equal(t, 8*time.Second, worker.backoff(4))
+	require.Equal(t, 10*time.Second, worker.backoff(5))
+	require.Equal(t, 10*time.Second, worker.backoff(100))
+}
+
+func TestSaveWorker_ShouldRecordExportOnNote(t *testing.T) {
+	service, job := queuedSave(t, nil)
+
+	saveWorker(service).deliverDue(context.TODO())
+
+	notes, err := service.GetNotes(context.TODO(), "user", job.NoteID.Hex())
+	require.Nil(t, err)
+	require.Equal(t, "file", notes[0].Export.FileID)
+	require.Equal(t, "txt", notes[0].Export.Format)
+	require.Equal(t, models.ExportStatusSynced, notes[0].ExportStatus)
+
+	_, err = service.UpdateNote(context.TODO(), "user", "token", job.NoteID.Hex(), notes[0].Version, models.NoteRequest{Name: "note", Text: "changed"})
+	require.Nil(t, err)
+
+	notes, err = service.GetNotes(context.TODO(), "user", job.NoteID.Hex())
+	require.Nil(t, err)
+	require.Equal(t, models.ExportStatusStale, notes[0].ExportStatus)
+}
+
+func TestSaveWorker_ShouldNotReplaceLaterExportWithEarlierOne(t *testing.T) {
+	service, _ := queuedSave(t, nil)
+	notes, err := service.GetNotes(context.TODO(), "user", "")
+	require.Nil(t, err)
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	earlier := &models.SaveJob{NoteID: notes[0].ID, OwnerID: "user", CreatedTs: now.Add(-time.Minute)}
+	later := &models.SaveJob{NoteID: notes[0].ID, OwnerID: "user", CreatedTs: now}
+
+	worker := saveWorker(service)
+	worker.recordExport(context.TODO(), later, &models.UploadedFile{ID: "later"}, later.CreatedTs)
+	worker.recordExport(context.TODO(), earlier, &models.UploadedFile{ID: "earlier"}, earlier.CreatedTs)
+
+	notes, err = service.GetNotes(context.TODO(), "user", notes[0].ID.Hex())
+	require.Nil(t, err)
+	require.Equal(t, "later", notes[0].Export.FileID)
+}
@@ -0,0 +1,237 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"notes-api/pkg/dao"
+	"notes-api/pkg/models"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+)
+
+type sortField struct {
+	sort       dao.NoteSort
+	descending bool
+}
+
+// sortFields maps the sort names accepted by ListNotes to the DAO's sort and its default order.
+var sortFields = map[string]sortField{
+	"lastEditedTs": {sort: dao.SortByLastEdited, descending: true},
+	"name":         {sort: dao.SortByName, descending: false},
+	"created":      {sort: dao.SortByCreated, descending: true},
+}
+
+// noteFields are the JSON names of the note fields that a listing can be narrowed to.
+var noteFields = map[string]bool{
+	"id":           true,
+	"ownerId":      true,
+	"name":         true,
+	"lastEditedTs": true,
+	"text":         true,
+	"version":      true,
+	"tags":         true,
+	"notebookId":   true,
+	"export":       true,
+	"exportStatus": true,
+}
+
+// exportStatusFields are the fields the export status of a note is worked out from.
+var exportStatusFields = []string{"name", "text", "tags", "export"}
+
+// pageCursor is the decoded form of a NotePage's Next token: the sort it was issued for and the sort key of the
+// last note on the page. The note ID breaks ties between notes with the same sort value.
+type pageCursor struct {
+	Sort         string             `json:"s"`
+	Descending   bool               `json:"d"`
+	ID           primitive.ObjectID `json:"id"`
+	Name         string             `json:"n,omitempty"`
+	LastEditedTs time.Time          `json:"t"`
+}
+
+// ListNotes returns one page of the user's notes, excluding the trash. Pages are keyed on the sort value of the last
+// note returned rather than an offset, so notes created or edited between requests do not shift later pages.
+func (svc *NotesService) ListNotes(ctx context.Context, userID string, query models.NoteQuery) (*models.NotePage, error) {
+	sortName := query.Sort
+	if sortName == "" {
+		sortName = "lastEditedTs"
+	}
+	field, ok := sortFields[sortName]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown sort '%v'", ErrInvalidQuery, query.Sort)
+	}
+
+	descending := field.descending
+	switch query.Order {
+	case "":
+	case "asc":
+		descending = false
+	case "desc":
+		descending = true
+	default:
+		return nil, fmt.Errorf("%w: unknown order '%v'", ErrInvalidQuery, query.Order)
+	}
+
+	limit, err := pageSize(query.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range query.Fields {
+		if !noteFields[name] {
+			return nil, fmt.Errorf("%w: unknown field '%v'", ErrInvalidQuery, name)
+		}
+	}
+
+	filter := dao.NoteFilter{OwnerID: userID}
+
+	if tags := normalizeTags(query.Tags); len(tags) > 0 {
+		switch query.TagMatch {
+		case "", "all":
+			filter.AllTags = tags
+		case "any":
+			filter.AnyTags = tags
+		default:
+			return nil, fmt.Errorf("%w: unknown tag match '%v'", ErrInvalidQuery, query.TagMatch)
+		}
+	}
+
+	if query.NotebookID != "" {
+		notebookID, err := primitive.ObjectIDFromHex(query.NotebookID)
+		if err != nil {
+			return nil, fmt.Errorf("%w: notebook ID '%v' is not valid", ErrInvalidQuery, query.NotebookID)
+		}
+		filter.NotebookIDs = []primitive.ObjectID{notebookID}
+	}
+
+	opts := dao.ListOptions{
+		Sort:       field.sort,
+		Descending: descending,
+		// One extra note is read to learn whether there is another page without a second query.
+		Limit:  int64(limit + 1),
+		Fields: storedFields(query.Fields),
+	}
+
+	if query.Next != "" {
+		var cursor pageCursor
+		if err := decodeCursor(query.Next, &cursor); err != nil {
+			return nil, err
+		}
+		if cursor.Sort != sortName || cursor.Descending != descending {
+			return nil, fmt.Errorf("%w: next token was issued for a different sort", ErrInvalidQuery)
+		}
+		opts.After = &models.Note{ID: cursor.ID, Name: cursor.Name, LastEditedTs: cursor.LastEditedTs}
+	}
+
+	notes, err := svc.Dao.ListNotes(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &models.NotePage{Notes: notes}
+	if page.Notes == nil {
+		page.Notes = []models.Note{}
+	}
+	if len(notes) > limit {
+		page.Notes = notes[:limit]
+		last := page.Notes[limit-1]
+		page.Next = encodeCursor(pageCursor{
+			Sort:         sortName,
+			Descending:   descending,
+			ID:           last.ID,
+			Name:         last.Name,
+			LastEditedTs: last.LastEditedTs,
+		})
+	}
+
+	requested := fieldSet(query.Fields)
+	if len(query.Fields) == 0 || requested["exportStatus"] {
+		setExportStatus(page.Notes)
+	}
+	if requested["exportStatus"] {
+		// The sort field is always read, so it is returned whether or not it was asked for.
+		requested[string(field.sort)] = true
+		clearUnrequestedFields(page.Notes, requested)
+	}
+
+	return page, nil
+}
+
+// storedFields returns the stored fields to read for the requested fields of a listing: the export status is not
+// stored, and is worked out from other fields.
+func storedFields(requested []string) []string {
+	var stored []string
+	for _, name := range requested {
+		if name == "exportStatus" {
+			stored = append(stored, exportStatusFields...)
+		} else {
+			stored = append(stored, name)
+		}
+	}
+	return stored
+}
+
+func fieldSet(names []string) map[string]bool {
+	set := make(map[string]bool, len(names))
+	for _, name := range names {
+		set[name] = true
+	}
+	return set
+}
+
+// clearUnrequestedFields clears the fields that were only read to work out the export status of notes.
+func clearUnrequestedFields(notes []models.Note, requested map[string]bool) {
+	for i := range notes {
+		if !requested["name"] {
+			notes[i].Name = ""
+		}
+		if !requested["text"] {
+			notes[i].Text = ""
+		}
+		if !requested["tags"] {
+			notes[i].Tags = nil
+		}
+		if !requested["export"] {
+			notes[i].Export = nil
+		}
+	}
+}
+
+// pageSize applies the default and maximum page sizes to a requested limit.
+func pageSize(limit int) (int, error) {
+	if limit < 0 {
+		return 0, fmt.Errorf("%w: limit must not be negative", ErrInvalidQuery)
+	} else if limit == 0 {
+		return defaultPageSize, nil
+	} else if limit > maxPageSize {
+		return maxPageSize, nil
+	}
+	return limit, nil
+}
+
+// encodeCursor and decodeCursor convert the position of a listing to and from the opaque token handed to clients.
+func encodeCursor(cursor interface{}) string {
+	// Cursors are plain structs of strings, numbers, times and object IDs, so marshalling them cannot fail.
+	raw, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeCursor(token string, cursor interface{}) error {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return fmt.Errorf("%w: malformed next token", ErrInvalidQuery)
+	}
+	if err := json.Unmarshal(raw, cursor); err != nil {
+		return fmt.Errorf("%w: malformed next token", ErrInvalidQuery)
+	}
+
+	return nil
+}
@@ -0,0 +1,263 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"notes-api/pkg/dao"
+	"notes-api/pkg/models"
+	"notes-api/pkg/testhelper/mocks"
+)
+
+func TestService_ListNotes_ShouldReturnErrorIfSortIsUnknown(t *testing.T) {
+	service := NotesService{}
+
+	page, err := service.ListNotes(context.TODO(), "user", models.NoteQuery{Sort: "size"})
+	require.Nil(t, page)
+	require.True(t, errors.Is(err, ErrInvalidQuery))
+}
+
+func TestService_ListNotes_ShouldReturnErrorIfOrderIsUnknown(t *testing.T) {
+	service := NotesService{}
+
+	page, err := service.ListNotes(context.TODO(), "user", models.NoteQuery{Order: "up"})
+	require.Nil(t, page)
+	require.True(t, errors.Is(err, ErrInvalidQuery))
+}
+
+func TestService_ListNotes_ShouldReturnErrorIfLimitIsNegative(t *testing.T) {
+	service := NotesService{}
+
+	page, err := service.ListNotes(context.TODO(), "user", models.NoteQuery{Limit: -1})
+	require.Nil(t, page)
+	require.True(t, errors.Is(err, ErrInvalidQuery))
+}
+
+func TestService_ListNotes_ShouldReturnErrorIfFieldIsUnknown(t *testing.T) {
+	service := NotesService{}
+
+	page, err := service.ListNotes(context.TODO(), "user", models.NoteQuery{Fields: []string{"name", "size"}})
+	require.Nil(t, page)
+	require.True(t, errors.Is(err, ErrInvalidQuery))
+}
+
+func TestService_ListNotes_ShouldReturnErrorIfNextIsMalformed(t *testing.T) {
+	service := NotesService{}
+
+	page, err := service.ListNotes(context.TODO(), "user", models.NoteQuery{Next: "not a token"})
+	require.Nil(t, page)
+	require.True(t, errors.Is(err, ErrInvalidQuery))
+}
+
+func TestService_ListNotes_ShouldReturnErrorOnDaoError(t *testing.T) {
+	mockDao := &mocks.NoteDaoHandler{}
+	mockDao.On("ListNotes", mock.Anything, mock.Anything, mock.Anything).Return(nil, errors.New("test"))
+
+	service := NotesService{
+		Dao: mockDao,
+	}
+
+	page, err := service.ListNotes(context.TODO(), "user", models.NoteQuery{})
+	require.Nil(t, page)
+	require.NotNil(t, err)
+	require.Equal(t, "test", err.Error())
+}
+
+func TestService_ListNotes_ShouldUseDefaultsIfQueryIsEmpty(t *testing.T) {
+	mockDao := &mocks.NoteDaoHandler{}
+	mockDao.On("ListNotes", mock.Anything, dao.NoteFilter{OwnerID: "user"}, dao.ListOptions{
+		Sort:       dao.SortByLastEdited,
+		Descending: true,
+		Limit:      defaultPageSize + 1,
+	}).Return(nil, nil)
+
+	service := NotesService{
+		Dao: mockDao,
+	}
+
+	page, err := service.ListNotes(context.TODO(), "user", models.NoteQuery{})
+	require.Nil(t, err)
+	require.Equal(t, []models.Note{}, page.Notes)
+	require.Empty(t, page.Next)
+	mockDao.AssertExpectations(t)
+}
+
+func TestService_ListNotes_ShouldCapLimit(t *testing.T) {
+	mockDao := &mocks.NoteDaoHandler{}
+	mockDao.On("ListNotes", mock.Anything, mock.Anything, mock.MatchedBy(func(opts dao.ListOptions) bool {
+		return opts.Limit == maxPageSize+1
+	})).Return([]models.Note{}, nil)
+
+	service := NotesService{
+		Dao: mockDao,
+	}
+
+	_, err := service.ListNotes(context.TODO(), "user", models.NoteQuery{Limit: 10000})
+	require.Nil(t, err)
+	mockDao.AssertExpectations(t)
+}
+
+func TestService_ListNotes_ShouldReadRequestedFields(t *testing.T) {
+	mockDao := &mocks.NoteDaoHandler{}
+	mockDao.On("ListNotes", mock.Anything, mock.Anything, dao.ListOptions{
+		Sort:   dao.SortByName,
+		Limit:  defaultPageSize + 1,
+		Fields: []string{"lastEditedTs"},
+	}).Return([]models.Note{}, nil)
+
+	service := NotesService{
+		Dao: mockDao,
+	}
+
+	_, err := service.ListNotes(context.TODO(), "user", models.NoteQuery{Sort: "name", Fields: []string{"lastEditedTs"}})
+	require.Nil(t, err)
+	mockDao.AssertExpectations(t)
+}
+
+func TestService_ListNotes_ShouldReturnNextTokenThatContinuesAfterLastNote(t *testing.T) {
+	first := models.Note{ID: primitive.NewObjectID(), Name: "a", LastEditedTs: time.Unix(300, 0).UTC()}
+	second := models.Note{ID: primitive.NewObjectID(), Name: "b", LastEditedTs: time.Unix(200, 0).UTC()}
+	third := models.Note{ID: primitive.NewObjectID(), Name: "c", LastEditedTs: time.Unix(100, 0).UTC()}
+
+	mockDao := &mocks.NoteDaoHandler{}
+	mockDao.On("ListNotes", mock.Anything, mock.Anything, mock.MatchedBy(func(opts dao.ListOptions) bool {
+		return opts.After == nil
+	})).Return([]models.Note{first, second, third}, nil).Once()
+	mockDao.On("ListNotes", mock.Anything, dao.NoteFilter{OwnerID: "user"}, dao.ListOptions{
+		Sort:       dao.SortByLastEdited,
+		Descending: true,
+		After:      &models.Note{ID: second.ID, Name: second.Name, LastEditedTs: second.LastEditedTs},
+		Limit:      3,
+	}).Return([]models.Note{third}, nil).Once()
+
+	service := NotesService{
+		Dao: mockDao,
+	}
+
+	page, err := service.ListNotes(context.TODO(), "user", models.NoteQuery{Limit: 2})
+	require.Nil(t, err)
+	want := []models.Note{first, second}
+	setExportStatus(want)
+	require.Equal(t, want, page.Notes)
+	require.NotEmpty(t, page.Next)
+
+	page, err = service.ListNotes(context.TODO(), "user", models.NoteQuery{Limit: 2, Next: page.Next})
+	require.Nil(t, err)
+	want = []models.Note{third}
+	setExportStatus(want)
+	require.Equal(t, want, page.Notes)
+	require.Empty(t, page.Next)
+	mockDao.AssertExpectations(t)
+}
+
+func TestService_ListNotes_ShouldContinueAfterNextTokenInRequestedOrder(t *testing.T) {
+	id := primitive.NewObjectID()
+	next := encodeCursor(pageCursor{Sort: "created", Descending: false, ID: id})
+
+	mockDao := &mocks.NoteDaoHandler{}
+	mockDao.On("ListNotes", mock.Anything, dao.NoteFilter{OwnerID: "user"}, dao.ListOptions{
+		Sort:  dao.SortByCreated,
+		After: &models.Note{ID: id},
+		Limit: defaultPageSize + 1,
+	}).Return([]models.Note{}, nil)
+
+	service := NotesService{
+		Dao: mockDao,
+	}
+
+	_, err := service.ListNotes(context.TODO(), "user", models.NoteQuery{Sort: "created", Order: "asc", Next: next})
+	require.Nil(t, err)
+	mockDao.AssertExpectations(t)
+}
+
+func TestService_ListNotes_ShouldReturnErrorIfNextWasIssuedForAnotherSort(t *testing.T) {
+	next := encodeCursor(pageCursor{Sort: "name", ID: primitive.NewObjectID(), Name: "a"})
+
+	service := NotesService{}
+
+	page, err := service.ListNotes(context.TODO(), "user", models.NoteQuery{Sort: "lastEditedTs", Next: next})
+	require.Nil(t, page)
+	require.True(t, errors.Is(err, ErrInvalidQuery))
+}
+
+func TestService_ListNotes_ShouldRequireAllTagsByDefault(t *testing.T) {
+	mockDao := &mocks.NoteDaoHandler{}
+	mockDao.On("ListNotes", mock.Anything, dao.NoteFilter{OwnerID: "user", AllTags: []string{"a", "b"}}, mock.Anything).Return([]models.Note{}, nil)
+
+	service := NotesService{
+		Dao: mockDao,
+	}
+
+	_, err := service.ListNotes(context.TODO(), "user", models.NoteQuery{Tags: []string{"a", "b", "a"}})
+	require.Nil(t, err)
+	mockDao.AssertExpectations(t)
+}
+
+func TestService_ListNotes_ShouldRequireAnyTagIfAsked(t *testing.T) {
+	mockDao := &mocks.NoteDaoHandler{}
+	mockDao.On("ListNotes", mock.Anything, dao.NoteFilter{OwnerID: "user", AnyTags: []string{"a", "b"}}, mock.Anything).Return([]models.Note{}, nil)
+
+	service := NotesService{
+		Dao: mockDao,
+	}
+
+	_, err := service.ListNotes(context.TODO(), "user", models.NoteQuery{Tags: []string{"a", "b"}, TagMatch: "any"})
+	require.Nil(t, err)
+	mockDao.AssertExpectations(t)
+}
+
+func TestService_ListNotes_ShouldReturnErrorIfTagMatchIsUnknown(t *testing.T) {
+	service := NotesService{}
+
+	page, err := service.ListNotes(context.TODO(), "user", models.NoteQuery{Tags: []string{"a"}, TagMatch: "some"})
+	require.Nil(t, page)
+	require.True(t, errors.Is(err, ErrInvalidQuery))
+}
+
+func TestService_ListNotes_ShouldFilterOnNotebook(t *testing.T) {
+	notebookID := primitive.NewObjectID()
+
+	mockDao := &mocks.NoteDaoHandler{}
+	mockDao.On("ListNotes", mock.Anything, dao.NoteFilter{OwnerID: "user", NotebookIDs: []primitive.ObjectID{notebookID}}, mock.Anything).Return([]models.Note{}, nil)
+
+	service := NotesService{
+		Dao: mockDao,
+	}
+
+	_, err := service.ListNotes(context.TODO(), "user", models.NoteQuery{NotebookID: notebookID.Hex()})
+	require.Nil(t, err)
+	mockDao.AssertExpectations(t)
+}
+
+func TestService_ListNotes_ShouldReturnErrorIfNotebookIDIsNotValid(t *testing.T) {
+	service := NotesService{}
+
+	page, err := service.ListNotes(context.TODO(), "user", models.NoteQuery{NotebookID: "test"})
+	require.Nil(t, page)
+	require.True(t, errors.Is(err, ErrInvalidQuery))
+}
+
+func TestService_ListNotes_ShouldReadFieldsExportStatusNeedsButOnlyReturnRequestedOnes(t *testing.T) {
+	note := models.Note{ID: primitive.NewObjectID(), Name: "a", Text: "text", Tags: []string{"x"}}
+	note.Export = &models.NoteExport{FileID: "file", ContentHash: contentHash(note)}
+
+	mockDao := &mocks.NoteDaoHandler{}
+	mockDao.On("ListNotes", mock.Anything, mock.Anything, mock.MatchedBy(func(opts dao.ListOptions) bool {
+		return reflect.DeepEqual(opts.Fields, []string{"id", "name", "text", "tags", "export"})
+	})).Return([]models.Note{note}, nil)
+
+	service := NotesService{
+		Dao: mockDao,
+	}
+
+	page, err := service.ListNotes(context.TODO(), "user", models.NoteQuery{Sort: "name", Fields: []string{"id", "exportStatus"}})
+	require.Nil(t, err)
+	require.Equal(t, []models.Note{{ID: note.ID, Name: "a", ExportStatus: models.ExportStatusSynced}}, page.Notes)
+}
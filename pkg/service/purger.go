@@ -0,0 +1,45 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// TrashPurger periodically removes notes that have been in the trash for longer than Retention.
+type TrashPurger struct {
+	Service   *NotesService
+	Retention time.Duration
+	Interval  time.Duration
+}
+
+// Run purges the trash once immediately and then every Interval until ctx is cancelled.
+func (p *TrashPurger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.Interval)
+	defer ticker.Stop()
+
+	for {
+		p.purge(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *TrashPurger) purge(ctx context.Context) {
+	logger := logrus.WithContext(ctx)
+
+	purged, err := p.Service.PurgeTrash(ctx, time.Now().Add(-p.Retention))
+	if err != nil {
+		logger.WithError(err).Error("Error purging trash")
+		return
+	}
+
+	if purged > 0 {
+		logger.WithField("count", purged).Info("Purged notes from trash")
+	}
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"notes-api/pkg/models"
+	"notes-api/pkg/testhelper/mocks"
+
+	"github.com/stretchr/testify/mock"
+)
+
+func TestPurger_Run_ShouldPurgeImmediatelyAndStopWhenContextIsCancelled(t *testing.T) {
+	purged := make(chan struct{}, 1)
+
+	mockDao := &mocks.NoteDaoHandler{}
+	mockDao.On("ListNotes", mock.Anything, mock.Anything, mock.Anything).Return([]models.Note{}, nil).Run(func(args mock.Arguments) {
+		purged <- struct{}{}
+	})
+
+	purger := TrashPurger{
+		Service:   &NotesService{Dao: mockDao},
+		Retention: time.Hour,
+		Interval:  time.Hour,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		purger.Run(ctx)
+		close(done)
+	}()
+
+	select {
+	case <-purged:
+	case <-time.After(time.Second):
+		t.Fatal("trash was not purged on start")
+	}
+
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("purger did not stop after its context was cancelled")
+	}
+}
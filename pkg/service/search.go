@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+	"unicode"
+
+	"notes-api/pkg/dao"
+	"notes-api/pkg/models"
+)
+
+const (
+	// snippetWordsBefore and snippetWordsAfter are the number of words of context kept around the first match in a
+	// note's text.
+	snippetWordsBefore = 8
+	snippetWordsAfter  = 24
+)
+
+// searchCursor is the decoded form of a SearchPage's Next token. Search results are ordered by score, which cannot
+// be filtered on, so pages are offsets into the results of the same search.
+type searchCursor struct {
+	Text   string `json:"q"`
+	Offset int    `json:"o"`
+}
+
+// SearchNotes returns one page of the user's notes matching the search text, excluding the trash, most relevant
+// first, with the matching words of each note highlighted.
+func (svc *NotesService) SearchNotes(ctx context.Context, userID string, query models.SearchQuery) (*models.SearchPage, error) {
+	if strings.TrimSpace(query.Text) == "" {
+		return nil, fmt.Errorf("%w: search text is required", ErrInvalidQuery)
+	}
+
+	limit, err := pageSize(query.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	offset := 0
+	if query.Next != "" {
+		var cursor searchCursor
+		if err := decodeCursor(query.Next, &cursor); err != nil {
+			return nil, err
+		}
+		if cursor.Text != query.Text || cursor.Offset < 0 {
+			return nil, fmt.Errorf("%w: next token was issued for a different search", ErrInvalidQuery)
+		}
+		offset = cursor.Offset
+	}
+
+	results, err := svc.Dao.SearchNotes(ctx, dao.NoteFilter{OwnerID: userID}, query.Text, dao.ListOptions{
+		Skip:  int64(offset),
+		Limit: int64(limit + 1),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	page := &models.SearchPage{Results: results}
+	if page.Results == nil {
+		page.Results = []models.SearchResult{}
+	}
+	if len(results) > limit {
+		page.Results = results[:limit]
+		page.Next = encodeCursor(searchCursor{Text: query.Text, Offset: offset + limit})
+	}
+
+	terms := searchTerms(query.Text)
+	for i := range page.Results {
+		page.Results[i].Highlights = highlights(page.Results[i].Note, terms)
+		page.Results[i].Note.ExportStatus = exportStatus(page.Results[i].Note)
+	}
+
+	return page, nil
+}
+
+// searchTerms returns the lower-cased words of a $text search string that a matching note may contain. Words of
+// quoted phrases are included; negated words are not, since matching notes cannot contain them.
+func searchTerms(search string) []string {
+	var terms []string
+	for i, part := range strings.Split(search, `"`) {
+		inPhrase := i%2 == 1
+		for _, field := range strings.Fields(part) {
+			if !inPhrase && strings.HasPrefix(field, "-") {
+				continue
+			}
+			for _, word := range strings.FieldsFunc(field, isNotWordRune) {
+				terms = append(terms, strings.ToLower(word))
+			}
+		}
+	}
+	return terms
+}
+
+// highlights returns the highlighted name and text snippet of a note, leaving out fields without a match.
+func highlights(note models.Note, terms []string) map[string]string {
+	result := map[string]string{}
+
+	words := wordSpans(note.Name)
+	if matches := matchingWords(note.Name, words, terms); len(matches) > 0 {
+		result["name"] = highlight(note.Name, words, 0, len(words), matches)
+	}
+
+	words = wordSpans(note.Text)
+	if matches := matchingWords(note.Text, words, terms); len(matches) > 0 {
+		from := matches[0] - snippetWordsBefore
+		if from < 0 {
+			from = 0
+		}
+		to := matches[0] + snippetWordsAfter
+		if to > len(words) {
+			to = len(words)
+		}
+
+		snippet := highlight(note.Text, words, from, to, matches)
+		if from > 0 {
+			snippet = "…" + snippet
+		}
+		if to < len(words) {
+			snippet += "…"
+		}
+		result["text"] = snippet
+	}
+
+	if len(result) == 0 {
+		return nil
+	}
+	return result
+}
+
+// highlight escapes s from the start of word from to the end of word to-1, wrapping the words whose indexes are in
+// matches in <mark> elements. Text before the first word and after the last word of s is kept when the range
+// includes them.
+func highlight(s string, words [][2]int, from int, to int, matches []int) string {
+	matched := make(map[int]bool, len(matches))
+	for _, i := range matches {
+		matched[i] = true
+	}
+
+	var b strings.Builder
+	start := 0
+	if from > 0 {
+		start = words[from][0]
+	}
+	for i := from; i < to; i++ {
+		word := s[words[i][0]:words[i][1]]
+		b.WriteString(html.EscapeString(s[start:words[i][0]]))
+		if matched[i] {
+			b.WriteString("<mark>" + html.EscapeString(word) + "</mark>")
+		} else {
+			b.WriteString(html.EscapeString(word))
+		}
+		start = words[i][1]
+	}
+	if to == len(words) {
+		b.WriteString(html.EscapeString(s[start:]))
+	}
+
+	return b.String()
+}
+
+// matchingWords returns the indexes of the words of s that match a search term. MongoDB stems words when it searches,
+// so a word also matches a term that it starts with, or that starts with it, if the shorter of the two has at least
+// three letters. This finds "running" for "run" at the cost of the occasional false positive.
+func matchingWords(s string, words [][2]int, terms []string) []int {
+	var matches []int
+	for i, word := range words {
+		lower := strings.ToLower(s[word[0]:word[1]])
+		for _, term := range terms {
+			if wordMatches(lower, term) {
+				matches = append(matches, i)
+				break
+			}
+		}
+	}
+	return matches
+}
+
+func wordMatches(word string, term string) bool {
+	if word == term {
+		return true
+	}
+	shorter, longer := word, term
+	if len(shorter) > len(longer) {
+		shorter, longer = longer, shorter
+	}
+	return len([]rune(shorter)) >= 3 && strings.HasPrefix(longer, shorter)
+}
+
+// wordSpans returns the byte offsets of the start and end of each word in s.
+func wordSpans(s string) [][2]int {
+	var spans [][2]int
+	start := -1
+	for i, r := range s {
+		if isNotWordRune(r) {
+			if start >= 0 {
+				spans = append(spans, [2]int{start, i})
+				start = -1
+			}
+		} else if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		spans = append(spans, [2]int{start, len(s)})
+	}
+	return spans
+}
+
+func isNotWordRune(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"notes-api/pkg/dao"
+	"notes-api/pkg/models"
+	"notes-api/pkg/testhelper/mocks"
+)
+
+func TestService_SearchNotes_ShouldReturnErrorIfTextIsEmpty(t *testing.T) {
+	service := NotesService{}
+
+	page, err := service.SearchNotes(context.TODO(), "user", models.SearchQuery{Text: "  "})
+	require.Nil(t, page)
+	require.True(t, errors.Is(err, ErrInvalidQuery))
+}
+
+func TestService_SearchNotes_ShouldReturnErrorIfNextWasIssuedForAnotherSearch(t *testing.T) {
+	service := NotesService{}
+
+	next := encodeCursor(searchCursor{Text: "other", Offset: 10})
+	page, err := service.SearchNotes(context.TODO(), "user", models.SearchQuery{Text: "test", Next: next})
+	require.Nil(t, page)
+	require.True(t, errors.Is(err, ErrInvalidQuery))
+}
+
+func TestService_SearchNotes_ShouldReturnErrorOnDaoError(t *testing.T) {
+	mockDao := &mocks.NoteDaoHandler{}
+	mockDao.On("SearchNotes", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, errors.New("test"))
+
+	service := NotesService{
+		Dao: mockDao,
+	}
+
+	page, err := service.SearchNotes(context.TODO(), "user", models.SearchQuery{Text: "test"})
+	require.Nil(t, page)
+	require.NotNil(t, err)
+	require.Equal(t, "test", err.Error())
+}
+
+func TestService_SearchNotes_ShouldScopeSearchToUserAndExcludeTrash(t *testing.T) {
+	mockDao := &mocks.NoteDaoHandler{}
+	mockDao.On("SearchNotes", mock.Anything, dao.NoteFilter{OwnerID: "user"}, "test", dao.ListOptions{Limit: defaultPageSize + 1}).Return(nil, nil)
+
+	service := NotesService{
+		Dao: mockDao,
+	}
+
+	page, err := service.SearchNotes(context.TODO(), "user", models.SearchQuery{Text: "test"})
+	require.Nil(t, err)
+	require.Equal(t, []models.SearchResult{}, page.Results)
+	mockDao.AssertExpectations(t)
+}
+
+func TestService_SearchNotes_ShouldPageThroughResultsByOffset(t *testing.T) {
+	results := []models.SearchResult{
+		{Note: models.Note{ID: primitive.NewObjectID()}, Score: 3},
+		{Note: models.Note{ID: primitive.NewObjectID()}, Score: 2},
+		{Note: models.Note{ID: primitive.NewObjectID()}, Score: 1},
+	}
+
+	mockDao := &mocks.NoteDaoHandler{}
+	mockDao.On("SearchNotes", mock.Anything, mock.Anything, "test", dao.ListOptions{Limit: 3}).Return(results, nil).Once()
+	mockDao.On("SearchNotes", mock.Anything, mock.Anything, "test", dao.ListOptions{Skip: 2, Limit: 3}).Return(results[2:], nil).Once()
+
+	service := NotesService{
+		Dao: mockDao,
+	}
+
+	page, err := service.SearchNotes(context.TODO(), "user", models.SearchQuery{Text: "test", Limit: 2})
+	require.Nil(t, err)
+	require.Len(t, page.Results, 2)
+	require.NotEmpty(t, page.Next)
+
+	page, err = service.SearchNotes(context.TODO(), "user", models.SearchQuery{Text: "test", Limit: 2, Next: page.Next})
+	require.Nil(t, err)
+	require.Len(t, page.Results, 1)
+	require.Empty(t, page.Next)
+	mockDao.AssertExpectations(t)
+}
+
+func TestService_SearchNotes_ShouldHighlightMatchingWords(t *testing.T) {
+	mockDao := &mocks.NoteDaoHandler{}
+	mockDao.On("SearchNotes", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]models.SearchResult{{
+		Note: models.Note{Name: "Deploy runbook", Text: "Before <deploying>, check the dashboards."},
+	}}, nil)
+
+	service := NotesService{
+		Dao: mockDao,
+	}
+
+	page, err := service.SearchNotes(context.TODO(), "user", models.SearchQuery{Text: "deploy -dashboards"})
+	require.Nil(t, err)
+	require.Equal(t, map[string]string{
+		"name": "<mark>Deploy</mark> runbook",
+		"text": "Before &lt;<mark>deploying</mark>&gt;, check the dashboards.",
+	}, page.Results[0].Highlights)
+}
+
+func TestService_SearchTerms_ShouldIncludePhrasesAndSkipNegatedWords(t *testing.T) {
+	require.Equal(t, []string{"coffee", "shop", "brew", "cake"}, searchTerms(`coffee "shop -brew" -tea cake`))
+}
+
+func TestService_Highlights_ShouldTrimTextAroundFirstMatch(t *testing.T) {
+	text := "one two three four five six seven eight nine ten eleven twelve match " +
+		"a b c d e f g h i j k l m n o p q r s t u v w x y z"
+
+	result := highlights(models.Note{Text: text}, []string{"match"})
+	require.Equal(t, "…five six seven eight nine ten eleven twelve <mark>match</mark> "+
+		"a b c d e f g h i j k l m n o p q r s t u v w…", result["text"])
+	require.NotContains(t, result, "name")
+}
+
+func TestService_Highlights_ShouldReturnNilIfNothingMatches(t *testing.T) {
+	require.Nil(t, highlights(models.Note{Name: "name", Text: "text"}, []string{"other"}))
+}
@@ -2,17 +2,51 @@ package service
 
 import (
 	"context"
+	"errors"
 
 	"notes-api/pkg/models"
 )
 
+var (
+	// ErrVersionConflict is returned when an update names a version of a note that is no longer the current one.
+	ErrVersionConflict = errors.New("note has been modified since the given version")
+	// ErrInvalidQuery is returned, wrapped with the reason, when a listing asks for an unknown sort, field or page.
+	ErrInvalidQuery = errors.New("invalid query")
+	// ErrNotebookCycle is returned when a notebook would be moved into itself or into one of its descendants.
+	ErrNotebookCycle = errors.New("notebook cannot be moved into itself or a notebook inside it")
+	// ErrNotebookNotEmpty is returned when a notebook that still holds notes or notebooks is deleted without cascading.
+	ErrNotebookNotEmpty = errors.New("notebook is not empty")
+	// ErrSaveTokenExpired is the error of a queued save whose token the content service no longer accepts, or that
+	// cannot be opened with the current key. The note has to be saved again.
+	ErrSaveTokenExpired = errors.New("token expired, save again")
+)
+
 type NoteServiceHandler interface {
 	Ping(ctx context.Context) error
-	GetNotes(ctx context.Context, id string) ([]models.Note, error)
-	UpdateNote(ctx context.Context, id string, noteRequest models.NoteRequest) error
-	DeleteNote(ctx context.Context, id string) error
-	CreateNote(ctx context.Context, noteRequest models.NoteRequest) (string, error)
-	SendToContentService(ctx context.Context, id string) error
-	ValidateToken(ctx context.Context, token string) error
-	SetToken(token string)
+	GetNotes(ctx context.Context, userID string, id string) ([]models.Note, error)
+	ListNotes(ctx context.Context, userID string, query models.NoteQuery) (*models.NotePage, error)
+	SearchNotes(ctx context.Context, userID string, query models.SearchQuery) (*models.SearchPage, error)
+	UpdateNote(ctx context.Context, userID string, token string, id string, version int64, noteRequest models.NoteRequest) (int64, error)
+	DeleteNote(ctx context.Context, userID string, id string) error
+	GetTrash(ctx context.Context, userID string) ([]models.Note, error)
+	RestoreFromTrash(ctx context.Context, userID string, id string) error
+	PurgeNote(ctx context.Context, userID string, id string) error
+	CreateNote(ctx context.Context, userID string, noteRequest models.NoteRequest) (string, error)
+	SendToContentService(ctx context.Context, userID string, token string, id string, format string) (*models.SaveJob, error)
+	GetSaveJob(ctx context.Context, userID string, id string) (*models.SaveJob, error)
+	ListContentFiles(ctx context.Context, token string) ([]models.ContentFile, error)
+	ImportNotes(ctx context.Context, userID string, token string, request models.ImportRequest) (*models.ImportResult, error)
+	ValidateToken(ctx context.Context, token string) (*models.Principal, error)
+	GetTags(ctx context.Context, userID string) ([]models.TagCount, error)
+	RenameTag(ctx context.Context, userID string, oldName string, newName string) (int64, error)
+	MoveNote(ctx context.Context, userID string, id string, notebookID string) error
+	GetNotebooks(ctx context.Context, userID string) ([]models.Notebook, error)
+	GetNotebook(ctx context.Context, userID string, id string) (*models.Notebook, error)
+	CreateNotebook(ctx context.Context, userID string, notebookRequest models.NotebookRequest) (string, error)
+	UpdateNotebook(ctx context.Context, userID string, id string, notebookRequest models.NotebookRequest) error
+	MoveNotebook(ctx context.Context, userID string, id string, parentID string) error
+	DeleteNotebook(ctx context.Context, userID string, id string, cascade bool) error
+	GetRevisions(ctx context.Context, userID string, noteID string) ([]models.Revision, error)
+	GetRevision(ctx context.Context, userID string, noteID string, revisionID string) (*models.Revision, error)
+	RestoreRevision(ctx context.Context, userID string, token string, noteID string, revisionID string, version int64) (int64, error)
 }
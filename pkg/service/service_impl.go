@@ -1,147 +1,352 @@
 package service
 
 import (
-	"bytes"
 	"context"
-	"fmt"
+	"errors"
 	"github.com/sirupsen/logrus"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"io"
-	"mime/multipart"
+	"notes-api/pkg/auth"
 	"notes-api/pkg/dao"
+	"notes-api/pkg/export"
 	"notes-api/pkg/external"
 	"notes-api/pkg/models"
-	"path/filepath"
-	"strings"
 	"time"
 )
 
 type NotesService struct {
-	Dao dao.NoteDaoHandler
-	Ext external.ExtAPIHandler
+	Dao       dao.NoteDaoHandler
+	Notebooks dao.NotebookDaoHandler
+	SaveJobs  dao.SaveJobDaoHandler
+	Ext       external.ExtAPIHandler
+	Auth      auth.Validator
+	// Tokens seals the tokens that queued saves are delivered with.
+	Tokens    *auth.Sealer
+	Exporters *export.Registry
+	// SyncDelay is how long after an update of a note that is synced automatically it is uploaded. Updates made in
+	// the meantime are part of the same upload.
+	SyncDelay time.Duration
 }
 
 func (svc *NotesService) Ping(ctx context.Context) error {
 	return svc.Dao.Ping(ctx)
 }
 
-func (svc *NotesService) GetNotes(ctx context.Context, id string) ([]models.Note, error) {
-	filter := make(map[string]interface{})
+func (svc *NotesService) GetNotes(ctx context.Context, userID string, id string) ([]models.Note, error) {
+	filter := dao.NoteFilter{OwnerID: userID}
 
 	if id != "" {
 		objectId, err := primitive.ObjectIDFromHex(id)
 		if err != nil {
 			return nil, err
 		}
-		filter["_id"] = objectId
+		filter.IDs = []primitive.ObjectID{objectId}
 	}
 
-	return svc.Dao.GetNotes(ctx, filter)
+	notes, err := svc.Dao.GetNotes(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	setExportStatus(notes)
+	return notes, nil
 }
 
-func (svc *NotesService) UpdateNote(ctx context.Context, id string, noteRequest models.NoteRequest) error {
+// UpdateNote overwrites a note if, and only if, its current version is the given one, and returns the new version.
+// The version check is part of the update filter, so two concurrent writers of the same version cannot both succeed.
+// A note that is synced automatically is then queued to be uploaded on behalf of the caller whose token is given.
+func (svc *NotesService) UpdateNote(ctx context.Context, userID string, token string, id string, version int64, noteRequest models.NoteRequest) (int64, error) {
+	objectId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return 0, err
+	}
+
+	// Notes created before versioning are reported as version 0, which the filter matches.
+	filter := dao.NoteFilter{
+		IDs:     []primitive.ObjectID{objectId},
+		OwnerID: userID,
+		Version: &version,
+	}
+
+	now := time.Now()
+
+	patch := dao.NotePatch{
+		Name:             &noteRequest.Name,
+		Text:             &noteRequest.Text,
+		Tags:             normalizeTags(noteRequest.Tags),
+		LastEditedTs:     &now,
+		IncrementVersion: true,
+		AutoSync:         noteRequest.AutoSync,
+	}
+
+	if err := svc.Dao.UpdateNote(ctx, filter, patch); errors.Is(err, dao.ErrNotFound) {
+		return 0, svc.versionConflictOrNotFound(ctx, userID, id)
+	} else if err != nil {
+		return 0, err
+	}
+
+	// The update has been made, so failing to record its revision or to queue the upload is not reported as failing
+	// the update: a client retrying it would only be told its version is stale.
+	if err := svc.recordRevision(ctx, userID, objectId, version+1, noteRequest, now); err != nil {
+		logrus.WithContext(ctx).WithError(err).WithField("noteId", id).Error("Error recording revision of note")
+	}
+
+	if err := svc.queueSync(ctx, userID, token, id); err != nil {
+		logrus.WithContext(ctx).WithError(err).WithField("noteId", id).Error("Error queueing note to be synced")
+	}
+
+	return version + 1, nil
+}
+
+// DeleteNote moves a note to the trash. It disappears from GetNotes but can be restored until it is purged.
+func (svc *NotesService) DeleteNote(ctx context.Context, userID string, id string) error {
 	objectId, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return err
 	}
 
-	filter := map[string]interface{}{
-		"_id": objectId,
+	filter := dao.NoteFilter{
+		IDs:     []primitive.ObjectID{objectId},
+		OwnerID: userID,
+	}
+
+	now := time.Now()
+
+	return svc.Dao.UpdateNote(ctx, filter, dao.NotePatch{DeletedAt: &now})
+}
+
+func (svc *NotesService) GetTrash(ctx context.Context, userID string) ([]models.Note, error) {
+	filter := dao.NoteFilter{
+		OwnerID: userID,
+		Trash:   dao.OnlyTrash,
 	}
 
-	updates := bson.M{
-		"$set": bson.M{
-			"name": noteRequest.Name,
-			"text": noteRequest.Text,
-			"lastEditedTs": time.Now(),
-		},
+	notes, err := svc.Dao.GetNotes(ctx, filter)
+	if err != nil {
+		return nil, err
 	}
 
-	return svc.Dao.UpdateNote(ctx, filter, updates)
+	setExportStatus(notes)
+	return notes, nil
 }
 
-func (svc *NotesService) DeleteNote(ctx context.Context, id string) error {
+func (svc *NotesService) RestoreFromTrash(ctx context.Context, userID string, id string) error {
 	objectId, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return err
 	}
 
-	filter := map[string]interface{}{
-		"_id": objectId,
+	filter := dao.NoteFilter{
+		IDs:     []primitive.ObjectID{objectId},
+		OwnerID: userID,
+		Trash:   dao.OnlyTrash,
+	}
+
+	return svc.Dao.UpdateNote(ctx, filter, dao.NotePatch{Restore: true})
+}
+
+// PurgeNote permanently removes a note that is in the trash, along with its revisions.
+func (svc *NotesService) PurgeNote(ctx context.Context, userID string, id string) error {
+	objectId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	filter := dao.NoteFilter{
+		IDs:     []primitive.ObjectID{objectId},
+		OwnerID: userID,
+		Trash:   dao.OnlyTrash,
 	}
 
 	if err := svc.Dao.DeleteNote(ctx, filter); err != nil {
 		return err
 	}
 
-	return nil
+	return svc.Dao.DeleteRevisions(ctx, dao.RevisionFilter{
+		NoteIDs: []primitive.ObjectID{objectId},
+		OwnerID: userID,
+	})
+}
+
+// purgeBatchSize is the number of notes PurgeTrash removes at a time.
+const purgeBatchSize = 500
+
+// PurgeTrash permanently removes every user's notes that were moved to the trash before the given time, along with
+// their revisions, and returns how many notes were removed. Only the IDs of the notes are read, a batch at a time.
+func (svc *NotesService) PurgeTrash(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	filter := dao.NoteFilter{Trash: dao.OnlyTrash, DeletedBefore: deletedBefore}
+
+	var purged int64
+	for {
+		notes, err := svc.Dao.ListNotes(ctx, filter, dao.ListOptions{Limit: purgeBatchSize, Fields: []string{"id"}})
+		if err != nil || len(notes) == 0 {
+			return purged, err
+		}
+
+		ids := make([]primitive.ObjectID, len(notes))
+		for i, note := range notes {
+			ids[i] = note.ID
+		}
+
+		deleted, err := svc.purgeNotes(ctx, ids, deletedBefore)
+		purged += deleted
+		if err != nil || len(notes) < purgeBatchSize {
+			return purged, err
+		}
+	}
+}
+
+// purgeNotes removes the notes with the given IDs that are still in the trash since before deletedBefore, along with
+// their revisions, and returns how many notes were removed.
+func (svc *NotesService) purgeNotes(ctx context.Context, ids []primitive.ObjectID, deletedBefore time.Time) (int64, error) {
+	// The filter is repeated, so that a note restored since it was read is left alone.
+	deleted, err := svc.Dao.DeleteNotes(ctx, dao.NoteFilter{IDs: ids, Trash: dao.OnlyTrash, DeletedBefore: deletedBefore})
+	if err != nil {
+		return 0, err
+	}
+
+	if deleted < int64(len(ids)) {
+		remaining, err := svc.Dao.GetNotes(ctx, dao.NoteFilter{IDs: ids, Trash: dao.AnyTrash})
+		if err != nil {
+			return deleted, err
+		}
+		if ids = withoutNotes(ids, remaining); len(ids) == 0 {
+			return deleted, nil
+		}
+	}
+
+	return deleted, svc.Dao.DeleteRevisions(ctx, dao.RevisionFilter{NoteIDs: ids})
+}
+
+// withoutNotes returns the IDs that are not the ID of any of the notes.
+func withoutNotes(ids []primitive.ObjectID, notes []models.Note) []primitive.ObjectID {
+	kept := make(map[primitive.ObjectID]bool, len(notes))
+	for _, note := range notes {
+		kept[note.ID] = true
+	}
+
+	var gone []primitive.ObjectID
+	for _, id := range ids {
+		if !kept[id] {
+			gone = append(gone, id)
+		}
+	}
+	return gone
 }
 
-func (svc *NotesService) CreateNote(ctx context.Context, noteRequest models.NoteRequest) (string, error) {
+func (svc *NotesService) CreateNote(ctx context.Context, userID string, noteRequest models.NoteRequest) (string, error) {
+	notebookID, err := svc.notebookRef(ctx, userID, noteRequest.NotebookID)
+	if err != nil {
+		return "", err
+	}
+
 	id := primitive.NewObjectID()
 
 	note := models.Note{
 		ID:           id,
+		OwnerID:      userID,
 		Name:         noteRequest.Name,
 		LastEditedTs: time.Now(),
 		Text:         noteRequest.Text,
+		TextHash:     dao.TextHash(noteRequest.Text),
+		Version:      1,
+		Tags:         normalizeTags(noteRequest.Tags),
+		NotebookID:   notebookID,
+		AutoSync:     noteRequest.AutoSync != nil && *noteRequest.AutoSync,
 	}
 
 	if err := svc.Dao.CreateNote(ctx, note); err != nil {
 		return "", err
 	}
 
+	// As in UpdateNote, the note has been created whether or not its revision can be recorded.
+	if err := svc.recordRevision(ctx, userID, id, note.Version, noteRequest, note.LastEditedTs); err != nil {
+		logrus.WithContext(ctx).WithError(err).WithField("noteId", id.Hex()).Error("Error recording revision of note")
+	}
+
 	return id.Hex(), nil
 }
 
-func (svc *NotesService) SendToContentService(ctx context.Context, id string) error {
-	logger := logrus.WithContext(ctx)
+func (svc *NotesService) ValidateToken(ctx context.Context, token string) (*models.Principal, error) {
+	return svc.Auth.ValidateToken(ctx, token)
+}
 
-	notes, err := svc.GetNotes(ctx, id)
+func (svc *NotesService) GetRevisions(ctx context.Context, userID string, noteID string) ([]models.Revision, error) {
+	noteObjectId, err := primitive.ObjectIDFromHex(noteID)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	note := notes[0]
 
-	var body bytes.Buffer
-	writer := multipart.NewWriter(&body)
+	filter := dao.RevisionFilter{
+		NoteIDs: []primitive.ObjectID{noteObjectId},
+		OwnerID: userID,
+	}
+
+	return svc.Dao.GetRevisions(ctx, filter)
+}
 
-	fileName := strings.Replace(note.Name, " ", "", -1)
-	extension := filepath.Ext(fileName)
-	if extension != "" {
-		fileName = fileName[0 : len(fileName)-len(extension)]
+func (svc *NotesService) GetRevision(ctx context.Context, userID string, noteID string, revisionID string) (*models.Revision, error) {
+	noteObjectId, err := primitive.ObjectIDFromHex(noteID)
+	if err != nil {
+		return nil, err
 	}
-	fileName = fmt.Sprintf("%v.txt", fileName)
 
-	formFile, err := writer.CreateFormFile("file", fileName)
+	revisionObjectId, err := primitive.ObjectIDFromHex(revisionID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	if _, err := io.Copy(formFile, bytes.NewBuffer([]byte(note.Text))); err != nil {
-		return err
+	filter := dao.RevisionFilter{
+		IDs:     []primitive.ObjectID{revisionObjectId},
+		NoteIDs: []primitive.ObjectID{noteObjectId},
+		OwnerID: userID,
 	}
 
-	if err := writer.Close(); err != nil {
-		logger.WithError(err).Error("Error closing multipart writer")
+	revisions, err := svc.Dao.GetRevisions(ctx, filter)
+	if err != nil {
+		return nil, err
+	} else if len(revisions) == 0 {
+		return nil, dao.ErrRevisionNotFound
 	}
 
-	if err := svc.Ext.SendToContentService(ctx, body, writer.FormDataContentType()); err != nil {
-		return err
+	return &revisions[0], nil
+}
+
+// RestoreRevision overwrites the given version of a note with the content of one of its revisions. The restore is
+// itself an update, so it is recorded as a new revision and can be undone the same way.
+func (svc *NotesService) RestoreRevision(ctx context.Context, userID string, token string, noteID string, revisionID string, version int64) (int64, error) {
+	revision, err := svc.GetRevision(ctx, userID, noteID, revisionID)
+	if err != nil {
+		return 0, err
 	}
 
-	return nil
+	return svc.UpdateNote(ctx, userID, token, noteID, version, models.NoteRequest{
+		Name: revision.Name,
+		Text: revision.Text,
+	})
 }
 
-func (svc *NotesService) ValidateToken(ctx context.Context, token string) error {
-	if err := svc.Ext.ValidateToken(ctx, token); err != nil {
+// versionConflictOrNotFound tells apart the two reasons a versioned update can match nothing.
+func (svc *NotesService) versionConflictOrNotFound(ctx context.Context, userID string, id string) error {
+	notes, err := svc.GetNotes(ctx, userID, id)
+	if err != nil {
 		return err
+	} else if len(notes) == 0 {
+		return dao.ErrNotFound
 	}
-
-	return nil
+	return ErrVersionConflict
 }
 
-func (svc *NotesService) SetToken(token string) {
-	svc.Ext.SetToken(token)
+func (svc *NotesService) recordRevision(ctx context.Context, userID string, noteID primitive.ObjectID, version int64, noteRequest models.NoteRequest, editedTs time.Time) error {
+	revision := models.Revision{
+		ID:       primitive.NewObjectID(),
+		NoteID:   noteID,
+		OwnerID:  userID,
+		Name:     noteRequest.Name,
+		Text:     noteRequest.Text,
+		Version:  version,
+		EditedBy: userID,
+		EditedTs: editedTs,
+	}
+
+	return svc.Dao.CreateRevision(ctx, revision)
 }
@@ -4,10 +4,13 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 
+	"notes-api/pkg/dao"
 	"notes-api/pkg/models"
 	"notes-api/pkg/testhelper/mocks"
 )
@@ -39,7 +42,7 @@ func TestService_Ping_ShouldReturnNilIfNoErrorOccurs(t *testing.T) {
 func TestService_GetNotes_ShouldReturnErrorIfIDIsNotEmptyAndIsNotValidHex(t *testing.T) {
 	service := NotesService{}
 
-	notes, err := service.GetNotes(context.TODO(), "test")
+	notes, err := service.GetNotes(context.TODO(), "user", "test")
 	require.Nil(t, notes)
 	require.NotNil(t, err)
 	require.Contains(t, err.Error(), "encoding/hex: invalid byte")
@@ -53,7 +56,7 @@ func TestService_GetNotes_ShouldReturnErrorOnDaoError(t *testing.T) {
 		Dao: mockDao,
 	}
 
-	notes, err := service.GetNotes(context.TODO(), "")
+	notes, err := service.GetNotes(context.TODO(), "user", "")
 	require.Nil(t, notes)
 	require.NotNil(t, err)
 	require.Equal(t, "test", err.Error())
@@ -67,15 +70,28 @@ func TestService_GetNotes_ShouldReturnNoErrorIfNoErrorOccurs(t *testing.T) {
 		Dao: mockDao,
 	}
 
-	notes, err := service.GetNotes(context.TODO(), "")
+	notes, err := service.GetNotes(context.TODO(), "user", "")
 	require.Nil(t, err)
 	require.NotNil(t, notes)
 }
 
+func TestService_GetNotes_ShouldScopeFilterToUserAndExcludeTrash(t *testing.T) {
+	mockDao := &mocks.NoteDaoHandler{}
+	mockDao.On("GetNotes", mock.Anything, dao.NoteFilter{OwnerID: "user"}).Return([]models.Note{}, nil)
+
+	service := NotesService{
+		Dao: mockDao,
+	}
+
+	_, err := service.GetNotes(context.TODO(), "user", "")
+	require.Nil(t, err)
+	mockDao.AssertExpectations(t)
+}
+
 func TestService_UpdateNote_ShouldReturnErrorIfIDIsNotValidHex(t *testing.T) {
 	service := NotesService{}
 
-	err := service.UpdateNote(context.TODO(), "test", models.NoteRequest{})
+	_, err := service.UpdateNote(context.TODO(), "user", "token", "test", 1, models.NoteRequest{})
 	require.NotNil(t, err)
 	require.Contains(t, err.Error(), "encoding/hex: invalid byte")
 }
@@ -88,52 +104,331 @@ func TestService_UpdateNote_ShouldReturnErrorOnDaoError(t *testing.T) {
 		Dao: mockDao,
 	}
 
-	err := service.UpdateNote(context.TODO(), "000000000000000000000000", models.NoteRequest{})
+	_, err := service.UpdateNote(context.TODO(), "user", "token", "000000000000000000000000", 1, models.NoteRequest{})
 	require.NotNil(t, err)
 	require.Equal(t, "test", err.Error())
 }
 
+func TestService_UpdateNote_ShouldReturnNewVersionIfRevisionCannotBeRecorded(t *testing.T) {
+	mockDao := &mocks.NoteDaoHandler{}
+	mockDao.On("UpdateNote", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockDao.On("CreateRevision", mock.Anything, mock.Anything).Return(errors.New("test"))
+	mockDao.On("GetNotes", mock.Anything, mock.Anything).Return(nil, nil)
+
+	service := NotesService{
+		Dao: mockDao,
+	}
+
+	version, err := service.UpdateNote(context.TODO(), "user", "token", "000000000000000000000000", 1, models.NoteRequest{})
+	require.Nil(t, err)
+	require.Equal(t, int64(2), version)
+}
+
+func TestService_UpdateNote_ShouldRecordRevisionOfNewContent(t *testing.T) {
+	mockDao := &mocks.NoteDaoHandler{}
+	mockDao.On("UpdateNote", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockDao.On("CreateRevision", mock.Anything, mock.MatchedBy(func(revision models.Revision) bool {
+		return revision.NoteID.Hex() == "000000000000000000000001" &&
+			revision.Version == 2 &&
+			revision.OwnerID == "user" &&
+			revision.EditedBy == "user" &&
+			revision.Name == "name" &&
+			revision.Text == "text"
+	})).Return(nil)
+	mockDao.On("GetNotes", mock.Anything, mock.Anything).Return(nil, nil)
+
+	service := NotesService{
+		Dao: mockDao,
+	}
+
+	_, err := service.UpdateNote(context.TODO(), "user", "token", "000000000000000000000001", 1, models.NoteRequest{Name: "name", Text: "text"})
+	require.Nil(t, err)
+	mockDao.AssertExpectations(t)
+}
+
 func TestService_UpdateNote_ShouldReturnNoErrorIfNoErrorOccurs(t *testing.T) {
 	mockDao := &mocks.NoteDaoHandler{}
 	mockDao.On("UpdateNote", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockDao.On("CreateRevision", mock.Anything, mock.Anything).Return(nil)
+	mockDao.On("GetNotes", mock.Anything, mock.Anything).Return(nil, nil)
 
 	service := NotesService{
 		Dao: mockDao,
 	}
 
-	require.Nil(t, service.UpdateNote(context.TODO(), "000000000000000000000000", models.NoteRequest{}))
+	version, err := service.UpdateNote(context.TODO(), "user", "token", "000000000000000000000000", 1, models.NoteRequest{})
+	require.Nil(t, err)
+	require.Equal(t, int64(2), version)
+}
+
+func TestService_UpdateNote_ShouldMatchOnVersionAndIncrementIt(t *testing.T) {
+	mockDao := &mocks.NoteDaoHandler{}
+	mockDao.On("UpdateNote", mock.Anything, mock.MatchedBy(func(filter dao.NoteFilter) bool {
+		return filter.Version != nil && *filter.Version == 3
+	}), mock.MatchedBy(func(patch dao.NotePatch) bool {
+		return patch.IncrementVersion
+	})).Return(nil)
+	mockDao.On("CreateRevision", mock.Anything, mock.Anything).Return(nil)
+	mockDao.On("GetNotes", mock.Anything, mock.Anything).Return(nil, nil)
+
+	service := NotesService{
+		Dao: mockDao,
+	}
+
+	_, err := service.UpdateNote(context.TODO(), "user", "token", "000000000000000000000000", 3, models.NoteRequest{})
+	require.Nil(t, err)
+	mockDao.AssertExpectations(t)
+}
+
+func TestService_UpdateNote_ShouldLeaveTagsUnchangedIfRequestHasNone(t *testing.T) {
+	mockDao := &mocks.NoteDaoHandler{}
+	mockDao.On("UpdateNote", mock.Anything, mock.Anything, mock.MatchedBy(func(patch dao.NotePatch) bool {
+		return patch.Tags == nil
+	})).Return(nil)
+	mockDao.On("CreateRevision", mock.Anything, mock.Anything).Return(nil)
+	mockDao.On("GetNotes", mock.Anything, mock.Anything).Return(nil, nil)
+
+	service := NotesService{
+		Dao: mockDao,
+	}
+
+	_, err := service.UpdateNote(context.TODO(), "user", "token", "000000000000000000000000", 1, models.NoteRequest{})
+	require.Nil(t, err)
+	mockDao.AssertExpectations(t)
+}
+
+func TestService_UpdateNote_ShouldReplaceTagsIfRequestHasThem(t *testing.T) {
+	mockDao := &mocks.NoteDaoHandler{}
+	mockDao.On("UpdateNote", mock.Anything, mock.Anything, mock.MatchedBy(func(patch dao.NotePatch) bool {
+		return patch.Tags != nil && len(patch.Tags) == 0
+	})).Return(nil)
+	mockDao.On("CreateRevision", mock.Anything, mock.Anything).Return(nil)
+	mockDao.On("GetNotes", mock.Anything, mock.Anything).Return(nil, nil)
+
+	service := NotesService{
+		Dao: mockDao,
+	}
+
+	_, err := service.UpdateNote(context.TODO(), "user", "token", "000000000000000000000000", 1, models.NoteRequest{Tags: []string{" "}})
+	require.Nil(t, err)
+	mockDao.AssertExpectations(t)
+}
+
+func TestService_UpdateNote_ShouldReturnVersionConflictIfNoteExistsWithAnotherVersion(t *testing.T) {
+	mockDao := &mocks.NoteDaoHandler{}
+	mockDao.On("UpdateNote", mock.Anything, mock.Anything, mock.Anything).Return(dao.ErrNotFound)
+	mockDao.On("GetNotes", mock.Anything, mock.Anything).Return([]models.Note{{Version: 4}}, nil)
+
+	service := NotesService{
+		Dao: mockDao,
+	}
+
+	_, err := service.UpdateNote(context.TODO(), "user", "token", "000000000000000000000000", 3, models.NoteRequest{})
+	require.Equal(t, ErrVersionConflict, err)
+}
+
+func TestService_UpdateNote_ShouldReturnNotFoundIfNoteDoesNotExist(t *testing.T) {
+	mockDao := &mocks.NoteDaoHandler{}
+	mockDao.On("UpdateNote", mock.Anything, mock.Anything, mock.Anything).Return(dao.ErrNotFound)
+	mockDao.On("GetNotes", mock.Anything, mock.Anything).Return([]models.Note{}, nil)
+
+	service := NotesService{
+		Dao: mockDao,
+	}
+
+	_, err := service.UpdateNote(context.TODO(), "user", "token", "000000000000000000000000", 3, models.NoteRequest{})
+	require.Equal(t, dao.ErrNotFound, err)
 }
 
 func TestService_DeleteNote_ShouldReturnErrorIfIDIsNotValidHex(t *testing.T) {
 	service := NotesService{}
 
-	err := service.DeleteNote(context.TODO(), "test")
+	err := service.DeleteNote(context.TODO(), "user", "test")
 	require.NotNil(t, err)
 	require.Contains(t, err.Error(), "encoding/hex: invalid byte")
 }
 
 func TestService_DeleteNote_ShouldReturnErrorOnDaoError(t *testing.T) {
 	mockDao := &mocks.NoteDaoHandler{}
-	mockDao.On("DeleteNote", mock.Anything, mock.Anything).Return(errors.New("test"))
+	mockDao.On("UpdateNote", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("test"))
 
 	service := NotesService{
 		Dao: mockDao,
 	}
 
-	err := service.DeleteNote(context.TODO(), "000000000000000000000000")
+	err := service.DeleteNote(context.TODO(), "user", "000000000000000000000000")
 	require.NotNil(t, err)
 	require.Equal(t, "test", err.Error())
 }
 
-func TestService_DeleteNote_ShouldReturnNoErrorIfNoErrorOccurs(t *testing.T) {
+func TestService_DeleteNote_ShouldMoveNoteToTrash(t *testing.T) {
+	mockDao := &mocks.NoteDaoHandler{}
+	mockDao.On("UpdateNote", mock.Anything, mock.MatchedBy(func(filter dao.NoteFilter) bool {
+		return filter.OwnerID == "user" && filter.Trash == dao.ExcludeTrash
+	}), mock.MatchedBy(func(patch dao.NotePatch) bool {
+		return patch.DeletedAt != nil
+	})).Return(nil)
+
+	service := NotesService{
+		Dao: mockDao,
+	}
+
+	require.Nil(t, service.DeleteNote(context.TODO(), "user", "000000000000000000000000"))
+	mockDao.AssertExpectations(t)
+}
+
+func TestService_GetTrash_ShouldOnlyReturnTrashedNotes(t *testing.T) {
+	mockDao := &mocks.NoteDaoHandler{}
+	mockDao.On("GetNotes", mock.Anything, dao.NoteFilter{OwnerID: "user", Trash: dao.OnlyTrash}).Return([]models.Note{{}}, nil)
+
+	service := NotesService{
+		Dao: mockDao,
+	}
+
+	notes, err := service.GetTrash(context.TODO(), "user")
+	require.Nil(t, err)
+	require.Len(t, notes, 1)
+}
+
+func TestService_RestoreFromTrash_ShouldReturnErrorIfIDIsNotValidHex(t *testing.T) {
+	service := NotesService{}
+
+	err := service.RestoreFromTrash(context.TODO(), "user", "test")
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "encoding/hex: invalid byte")
+}
+
+func TestService_RestoreFromTrash_ShouldUnsetDeletedAt(t *testing.T) {
+	mockDao := &mocks.NoteDaoHandler{}
+	mockDao.On("UpdateNote", mock.Anything, mock.MatchedBy(func(filter dao.NoteFilter) bool {
+		return filter.Trash == dao.OnlyTrash
+	}), dao.NotePatch{Restore: true}).Return(nil)
+
+	service := NotesService{
+		Dao: mockDao,
+	}
+
+	require.Nil(t, service.RestoreFromTrash(context.TODO(), "user", "000000000000000000000000"))
+	mockDao.AssertExpectations(t)
+}
+
+func TestService_PurgeNote_ShouldReturnErrorOnDaoError(t *testing.T) {
+	mockDao := &mocks.NoteDaoHandler{}
+	mockDao.On("DeleteNote", mock.Anything, mock.Anything).Return(dao.ErrNotFound)
+
+	service := NotesService{
+		Dao: mockDao,
+	}
+
+	err := service.PurgeNote(context.TODO(), "user", "000000000000000000000000")
+	require.Equal(t, dao.ErrNotFound, err)
+}
+
+func TestService_PurgeNote_ShouldDeleteNoteAndRevisions(t *testing.T) {
 	mockDao := &mocks.NoteDaoHandler{}
 	mockDao.On("DeleteNote", mock.Anything, mock.Anything).Return(nil)
+	mockDao.On("DeleteRevisions", mock.Anything, mock.Anything).Return(nil)
+
+	service := NotesService{
+		Dao: mockDao,
+	}
+
+	require.Nil(t, service.PurgeNote(context.TODO(), "user", "000000000000000000000000"))
+	mockDao.AssertExpectations(t)
+}
+
+func TestService_PurgeTrash_ShouldNotDeleteAnythingIfNoNotesAreExpired(t *testing.T) {
+	mockDao := &mocks.NoteDaoHandler{}
+	mockDao.On("ListNotes", mock.Anything, mock.Anything, mock.Anything).Return([]models.Note{}, nil)
+
+	service := NotesService{
+		Dao: mockDao,
+	}
+
+	purged, err := service.PurgeTrash(context.TODO(), time.Now())
+	require.Nil(t, err)
+	require.Equal(t, int64(0), purged)
+	mockDao.AssertNotCalled(t, "DeleteNotes", mock.Anything, mock.Anything)
+}
+
+func TestService_PurgeTrash_ShouldDeleteExpiredNotesAndTheirRevisions(t *testing.T) {
+	id := primitive.NewObjectID()
+
+	mockDao := &mocks.NoteDaoHandler{}
+	mockDao.On("ListNotes", mock.Anything, mock.MatchedBy(func(filter dao.NoteFilter) bool {
+		return filter.Trash == dao.OnlyTrash && !filter.DeletedBefore.IsZero() && filter.OwnerID == ""
+	}), dao.ListOptions{Limit: purgeBatchSize, Fields: []string{"id"}}).Return([]models.Note{{ID: id}}, nil)
+	mockDao.On("DeleteNotes", mock.Anything, mock.MatchedBy(func(filter dao.NoteFilter) bool {
+		return filter.Trash == dao.OnlyTrash && !filter.DeletedBefore.IsZero() && len(filter.IDs) == 1 && filter.IDs[0] == id
+	})).Return(int64(1), nil)
+	mockDao.On("DeleteRevisions", mock.Anything, dao.RevisionFilter{
+		NoteIDs: []primitive.ObjectID{id},
+	}).Return(nil)
+
+	service := NotesService{
+		Dao: mockDao,
+	}
+
+	purged, err := service.PurgeTrash(context.TODO(), time.Now())
+	require.Nil(t, err)
+	require.Equal(t, int64(1), purged)
+	mockDao.AssertExpectations(t)
+}
+
+func TestService_PurgeTrash_ShouldPurgeInBatchesUntilTrashIsEmpty(t *testing.T) {
+	full := make([]models.Note, purgeBatchSize)
+	for i := range full {
+		full[i].ID = primitive.NewObjectID()
+	}
+	last := []models.Note{{ID: primitive.NewObjectID()}}
+
+	mockDao := &mocks.NoteDaoHandler{}
+	mockDao.On("ListNotes", mock.Anything, mock.Anything, mock.Anything).Return(full, nil).Once()
+	mockDao.On("ListNotes", mock.Anything, mock.Anything, mock.Anything).Return(last, nil).Once()
+	mockDao.On("DeleteNotes", mock.Anything, mock.MatchedBy(func(filter dao.NoteFilter) bool {
+		return len(filter.IDs) == purgeBatchSize
+	})).Return(int64(purgeBatchSize), nil).Once()
+	mockDao.On("DeleteNotes", mock.Anything, mock.MatchedBy(func(filter dao.NoteFilter) bool {
+		return len(filter.IDs) == 1
+	})).Return(int64(1), nil).Once()
+	mockDao.On("DeleteRevisions", mock.Anything, mock.Anything).Return(nil).Twice()
 
 	service := NotesService{
 		Dao: mockDao,
 	}
 
-	require.Nil(t, service.DeleteNote(context.TODO(), "000000000000000000000000"))
+	purged, err := service.PurgeTrash(context.TODO(), time.Now())
+	require.Nil(t, err)
+	require.Equal(t, int64(purgeBatchSize+1), purged)
+	mockDao.AssertExpectations(t)
+	mockDao.AssertNumberOfCalls(t, "ListNotes", 2)
+}
+
+func TestService_PurgeTrash_ShouldKeepNotesRestoredWhilePurging(t *testing.T) {
+	memoryDao := dao.NewMemoryDao()
+	service := NotesService{Dao: memoryDao}
+
+	restoredID, err := service.CreateNote(context.TODO(), "user", models.NoteRequest{Name: "restored"})
+	require.Nil(t, err)
+	purgedID, err := service.CreateNote(context.TODO(), "user", models.NoteRequest{Name: "purged"})
+	require.Nil(t, err)
+	require.Nil(t, service.DeleteNote(context.TODO(), "user", restoredID))
+	require.Nil(t, service.DeleteNote(context.TODO(), "user", purgedID))
+
+	// The note is restored after the purge has read the trash, and before it deletes anything.
+	racingDao := &restoringDao{NoteDaoHandler: memoryDao, restore: func() {
+		require.Nil(t, service.RestoreFromTrash(context.TODO(), "user", restoredID))
+	}}
+	purged, err := (&NotesService{Dao: racingDao}).PurgeTrash(context.TODO(), time.Now().Add(time.Minute))
+	require.Nil(t, err)
+	require.Equal(t, int64(1), purged)
+
+	revisions, err := service.GetRevisions(context.TODO(), "user", restoredID)
+	require.Nil(t, err)
+	require.NotEmpty(t, revisions)
+	notes, err := service.GetNotes(context.TODO(), "user", restoredID)
+	require.Nil(t, err)
+	require.Len(t, notes, 1)
 }
 
 func TestService_CreateNote_ShouldReturnErrorOnDaoError(t *testing.T) {
@@ -144,7 +439,7 @@ func TestService_CreateNote_ShouldReturnErrorOnDaoError(t *testing.T) {
 		Dao: mockDao,
 	}
 
-	id, err := service.CreateNote(context.TODO(), models.NoteRequest{})
+	id, err := service.CreateNote(context.TODO(), "user", models.NoteRequest{})
 	require.Equal(t, "", id)
 	require.NotNil(t, err)
 	require.Equal(t, "test", err.Error())
@@ -153,92 +448,142 @@ func TestService_CreateNote_ShouldReturnErrorOnDaoError(t *testing.T) {
 func TestService_CreateNote_ShouldReturnNoteIDAndNilErrorIfNoErrorOccurs(t *testing.T) {
 	mockDao := &mocks.NoteDaoHandler{}
 	mockDao.On("CreateNote", mock.Anything, mock.Anything).Return(nil)
+	mockDao.On("CreateRevision", mock.Anything, mock.Anything).Return(nil)
 
 	service := NotesService{
 		Dao: mockDao,
 	}
 
-	id, err := service.CreateNote(context.TODO(), models.NoteRequest{})
+	id, err := service.CreateNote(context.TODO(), "user", models.NoteRequest{})
 	require.NotEqual(t, "", id)
 	require.Nil(t, err)
 }
 
-func TestService_SendToContentService_ShouldReturnErrorOnDaoError(t *testing.T) {
+func TestService_CreateNote_ShouldReturnNoteIDIfRevisionCannotBeRecorded(t *testing.T) {
 	mockDao := &mocks.NoteDaoHandler{}
-	mockDao.On("GetNotes", mock.Anything, mock.Anything).Return([]models.Note{}, errors.New("test"))
+	mockDao.On("CreateNote", mock.Anything, mock.Anything).Return(nil)
+	mockDao.On("CreateRevision", mock.Anything, mock.Anything).Return(errors.New("test"))
 
 	service := NotesService{
 		Dao: mockDao,
 	}
 
-	err := service.SendToContentService(context.TODO(), "")
+	id, err := service.CreateNote(context.TODO(), "user", models.NoteRequest{})
+	require.NotEqual(t, "", id)
+	require.Nil(t, err)
+}
+
+func TestService_GetRevisions_ShouldReturnErrorIfNoteIDIsNotValidHex(t *testing.T) {
+	service := NotesService{}
+
+	revisions, err := service.GetRevisions(context.TODO(), "user", "test")
+	require.Nil(t, revisions)
 	require.NotNil(t, err)
-	require.Equal(t, "test", err.Error())
+	require.Contains(t, err.Error(), "encoding/hex: invalid byte")
 }
 
-func TestService_SendToContentService_ShouldReturnErrorOnExtHandlerError(t *testing.T) {
+func TestService_GetRevisions_ShouldScopeFilterToNoteAndUser(t *testing.T) {
+	noteID, _ := primitive.ObjectIDFromHex("000000000000000000000001")
+
 	mockDao := &mocks.NoteDaoHandler{}
-	mockDao.On("GetNotes", mock.Anything, mock.Anything).Return([]models.Note{{}}, nil)
+	mockDao.On("GetRevisions", mock.Anything, dao.RevisionFilter{
+		NoteIDs: []primitive.ObjectID{noteID},
+		OwnerID: "user",
+	}).Return([]models.Revision{{}}, nil)
+
+	service := NotesService{
+		Dao: mockDao,
+	}
+
+	revisions, err := service.GetRevisions(context.TODO(), "user", noteID.Hex())
+	require.Nil(t, err)
+	require.Len(t, revisions, 1)
+}
 
-	mockExt := &mocks.ExtAPIHandler{}
-	mockExt.On("SendToContentService", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("test"))
+func TestService_GetRevision_ShouldReturnErrorIfRevisionIsNotFound(t *testing.T) {
+	mockDao := &mocks.NoteDaoHandler{}
+	mockDao.On("GetRevisions", mock.Anything, mock.Anything).Return([]models.Revision{}, nil)
 
 	service := NotesService{
 		Dao: mockDao,
-		Ext: mockExt,
 	}
 
-	err := service.SendToContentService(context.TODO(), "")
+	revision, err := service.GetRevision(context.TODO(), "user", "000000000000000000000001", "000000000000000000000002")
+	require.Nil(t, revision)
+	require.Equal(t, dao.ErrRevisionNotFound, err)
+}
+
+func TestService_RestoreRevision_ShouldReturnErrorOnDaoError(t *testing.T) {
+	mockDao := &mocks.NoteDaoHandler{}
+	mockDao.On("GetRevisions", mock.Anything, mock.Anything).Return(nil, errors.New("test"))
+
+	service := NotesService{
+		Dao: mockDao,
+	}
+
+	_, err := service.RestoreRevision(context.TODO(), "user", "token", "000000000000000000000001", "000000000000000000000002", 1)
 	require.NotNil(t, err)
 	require.Equal(t, "test", err.Error())
 }
 
-func TestService_SendToContentService_ShouldReturnNoErrorIfNoErrorOccurs(t *testing.T) {
+func TestService_RestoreRevision_ShouldUpdateNoteWithRevisionContent(t *testing.T) {
 	mockDao := &mocks.NoteDaoHandler{}
-	mockDao.On("GetNotes", mock.Anything, mock.Anything).Return([]models.Note{{}}, nil)
-
-	mockExt := &mocks.ExtAPIHandler{}
-	mockExt.On("SendToContentService", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockDao.On("GetRevisions", mock.Anything, mock.Anything).Return([]models.Revision{{Name: "old name", Text: "old text"}}, nil)
+	mockDao.On("UpdateNote", mock.Anything, mock.Anything, mock.MatchedBy(func(patch dao.NotePatch) bool {
+		return *patch.Name == "old name" && *patch.Text == "old text"
+	})).Return(nil)
+	mockDao.On("CreateRevision", mock.Anything, mock.Anything).Return(nil)
+	mockDao.On("GetNotes", mock.Anything, mock.Anything).Return(nil, nil)
 
 	service := NotesService{
 		Dao: mockDao,
-		Ext: mockExt,
 	}
 
-	require.Nil(t, service.SendToContentService(context.TODO(), ""))
+	version, err := service.RestoreRevision(context.TODO(), "user", "token", "000000000000000000000001", "000000000000000000000002", 1)
+	require.Nil(t, err)
+	require.Equal(t, int64(2), version)
+	mockDao.AssertExpectations(t)
 }
 
-func TestService_ValidateToken_ShouldReturnErrorOnExtHandlerError(t *testing.T) {
-	mockExt := &mocks.ExtAPIHandler{}
-	mockExt.On("ValidateToken", mock.Anything, mock.Anything).Return(errors.New("test"))
+func TestService_ValidateToken_ShouldReturnErrorOnValidatorError(t *testing.T) {
+	mockValidator := &mocks.Validator{}
+	mockValidator.On("ValidateToken", mock.Anything, mock.Anything).Return(nil, errors.New("test"))
 
 	service := NotesService{
-		Ext: mockExt,
+		Auth: mockValidator,
 	}
 
-	err := service.ValidateToken(context.TODO(), "test")
+	principal, err := service.ValidateToken(context.TODO(), "test")
+	require.Nil(t, principal)
 	require.NotNil(t, err)
 	require.Equal(t, "test", err.Error())
 }
 
 func TestService_ValidateToken_ShouldReturnNoErrorIfNoErrorOccurs(t *testing.T) {
-	mockExt := &mocks.ExtAPIHandler{}
-	mockExt.On("ValidateToken", mock.Anything, mock.Anything).Return(nil)
+	mockValidator := &mocks.Validator{}
+	mockValidator.On("ValidateToken", mock.Anything, mock.Anything).Return(&models.Principal{UserID: "user"}, nil)
 
 	service := NotesService{
-		Ext: mockExt,
+		Auth: mockValidator,
 	}
 
-	require.Nil(t, service.ValidateToken(context.TODO(), "test"))
+	principal, err := service.ValidateToken(context.TODO(), "test")
+	require.Nil(t, err)
+	require.Equal(t, "user", principal.UserID)
 }
 
-func TestService_SetToken_ShouldSetToken(t *testing.T) {
-	mockExt := &mocks.ExtAPIHandler{}
-	mockExt.On("SetToken", mock.Anything).Return()
+// restoringDao runs restore once it first lists notes deleted before a time, as a user restoring a note while the
+// trash is purged would.
+type restoringDao struct {
+	dao.NoteDaoHandler
+	restore func()
+}
 
-	service := NotesService{
-		Ext: mockExt,
+func (d *restoringDao) ListNotes(ctx context.Context, filter dao.NoteFilter, opts dao.ListOptions) ([]models.Note, error) {
+	notes, err := d.NoteDaoHandler.ListNotes(ctx, filter, opts)
+	if !filter.DeletedBefore.IsZero() && d.restore != nil {
+		d.restore()
+		d.restore = nil
 	}
-
-	service.SetToken("test")
+	return notes, err
 }
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"notes-api/pkg/dao"
+	"notes-api/pkg/export"
+	"notes-api/pkg/external"
+	"notes-api/pkg/models"
+)
+
+// queueSync queues an upload of an updated note if it is synced automatically. The upload is delivered SyncDelay
+// after the first update that queued it, and carries every update made in the meantime, since it renders the note
+// when it is delivered; later updates queue the next upload.
+func (svc *NotesService) queueSync(ctx context.Context, userID string, token string, id string) error {
+	notes, err := svc.GetNotes(ctx, userID, id)
+	if err != nil {
+		return err
+	} else if len(notes) == 0 || !notes[0].AutoSync {
+		return nil
+	}
+	note := notes[0]
+
+	// The note is synced in the format it was last delivered in.
+	format := export.FormatText
+	if note.Export != nil {
+		if _, ok := svc.Exporters.Get(note.Export.Format); ok {
+			format = note.Export.Format
+		}
+	}
+
+	sealed, err := svc.Tokens.Seal(token)
+	if err != nil {
+		return err
+	}
+
+	// A job that has been attempted may have rendered the note before this update, so only one that has not been
+	// attempted yet carries it, and the job is queued only if there is no such job.
+	attempts := 0
+	now := time.Now()
+	_, err = svc.SaveJobs.CreateSaveJobIfNone(ctx, dao.SaveJobFilter{
+		OwnerID:  userID,
+		NoteID:   &note.ID,
+		Status:   models.SaveJobPending,
+		AutoSync: true,
+		Attempts: &attempts,
+	}, models.SaveJob{
+		ID:            primitive.NewObjectID(),
+		OwnerID:       userID,
+		NoteID:        note.ID,
+		Format:        format,
+		Token:         sealed,
+		Status:        models.SaveJobPending,
+		AutoSync:      true,
+		CreatedTs:     now,
+		NextAttemptTs: now.Add(svc.SyncDelay),
+	})
+	return err
+}
+
+// renderSync renders the current version of the note of a sync job into the job, and returns the copy of the note
+// last delivered, which the job replaces.
+func (svc *NotesService) renderSync(ctx context.Context, job *models.SaveJob) (*models.NoteExport, error) {
+	notes, err := svc.GetNotes(ctx, job.OwnerID, job.NoteID.Hex())
+	if err != nil {
+		return nil, err
+	} else if len(notes) == 0 {
+		return nil, dao.ErrNotFound
+	}
+	note := notes[0]
+
+	exporter, ok := svc.Exporters.Get(job.Format)
+	if !ok {
+		exporter, _ = svc.Exporters.Get(export.FormatText)
+	}
+
+	content, err := exporter.Export(note)
+	if err != nil {
+		return nil, err
+	}
+
+	job.FileName = export.FileName(note.Name, exporter.Extension())
+	job.ContentType = exporter.ContentType()
+	job.Content = string(content)
+	job.ContentHash = contentHash(note)
+
+	return note.Export, nil
+}
+
+// replaceExport deletes the file that a delivered sync job replaced, so that the content service keeps one copy of
+// the note. If a later copy was recorded on the note before the job's, it is the job's own file that is deleted.
+func (w *SaveWorker) replaceExport(ctx context.Context, job *models.SaveJob, token string, previous *models.NoteExport, file *models.UploadedFile, recordErr error) {
+	var replaced string
+	switch {
+	case recordErr == nil && previous != nil && previous.FileID != file.ID:
+		replaced = previous.FileID
+	case errors.Is(recordErr, dao.ErrNotFound):
+		replaced = file.ID
+	}
+	if replaced == "" {
+		return
+	}
+
+	logger := logrus.WithContext(ctx).WithField("jobId", job.ID.Hex()).WithField("fileId", replaced)
+
+	err := w.Service.Ext.DeleteContentFile(ctx, token, replaced)
+	var statusErr *external.StatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound {
+		err = nil
+	}
+	if err != nil {
+		logger.WithError(err).Warn("Error deleting replaced file from content service")
+		return
+	}
+
+	logger.Info("Deleted replaced file from content service")
+}
@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"notes-api/pkg/dao"
+	"notes-api/pkg/export"
+	"notes-api/pkg/models"
+	"notes-api/pkg/testhelper/mocks"
+)
+
+func syncedNote(t *testing.T, mockExt *mocks.ExtAPIHandler) (*NotesService, string) {
+	memoryDao := dao.NewMemoryDao()
+	service := &NotesService{
+		Dao:       memoryDao,
+		SaveJobs:  memoryDao,
+		Tokens:    testSealer(t),
+		Ext:       mockExt,
+		Exporters: export.NewRegistry(),
+		SyncDelay: time.Minute,
+	}
+
+	autoSync := true
+	id, err := service.CreateNote(context.TODO(), "user", models.NoteRequest{Name: "note", AutoSync: &autoSync})
+	require.Nil(t, err)
+
+	return service, id
+}
+
+// syncJobs returns the sync jobs of a note, after making the pending ones due.
+func syncJobs(t *testing.T, service *NotesService, id string) []models.SaveJob {
+	noteID, err := primitive.ObjectIDFromHex(id)
+	require.Nil(t, err)
+
+	filter := dao.SaveJobFilter{NoteID: &noteID, AutoSync: true}
+	jobs, err := service.SaveJobs.GetSaveJobs(context.TODO(), filter)
+	require.Nil(t, err)
+
+	now := time.Now()
+	filter.Status = models.SaveJobPending
+	err = service.SaveJobs.UpdateSaveJob(context.TODO(), filter, dao.SaveJobPatch{NextAttemptTs: &now})
+	if err != dao.ErrSaveJobNotFound {
+		require.Nil(t, err)
+	}
+
+	return jobs
+}
+
+func TestService_UpdateNote_ShouldNotQueueSyncOfNoteThatIsNotSynced(t *testing.T) {
+	memoryDao := dao.NewMemoryDao()
+	service := &NotesService{Dao: memoryDao, SaveJobs: memoryDao, Tokens: testSealer(t), Exporters: export.NewRegistry()}
+
+	id, err := service.CreateNote(context.TODO(), "user", models.NoteRequest{Name: "note"})
+	require.Nil(t, err)
+	_, err = service.UpdateNote(context.TODO(), "user", "token", id, 1, models.NoteRequest{Name: "note", Text: "text"})
+	require.Nil(t, err)
+	require.Empty(t, syncJobs(t, service, id))
+
+	autoSync := true
+	_, err = service.UpdateNote(context.TODO(), "user", "token", id, 2, models.NoteRequest{Name: "note", AutoSync: &autoSync})
+	require.Nil(t, err)
+	require.Len(t, syncJobs(t, service, id), 1)
+}
+
+// Run with -race: updates of a synced note made at once must not queue a job each.
+func TestService_QueueSync_ShouldQueueOneJobForConcurrentUpdates(t *testing.T) {
+	service, id := syncedNote(t, &mocks.ExtAPIHandler{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			require.Nil(t, service.queueSync(context.TODO(), "user", "token", id))
+		}()
+	}
+	wg.Wait()
+
+	require.Len(t, syncJobs(t, service, id), 1)
+}
+
+func TestSaveWorker_ShouldUploadRapidUpdatesOnceAndReplacePreviousFile(t *testing.T) {
+	mockExt := &mocks.ExtAPIHandler{}
+	mockExt.On("SendToContentService", mock.Anything, "token", mock.Anything, mock.Anything).Return(&models.UploadedFile{ID: "first"}, nil).Once()
+	mockExt.On("SendToContentService", mock.Anything, "token", mock.Anything, mock.Anything).Return(&models.UploadedFile{ID: "second"}, nil).Once()
+	mockExt.On("DeleteContentFile", mock.Anything, "token", "first").Return(nil)
+
+	service, id := syncedNote(t, mockExt)
+	worker := saveWorker(service)
+
+	for version := int64(1); version <= 3; version++ {
+		_, err := service.UpdateNote(context.TODO(), "user", "token", id, version, models.NoteRequest{Name: "note", Text: "edit"})
+		require.Nil(t, err)
+	}
+
+	// Nothing is uploaded until SyncDelay has passed.
+	worker.deliverDue(context.TODO())
+	mockExt.AssertNotCalled(t, "SendToContentService", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+	require.Len(t, syncJobs(t, service, id), 1)
+	worker.deliverDue(context.TODO())
+	mockExt.AssertNumberOfCalls(t, "SendToContentService", 1)
+
+	notes, err := service.GetNotes(context.TODO(), "user", id)
+	require.Nil(t, err)
+	require.Equal(t, "first", notes[0].Export.FileID)
+	require.Equal(t, models.ExportStatusSynced, notes[0].ExportStatus)
+
+	_, err = service.UpdateNote(context.TODO(), "user", "token", id, 4, models.NoteRequest{Name: "note", Text: "later edit"})
+	require.Nil(t, err)
+	require.Len(t, syncJobs(t, service, id), 2)
+	worker.deliverDue(context.TODO())
+
+	notes, err = service.GetNotes(context.TODO(), "user", id)
+	require.Nil(t, err)
+	require.Equal(t, "second", notes[0].Export.FileID)
+	require.Equal(t, models.ExportStatusSynced, notes[0].ExportStatus)
+	mockExt.AssertCalled(t, "DeleteContentFile", mock.Anything, "token", "first")
+}
+
+func TestSaveWorker_ShouldDeleteSyncedFileIfLaterCopyIsRecorded(t *testing.T) {
+	mockExt := &mocks.ExtAPIHandler{}
+	mockExt.On("SendToContentService", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(&models.UploadedFile{ID: "synced"}, nil)
+	mockExt.On("DeleteContentFile", mock.Anything, "token", "synced").Return(nil)
+
+	service, id := syncedNote(t, mockExt)
+	_, err := service.UpdateNote(context.TODO(), "user", "token", id, 1, models.NoteRequest{Name: "note", Text: "edit"})
+	require.Nil(t, err)
+
+	noteID, err := primitive.ObjectIDFromHex(id)
+	require.Nil(t, err)
+	err = service.Dao.UpdateNote(context.TODO(), dao.NoteFilter{IDs: []primitive.ObjectID{noteID}}, dao.NotePatch{
+		Export: &models.NoteExport{FileID: "later", ExportedTs: time.Now().Add(time.Hour)},
+	})
+	require.Nil(t, err)
+
+	syncJobs(t, service, id)
+	saveWorker(service).deliverDue(context.TODO())
+
+	notes, err := service.GetNotes(context.TODO(), "user", id)
+	require.Nil(t, err)
+	require.Equal(t, "later", notes[0].Export.FileID)
+	mockExt.AssertExpectations(t)
+}
+
+func TestSaveWorker_ShouldFailSyncOfDeletedNote(t *testing.T) {
+	mockExt := &mocks.ExtAPIHandler{}
+
+	service, id := syncedNote(t, mockExt)
+	_, err := service.UpdateNote(context.TODO(), "user", "token", id, 1, models.NoteRequest{Name: "note", Text: "edit"})
+	require.Nil(t, err)
+	require.Nil(t, service.DeleteNote(context.TODO(), "user", id))
+
+	syncJobs(t, service, id)
+	saveWorker(service).deliverDue(context.TODO())
+
+	jobs := syncJobs(t, service, id)
+	require.Equal(t, models.SaveJobFailed, jobs[0].Status)
+	require.Equal(t, dao.ErrNotFound.Error(), jobs[0].LastError)
+	mockExt.AssertNotCalled(t, "SendToContentService", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
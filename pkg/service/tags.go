@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"notes-api/pkg/dao"
+	"notes-api/pkg/models"
+)
+
+// GetTags returns the user's tags with the number of notes outside the trash that have each, most used first.
+func (svc *NotesService) GetTags(ctx context.Context, userID string) ([]models.TagCount, error) {
+	counts, err := svc.Dao.CountTags(ctx, dao.NoteFilter{OwnerID: userID})
+	if err != nil {
+		return nil, err
+	} else if counts == nil {
+		counts = []models.TagCount{}
+	}
+
+	return counts, nil
+}
+
+// renameBatchSize is the number of notes RenameTag reads at a time.
+const renameBatchSize = 100
+
+// RenameTag replaces a tag with another on every one of the user's notes, including those in the trash, and returns
+// the number of notes changed. Renaming to a tag a note already has merges the two.
+//
+// Each note is renamed by a single update of its tags, which is an edit like any other: it takes the next version, so
+// that a write based on the tags from before the rename is rejected rather than putting the old tag back. A note that
+// is edited between being read and renamed is read again. If the rename is interrupted, some notes have the old tag
+// and others the new one, and running the rename again finishes it.
+func (svc *NotesService) RenameTag(ctx context.Context, userID string, oldName string, newName string) (int64, error) {
+	oldName, newName = strings.TrimSpace(oldName), strings.TrimSpace(newName)
+	if oldName == "" || newName == "" {
+		return 0, fmt.Errorf("%w: tag names must not be empty", ErrInvalidQuery)
+	} else if oldName == newName {
+		return 0, nil
+	}
+
+	filter := dao.NoteFilter{
+		OwnerID: userID,
+		AllTags: []string{oldName},
+		Trash:   dao.AnyTrash,
+	}
+
+	var renamed int64
+	for {
+		notes, err := svc.Dao.ListNotes(ctx, filter, dao.ListOptions{
+			Limit:  renameBatchSize,
+			Fields: []string{"tags", "version"},
+		})
+		if err != nil || len(notes) == 0 {
+			return renamed, err
+		}
+
+		now := time.Now()
+		for _, note := range notes {
+			version := note.Version
+			err := svc.Dao.UpdateNote(ctx, dao.NoteFilter{
+				IDs:     []primitive.ObjectID{note.ID},
+				OwnerID: userID,
+				Version: &version,
+				Trash:   dao.AnyTrash,
+			}, dao.NotePatch{
+				Tags:             renameTag(note.Tags, oldName, newName),
+				LastEditedTs:     &now,
+				IncrementVersion: true,
+			})
+			if errors.Is(err, dao.ErrNotFound) {
+				// The note has changed since it was read. If it still has the old tag, the next batch reads it again.
+				continue
+			} else if err != nil {
+				return renamed, err
+			}
+			renamed++
+		}
+	}
+}
+
+// renameTag returns the tags with oldName replaced by newName in place, or dropped if the tags already have newName.
+func renameTag(tags []string, oldName string, newName string) []string {
+	merged := false
+	for _, tag := range tags {
+		merged = merged || tag == newName
+	}
+
+	renamed := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if tag == oldName {
+			if merged {
+				continue
+			}
+			tag = newName
+		}
+		renamed = append(renamed, tag)
+	}
+	return renamed
+}
+
+// normalizeTags trims tags and drops empty and repeated ones, keeping the order they were given in. A nil slice stays
+// nil so that callers can tell "no tags given" from "no tags".
+func normalizeTags(tags []string) []string {
+	if tags == nil {
+		return nil
+	}
+
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+
+	return normalized
+}
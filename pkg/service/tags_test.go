@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"notes-api/pkg/dao"
+	"notes-api/pkg/models"
+	"notes-api/pkg/testhelper/mocks"
+)
+
+func TestService_GetTags_ShouldReturnErrorOnDaoError(t *testing.T) {
+	mockDao := &mocks.NoteDaoHandler{}
+	mockDao.On("CountTags", mock.Anything, mock.Anything).Return(nil, errors.New("test"))
+
+	service := NotesService{
+		Dao: mockDao,
+	}
+
+	tags, err := service.GetTags(context.TODO(), "user")
+	require.Nil(t, tags)
+	require.NotNil(t, err)
+	require.Equal(t, "test", err.Error())
+}
+
+func TestService_GetTags_ShouldCountTagsOfUserOutsideTrash(t *testing.T) {
+	mockDao := &mocks.NoteDaoHandler{}
+	mockDao.On("CountTags", mock.Anything, dao.NoteFilter{OwnerID: "user"}).Return(nil, nil)
+
+	service := NotesService{
+		Dao: mockDao,
+	}
+
+	tags, err := service.GetTags(context.TODO(), "user")
+	require.Nil(t, err)
+	require.Equal(t, []models.TagCount{}, tags)
+	mockDao.AssertExpectations(t)
+}
+
+func TestService_RenameTag_ShouldReturnErrorIfNameIsEmpty(t *testing.T) {
+	service := NotesService{}
+
+	_, err := service.RenameTag(context.TODO(), "user", "old", " ")
+	require.True(t, errors.Is(err, ErrInvalidQuery))
+}
+
+func TestService_RenameTag_ShouldDoNothingIfNamesAreEqual(t *testing.T) {
+	mockDao := &mocks.NoteDaoHandler{}
+
+	service := NotesService{
+		Dao: mockDao,
+	}
+
+	count, err := service.RenameTag(context.TODO(), "user", "same", "same")
+	require.Nil(t, err)
+	require.Zero(t, count)
+	mockDao.AssertNotCalled(t, "UpdateNotes", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestService_RenameTag_ShouldReturnErrorIfNotesCannotBeRead(t *testing.T) {
+	mockDao := &mocks.NoteDaoHandler{}
+	mockDao.On("ListNotes", mock.Anything, mock.Anything, mock.Anything).Return(nil, errors.New("test"))
+
+	service := NotesService{
+		Dao: mockDao,
+	}
+
+	_, err := service.RenameTag(context.TODO(), "user", "old", "new")
+	require.NotNil(t, err)
+	require.Equal(t, "test", err.Error())
+	mockDao.AssertNotCalled(t, "UpdateNote", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestService_RenameTag_ShouldReplaceTagOfEachNoteAsNewVersion(t *testing.T) {
+	filter := dao.NoteFilter{
+		OwnerID: "user",
+		AllTags: []string{"old"},
+		Trash:   dao.AnyTrash,
+	}
+	first := models.Note{ID: primitive.NewObjectID(), Version: 3, Tags: []string{"a", "old", "b"}}
+	second := models.Note{ID: primitive.NewObjectID(), Version: 1, Tags: []string{"old", "new"}}
+
+	renamedTo := func(note models.Note, tags []string) (interface{}, interface{}) {
+		version := note.Version
+		return dao.NoteFilter{
+			IDs:     []primitive.ObjectID{note.ID},
+			OwnerID: "user",
+			Version: &version,
+			Trash:   dao.AnyTrash,
+		}, mock.MatchedBy(func(patch dao.NotePatch) bool {
+			return reflect.DeepEqual(tags, patch.Tags) && patch.IncrementVersion && patch.LastEditedTs != nil
+		})
+	}
+
+	mockDao := &mocks.NoteDaoHandler{}
+	mockDao.On("ListNotes", mock.Anything, filter, mock.Anything).Return([]models.Note{first, second}, nil).Once()
+	mockDao.On("ListNotes", mock.Anything, filter, mock.Anything).Return(nil, nil).Once()
+	firstFilter, firstPatch := renamedTo(first, []string{"a", "new", "b"})
+	mockDao.On("UpdateNote", mock.Anything, firstFilter, firstPatch).Return(nil).Once()
+	secondFilter, secondPatch := renamedTo(second, []string{"new"})
+	mockDao.On("UpdateNote", mock.Anything, secondFilter, secondPatch).Return(nil).Once()
+
+	service := NotesService{
+		Dao: mockDao,
+	}
+
+	count, err := service.RenameTag(context.TODO(), "user", " old ", "new")
+	require.Nil(t, err)
+	require.Equal(t, int64(2), count)
+	mockDao.AssertExpectations(t)
+}
+
+func TestService_RenameTag_ShouldReadNoteAgainIfItChangedSinceItWasRead(t *testing.T) {
+	stale := models.Note{ID: primitive.NewObjectID(), Version: 1, Tags: []string{"old"}}
+	current := models.Note{ID: stale.ID, Version: 2, Tags: []string{"old", "other"}}
+
+	mockDao := &mocks.NoteDaoHandler{}
+	mockDao.On("ListNotes", mock.Anything, mock.Anything, mock.Anything).Return([]models.Note{stale}, nil).Once()
+	mockDao.On("ListNotes", mock.Anything, mock.Anything, mock.Anything).Return([]models.Note{current}, nil).Once()
+	mockDao.On("ListNotes", mock.Anything, mock.Anything, mock.Anything).Return(nil, nil).Once()
+	mockDao.On("UpdateNote", mock.Anything, mock.Anything, mock.Anything).Return(dao.ErrNotFound).Once()
+	mockDao.On("UpdateNote", mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
+
+	service := NotesService{
+		Dao: mockDao,
+	}
+
+	count, err := service.RenameTag(context.TODO(), "user", "old", "new")
+	require.Nil(t, err)
+	require.Equal(t, int64(1), count)
+	require.Equal(t, []string{"new", "other"}, mockDao.Calls[3].Arguments.Get(2).(dao.NotePatch).Tags)
+}
+
+func TestService_NormalizeTags_ShouldTrimAndDropEmptyAndRepeatedTags(t *testing.T) {
+	require.Equal(t, []string{"b", "a"}, normalizeTags([]string{" b", "a", "", "b ", "  "}))
+	require.Equal(t, []string{}, normalizeTags([]string{}))
+	require.Nil(t, normalizeTags(nil))
+}
@@ -5,6 +5,7 @@ package mocks
 import (
 	bytes "bytes"
 	context "context"
+	models "notes-api/pkg/models"
 
 	mock "github.com/stretchr/testify/mock"
 )
@@ -14,13 +15,13 @@ type ExtAPIHandler struct {
 	mock.Mock
 }
 
-// SendToContentService provides a mock function with given fields: ctx, body, contentType
-func (_m *ExtAPIHandler) SendToContentService(ctx context.Context, body bytes.Buffer, contentType string) error {
-	ret := _m.Called(ctx, body, contentType)
+// DeleteContentFile provides a mock function with given fields: ctx, token, id
+func (_m *ExtAPIHandler) DeleteContentFile(ctx context.Context, token string, id string) error {
+	ret := _m.Called(ctx, token, id)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, bytes.Buffer, string) error); ok {
-		r0 = rf(ctx, body, contentType)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, token, id)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -28,21 +29,94 @@ func (_m *ExtAPIHandler) SendToContentService(ctx context.Context, body bytes.Bu
 	return r0
 }
 
-// SetToken provides a mock function with given fields: token
-func (_m *ExtAPIHandler) SetToken(token string) {
-	_m.Called(token)
+// DownloadContentFile provides a mock function with given fields: ctx, token, id
+func (_m *ExtAPIHandler) DownloadContentFile(ctx context.Context, token string, id string) ([]byte, error) {
+	ret := _m.Called(ctx, token, id)
+
+	var r0 []byte
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) []byte); ok {
+		r0 = rf(ctx, token, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]byte)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, token, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListContentFiles provides a mock function with given fields: ctx, token
+func (_m *ExtAPIHandler) ListContentFiles(ctx context.Context, token string) ([]models.ContentFile, error) {
+	ret := _m.Called(ctx, token)
+
+	var r0 []models.ContentFile
+	if rf, ok := ret.Get(0).(func(context.Context, string) []models.ContentFile); ok {
+		r0 = rf(ctx, token)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.ContentFile)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, token)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SendToContentService provides a mock function with given fields: ctx, token, body, contentType
+func (_m *ExtAPIHandler) SendToContentService(ctx context.Context, token string, body bytes.Buffer, contentType string) (*models.UploadedFile, error) {
+	ret := _m.Called(ctx, token, body, contentType)
+
+	var r0 *models.UploadedFile
+	if rf, ok := ret.Get(0).(func(context.Context, string, bytes.Buffer, string) *models.UploadedFile); ok {
+		r0 = rf(ctx, token, body, contentType)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.UploadedFile)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, bytes.Buffer, string) error); ok {
+		r1 = rf(ctx, token, body, contentType)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
 // ValidateToken provides a mock function with given fields: ctx, token
-func (_m *ExtAPIHandler) ValidateToken(ctx context.Context, token string) error {
+func (_m *ExtAPIHandler) ValidateToken(ctx context.Context, token string) (*models.Principal, error) {
 	ret := _m.Called(ctx, token)
 
-	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+	var r0 *models.Principal
+	if rf, ok := ret.Get(0).(func(context.Context, string) *models.Principal); ok {
 		r0 = rf(ctx, token)
 	} else {
-		r0 = ret.Error(0)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Principal)
+		}
 	}
 
-	return r0
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, token)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
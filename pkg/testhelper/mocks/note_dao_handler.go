@@ -4,12 +4,10 @@ package mocks
 
 import (
 	context "context"
-
-	mock "github.com/stretchr/testify/mock"
-
+	dao "notes-api/pkg/dao"
 	models "notes-api/pkg/models"
 
-	primitive "go.mongodb.org/mongo-driver/bson/primitive"
+	mock "github.com/stretchr/testify/mock"
 )
 
 // NoteDaoHandler is an autogenerated mock type for the NoteDaoHandler type
@@ -17,6 +15,29 @@ type NoteDaoHandler struct {
 	mock.Mock
 }
 
+// CountTags provides a mock function with given fields: ctx, filter
+func (_m *NoteDaoHandler) CountTags(ctx context.Context, filter dao.NoteFilter) ([]models.TagCount, error) {
+	ret := _m.Called(ctx, filter)
+
+	var r0 []models.TagCount
+	if rf, ok := ret.Get(0).(func(context.Context, dao.NoteFilter) []models.TagCount); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.TagCount)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, dao.NoteFilter) error); ok {
+		r1 = rf(ctx, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // CreateNote provides a mock function with given fields: ctx, note
 func (_m *NoteDaoHandler) CreateNote(ctx context.Context, note models.Note) error {
 	ret := _m.Called(ctx, note)
@@ -31,12 +52,61 @@ func (_m *NoteDaoHandler) CreateNote(ctx context.Context, note models.Note) erro
 	return r0
 }
 
+// CreateRevision provides a mock function with given fields: ctx, revision
+func (_m *NoteDaoHandler) CreateRevision(ctx context.Context, revision models.Revision) error {
+	ret := _m.Called(ctx, revision)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.Revision) error); ok {
+		r0 = rf(ctx, revision)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // DeleteNote provides a mock function with given fields: ctx, filter
-func (_m *NoteDaoHandler) DeleteNote(ctx context.Context, filter map[string]interface{}) error {
+func (_m *NoteDaoHandler) DeleteNote(ctx context.Context, filter dao.NoteFilter) error {
 	ret := _m.Called(ctx, filter)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, map[string]interface{}) error); ok {
+	if rf, ok := ret.Get(0).(func(context.Context, dao.NoteFilter) error); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteNotes provides a mock function with given fields: ctx, filter
+func (_m *NoteDaoHandler) DeleteNotes(ctx context.Context, filter dao.NoteFilter) (int64, error) {
+	ret := _m.Called(ctx, filter)
+
+	var r0 int64
+	if rf, ok := ret.Get(0).(func(context.Context, dao.NoteFilter) int64); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, dao.NoteFilter) error); ok {
+		r1 = rf(ctx, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DeleteRevisions provides a mock function with given fields: ctx, filter
+func (_m *NoteDaoHandler) DeleteRevisions(ctx context.Context, filter dao.RevisionFilter) error {
+	ret := _m.Called(ctx, filter)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, dao.RevisionFilter) error); ok {
 		r0 = rf(ctx, filter)
 	} else {
 		r0 = ret.Error(0)
@@ -46,11 +116,11 @@ func (_m *NoteDaoHandler) DeleteNote(ctx context.Context, filter map[string]inte
 }
 
 // GetNotes provides a mock function with given fields: ctx, filter
-func (_m *NoteDaoHandler) GetNotes(ctx context.Context, filter map[string]interface{}) ([]models.Note, error) {
+func (_m *NoteDaoHandler) GetNotes(ctx context.Context, filter dao.NoteFilter) ([]models.Note, error) {
 	ret := _m.Called(ctx, filter)
 
 	var r0 []models.Note
-	if rf, ok := ret.Get(0).(func(context.Context, map[string]interface{}) []models.Note); ok {
+	if rf, ok := ret.Get(0).(func(context.Context, dao.NoteFilter) []models.Note); ok {
 		r0 = rf(ctx, filter)
 	} else {
 		if ret.Get(0) != nil {
@@ -59,7 +129,7 @@ func (_m *NoteDaoHandler) GetNotes(ctx context.Context, filter map[string]interf
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(context.Context, map[string]interface{}) error); ok {
+	if rf, ok := ret.Get(1).(func(context.Context, dao.NoteFilter) error); ok {
 		r1 = rf(ctx, filter)
 	} else {
 		r1 = ret.Error(1)
@@ -68,6 +138,52 @@ func (_m *NoteDaoHandler) GetNotes(ctx context.Context, filter map[string]interf
 	return r0, r1
 }
 
+// GetRevisions provides a mock function with given fields: ctx, filter
+func (_m *NoteDaoHandler) GetRevisions(ctx context.Context, filter dao.RevisionFilter) ([]models.Revision, error) {
+	ret := _m.Called(ctx, filter)
+
+	var r0 []models.Revision
+	if rf, ok := ret.Get(0).(func(context.Context, dao.RevisionFilter) []models.Revision); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Revision)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, dao.RevisionFilter) error); ok {
+		r1 = rf(ctx, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListNotes provides a mock function with given fields: ctx, filter, opts
+func (_m *NoteDaoHandler) ListNotes(ctx context.Context, filter dao.NoteFilter, opts dao.ListOptions) ([]models.Note, error) {
+	ret := _m.Called(ctx, filter, opts)
+
+	var r0 []models.Note
+	if rf, ok := ret.Get(0).(func(context.Context, dao.NoteFilter, dao.ListOptions) []models.Note); ok {
+		r0 = rf(ctx, filter, opts)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Note)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, dao.NoteFilter, dao.ListOptions) error); ok {
+		r1 = rf(ctx, filter, opts)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Ping provides a mock function with given fields: ctx
 func (_m *NoteDaoHandler) Ping(ctx context.Context) error {
 	ret := _m.Called(ctx)
@@ -82,16 +198,60 @@ func (_m *NoteDaoHandler) Ping(ctx context.Context) error {
 	return r0
 }
 
-// UpdateNote provides a mock function with given fields: ctx, filter, updates
-func (_m *NoteDaoHandler) UpdateNote(ctx context.Context, filter map[string]interface{}, updates primitive.M) error {
-	ret := _m.Called(ctx, filter, updates)
+// SearchNotes provides a mock function with given fields: ctx, filter, search, opts
+func (_m *NoteDaoHandler) SearchNotes(ctx context.Context, filter dao.NoteFilter, search string, opts dao.ListOptions) ([]models.SearchResult, error) {
+	ret := _m.Called(ctx, filter, search, opts)
+
+	var r0 []models.SearchResult
+	if rf, ok := ret.Get(0).(func(context.Context, dao.NoteFilter, string, dao.ListOptions) []models.SearchResult); ok {
+		r0 = rf(ctx, filter, search, opts)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.SearchResult)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, dao.NoteFilter, string, dao.ListOptions) error); ok {
+		r1 = rf(ctx, filter, search, opts)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateNote provides a mock function with given fields: ctx, filter, patch
+func (_m *NoteDaoHandler) UpdateNote(ctx context.Context, filter dao.NoteFilter, patch dao.NotePatch) error {
+	ret := _m.Called(ctx, filter, patch)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, map[string]interface{}, primitive.M) error); ok {
-		r0 = rf(ctx, filter, updates)
+	if rf, ok := ret.Get(0).(func(context.Context, dao.NoteFilter, dao.NotePatch) error); ok {
+		r0 = rf(ctx, filter, patch)
 	} else {
 		r0 = ret.Error(0)
 	}
 
 	return r0
 }
+
+// UpdateNotes provides a mock function with given fields: ctx, filter, patch
+func (_m *NoteDaoHandler) UpdateNotes(ctx context.Context, filter dao.NoteFilter, patch dao.NotePatch) (int64, error) {
+	ret := _m.Called(ctx, filter, patch)
+
+	var r0 int64
+	if rf, ok := ret.Get(0).(func(context.Context, dao.NoteFilter, dao.NotePatch) int64); ok {
+		r0 = rf(ctx, filter, patch)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, dao.NoteFilter, dao.NotePatch) error); ok {
+		r1 = rf(ctx, filter, patch)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
@@ -14,20 +14,20 @@ type NoteServiceHandler struct {
 	mock.Mock
 }
 
-// CreateNote provides a mock function with given fields: ctx, noteRequest
-func (_m *NoteServiceHandler) CreateNote(ctx context.Context, noteRequest models.NoteRequest) (string, error) {
-	ret := _m.Called(ctx, noteRequest)
+// CreateNote provides a mock function with given fields: ctx, userID, noteRequest
+func (_m *NoteServiceHandler) CreateNote(ctx context.Context, userID string, noteRequest models.NoteRequest) (string, error) {
+	ret := _m.Called(ctx, userID, noteRequest)
 
 	var r0 string
-	if rf, ok := ret.Get(0).(func(context.Context, models.NoteRequest) string); ok {
-		r0 = rf(ctx, noteRequest)
+	if rf, ok := ret.Get(0).(func(context.Context, string, models.NoteRequest) string); ok {
+		r0 = rf(ctx, userID, noteRequest)
 	} else {
 		r0 = ret.Get(0).(string)
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(context.Context, models.NoteRequest) error); ok {
-		r1 = rf(ctx, noteRequest)
+	if rf, ok := ret.Get(1).(func(context.Context, string, models.NoteRequest) error); ok {
+		r1 = rf(ctx, userID, noteRequest)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -35,13 +35,48 @@ func (_m *NoteServiceHandler) CreateNote(ctx context.Context, noteRequest models
 	return r0, r1
 }
 
-// DeleteNote provides a mock function with given fields: ctx, id
-func (_m *NoteServiceHandler) DeleteNote(ctx context.Context, id string) error {
-	ret := _m.Called(ctx, id)
+// CreateNotebook provides a mock function with given fields: ctx, userID, notebookRequest
+func (_m *NoteServiceHandler) CreateNotebook(ctx context.Context, userID string, notebookRequest models.NotebookRequest) (string, error) {
+	ret := _m.Called(ctx, userID, notebookRequest)
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(context.Context, string, models.NotebookRequest) string); ok {
+		r0 = rf(ctx, userID, notebookRequest)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, models.NotebookRequest) error); ok {
+		r1 = rf(ctx, userID, notebookRequest)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DeleteNote provides a mock function with given fields: ctx, userID, id
+func (_m *NoteServiceHandler) DeleteNote(ctx context.Context, userID string, id string) error {
+	ret := _m.Called(ctx, userID, id)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, userID, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteNotebook provides a mock function with given fields: ctx, userID, id, cascade
+func (_m *NoteServiceHandler) DeleteNotebook(ctx context.Context, userID string, id string, cascade bool) error {
+	ret := _m.Called(ctx, userID, id, cascade)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
-		r0 = rf(ctx, id)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, bool) error); ok {
+		r0 = rf(ctx, userID, id, cascade)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -49,13 +84,174 @@ func (_m *NoteServiceHandler) DeleteNote(ctx context.Context, id string) error {
 	return r0
 }
 
-// GetNotes provides a mock function with given fields: ctx, id
-func (_m *NoteServiceHandler) GetNotes(ctx context.Context, id string) ([]models.Note, error) {
-	ret := _m.Called(ctx, id)
+// GetNotebook provides a mock function with given fields: ctx, userID, id
+func (_m *NoteServiceHandler) GetNotebook(ctx context.Context, userID string, id string) (*models.Notebook, error) {
+	ret := _m.Called(ctx, userID, id)
+
+	var r0 *models.Notebook
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *models.Notebook); ok {
+		r0 = rf(ctx, userID, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Notebook)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, userID, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetNotebooks provides a mock function with given fields: ctx, userID
+func (_m *NoteServiceHandler) GetNotebooks(ctx context.Context, userID string) ([]models.Notebook, error) {
+	ret := _m.Called(ctx, userID)
+
+	var r0 []models.Notebook
+	if rf, ok := ret.Get(0).(func(context.Context, string) []models.Notebook); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Notebook)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetNotes provides a mock function with given fields: ctx, userID, id
+func (_m *NoteServiceHandler) GetNotes(ctx context.Context, userID string, id string) ([]models.Note, error) {
+	ret := _m.Called(ctx, userID, id)
+
+	var r0 []models.Note
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) []models.Note); ok {
+		r0 = rf(ctx, userID, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Note)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, userID, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetRevision provides a mock function with given fields: ctx, userID, noteID, revisionID
+func (_m *NoteServiceHandler) GetRevision(ctx context.Context, userID string, noteID string, revisionID string) (*models.Revision, error) {
+	ret := _m.Called(ctx, userID, noteID, revisionID)
+
+	var r0 *models.Revision
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) *models.Revision); ok {
+		r0 = rf(ctx, userID, noteID, revisionID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Revision)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string) error); ok {
+		r1 = rf(ctx, userID, noteID, revisionID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetRevisions provides a mock function with given fields: ctx, userID, noteID
+func (_m *NoteServiceHandler) GetRevisions(ctx context.Context, userID string, noteID string) ([]models.Revision, error) {
+	ret := _m.Called(ctx, userID, noteID)
+
+	var r0 []models.Revision
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) []models.Revision); ok {
+		r0 = rf(ctx, userID, noteID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Revision)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, userID, noteID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetSaveJob provides a mock function with given fields: ctx, userID, id
+func (_m *NoteServiceHandler) GetSaveJob(ctx context.Context, userID string, id string) (*models.SaveJob, error) {
+	ret := _m.Called(ctx, userID, id)
+
+	var r0 *models.SaveJob
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *models.SaveJob); ok {
+		r0 = rf(ctx, userID, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.SaveJob)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, userID, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetTags provides a mock function with given fields: ctx, userID
+func (_m *NoteServiceHandler) GetTags(ctx context.Context, userID string) ([]models.TagCount, error) {
+	ret := _m.Called(ctx, userID)
+
+	var r0 []models.TagCount
+	if rf, ok := ret.Get(0).(func(context.Context, string) []models.TagCount); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.TagCount)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetTrash provides a mock function with given fields: ctx, userID
+func (_m *NoteServiceHandler) GetTrash(ctx context.Context, userID string) ([]models.Note, error) {
+	ret := _m.Called(ctx, userID)
 
 	var r0 []models.Note
 	if rf, ok := ret.Get(0).(func(context.Context, string) []models.Note); ok {
-		r0 = rf(ctx, id)
+		r0 = rf(ctx, userID)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]models.Note)
@@ -64,7 +260,7 @@ func (_m *NoteServiceHandler) GetNotes(ctx context.Context, id string) ([]models
 
 	var r1 error
 	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
-		r1 = rf(ctx, id)
+		r1 = rf(ctx, userID)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -72,6 +268,103 @@ func (_m *NoteServiceHandler) GetNotes(ctx context.Context, id string) ([]models
 	return r0, r1
 }
 
+// ImportNotes provides a mock function with given fields: ctx, userID, token, request
+func (_m *NoteServiceHandler) ImportNotes(ctx context.Context, userID string, token string, request models.ImportRequest) (*models.ImportResult, error) {
+	ret := _m.Called(ctx, userID, token, request)
+
+	var r0 *models.ImportResult
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, models.ImportRequest) *models.ImportResult); ok {
+		r0 = rf(ctx, userID, token, request)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.ImportResult)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, models.ImportRequest) error); ok {
+		r1 = rf(ctx, userID, token, request)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListContentFiles provides a mock function with given fields: ctx, token
+func (_m *NoteServiceHandler) ListContentFiles(ctx context.Context, token string) ([]models.ContentFile, error) {
+	ret := _m.Called(ctx, token)
+
+	var r0 []models.ContentFile
+	if rf, ok := ret.Get(0).(func(context.Context, string) []models.ContentFile); ok {
+		r0 = rf(ctx, token)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.ContentFile)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, token)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListNotes provides a mock function with given fields: ctx, userID, query
+func (_m *NoteServiceHandler) ListNotes(ctx context.Context, userID string, query models.NoteQuery) (*models.NotePage, error) {
+	ret := _m.Called(ctx, userID, query)
+
+	var r0 *models.NotePage
+	if rf, ok := ret.Get(0).(func(context.Context, string, models.NoteQuery) *models.NotePage); ok {
+		r0 = rf(ctx, userID, query)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.NotePage)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, models.NoteQuery) error); ok {
+		r1 = rf(ctx, userID, query)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MoveNote provides a mock function with given fields: ctx, userID, id, notebookID
+func (_m *NoteServiceHandler) MoveNote(ctx context.Context, userID string, id string, notebookID string) error {
+	ret := _m.Called(ctx, userID, id, notebookID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) error); ok {
+		r0 = rf(ctx, userID, id, notebookID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MoveNotebook provides a mock function with given fields: ctx, userID, id, parentID
+func (_m *NoteServiceHandler) MoveNotebook(ctx context.Context, userID string, id string, parentID string) error {
+	ret := _m.Called(ctx, userID, id, parentID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) error); ok {
+		r0 = rf(ctx, userID, id, parentID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Ping provides a mock function with given fields: ctx
 func (_m *NoteServiceHandler) Ping(ctx context.Context) error {
 	ret := _m.Called(ctx)
@@ -86,13 +379,13 @@ func (_m *NoteServiceHandler) Ping(ctx context.Context) error {
 	return r0
 }
 
-// SendToContentService provides a mock function with given fields: ctx, id
-func (_m *NoteServiceHandler) SendToContentService(ctx context.Context, id string) error {
-	ret := _m.Called(ctx, id)
+// PurgeNote provides a mock function with given fields: ctx, userID, id
+func (_m *NoteServiceHandler) PurgeNote(ctx context.Context, userID string, id string) error {
+	ret := _m.Called(ctx, userID, id)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
-		r0 = rf(ctx, id)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, userID, id)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -100,18 +393,34 @@ func (_m *NoteServiceHandler) SendToContentService(ctx context.Context, id strin
 	return r0
 }
 
-// SetToken provides a mock function with given fields: token
-func (_m *NoteServiceHandler) SetToken(token string) {
-	_m.Called(token)
+// RenameTag provides a mock function with given fields: ctx, userID, oldName, newName
+func (_m *NoteServiceHandler) RenameTag(ctx context.Context, userID string, oldName string, newName string) (int64, error) {
+	ret := _m.Called(ctx, userID, oldName, newName)
+
+	var r0 int64
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) int64); ok {
+		r0 = rf(ctx, userID, oldName, newName)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string) error); ok {
+		r1 = rf(ctx, userID, oldName, newName)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
-// UpdateNote provides a mock function with given fields: ctx, id, noteRequest
-func (_m *NoteServiceHandler) UpdateNote(ctx context.Context, id string, noteRequest models.NoteRequest) error {
-	ret := _m.Called(ctx, id, noteRequest)
+// RestoreFromTrash provides a mock function with given fields: ctx, userID, id
+func (_m *NoteServiceHandler) RestoreFromTrash(ctx context.Context, userID string, id string) error {
+	ret := _m.Called(ctx, userID, id)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, models.NoteRequest) error); ok {
-		r0 = rf(ctx, id, noteRequest)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, userID, id)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -119,16 +428,127 @@ func (_m *NoteServiceHandler) UpdateNote(ctx context.Context, id string, noteReq
 	return r0
 }
 
-// ValidateToken provides a mock function with given fields: ctx, token
-func (_m *NoteServiceHandler) ValidateToken(ctx context.Context, token string) error {
-	ret := _m.Called(ctx, token)
+// RestoreRevision provides a mock function with given fields: ctx, userID, token, noteID, revisionID, version
+func (_m *NoteServiceHandler) RestoreRevision(ctx context.Context, userID string, token string, noteID string, revisionID string, version int64) (int64, error) {
+	ret := _m.Called(ctx, userID, token, noteID, revisionID, version)
+
+	var r0 int64
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, string, int64) int64); ok {
+		r0 = rf(ctx, userID, token, noteID, revisionID, version)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string, string, int64) error); ok {
+		r1 = rf(ctx, userID, token, noteID, revisionID, version)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SearchNotes provides a mock function with given fields: ctx, userID, query
+func (_m *NoteServiceHandler) SearchNotes(ctx context.Context, userID string, query models.SearchQuery) (*models.SearchPage, error) {
+	ret := _m.Called(ctx, userID, query)
+
+	var r0 *models.SearchPage
+	if rf, ok := ret.Get(0).(func(context.Context, string, models.SearchQuery) *models.SearchPage); ok {
+		r0 = rf(ctx, userID, query)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.SearchPage)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, models.SearchQuery) error); ok {
+		r1 = rf(ctx, userID, query)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SendToContentService provides a mock function with given fields: ctx, userID, token, id, format
+func (_m *NoteServiceHandler) SendToContentService(ctx context.Context, userID string, token string, id string, format string) (*models.SaveJob, error) {
+	ret := _m.Called(ctx, userID, token, id, format)
+
+	var r0 *models.SaveJob
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, string) *models.SaveJob); ok {
+		r0 = rf(ctx, userID, token, id, format)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.SaveJob)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string, string) error); ok {
+		r1 = rf(ctx, userID, token, id, format)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateNote provides a mock function with given fields: ctx, userID, token, id, version, noteRequest
+func (_m *NoteServiceHandler) UpdateNote(ctx context.Context, userID string, token string, id string, version int64, noteRequest models.NoteRequest) (int64, error) {
+	ret := _m.Called(ctx, userID, token, id, version, noteRequest)
+
+	var r0 int64
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, int64, models.NoteRequest) int64); ok {
+		r0 = rf(ctx, userID, token, id, version, noteRequest)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string, int64, models.NoteRequest) error); ok {
+		r1 = rf(ctx, userID, token, id, version, noteRequest)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateNotebook provides a mock function with given fields: ctx, userID, id, notebookRequest
+func (_m *NoteServiceHandler) UpdateNotebook(ctx context.Context, userID string, id string, notebookRequest models.NotebookRequest) error {
+	ret := _m.Called(ctx, userID, id, notebookRequest)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
-		r0 = rf(ctx, token)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, models.NotebookRequest) error); ok {
+		r0 = rf(ctx, userID, id, notebookRequest)
 	} else {
 		r0 = ret.Error(0)
 	}
 
 	return r0
 }
+
+// ValidateToken provides a mock function with given fields: ctx, token
+func (_m *NoteServiceHandler) ValidateToken(ctx context.Context, token string) (*models.Principal, error) {
+	ret := _m.Called(ctx, token)
+
+	var r0 *models.Principal
+	if rf, ok := ret.Get(0).(func(context.Context, string) *models.Principal); ok {
+		r0 = rf(ctx, token)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Principal)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, token)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
@@ -0,0 +1,161 @@
+package tracing
+
+import (
+	"context"
+	"time"
+
+	"notes-api/pkg/dao"
+	"notes-api/pkg/models"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// startDao starts the span of a storage operation, and returns the function that ends it once the operation returns
+// its error. Finding nothing to read or change is an answer rather than a failure, so it leaves the span unmarked.
+func startDao(ctx context.Context, name string) (context.Context, func(err error) error) {
+	ctx, span := Tracer().Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient))
+	return ctx, func(err error) error {
+		if !dao.IsNotFound(err) {
+			recordError(span, err)
+		}
+		span.End()
+		return err
+	}
+}
+
+// NoteDao is a dao.NoteDaoHandler that wraps every operation of the one it wraps in a span.
+type NoteDao struct {
+	Next dao.NoteDaoHandler
+}
+
+func (d *NoteDao) Ping(ctx context.Context) error {
+	ctx, done := startDao(ctx, "NoteDao.Ping")
+	return done(d.Next.Ping(ctx))
+}
+
+func (d *NoteDao) GetNotes(ctx context.Context, filter dao.NoteFilter) ([]models.Note, error) {
+	ctx, done := startDao(ctx, "NoteDao.GetNotes")
+	notes, err := d.Next.GetNotes(ctx, filter)
+	return notes, done(err)
+}
+
+func (d *NoteDao) ListNotes(ctx context.Context, filter dao.NoteFilter, opts dao.ListOptions) ([]models.Note, error) {
+	ctx, done := startDao(ctx, "NoteDao.ListNotes")
+	notes, err := d.Next.ListNotes(ctx, filter, opts)
+	return notes, done(err)
+}
+
+func (d *NoteDao) SearchNotes(ctx context.Context, filter dao.NoteFilter, search string, opts dao.ListOptions) ([]models.SearchResult, error) {
+	ctx, done := startDao(ctx, "NoteDao.SearchNotes")
+	results, err := d.Next.SearchNotes(ctx, filter, search, opts)
+	return results, done(err)
+}
+
+func (d *NoteDao) UpdateNote(ctx context.Context, filter dao.NoteFilter, patch dao.NotePatch) error {
+	ctx, done := startDao(ctx, "NoteDao.UpdateNote")
+	return done(d.Next.UpdateNote(ctx, filter, patch))
+}
+
+func (d *NoteDao) UpdateNotes(ctx context.Context, filter dao.NoteFilter, patch dao.NotePatch) (int64, error) {
+	ctx, done := startDao(ctx, "NoteDao.UpdateNotes")
+	changed, err := d.Next.UpdateNotes(ctx, filter, patch)
+	return changed, done(err)
+}
+
+func (d *NoteDao) DeleteNote(ctx context.Context, filter dao.NoteFilter) error {
+	ctx, done := startDao(ctx, "NoteDao.DeleteNote")
+	return done(d.Next.DeleteNote(ctx, filter))
+}
+
+func (d *NoteDao) DeleteNotes(ctx context.Context, filter dao.NoteFilter) (int64, error) {
+	ctx, done := startDao(ctx, "NoteDao.DeleteNotes")
+	deleted, err := d.Next.DeleteNotes(ctx, filter)
+	return deleted, done(err)
+}
+
+func (d *NoteDao) CreateNote(ctx context.Context, note models.Note) error {
+	ctx, done := startDao(ctx, "NoteDao.CreateNote")
+	return done(d.Next.CreateNote(ctx, note))
+}
+
+func (d *NoteDao) CountTags(ctx context.Context, filter dao.NoteFilter) ([]models.TagCount, error) {
+	ctx, done := startDao(ctx, "NoteDao.CountTags")
+	tags, err := d.Next.CountTags(ctx, filter)
+	return tags, done(err)
+}
+
+func (d *NoteDao) GetRevisions(ctx context.Context, filter dao.RevisionFilter) ([]models.Revision, error) {
+	ctx, done := startDao(ctx, "NoteDao.GetRevisions")
+	revisions, err := d.Next.GetRevisions(ctx, filter)
+	return revisions, done(err)
+}
+
+func (d *NoteDao) CreateRevision(ctx context.Context, revision models.Revision) error {
+	ctx, done := startDao(ctx, "NoteDao.CreateRevision")
+	return done(d.Next.CreateRevision(ctx, revision))
+}
+
+func (d *NoteDao) DeleteRevisions(ctx context.Context, filter dao.RevisionFilter) error {
+	ctx, done := startDao(ctx, "NoteDao.DeleteRevisions")
+	return done(d.Next.DeleteRevisions(ctx, filter))
+}
+
+// NotebookDao is the dao.NotebookDaoHandler equivalent of NoteDao.
+type NotebookDao struct {
+	Next dao.NotebookDaoHandler
+}
+
+func (d *NotebookDao) GetNotebooks(ctx context.Context, filter dao.NotebookFilter) ([]models.Notebook, error) {
+	ctx, done := startDao(ctx, "NotebookDao.GetNotebooks")
+	notebooks, err := d.Next.GetNotebooks(ctx, filter)
+	return notebooks, done(err)
+}
+
+func (d *NotebookDao) CreateNotebook(ctx context.Context, notebook models.Notebook) error {
+	ctx, done := startDao(ctx, "NotebookDao.CreateNotebook")
+	return done(d.Next.CreateNotebook(ctx, notebook))
+}
+
+func (d *NotebookDao) UpdateNotebook(ctx context.Context, filter dao.NotebookFilter, patch dao.NotebookPatch) error {
+	ctx, done := startDao(ctx, "NotebookDao.UpdateNotebook")
+	return done(d.Next.UpdateNotebook(ctx, filter, patch))
+}
+
+func (d *NotebookDao) DeleteNotebooks(ctx context.Context, filter dao.NotebookFilter) (int64, error) {
+	ctx, done := startDao(ctx, "NotebookDao.DeleteNotebooks")
+	deleted, err := d.Next.DeleteNotebooks(ctx, filter)
+	return deleted, done(err)
+}
+
+// SaveJobDao is the dao.SaveJobDaoHandler equivalent of NoteDao.
+type SaveJobDao struct {
+	Next dao.SaveJobDaoHandler
+}
+
+func (d *SaveJobDao) CreateSaveJob(ctx context.Context, job models.SaveJob) error {
+	ctx, done := startDao(ctx, "SaveJobDao.CreateSaveJob")
+	return done(d.Next.CreateSaveJob(ctx, job))
+}
+
+func (d *SaveJobDao) CreateSaveJobIfNone(ctx context.Context, filter dao.SaveJobFilter, job models.SaveJob) (bool, error) {
+	ctx, done := startDao(ctx, "SaveJobDao.CreateSaveJobIfNone")
+	created, err := d.Next.CreateSaveJobIfNone(ctx, filter, job)
+	return created, done(err)
+}
+
+func (d *SaveJobDao) GetSaveJobs(ctx context.Context, filter dao.SaveJobFilter) ([]models.SaveJob, error) {
+	ctx, done := startDao(ctx, "SaveJobDao.GetSaveJobs")
+	jobs, err := d.Next.GetSaveJobs(ctx, filter)
+	return jobs, done(err)
+}
+
+func (d *SaveJobDao) ClaimSaveJob(ctx context.Context, now time.Time, leaseUntil time.Time) (*models.SaveJob, error) {
+	ctx, done := startDao(ctx, "SaveJobDao.ClaimSaveJob")
+	job, err := d.Next.ClaimSaveJob(ctx, now, leaseUntil)
+	return job, done(err)
+}
+
+func (d *SaveJobDao) UpdateSaveJob(ctx context.Context, filter dao.SaveJobFilter, patch dao.SaveJobPatch) error {
+	ctx, done := startDao(ctx, "SaveJobDao.UpdateSaveJob")
+	return done(d.Next.UpdateSaveJob(ctx, filter, patch))
+}
@@ -0,0 +1,76 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+
+	"notes-api/pkg/external"
+	"notes-api/pkg/models"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ExtAPI is an external.ExtAPIHandler that wraps every call of the one it wraps in a span.
+type ExtAPI struct {
+	Next external.ExtAPIHandler
+}
+
+func (e *ExtAPI) ValidateToken(ctx context.Context, token string) (*models.Principal, error) {
+	ctx, done := start(ctx, "ExtAPI.ValidateToken")
+	principal, err := e.Next.ValidateToken(ctx, token)
+	return principal, done(err)
+}
+
+func (e *ExtAPI) SendToContentService(ctx context.Context, token string, body bytes.Buffer, contentType string) (*models.UploadedFile, error) {
+	ctx, done := start(ctx, "ExtAPI.SendToContentService")
+	file, err := e.Next.SendToContentService(ctx, token, body, contentType)
+	return file, done(err)
+}
+
+func (e *ExtAPI) ListContentFiles(ctx context.Context, token string) ([]models.ContentFile, error) {
+	ctx, done := start(ctx, "ExtAPI.ListContentFiles")
+	files, err := e.Next.ListContentFiles(ctx, token)
+	return files, done(err)
+}
+
+func (e *ExtAPI) DownloadContentFile(ctx context.Context, token string, id string) ([]byte, error) {
+	ctx, done := start(ctx, "ExtAPI.DownloadContentFile")
+	content, err := e.Next.DownloadContentFile(ctx, token, id)
+	return content, done(err)
+}
+
+func (e *ExtAPI) DeleteContentFile(ctx context.Context, token string, id string) error {
+	ctx, done := start(ctx, "ExtAPI.DeleteContentFile")
+	return done(e.Next.DeleteContentFile(ctx, token, id))
+}
+
+// Requester is an external.Requester that sends each request in a client span, with the trace context of the span in
+// the W3C traceparent and tracestate headers, so that the services called carry on the trace of the request.
+type Requester struct {
+	Next external.Requester
+}
+
+func (c *Requester) Do(r *http.Request) (*http.Response, error) {
+	ctx, span := Tracer().Start(r.Context(), "HTTP "+r.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.HTTPClientAttributesFromHTTPRequest(r)...),
+	)
+	defer span.End()
+
+	r = r.Clone(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(r.Header))
+
+	res, err := c.Next.Do(r)
+	if err != nil {
+		recordError(span, err)
+		return nil, err
+	}
+
+	span.SetAttributes(semconv.HTTPAttributesFromHTTPStatusCode(res.StatusCode)...)
+	span.SetStatus(semconv.SpanStatusFromHTTPStatusCode(res.StatusCode))
+	return res, nil
+}
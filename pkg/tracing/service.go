@@ -0,0 +1,167 @@
+package tracing
+
+import (
+	"context"
+
+	"notes-api/pkg/models"
+	"notes-api/pkg/service"
+)
+
+// NoteService is a service.NoteServiceHandler that wraps every call to the one it wraps in a span.
+type NoteService struct {
+	Next service.NoteServiceHandler
+}
+
+func (s *NoteService) Ping(ctx context.Context) error {
+	ctx, done := start(ctx, "NotesService.Ping")
+	return done(s.Next.Ping(ctx))
+}
+
+func (s *NoteService) GetNotes(ctx context.Context, userID string, id string) ([]models.Note, error) {
+	ctx, done := start(ctx, "NotesService.GetNotes")
+	result, err := s.Next.GetNotes(ctx, userID, id)
+	return result, done(err)
+}
+
+func (s *NoteService) ListNotes(ctx context.Context, userID string, query models.NoteQuery) (*models.NotePage, error) {
+	ctx, done := start(ctx, "NotesService.ListNotes")
+	result, err := s.Next.ListNotes(ctx, userID, query)
+	return result, done(err)
+}
+
+func (s *NoteService) SearchNotes(ctx context.Context, userID string, query models.SearchQuery) (*models.SearchPage, error) {
+	ctx, done := start(ctx, "NotesService.SearchNotes")
+	result, err := s.Next.SearchNotes(ctx, userID, query)
+	return result, done(err)
+}
+
+func (s *NoteService) UpdateNote(ctx context.Context, userID string, token string, id string, version int64, noteRequest models.NoteRequest) (int64, error) {
+	ctx, done := start(ctx, "NotesService.UpdateNote")
+	result, err := s.Next.UpdateNote(ctx, userID, token, id, version, noteRequest)
+	return result, done(err)
+}
+
+func (s *NoteService) DeleteNote(ctx context.Context, userID string, id string) error {
+	ctx, done := start(ctx, "NotesService.DeleteNote")
+	return done(s.Next.DeleteNote(ctx, userID, id))
+}
+
+func (s *NoteService) GetTrash(ctx context.Context, userID string) ([]models.Note, error) {
+	ctx, done := start(ctx, "NotesService.GetTrash")
+	result, err := s.Next.GetTrash(ctx, userID)
+	return result, done(err)
+}
+
+func (s *NoteService) RestoreFromTrash(ctx context.Context, userID string, id string) error {
+	ctx, done := start(ctx, "NotesService.RestoreFromTrash")
+	return done(s.Next.RestoreFromTrash(ctx, userID, id))
+}
+
+func (s *NoteService) PurgeNote(ctx context.Context, userID string, id string) error {
+	ctx, done := start(ctx, "NotesService.PurgeNote")
+	return done(s.Next.PurgeNote(ctx, userID, id))
+}
+
+func (s *NoteService) CreateNote(ctx context.Context, userID string, noteRequest models.NoteRequest) (string, error) {
+	ctx, done := start(ctx, "NotesService.CreateNote")
+	result, err := s.Next.CreateNote(ctx, userID, noteRequest)
+	return result, done(err)
+}
+
+func (s *NoteService) SendToContentService(ctx context.Context, userID string, token string, id string, format string) (*models.SaveJob, error) {
+	ctx, done := start(ctx, "NotesService.SendToContentService")
+	result, err := s.Next.SendToContentService(ctx, userID, token, id, format)
+	return result, done(err)
+}
+
+func (s *NoteService) GetSaveJob(ctx context.Context, userID string, id string) (*models.SaveJob, error) {
+	ctx, done := start(ctx, "NotesService.GetSaveJob")
+	result, err := s.Next.GetSaveJob(ctx, userID, id)
+	return result, done(err)
+}
+
+func (s *NoteService) ListContentFiles(ctx context.Context, token string) ([]models.ContentFile, error) {
+	ctx, done := start(ctx, "NotesService.ListContentFiles")
+	result, err := s.Next.ListContentFiles(ctx, token)
+	return result, done(err)
+}
+
+func (s *NoteService) ImportNotes(ctx context.Context, userID string, token string, request models.ImportRequest) (*models.ImportResult, error) {
+	ctx, done := start(ctx, "NotesService.ImportNotes")
+	result, err := s.Next.ImportNotes(ctx, userID, token, request)
+	return result, done(err)
+}
+
+func (s *NoteService) ValidateToken(ctx context.Context, token string) (*models.Principal, error) {
+	ctx, done := start(ctx, "NotesService.ValidateToken")
+	result, err := s.Next.ValidateToken(ctx, token)
+	return result, done(err)
+}
+
+func (s *NoteService) GetTags(ctx context.Context, userID string) ([]models.TagCount, error) {
+	ctx, done := start(ctx, "NotesService.GetTags")
+	result, err := s.Next.GetTags(ctx, userID)
+	return result, done(err)
+}
+
+func (s *NoteService) RenameTag(ctx context.Context, userID string, oldName string, newName string) (int64, error) {
+	ctx, done := start(ctx, "NotesService.RenameTag")
+	result, err := s.Next.RenameTag(ctx, userID, oldName, newName)
+	return result, done(err)
+}
+
+func (s *NoteService) MoveNote(ctx context.Context, userID string, id string, notebookID string) error {
+	ctx, done := start(ctx, "NotesService.MoveNote")
+	return done(s.Next.MoveNote(ctx, userID, id, notebookID))
+}
+
+func (s *NoteService) GetNotebooks(ctx context.Context, userID string) ([]models.Notebook, error) {
+	ctx, done := start(ctx, "NotesService.GetNotebooks")
+	result, err := s.Next.GetNotebooks(ctx, userID)
+	return result, done(err)
+}
+
+func (s *NoteService) GetNotebook(ctx context.Context, userID string, id string) (*models.Notebook, error) {
+	ctx, done := start(ctx, "NotesService.GetNotebook")
+	result, err := s.Next.GetNotebook(ctx, userID, id)
+	return result, done(err)
+}
+
+func (s *NoteService) CreateNotebook(ctx context.Context, userID string, notebookRequest models.NotebookRequest) (string, error) {
+	ctx, done := start(ctx, "NotesService.CreateNotebook")
+	result, err := s.Next.CreateNotebook(ctx, userID, notebookRequest)
+	return result, done(err)
+}
+
+func (s *NoteService) UpdateNotebook(ctx context.Context, userID string, id string, notebookRequest models.NotebookRequest) error {
+	ctx, done := start(ctx, "NotesService.UpdateNotebook")
+	return done(s.Next.UpdateNotebook(ctx, userID, id, notebookRequest))
+}
+
+func (s *NoteService) MoveNotebook(ctx context.Context, userID string, id string, parentID string) error {
+	ctx, done := start(ctx, "NotesService.MoveNotebook")
+	return done(s.Next.MoveNotebook(ctx, userID, id, parentID))
+}
+
+func (s *NoteService) DeleteNotebook(ctx context.Context, userID string, id string, cascade bool) error {
+	ctx, done := start(ctx, "NotesService.DeleteNotebook")
+	return done(s.Next.DeleteNotebook(ctx, userID, id, cascade))
+}
+
+func (s *NoteService) GetRevisions(ctx context.Context, userID string, noteID string) ([]models.Revision, error) {
+	ctx, done := start(ctx, "NotesService.GetRevisions")
+	result, err := s.Next.GetRevisions(ctx, userID, noteID)
+	return result, done(err)
+}
+
+func (s *NoteService) GetRevision(ctx context.Context, userID string, noteID string, revisionID string) (*models.Revision, error) {
+	ctx, done := start(ctx, "NotesService.GetRevision")
+	result, err := s.Next.GetRevision(ctx, userID, noteID, revisionID)
+	return result, done(err)
+}
+
+func (s *NoteService) RestoreRevision(ctx context.Context, userID string, token string, noteID string, revisionID string, version int64) (int64, error) {
+	ctx, done := start(ctx, "NotesService.RestoreRevision")
+	result, err := s.Next.RestoreRevision(ctx, userID, token, noteID, revisionID, version)
+	return result, done(err)
+}
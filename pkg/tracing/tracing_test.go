@@ -0,0 +1,128 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"notes-api/pkg/config"
+	"notes-api/pkg/dao"
+	"notes-api/pkg/testhelper/mocks"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recordSpans sends the spans started during a test to the returned recorder.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+	return recorder
+}
+
+func TestTracing_Requester_ShouldPropagateTraceContext(t *testing.T) {
+	recorder := recordSpans(t)
+
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	ctx, parent := Tracer().Start(context.Background(), "parent")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/files", nil)
+	require.Nil(t, err)
+
+	res, err := (&Requester{Next: server.Client()}).Do(req)
+	require.Nil(t, err)
+	res.Body.Close()
+	parent.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	client := spans[0]
+	require.Equal(t, "HTTP GET", client.Name())
+	require.Equal(t, trace.SpanKindClient, client.SpanKind())
+	require.Equal(t, parent.SpanContext().SpanID(), client.Parent().SpanID())
+	require.Equal(t, codes.Error, client.Status().Code)
+	require.Equal(t, "00-"+client.SpanContext().TraceID().String()+"-"+client.SpanContext().SpanID().String()+"-01", traceparent)
+}
+
+func TestTracing_NoteDao_ShouldNotMarkNotFoundAsError(t *testing.T) {
+	recorder := recordSpans(t)
+
+	mockDao := &mocks.NoteDaoHandler{}
+	mockDao.On("DeleteNote", mock.Anything, mock.Anything).Return(dao.ErrNotFound).Once()
+	mockDao.On("DeleteNote", mock.Anything, mock.Anything).Return(errors.New("connection refused")).Once()
+
+	traced := &NoteDao{Next: mockDao}
+	require.Equal(t, dao.ErrNotFound, traced.DeleteNote(context.TODO(), dao.NoteFilter{}))
+	require.NotNil(t, traced.DeleteNote(context.TODO(), dao.NoteFilter{}))
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	require.Equal(t, "NoteDao.DeleteNote", spans[0].Name())
+	require.Equal(t, codes.Unset, spans[0].Status().Code)
+	require.Equal(t, codes.Error, spans[1].Status().Code)
+	require.Equal(t, "connection refused", spans[1].Status().Description)
+}
+
+func TestTracing_NoteService_ShouldNestCallsInItsSpan(t *testing.T) {
+	recorder := recordSpans(t)
+
+	mockSvc := &mocks.NoteServiceHandler{}
+	mockSvc.On("GetTags", mock.Anything, "user").Return(nil, nil).Run(func(args mock.Arguments) {
+		_, span := Tracer().Start(args.Get(0).(context.Context), "inner")
+		span.End()
+	})
+
+	_, err := (&NoteService{Next: mockSvc}).GetTags(context.TODO(), "user")
+	require.Nil(t, err)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	require.Equal(t, "NotesService.GetTags", spans[1].Name())
+	require.Equal(t, spans[1].SpanContext().SpanID(), spans[0].Parent().SpanID())
+}
+
+func TestTracing_Setup_ShouldExportSpansToFile(t *testing.T) {
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+
+	path := filepath.Join(t.TempDir(), "spans.json")
+	shutdown, err := Setup(context.TODO(), config.TracingConfig{
+		Exporter:    config.TraceExporterStdout,
+		ServiceName: "notes-api-test",
+		File:        path,
+	})
+	require.Nil(t, err)
+
+	_, span := Tracer().Start(context.TODO(), "exported")
+	span.End()
+	require.Nil(t, shutdown(context.TODO()))
+
+	content, err := ioutil.ReadFile(path)
+	require.Nil(t, err)
+	require.Contains(t, string(content), `"Name":"exported"`)
+	require.Contains(t, string(content), "notes-api-test")
+}